@@ -24,7 +24,7 @@
   // Check if AuraBot app is running
   async function checkAppStatus() {
     try {
-      const response = await fetch(`${AURABOT_API_URL}/health`, {
+      const response = await fetch(`${AURABOT_API_URL}/api/v1/health`, {
         method: 'GET',
         headers: { 'Content-Type': 'application/json' }
       });
@@ -37,7 +37,7 @@
   // Enhance prompt via AuraBot API
   async function enhancePrompt(prompt) {
     try {
-      const response = await fetch(`${AURABOT_API_URL}/api/enhance`, {
+      const response = await fetch(`${AURABOT_API_URL}/api/v1/enhance`, {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify({
@@ -51,8 +51,8 @@
         throw new Error(`HTTP error! status: ${response.status}`);
       }
 
-      const data = await response.json();
-      return data;
+      const body = await response.json();
+      return body.data;
     } catch (error) {
       console.error('[AuraBot] Enhancement failed:', error);
       throw error;
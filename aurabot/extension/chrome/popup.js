@@ -9,15 +9,21 @@ async function checkStatus() {
   const error = document.getElementById('error');
 
   try {
-    const response = await fetch(`${AURABOT_API_URL}/health`, {
+    const response = await fetch(`${AURABOT_API_URL}/api/v1/health`, {
       method: 'GET',
       headers: { 'Content-Type': 'application/json' }
     });
 
     if (response.ok) {
-      const data = await response.json();
+      const body = await response.json();
+      const health = body.data || body;
+      const offline = Object.entries(health.dependencies || {})
+        .filter(([, dep]) => !dep.healthy)
+        .map(([name]) => name === 'llm' ? 'LLM' : name);
       statusDot.classList.add('online');
-      statusText.textContent = 'AuraBot is running';
+      statusText.textContent = offline.length
+        ? `AuraBot is running (${offline.join(', ')} offline)`
+        : 'AuraBot is running';
       content.style.display = 'block';
       error.style.display = 'none';
     } else {
@@ -2,12 +2,20 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 	"screen-memory-assistant/internal/config"
 	"screen-memory-assistant/internal/enhancer"
+	"screen-memory-assistant/internal/llm"
+	"screen-memory-assistant/internal/logging"
 	"screen-memory-assistant/internal/memory"
 	"screen-memory-assistant/internal/quickenhance"
 	"screen-memory-assistant/internal/server"
@@ -16,12 +24,27 @@ import (
 
 // App struct
 type App struct {
-	ctx           context.Context
-	service       *service.Service
-	config        *config.Config
-	enhancer      *enhancer.Enhancer
-	apiServer     *server.Server
-	quickEnhance  *quickenhance.QuickEnhance
+	ctx          context.Context
+	service      *service.Service
+	config       *config.Config
+	configMu     sync.RWMutex
+	enhancer     *enhancer.Enhancer
+	apiServer    *server.Server
+	quickEnhance *quickenhance.QuickEnhance
+
+	// Cancels the daily summary being streamed, if any
+	summaryMu     sync.Mutex
+	summaryCancel context.CancelFunc
+
+	// Stops the service; serviceDone closes once it has drained
+	serviceCancel context.CancelFunc
+	serviceDone   chan struct{}
+
+	// Cleanup runs once, whether a signal or the Wails hook comes first
+	shutdownOnce sync.Once
+
+	// dryRun (--dry-run) turns on App.DryRun whatever the config says
+	dryRun bool
 }
 
 // NewApp creates a new App application struct
@@ -39,32 +62,107 @@ func (a *App) Startup(ctx context.Context) {
 		fmt.Printf("Failed to load config: %v\n", err)
 		return
 	}
+	if a.dryRun {
+		cfg.App.DryRun = true
+	}
 	a.config = cfg
 
+	// Leveled logging; remaining log.Printf calls go through it too
+	logger := logging.New(os.Stderr, &cfg.App)
+	slog.SetDefault(logger)
+
 	// Create service instance
 	svc, err := service.New(cfg)
 	if err != nil {
 		fmt.Printf("Failed to create service: %v\n", err)
 		return
 	}
+	svc.SetLogger(logger)
+	if cfg.App.DryRun {
+		svc.SetDryRunOutput(os.Stdout)
+	}
+	// Edits to config.yaml reach the settings page too
+	svc.SetOnConfigReload(func(cfg *config.Config) {
+		a.configMu.Lock()
+		a.config = cfg
+		a.configMu.Unlock()
+	})
 	a.service = svc
 
-	// Create memory store for enhancer
-	memoryStore := memory.NewStore(&cfg.Memory)
+	// Create memory store for enhancer; only it may search across users,
+	// and only through the token-guarded admin endpoint
+	memoryStore, err := memory.Open(&cfg.Memory)
+	if err != nil {
+		fmt.Printf("Failed to create memory store: %v\n", err)
+		return
+	}
+	memoryStore.SetAdmin(cfg.Extension.Enabled && cfg.Extension.AdminToken != "")
+	memoryStore.SetLogger(logger)
 
 	// Create enhancer
 	a.enhancer = enhancer.New(memoryStore)
+	a.enhancer.SetLogger(logger)
+	a.enhancer.SetRequireStrong(cfg.Enhancer.RequireStrong)
+	a.enhancer.SetFailOpen(cfg.Enhancer.FailOpen)
+	a.enhancer.SetRelevance(enhancer.RelevanceOptions{
+		Threshold:        cfg.Enhancer.HighRelevanceThreshold,
+		MaxHighRelevance: cfg.Enhancer.MaxHighRelevance,
+		MaxContextual:    cfg.Enhancer.MaxContextual,
+	})
+	a.enhancer.SetMaxMemoryChars(cfg.Enhancer.MaxMemoryChars)
+	a.enhancer.SetMaxPromptTokens(cfg.Enhancer.MaxPromptTokens)
+	if cfg.Enhancer.IncludeProfile {
+		a.enhancer.SetProfile(svc.ProfileSummary)
+	}
+	if cfg.Enhancer.StoreEnhancements {
+		a.enhancer.SetHistory(&enhancer.HistoryOptions{
+			Prompts: cfg.Enhancer.StoreEnhancementPrompts,
+			Sites:   cfg.Enhancer.StoreEnhancementSites,
+		})
+	}
+	if cfg.Enhancer.IncludeNow {
+		a.enhancer.SetIncludeNow(cfg.Enhancer.NowPosition, cfg.Enhancer.NowFormat)
+	}
+	if len(cfg.Enhancer.FetchAllowedHosts) > 0 {
+		a.enhancer.SetContextFetcher(enhancer.NewContextFetcher(cfg.Enhancer.FetchAllowedHosts,
+			time.Duration(cfg.Enhancer.FetchTimeoutMs)*time.Millisecond))
+	}
+	if err := a.enhancer.UseReranker(cfg.Enhancer.Reranker); err != nil {
+		fmt.Printf("Invalid reranker, keeping search order: %v\n", err)
+	}
+	if cfg.Enhancer.Rerank {
+		a.enhancer.SetEmbedder(llm.NewClient(&cfg.LLM))
+	}
 
 	// Start API server for browser extension
 	if cfg.Extension.Enabled {
 		a.apiServer = server.New(a.enhancer, cfg.Extension.Port)
+		a.apiServer.SetMetricsSource(svc.WriteMetrics)
+		a.apiServer.SetChatSource(svc)
+		a.apiServer.SetHealthSource(func() (string, interface{}) {
+			report := svc.HealthReport()
+			return report.Status, report.Dependencies
+		})
+		a.apiServer.SetWebUI(cfg.Extension.WebUI)
+		a.apiServer.SetAdminToken(cfg.Extension.AdminToken)
+		a.apiServer.SetAPIKey(cfg.Extension.APIKey)
+		a.apiServer.SetBindAddress(cfg.Extension.BindAddress)
+		a.apiServer.SetRateLimit(cfg.Extension.RateLimitPerSec)
 		if err := a.apiServer.Start(); err != nil {
 			fmt.Printf("Failed to start extension API server: %v\n", err)
 		}
 	}
 
 	// Initialize quick enhance (global hotkey)
-	a.quickEnhance = quickenhance.New(a.enhancer)
+	hotkeys, err := quickenhance.ParseHotkeyConfig(cfg.QuickEnhance.Hotkeys, cfg.QuickEnhance.HotkeyAttempts,
+		time.Duration(cfg.QuickEnhance.HotkeyBackoffMs)*time.Millisecond)
+	if err != nil {
+		fmt.Printf("Invalid quick enhance hotkeys, using defaults: %v\n", err)
+		hotkeys = quickenhance.DefaultHotkeyConfig()
+	}
+	a.quickEnhance = quickenhance.New(a.enhancer, hotkeys)
+	a.quickEnhance.SetTimeout(time.Duration(cfg.QuickEnhance.TimeoutSeconds) * time.Second)
+	a.quickEnhance.SetOverlayConfig(cfg.QuickEnhance.Overlay)
 	a.quickEnhance.SetCallback(func(text string) {
 		// When hotkey pressed, emit event to frontend
 		// Frontend will show the quick enhance dialog
@@ -74,44 +172,95 @@ func (a *App) Startup(ctx context.Context) {
 			})
 		}
 	})
-	
+
+	// Images on the clipboard are analyzed with the vision model instead
+	a.quickEnhance.SetImageAnalyzer(llm.NewClient(&cfg.LLM), func(result *llm.AnalysisResult) {
+		content := fmt.Sprintf("%s | Context: %s", result.Summary, result.Context)
+		if _, err := a.enhancer.AddMemory(content, result.Context, memory.SourceClipboard); err != nil {
+			fmt.Printf("Failed to store clipboard image memory: %v\n", err)
+		}
+		if a.ctx != nil {
+			runtime.EventsEmit(a.ctx, "quickenhance:image-analyzed", result)
+		}
+	})
+
 	if err := a.quickEnhance.Start(); err != nil {
 		fmt.Printf("Failed to start quick enhance: %v\n", err)
+		// Let the user know why the hotkey does nothing
+		if errors.Is(err, quickenhance.ErrHotkeyUnavailable) {
+			runtime.EventsEmit(a.ctx, "quickenhance:hotkey-unavailable", err.Error())
+		}
 	}
 
 	// Start service in background
+	serviceCtx, cancel := context.WithCancel(context.Background())
+	a.serviceCancel = cancel
+	a.serviceDone = make(chan struct{})
 	go func() {
-		serviceCtx, cancel := context.WithCancel(context.Background())
-		defer cancel()
+		defer close(a.serviceDone)
 		if err := svc.Run(serviceCtx); err != nil {
 			fmt.Printf("Service error: %v\n", err)
 		}
 	}()
+
+	if cfg.App.HandleSignals {
+		a.handleSignals()
+	}
 }
 
-// Shutdown is called when the app shuts down
+// handleSignals runs the shutdown cleanup on SIGINT/SIGTERM (e.g. from a
+// service manager) and then asks Wails to quit
+func (a *App) handleSignals() {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		signal.Stop(sigChan)
+		fmt.Println("Shutting down...")
+		a.Shutdown(a.ctx)
+		runtime.Quit(a.ctx)
+	}()
+}
+
+// Shutdown is called when the app shuts down. It is safe to call more
+// than once; only the first call does anything.
 func (a *App) Shutdown(ctx context.Context) {
+	a.shutdownOnce.Do(a.cleanup)
+}
+
+// cleanup stops quick enhance, the API server and the service
+func (a *App) cleanup() {
 	// Shutdown quick enhance
 	if a.quickEnhance != nil {
 		a.quickEnhance.Stop()
 	}
-	
+
 	// Shutdown API server
 	if a.apiServer != nil {
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 		a.apiServer.Stop(shutdownCtx)
 	}
+
+	// Drain the service so pending events and webhooks are flushed
+	if a.serviceCancel != nil {
+		a.serviceCancel()
+		select {
+		case <-a.serviceDone:
+		case <-time.After(10 * time.Second):
+			fmt.Println("Timed out waiting for the service to stop")
+		}
+	}
 }
 
 // GetStatus returns the current service status
 func (a *App) GetStatus() map[string]interface{} {
 	if a.service == nil {
 		return map[string]interface{}{
-			"running":    false,
-			"platform":   "unknown",
-			"lastState":  "Service not initialized",
-			"extension":  a.getExtensionStatus(),
+			"running":   false,
+			"platform":  "unknown",
+			"lastState": "Service not initialized",
+			"extension": a.getExtensionStatus(),
 			"quickEnhance": map[string]bool{
 				"running": a.quickEnhance != nil,
 			},
@@ -128,90 +277,172 @@ func (a *App) GetStatus() map[string]interface{} {
 
 // getExtensionStatus returns extension server status
 func (a *App) getExtensionStatus() map[string]interface{} {
-	if a.config == nil {
+	cfg := a.currentConfig()
+	if cfg == nil {
 		return map[string]interface{}{"enabled": false}
 	}
 	return map[string]interface{}{
-		"enabled": a.config.Extension.Enabled,
-		"port":    a.config.Extension.Port,
+		"enabled": cfg.Extension.Enabled,
+		"port":    cfg.Extension.Port,
 		"running": a.apiServer != nil,
 	}
 }
 
-// Chat sends a message and returns the response
-func (a *App) Chat(message string) (string, error) {
+// Chat sends a message and returns the response, including which backend
+// answered and whether it was a degraded local fallback. The LLM client
+// times each backend out on its own, so no overall deadline is added here.
+func (a *App) Chat(message string) (*llm.ChatResponse, error) {
+	if a.service == nil {
+		return nil, fmt.Errorf("service not initialized")
+	}
+
+	return a.service.Chat(a.ctx, message)
+}
+
+// DailySummary summarizes today's memories, emitting "summary:chunk"
+// events as the text streams in. With store set the summary is saved as
+// a memory.
+func (a *App) DailySummary(store bool) (string, error) {
 	if a.service == nil {
 		return "", fmt.Errorf("service not initialized")
 	}
 
-	ctx, cancel := context.WithTimeout(a.ctx, 30*time.Second)
+	ctx, cancel := context.WithCancel(a.ctx)
+	a.summaryMu.Lock()
+	if a.summaryCancel != nil {
+		a.summaryCancel()
+	}
+	a.summaryCancel = cancel
+	a.summaryMu.Unlock()
 	defer cancel()
 
-	return a.service.Chat(ctx, message)
+	return a.service.DailySummary(ctx, time.Now(), store, func(chunk string) {
+		runtime.EventsEmit(a.ctx, "summary:chunk", chunk)
+	})
+}
+
+// CancelDailySummary stops a daily summary that is still streaming
+func (a *App) CancelDailySummary() bool {
+	a.summaryMu.Lock()
+	defer a.summaryMu.Unlock()
+	if a.summaryCancel == nil {
+		return false
+	}
+	a.summaryCancel()
+	a.summaryCancel = nil
+	return true
 }
 
 // GetConfig returns the current configuration
 func (a *App) GetConfig() map[string]interface{} {
-	if a.config == nil {
+	cfg := a.currentConfig()
+	if cfg == nil {
 		return map[string]interface{}{}
 	}
 
 	return map[string]interface{}{
 		"capture": map[string]interface{}{
-			"intervalSeconds": a.config.Capture.IntervalSeconds,
-			"quality":         a.config.Capture.Quality,
-			"maxWidth":        a.config.Capture.MaxWidth,
-			"maxHeight":       a.config.Capture.MaxHeight,
-			"enabled":         a.config.Capture.Enabled,
+			"intervalSeconds": cfg.Capture.IntervalSeconds,
+			"quality":         cfg.Capture.Quality,
+			"maxWidth":        cfg.Capture.MaxWidth,
+			"maxHeight":       cfg.Capture.MaxHeight,
+			"enabled":         cfg.Capture.Enabled,
 		},
 		"llm": map[string]interface{}{
-			"baseUrl":        a.config.LLM.BaseURL,
-			"model":          a.config.LLM.Model,
-			"maxTokens":      a.config.LLM.MaxTokens,
-			"temperature":    a.config.LLM.Temperature,
-			"timeoutSeconds": a.config.LLM.TimeoutSeconds,
+			"baseUrl":        cfg.LLM.BaseURL,
+			"model":          cfg.LLM.Model,
+			"maxTokens":      cfg.LLM.MaxTokens,
+			"temperature":    cfg.LLM.Temperature,
+			"timeoutSeconds": cfg.LLM.TimeoutSeconds,
 		},
 		"memory": map[string]interface{}{
-			"baseUrl":        a.config.Memory.BaseURL,
-			"userId":         a.config.Memory.UserID,
-			"collectionName": a.config.Memory.CollectionName,
+			"baseUrl":        cfg.Memory.BaseURL,
+			"userId":         cfg.Memory.UserID,
+			"collectionName": cfg.Memory.CollectionName,
 		},
 		"app": map[string]interface{}{
-			"verbose":          a.config.App.Verbose,
-			"processOnCapture": a.config.App.ProcessOnCapture,
-			"memoryWindow":     a.config.App.MemoryWindow,
+			"verbose":          cfg.App.Verbose,
+			"processOnCapture": cfg.App.ProcessOnCapture,
+			"memoryWindow":     cfg.App.MemoryWindow,
 		},
 		"extension": map[string]interface{}{
-			"enabled": a.config.Extension.Enabled,
-			"port":    a.config.Extension.Port,
+			"enabled": cfg.Extension.Enabled,
+			"port":    cfg.Extension.Port,
 		},
 		"quickEnhance": map[string]interface{}{
-			"hotkey": "Ctrl+Alt+E",
+			// The combination that registered; empty when none did
+			"hotkey":  a.registeredHotkey(),
+			"hotkeys": cfg.QuickEnhance.Hotkeys,
 		},
 	}
 }
 
-// UpdateConfig updates configuration values
+// registeredHotkey returns the quick enhance hotkey in use, or ""
+func (a *App) registeredHotkey() string {
+	if a.quickEnhance == nil {
+		return ""
+	}
+	return a.quickEnhance.Hotkey()
+}
+
+// UpdateConfig updates configuration values. The update is applied to a
+// copy which is saved and then swapped in, so concurrent readers never see
+// a partially updated config.
 func (a *App) UpdateConfig(updates map[string]interface{}) error {
+	a.configMu.Lock()
+	defer a.configMu.Unlock()
+
 	if a.config == nil {
 		return fmt.Errorf("config not initialized")
 	}
 
+	next := *a.config
+
 	// Update capture settings
 	if capture, ok := updates["capture"].(map[string]interface{}); ok {
 		if v, ok := capture["intervalSeconds"].(float64); ok {
-			a.config.Capture.IntervalSeconds = int(v)
+			next.Capture.IntervalSeconds = int(v)
 		}
 		if v, ok := capture["quality"].(float64); ok {
-			a.config.Capture.Quality = int(v)
+			next.Capture.Quality = int(v)
 		}
 		if v, ok := capture["enabled"].(bool); ok {
-			a.config.Capture.Enabled = v
+			next.Capture.Enabled = v
 		}
 	}
 
-	// Save config to file
-	return a.config.Save("config.yaml")
+	if err := next.Validate(); err != nil {
+		return err
+	}
+
+	// Save config to file before applying it
+	if err := next.Save("config.yaml"); err != nil {
+		return err
+	}
+
+	a.applyConfig(&next)
+	return nil
+}
+
+// currentConfig returns the active configuration snapshot
+func (a *App) currentConfig() *config.Config {
+	a.configMu.RLock()
+	defer a.configMu.RUnlock()
+	return a.config
+}
+
+// applyConfig swaps in a new config; callers must hold configMu
+func (a *App) applyConfig(cfg *config.Config) {
+	a.config = cfg
+	if a.service != nil {
+		a.service.UpdateConfig(cfg)
+		// Pause or resume the running capture loop; no restart needed
+		if cfg.Capture.Enabled {
+			a.service.Resume()
+		} else {
+			a.service.Pause()
+		}
+	}
 }
 
 // GetMemories returns recent memories
@@ -219,7 +450,7 @@ func (a *App) GetMemories(limit int) ([]enhancer.MemoryInfo, error) {
 	if a.enhancer == nil {
 		return nil, fmt.Errorf("enhancer not initialized")
 	}
-	return a.enhancer.GetRecentMemories(limit)
+	return a.enhancer.GetRecentMemories("", limit)
 }
 
 // SearchMemories searches memories by query
@@ -229,7 +460,26 @@ func (a *App) SearchMemories(query string, limit int) ([]enhancer.MemoryInfo, er
 	}
 	ctx, cancel := context.WithTimeout(a.ctx, 10*time.Second)
 	defer cancel()
-	return a.enhancer.SearchMemories(ctx, query, limit)
+	return a.enhancer.SearchMemories(ctx, query, "", limit)
+}
+
+// GetMemoriesBySource returns recent memories from one source
+// ("capture", "manual", "extension", "clipboard" or "enhancement")
+func (a *App) GetMemoriesBySource(source string, limit int) ([]enhancer.MemoryInfo, error) {
+	if a.enhancer == nil {
+		return nil, fmt.Errorf("enhancer not initialized")
+	}
+	return a.enhancer.GetRecentMemories(source, limit)
+}
+
+// SearchMemoriesBySource searches memories from one source by query
+func (a *App) SearchMemoriesBySource(query, source string, limit int) ([]enhancer.MemoryInfo, error) {
+	if a.enhancer == nil {
+		return nil, fmt.Errorf("enhancer not initialized")
+	}
+	ctx, cancel := context.WithTimeout(a.ctx, 10*time.Second)
+	defer cancel()
+	return a.enhancer.SearchMemories(ctx, query, source, limit)
 }
 
 // EnhancePrompt enhances a prompt with memories
@@ -244,9 +494,20 @@ func (a *App) EnhancePrompt(prompt string, pageContext string) (*enhancer.Enhanc
 
 // QuickEnhanceText enhances text and returns result (called from frontend)
 func (a *App) QuickEnhanceText(text string) (*enhancer.EnhancementResult, error) {
+	if a.quickEnhance != nil {
+		return a.quickEnhance.EnhancePrompt(text)
+	}
 	return a.EnhancePrompt(text, "")
 }
 
+// CancelQuickEnhance aborts an in-flight quick enhancement
+func (a *App) CancelQuickEnhance() bool {
+	if a.quickEnhance == nil {
+		return false
+	}
+	return a.quickEnhance.CancelEnhancement()
+}
+
 // PasteEnhanced pastes enhanced text to active window
 func (a *App) PasteEnhanced(text string) error {
 	if a.quickEnhance != nil {
@@ -256,13 +517,44 @@ func (a *App) PasteEnhanced(text string) error {
 	return fmt.Errorf("quick enhance not initialized")
 }
 
+// GetProfile returns the user profile built from recent memories
+func (a *App) GetProfile() (service.Profile, error) {
+	if a.service == nil {
+		return service.Profile{}, fmt.Errorf("service not initialized")
+	}
+	return a.service.GetProfile(), nil
+}
+
 // ToggleCapture enables/disables screen capture
 func (a *App) ToggleCapture(enabled bool) bool {
+	a.configMu.Lock()
+	defer a.configMu.Unlock()
+
 	if a.config == nil {
 		return false
 	}
-	a.config.Capture.Enabled = enabled
-	return a.config.Capture.Enabled
+	next := *a.config
+	next.Capture.Enabled = enabled
+	a.applyConfig(&next)
+	return next.Capture.Enabled
+}
+
+// GetHealth reports whether the LLM and memory backend are reachable,
+// so the UI can show e.g. "LLM offline" while the app keeps running
+func (a *App) GetHealth() (service.HealthReport, error) {
+	if a.service == nil {
+		return service.HealthReport{}, fmt.Errorf("service not initialized")
+	}
+	return a.service.HealthReport(), nil
+}
+
+// CaptureNow captures, analyzes and stores the screen right away, e.g.
+// for a "remember this screen" button, and returns the analysis
+func (a *App) CaptureNow() (*llm.AnalysisResult, error) {
+	if a.service == nil {
+		return nil, fmt.Errorf("service not initialized")
+	}
+	return a.service.CaptureNow(a.ctx)
 }
 
 // TriggerQuickEnhance manually triggers quick enhance (for UI button)
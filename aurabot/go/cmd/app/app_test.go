@@ -1,7 +1,15 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+
+	"screen-memory-assistant/internal/config"
+	"screen-memory-assistant/internal/enhancer"
+	"screen-memory-assistant/internal/memory"
 )
 
 func TestNewApp(t *testing.T) {
@@ -71,8 +79,8 @@ func TestAppChatBeforeStartup(t *testing.T) {
 		t.Error("Expected error when chatting before startup")
 	}
 
-	if response != "" {
-		t.Error("Expected empty response when service not initialized")
+	if response != nil {
+		t.Error("Expected no response when service not initialized")
 	}
 
 	expectedErr := "service not initialized"
@@ -84,26 +92,38 @@ func TestAppChatBeforeStartup(t *testing.T) {
 func TestAppGetMemories(t *testing.T) {
 	app := NewApp()
 
-	memories := app.GetMemories(10)
-	if memories == nil {
-		t.Fatal("GetMemories() returned nil")
+	// Test before startup - should return error
+	if memories, err := app.GetMemories(10); err == nil || memories != nil {
+		t.Errorf("GetMemories before startup = %v, %v; want an error", memories, err)
 	}
 
-	// Should return at least one mock memory
-	if len(memories) == 0 {
-		t.Error("Expected at least one mock memory")
+	mem0 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]interface{}{
+			{"id": "m1", "memory": "Reviewing the design doc", "created_at": "2026-10-18T09:00:00Z"},
+			{"id": "m2", "memory": "Answering email", "created_at": "2026-10-18T08:00:00Z"},
+		})
+	}))
+	defer mem0.Close()
+	app.enhancer = enhancer.New(memory.NewStore(&config.MemoryConfig{BaseURL: mem0.URL, CollectionName: "screen"}))
+
+	memories, err := app.GetMemories(10)
+	if err != nil {
+		t.Fatalf("GetMemories failed: %v", err)
+	}
+	if len(memories) != 2 {
+		t.Fatalf("got %d memories, want 2", len(memories))
 	}
 
 	// Check memory structure
 	for i, memory := range memories {
-		if _, ok := memory["id"]; !ok {
-			t.Errorf("Memory %d missing 'id' field", i)
+		if memory.ID == "" {
+			t.Errorf("Memory %d missing id", i)
 		}
-		if _, ok := memory["content"]; !ok {
-			t.Errorf("Memory %d missing 'content' field", i)
+		if memory.Content == "" {
+			t.Errorf("Memory %d missing content", i)
 		}
-		if _, ok := memory["timestamp"]; !ok {
-			t.Errorf("Memory %d missing 'timestamp' field", i)
+		if memory.Date.IsZero() {
+			t.Errorf("Memory %d missing timestamp", i)
 		}
 	}
 }
@@ -133,3 +153,23 @@ func TestAppUpdateConfigBeforeStartup(t *testing.T) {
 		t.Error("Expected error when updating config before startup")
 	}
 }
+
+func TestAppShutdownIdempotent(t *testing.T) {
+	app := NewApp()
+
+	cancels := 0
+	done := make(chan struct{})
+	app.serviceCancel = func() {
+		cancels++
+		close(done)
+	}
+	app.serviceDone = done
+
+	// Both a signal and the Wails hook may call Shutdown
+	app.Shutdown(context.Background())
+	app.Shutdown(context.Background())
+
+	if cancels != 1 {
+		t.Errorf("service cancelled %d times, want 1", cancels)
+	}
+}
@@ -1,7 +1,7 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
-export function Chat(arg1:string):Promise<string>;
+export function Chat(arg1:string):Promise<Record<string, any>>;
 
 export function GetConfig():Promise<Record<string, any>>;
 
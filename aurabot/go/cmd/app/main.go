@@ -2,6 +2,7 @@ package main
 
 import (
 	"embed"
+	"flag"
 	"fmt"
 
 	"github.com/wailsapp/wails/v2"
@@ -13,8 +14,12 @@ import (
 var assets embed.FS
 
 func main() {
+	dryRun := flag.Bool("dry-run", false, "analyze captures and print the results without storing them")
+	flag.Parse()
+
 	// Create an instance of the app structure
 	app := NewApp()
+	app.dryRun = *dryRun
 
 	// Create application with options
 	err := wails.Run(&options.App{
@@ -5,10 +5,15 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"log/slog"
 	"os"
+	"os/signal"
 	"strings"
+	"time"
 
 	"screen-memory-assistant/internal/config"
+	"screen-memory-assistant/internal/logging"
+	"screen-memory-assistant/internal/memory"
 	"screen-memory-assistant/internal/service"
 )
 
@@ -19,17 +24,29 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// Logs go to stderr, leaving stdout to the conversation
+	logger := logging.New(os.Stderr, &cfg.App)
+	slog.SetDefault(logger)
+
 	svc, err := service.New(cfg)
 	if err != nil {
 		log.Fatalf("Failed to create service: %v", err)
 	}
+	svc.SetLogger(logger)
 
-	ctx := context.Background()
+	store, err := memory.Open(&cfg.Memory)
+	if err != nil {
+		log.Fatalf("Failed to create memory store: %v", err)
+	}
+	store.SetLogger(logger)
 
 	fmt.Println("╔════════════════════════════════════════╗")
 	fmt.Println("║     Screen Memory Assistant Chat       ║")
 	fmt.Println("╚════════════════════════════════════════╝")
 	fmt.Println("Type 'exit' to quit, 'status' for info")
+	fmt.Println("Type 'search <window>: <query>' to search a time window (e.g. 'search yesterday: golang')")
+	fmt.Println("Type 'remember <text>' to store a note")
+	fmt.Println("Type 'summary' for a summary of today ('summary save' also stores it)")
 	fmt.Println()
 
 	scanner := bufio.NewScanner(os.Stdin)
@@ -45,7 +62,23 @@ func main() {
 			continue
 		}
 
-		switch strings.ToLower(input) {
+		lower := strings.ToLower(input)
+		if strings.HasPrefix(lower, "search ") {
+			searchRelative(store, strings.TrimSpace(input[len("search "):]))
+			continue
+		}
+		if strings.HasPrefix(lower, "remember ") {
+			if _, err := svc.AddNote(strings.TrimSpace(input[len("remember "):])); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				continue
+			}
+			fmt.Println("Noted.")
+			continue
+		}
+
+		switch lower {
+		case "summary", "summary save":
+			dailySummary(svc, lower == "summary save")
 		case "exit", "quit":
 			fmt.Println("Goodbye!")
 			return
@@ -55,12 +88,78 @@ func main() {
 			fmt.Printf("Platform: %v\n", status["platform"])
 			fmt.Printf("Last State: %v\n", status["last_state"])
 		default:
-			response, err := svc.Chat(ctx, input)
-			if err != nil {
-				fmt.Printf("Error: %v\n", err)
-				continue
-			}
-			fmt.Printf("Assistant: %s\n\n", response)
+			chat(svc, input)
+		}
+	}
+}
+
+// searchRelative handles "search <window>: <query>"
+func searchRelative(store memory.MemoryStore, args string) {
+	window, query, ok := strings.Cut(args, ":")
+	if !ok || strings.TrimSpace(query) == "" {
+		fmt.Println("Usage: search <window>: <query>")
+		return
+	}
+
+	results, err := store.SearchRelative(strings.TrimSpace(query), "", window, 10)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	if len(results) == 0 {
+		fmt.Println("No memories found")
+		return
+	}
+	for _, r := range results {
+		fmt.Printf("[%s] %s\n", r.Memory.Time().Format("2006-01-02 15:04"), r.Memory.Content)
+	}
+	fmt.Println()
+}
+
+// chat streams the answer to input to the terminal; Ctrl+C stops it
+func chat(svc *service.Service, input string) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	chunks, err := svc.ChatStream(ctx, input)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	fmt.Print("Assistant: ")
+	degraded := false
+	for chunk := range chunks {
+		if chunk.Err != nil {
+			fmt.Printf("\nError: %v", chunk.Err)
+			break
 		}
+		degraded = degraded || chunk.Degraded
+		fmt.Print(chunk.Text)
+	}
+	if degraded {
+		fmt.Print("\n(Answered by the local model; the chat provider was unavailable.)")
+	}
+	fmt.Print("\n\n")
+}
+
+// dailySummary streams today's summary to the terminal; Ctrl+C stops it
+func dailySummary(svc *service.Service, store bool) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Print("Summary: ")
+	_, err := svc.DailySummary(ctx, time.Now(), store, func(chunk string) {
+		fmt.Print(chunk)
+	})
+	fmt.Println()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
 	}
+	if store {
+		fmt.Println("Summary saved.")
+	}
+	fmt.Println()
 }
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package capture
+
+import "errors"
+
+// ActiveWindowTitle returns the title of the foreground window. It is only
+// implemented on Windows; elsewhere it always fails.
+func ActiveWindowTitle() (string, error) {
+	return "", errors.New("active window title is not supported on this platform")
+}
@@ -0,0 +1,20 @@
+package capture
+
+import (
+	"errors"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// ActiveWindowTitle returns the title of the foreground window
+func ActiveWindowTitle() (string, error) {
+	hwnd := windows.GetForegroundWindow()
+	if hwnd == 0 {
+		return "", errors.New("no foreground window")
+	}
+
+	var title [256]uint16
+	n, _, _ := procGetWindowTextW.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&title[0])), uintptr(len(title)))
+	return windows.UTF16ToString(title[:n]), nil
+}
@@ -5,84 +5,180 @@ import (
 	"fmt"
 	"image"
 	"image/jpeg"
+	"image/png"
+	"log"
 	"runtime"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/kbinani/screenshot"
 	"screen-memory-assistant/internal/config"
+	"screen-memory-assistant/internal/ratelog"
 )
 
-// resizeImage scales down image if it exceeds max dimensions while maintaining aspect ratio
-func resizeImage(img image.Image, maxWidth, maxHeight int) image.Image {
-	if maxWidth <= 0 || maxHeight <= 0 {
-		return img
-	}
-
-	bounds := img.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
-
-	// Check if resizing is needed
-	if width <= maxWidth && height <= maxHeight {
-		return img
-	}
-
-	// Calculate scaling factor to fit within bounds while maintaining aspect ratio
-	scaleX := float64(maxWidth) / float64(width)
-	scaleY := float64(maxHeight) / float64(height)
-	scale := scaleX
-	if scaleY < scaleX {
-		scale = scaleY
-	}
-
-	newWidth := int(float64(width) * scale)
-	newHeight := int(float64(height) * scale)
-
-	// Simple nearest-neighbor resize
-	resized := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
-	for y := 0; y < newHeight; y++ {
-		for x := 0; x < newWidth; x++ {
-			srcX := int(float64(x) / scale)
-			srcY := int(float64(y) / scale)
-			resized.Set(x, y, img.At(srcX, srcY))
-		}
-	}
-	return resized
-}
-
 // Capture represents a screen capture with metadata
 type Capture struct {
 	Timestamp  time.Time
 	Image      image.Image
 	Compressed []byte
 	DisplayNum int
+	// MIMEType is the encoding of Compressed, e.g. "image/jpeg"
+	MIMEType string
+	// WindowTitle is the foreground window's title when the frame was
+	// taken, if known
+	WindowTitle string
+}
+
+// Capture formats, as used in CaptureConfig.Format
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+)
+
+// pngSignature starts every PNG file
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// MIMEType returns the image type of encoded capture data
+func MIMEType(data []byte) string {
+	if bytes.HasPrefix(data, pngSignature) {
+		return "image/png"
+	}
+	return "image/jpeg"
 }
 
 // Capturer handles screen capture operations
 type Capturer struct {
+	mu     sync.RWMutex
 	config *config.CaptureConfig
+
+	// Display access, replaceable in tests
+	numDisplays   func() int
+	grab          func(display int) (image.Image, error)
+	displayBounds func(display int) image.Rectangle
+	grabRect      func(r image.Rectangle) (image.Image, error)
+	listWindows   func() ([]Window, error)
+
+	// logs collapses messages repeated on every capture; nil logs them all
+	logs *ratelog.Logger
+
+	// Quality chosen by the last SSIM search and the frame it was chosen for
+	ssimQuality     int
+	ssimFingerprint []uint8
 }
 
 // New creates a new screen capturer
 func New(cfg *config.CaptureConfig) *Capturer {
 	return &Capturer{
-		config: cfg,
+		config:        cfg,
+		numDisplays:   screenshot.NumActiveDisplays,
+		grab:          grabDisplay,
+		displayBounds: screenshot.GetDisplayBounds,
+		grabRect:      grabScreenRect,
+		listWindows:   listWindows,
 	}
 }
 
-// CaptureScreen captures all displays and returns them
-func (c *Capturer) CaptureScreen() ([]*Capture, error) {
-	n := screenshot.NumActiveDisplays()
+// grabDisplay captures the full bounds of a display
+func grabDisplay(display int) (image.Image, error) {
+	return screenshot.CaptureRect(screenshot.GetDisplayBounds(display))
+}
+
+// selectDisplays returns the displays to capture out of n active ones,
+// skipping excluded indices. Excluded indices that don't match an active
+// display are returned as invalid.
+func selectDisplays(n int, exclude []int) (displays, invalid []int) {
+	excluded := make(map[int]bool, len(exclude))
+	for _, d := range exclude {
+		if d < 0 || d >= n {
+			invalid = append(invalid, d)
+			continue
+		}
+		excluded[d] = true
+	}
+
+	for i := 0; i < n; i++ {
+		if !excluded[i] {
+			displays = append(displays, i)
+		}
+	}
+	return displays, invalid
+}
+
+// activeDisplays returns the displays that may be captured
+func (c *Capturer) activeDisplays() ([]int, error) {
+	n := c.numDisplays()
 	if n == 0 {
 		return nil, fmt.Errorf("no active displays found")
 	}
 
+	c.mu.RLock()
+	exclude := c.config.ExcludeDisplays
+	c.mu.RUnlock()
+
+	displays, invalid := selectDisplays(n, exclude)
+	if len(invalid) > 0 {
+		c.logf("[Capture] Ignoring excluded displays %v: only %d active", invalid, n)
+	}
+	if len(displays) == 0 {
+		return nil, fmt.Errorf("all %d active displays are excluded", n)
+	}
+	return displays, nil
+}
+
+// SetLogger routes the capturer's log messages through l
+func (c *Capturer) SetLogger(l *ratelog.Logger) {
+	c.logs = l
+}
+
+// logf logs through the rate-limited logger, if set
+func (c *Capturer) logf(format string, args ...interface{}) {
+	if c.logs != nil {
+		c.logs.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// SetConfig swaps the capture settings used for subsequent captures
+func (c *Capturer) SetConfig(cfg *config.CaptureConfig) {
+	c.mu.Lock()
+	c.config = cfg
+	c.mu.Unlock()
+}
+
+// retryEnabled reports whether failed captures are retried
+func (c *Capturer) retryEnabled() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.config.RetryOnFailure
+}
+
+// CaptureScreen captures all displays that aren't excluded and returns
+// them. A failed capture is retried once with freshly enumerated displays
+// when RetryOnFailure is set.
+func (c *Capturer) CaptureScreen() ([]*Capture, error) {
+	captures, err := c.captureScreenOnce()
+	if err != nil && c.retryEnabled() {
+		c.logf("[Capture] Capture failed (%v), re-enumerating displays and retrying", err)
+		captures, err = c.captureScreenOnce()
+	}
+	return captures, err
+}
+
+// captureScreenOnce enumerates the displays and captures each of them
+func (c *Capturer) captureScreenOnce() ([]*Capture, error) {
+	displays, err := c.activeDisplays()
+	if err != nil {
+		return nil, err
+	}
+
 	var captures []*Capture
 	now := time.Now()
 
-	for i := 0; i < n; i++ {
-		bounds := screenshot.GetDisplayBounds(i)
-		img, err := screenshot.CaptureRect(bounds)
+	for _, i := range displays {
+		img, err := c.grab(i)
 		if err != nil {
 			return nil, fmt.Errorf("capturing display %d: %w", i, err)
 		}
@@ -97,6 +193,7 @@ func (c *Capturer) CaptureScreen() ([]*Capture, error) {
 			Timestamp:  now,
 			Image:      img,
 			Compressed: compressed,
+			MIMEType:   MIMEType(compressed),
 			DisplayNum: i,
 		})
 	}
@@ -104,17 +201,57 @@ func (c *Capturer) CaptureScreen() ([]*Capture, error) {
 	return captures, nil
 }
 
-// CapturePrimary captures only the primary display
+// CapturePrimary captures only the primary display, or the first display
+// that isn't excluded when the primary is. Like CaptureScreen it retries
+// once with freshly enumerated displays when RetryOnFailure is set.
 func (c *Capturer) CapturePrimary() (*Capture, error) {
-	n := screenshot.NumActiveDisplays()
-	if n == 0 {
-		return nil, fmt.Errorf("no active displays found")
+	cap, err := c.capturePrimaryOnce()
+	if err != nil && c.retryEnabled() {
+		c.logf("[Capture] Capture failed (%v), re-enumerating displays and retrying", err)
+		cap, err = c.capturePrimaryOnce()
 	}
+	return cap, err
+}
 
-	bounds := screenshot.GetDisplayBounds(0)
-	img, err := screenshot.CaptureRect(bounds)
+// capturePrimaryOnce enumerates the displays and captures the first one
+func (c *Capturer) capturePrimaryOnce() (*Capture, error) {
+	displays, err := c.activeDisplays()
 	if err != nil {
-		return nil, fmt.Errorf("capturing primary display: %w", err)
+		return nil, err
+	}
+	return c.grabAndCompress(displays[0])
+}
+
+// CaptureDisplay captures one display by index. The display must be
+// active and not excluded. Like CaptureScreen it retries once with freshly
+// enumerated displays when RetryOnFailure is set.
+func (c *Capturer) CaptureDisplay(display int) (*Capture, error) {
+	cap, err := c.captureDisplayOnce(display)
+	if err != nil && c.retryEnabled() {
+		c.logf("[Capture] Capture failed (%v), re-enumerating displays and retrying", err)
+		cap, err = c.captureDisplayOnce(display)
+	}
+	return cap, err
+}
+
+// captureDisplayOnce checks display is one of the active displays and
+// captures it
+func (c *Capturer) captureDisplayOnce(display int) (*Capture, error) {
+	displays, err := c.activeDisplays()
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(displays, display) {
+		return nil, fmt.Errorf("display %d is not active or is excluded (capturable: %v)", display, displays)
+	}
+	return c.grabAndCompress(display)
+}
+
+// grabAndCompress captures and compresses a single display
+func (c *Capturer) grabAndCompress(display int) (*Capture, error) {
+	img, err := c.grab(display)
+	if err != nil {
+		return nil, fmt.Errorf("capturing display %d: %w", display, err)
 	}
 
 	// Compress full image
@@ -127,7 +264,8 @@ func (c *Capturer) CapturePrimary() (*Capture, error) {
 		Timestamp:  time.Now(),
 		Image:      img,
 		Compressed: compressed,
-		DisplayNum: 0,
+		MIMEType:   MIMEType(compressed),
+		DisplayNum: display,
 	}, nil
 }
 
@@ -135,12 +273,29 @@ func (c *Capturer) CapturePrimary() (*Capture, error) {
 func (c *Capturer) compress(img image.Image) ([]byte, error) {
 	var buf bytes.Buffer
 
+	c.mu.RLock()
+	cfg := c.config
+	c.mu.RUnlock()
+
+	img = NormalizeImage(img)
+
 	// Resize if configured
-	if c.config.MaxWidth > 0 || c.config.MaxHeight > 0 {
-		img = resizeImage(img, c.config.MaxWidth, c.config.MaxHeight)
+	if cfg.MaxWidth > 0 || cfg.MaxHeight > 0 {
+		img = resizeImage(img, cfg.MaxWidth, cfg.MaxHeight, cfg.ResizeAlgorithm)
 	}
 
-	quality := c.config.Quality
+	if strings.EqualFold(cfg.Format, FormatPNG) {
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	if cfg.TargetSSIM > 0 {
+		return c.compressToSSIM(img, cfg.TargetSSIM)
+	}
+
+	quality := cfg.Quality
 	if quality <= 0 || quality > 100 {
 		quality = 60
 	}
@@ -153,6 +308,37 @@ func (c *Capturer) compress(img image.Image) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// compressToSSIM encodes at the lowest quality meeting the target SSIM.
+// Similar consecutive frames reuse the previously chosen quality.
+func (c *Capturer) compressToSSIM(img image.Image, target float64) ([]byte, error) {
+	fp := fingerprint(img)
+
+	c.mu.RLock()
+	quality := c.ssimQuality
+	cached := quality > 0 && similarFingerprints(fp, c.ssimFingerprint)
+	c.mu.RUnlock()
+
+	if cached {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	quality, data, err := searchQuality(img, target)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.ssimQuality = quality
+	c.ssimFingerprint = fp
+	c.mu.Unlock()
+
+	return data, nil
+}
+
 // GetPlatform returns the current platform name
 func GetPlatform() string {
 	return runtime.GOOS
@@ -1,8 +1,12 @@
 package capture
 
 import (
+	"bytes"
+	"errors"
 	"image"
 	"image/color"
+	"image/jpeg"
+	"image/png"
 	"testing"
 
 	"screen-memory-assistant/internal/config"
@@ -52,3 +56,346 @@ func TestGetPlatform(t *testing.T) {
 		t.Errorf("GetPlatform() returned unknown platform: %s", platform)
 	}
 }
+
+// sampleScreen builds an image with gradients, edges and text-like detail
+func sampleScreen(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.RGBA{uint8(x * 255 / w), uint8(y * 255 / h), 180, 255}
+			if (x/4+y/6)%5 == 0 && y%12 < 8 {
+				c = color.RGBA{20, 20, 20, 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestCapturer_compressTargetSSIM(t *testing.T) {
+	const target = 0.95
+	c := New(&config.CaptureConfig{TargetSSIM: target})
+	img := sampleScreen(160, 120)
+
+	data, err := c.compress(img)
+	if err != nil {
+		t.Fatalf("compress failed: %v", err)
+	}
+
+	decoded, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decoding output: %v", err)
+	}
+	if score := ssim(toGray(img), toGray(decoded)); score < target {
+		t.Errorf("SSIM = %.4f at quality %d, want >= %.2f", score, c.ssimQuality, target)
+	}
+	if c.ssimQuality < ssimMinQuality || c.ssimQuality > ssimMaxQuality {
+		t.Errorf("chosen quality %d outside [%d, %d]", c.ssimQuality, ssimMinQuality, ssimMaxQuality)
+	}
+
+	// A similar frame reuses the cached quality
+	chosen := c.ssimQuality
+	c.ssimQuality = chosen - 1
+	if _, err := c.compress(img); err != nil {
+		t.Fatalf("compress failed: %v", err)
+	}
+	if c.ssimQuality != chosen-1 {
+		t.Error("similar frame should reuse the cached quality instead of searching again")
+	}
+}
+
+func TestSSIM_Identical(t *testing.T) {
+	gray := toGray(sampleScreen(64, 64))
+	if score := ssim(gray, gray); score < 0.9999 {
+		t.Errorf("SSIM of identical images = %f, want 1", score)
+	}
+}
+
+func TestCaptureScreen_ExcludeDisplays(t *testing.T) {
+	c := New(&config.CaptureConfig{Quality: 60, ExcludeDisplays: []int{1, 5}})
+	c.numDisplays = func() int { return 3 }
+	c.grab = func(display int) (image.Image, error) {
+		return image.NewRGBA(image.Rect(0, 0, 8, 8)), nil
+	}
+
+	captures, err := c.CaptureScreen()
+	if err != nil {
+		t.Fatalf("CaptureScreen failed: %v", err)
+	}
+
+	var got []int
+	for _, cap := range captures {
+		got = append(got, cap.DisplayNum)
+	}
+	if len(got) != 2 || got[0] != 0 || got[1] != 2 {
+		t.Errorf("captured displays = %v, want [0 2]", got)
+	}
+}
+
+func TestCapturePrimary_SkipsExcludedPrimary(t *testing.T) {
+	c := New(&config.CaptureConfig{Quality: 60, ExcludeDisplays: []int{0}})
+	c.numDisplays = func() int { return 2 }
+	c.grab = func(display int) (image.Image, error) {
+		return image.NewRGBA(image.Rect(0, 0, 8, 8)), nil
+	}
+
+	cap, err := c.CapturePrimary()
+	if err != nil {
+		t.Fatalf("CapturePrimary failed: %v", err)
+	}
+	if cap.DisplayNum != 1 {
+		t.Errorf("DisplayNum = %d, want 1", cap.DisplayNum)
+	}
+
+	c.SetConfig(&config.CaptureConfig{ExcludeDisplays: []int{0, 1}})
+	if _, err := c.CapturePrimary(); err == nil {
+		t.Error("CapturePrimary should fail when every display is excluded")
+	}
+}
+
+func TestCaptureDisplay(t *testing.T) {
+	c := New(&config.CaptureConfig{Quality: 60, ExcludeDisplays: []int{0}})
+	c.numDisplays = func() int { return 3 }
+	c.grab = func(display int) (image.Image, error) {
+		return image.NewRGBA(image.Rect(0, 0, 8, 8)), nil
+	}
+
+	cap, err := c.CaptureDisplay(2)
+	if err != nil {
+		t.Fatalf("CaptureDisplay failed: %v", err)
+	}
+	if cap.DisplayNum != 2 {
+		t.Errorf("DisplayNum = %d, want 2", cap.DisplayNum)
+	}
+
+	for _, display := range []int{0, 3, -1} {
+		if _, err := c.CaptureDisplay(display); err == nil {
+			t.Errorf("CaptureDisplay(%d) should fail for an excluded or missing display", display)
+		}
+	}
+}
+
+func TestSelectDisplays(t *testing.T) {
+	displays, invalid := selectDisplays(2, []int{1, 2, -1})
+	if len(displays) != 1 || displays[0] != 0 {
+		t.Errorf("displays = %v, want [0]", displays)
+	}
+	if len(invalid) != 2 {
+		t.Errorf("invalid = %v, want [2 -1]", invalid)
+	}
+}
+
+func TestCapturer_compressPaletted(t *testing.T) {
+	c := New(&config.CaptureConfig{Quality: 60, MaxWidth: 32, MaxHeight: 32})
+
+	palette := color.Palette{color.Transparent, color.RGBA{200, 30, 30, 255}, color.RGBA{30, 30, 200, 255}}
+	img := image.NewPaletted(image.Rect(10, 10, 74, 74), palette)
+	for y := 10; y < 74; y++ {
+		for x := 10; x < 74; x++ {
+			img.SetColorIndex(x, y, uint8((x/8+y/8)%3))
+		}
+	}
+
+	data, err := c.compress(img)
+	if err != nil {
+		t.Fatalf("compress failed: %v", err)
+	}
+
+	decoded, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("output is not valid JPEG: %v", err)
+	}
+	if b := decoded.Bounds(); b.Dx() != 32 || b.Dy() != 32 {
+		t.Errorf("decoded size = %dx%d, want 32x32", b.Dx(), b.Dy())
+	}
+}
+
+func TestNormalizeImage(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(5, 5, 7, 7))
+	img.Set(5, 5, color.NRGBA{0, 0, 0, 0})
+	img.Set(6, 6, color.NRGBA{255, 0, 0, 255})
+
+	out := NormalizeImage(img)
+
+	if out.Bounds() != image.Rect(0, 0, 2, 2) {
+		t.Errorf("bounds = %v, want origin-anchored 2x2", out.Bounds())
+	}
+	if got := out.RGBAAt(0, 0); got != (color.RGBA{255, 255, 255, 255}) {
+		t.Errorf("transparent pixel = %v, want white", got)
+	}
+	if got := out.RGBAAt(1, 1); got != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("opaque pixel = %v, want red", got)
+	}
+}
+
+func TestCapturePrimary_RetriesWithFreshDisplays(t *testing.T) {
+	c := New(&config.CaptureConfig{Quality: 60, RetryOnFailure: true})
+
+	// Undocked between enumeration and capture: display 1 is gone
+	enumerations := 0
+	c.numDisplays = func() int {
+		enumerations++
+		if enumerations == 1 {
+			return 2
+		}
+		return 1
+	}
+	grabs := 0
+	c.grab = func(display int) (image.Image, error) {
+		grabs++
+		if grabs == 1 {
+			return nil, errors.New("display bounds out of date")
+		}
+		return image.NewRGBA(image.Rect(0, 0, 8, 8)), nil
+	}
+
+	cap, err := c.CapturePrimary()
+	if err != nil {
+		t.Fatalf("CapturePrimary failed: %v", err)
+	}
+	if cap.DisplayNum != 0 {
+		t.Errorf("DisplayNum = %d, want 0", cap.DisplayNum)
+	}
+	if enumerations != 2 || grabs != 2 {
+		t.Errorf("enumerations = %d, grabs = %d, want 2 each", enumerations, grabs)
+	}
+
+	// Without retries the first failure is returned
+	c.SetConfig(&config.CaptureConfig{Quality: 60})
+	grabs = 0
+	if _, err := c.CapturePrimary(); err == nil {
+		t.Error("CapturePrimary should fail without retries")
+	}
+}
+
+func TestWorkspaceBounds(t *testing.T) {
+	displays := []image.Rectangle{image.Rect(0, 0, 1920, 1080), image.Rect(1920, 0, 3840, 1080)}
+
+	tests := []struct {
+		name    string
+		windows []image.Rectangle
+		want    image.Rectangle
+		ok      bool
+	}{
+		{"single window", []image.Rectangle{image.Rect(100, 100, 900, 700)}, image.Rect(100, 100, 900, 700), true},
+		{"overlapping windows", []image.Rectangle{image.Rect(100, 100, 900, 700), image.Rect(500, 50, 1200, 600)}, image.Rect(100, 50, 1200, 700), true},
+		{"across displays", []image.Rectangle{image.Rect(1500, 200, 2500, 800), image.Rect(200, 300, 600, 500)}, image.Rect(200, 200, 2500, 800), true},
+		{"clamped to displays", []image.Rectangle{image.Rect(-300, -50, 800, 1300)}, image.Rect(0, 0, 800, 1080), true},
+		{"off screen ignored", []image.Rectangle{image.Rect(-3000, 0, -2000, 500), image.Rect(100, 100, 200, 200)}, image.Rect(100, 100, 200, 200), true},
+		{"nothing visible", []image.Rectangle{image.Rect(5000, 0, 6000, 500)}, image.Rectangle{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := workspaceBounds(tt.windows, displays)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("workspaceBounds = %v, %v; want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestCapture_WorkspaceWindows(t *testing.T) {
+	c := New(&config.CaptureConfig{Quality: 60, WorkspaceWindows: []string{"Visual Studio Code"}, WorkspaceApps: []string{"chrome.exe"}})
+	c.numDisplays = func() int { return 1 }
+	c.displayBounds = func(int) image.Rectangle { return image.Rect(0, 0, 1920, 1080) }
+	c.listWindows = func() ([]Window, error) {
+		return []Window{
+			{Title: "main.go - Visual Studio Code", Bounds: image.Rect(0, 0, 960, 1080)},
+			{Title: "Docs", App: `C:\Program Files\Google\Chrome\chrome.exe`, Bounds: image.Rect(960, 100, 1800, 900)},
+			{Title: "Spotify", App: "spotify.exe", Bounds: image.Rect(1800, 0, 1920, 1080)},
+			{Title: "Visual Studio Code", Bounds: image.Rect(0, 0, 1920, 1080), Minimized: true},
+		}, nil
+	}
+	var grabbed image.Rectangle
+	c.grabRect = func(r image.Rectangle) (image.Image, error) {
+		grabbed = r
+		return image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy())), nil
+	}
+
+	if _, err := c.Capture(); err != nil {
+		t.Fatalf("Capture failed: %v", err)
+	}
+	if want := image.Rect(0, 0, 1800, 1080); grabbed != want {
+		t.Errorf("captured %v, want %v", grabbed, want)
+	}
+
+	// With no workspace window open the primary display is captured
+	c.listWindows = func() ([]Window, error) { return nil, nil }
+	c.grab = func(display int) (image.Image, error) {
+		return image.NewRGBA(image.Rect(0, 0, 8, 8)), nil
+	}
+	grabbed = image.Rectangle{}
+	if _, err := c.Capture(); err != nil {
+		t.Fatalf("Capture fallback failed: %v", err)
+	}
+	if !grabbed.Empty() {
+		t.Errorf("fallback grabbed workspace %v", grabbed)
+	}
+}
+
+func TestCapturer_compressPNG(t *testing.T) {
+	c := New(&config.CaptureConfig{Format: FormatPNG, Quality: 10})
+	c.numDisplays = func() int { return 1 }
+	c.grab = func(display int) (image.Image, error) {
+		img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+		img.Set(3, 4, color.RGBA{200, 10, 10, 255})
+		return img, nil
+	}
+
+	cap, err := c.CapturePrimary()
+	if err != nil {
+		t.Fatalf("CapturePrimary failed: %v", err)
+	}
+	if cap.MIMEType != "image/png" {
+		t.Errorf("MIMEType = %q, want image/png", cap.MIMEType)
+	}
+	decoded, err := png.Decode(bytes.NewReader(cap.Compressed))
+	if err != nil {
+		t.Fatalf("not a PNG: %v", err)
+	}
+	// Lossless: the pixel survives exactly
+	if r, g, b, _ := decoded.At(3, 4).RGBA(); r>>8 != 200 || g>>8 != 10 || b>>8 != 10 {
+		t.Errorf("pixel = %d,%d,%d, want 200,10,10", r>>8, g>>8, b>>8)
+	}
+
+	c.SetConfig(&config.CaptureConfig{Quality: 60})
+	if cap, _ := c.CapturePrimary(); cap.MIMEType != "image/jpeg" {
+		t.Errorf("default MIMEType = %q, want image/jpeg", cap.MIMEType)
+	}
+}
+
+func TestCapture_Region(t *testing.T) {
+	c := New(&config.CaptureConfig{Quality: 60, Region: config.RegionConfig{X: 100, Y: 50, Width: 800, Height: 600}})
+	c.numDisplays = func() int { return 2 }
+	c.displayBounds = func(d int) image.Rectangle {
+		return image.Rect(d*1920, 0, (d+1)*1920, 1080)
+	}
+	var grabbed image.Rectangle
+	c.grabRect = func(r image.Rectangle) (image.Image, error) {
+		grabbed = r
+		return image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy())), nil
+	}
+	c.grab = func(display int) (image.Image, error) {
+		t.Error("primary display captured with a region configured")
+		return image.NewRGBA(image.Rect(0, 0, 8, 8)), nil
+	}
+
+	cap, err := c.Capture()
+	if err != nil {
+		t.Fatalf("Capture failed: %v", err)
+	}
+	if want := image.Rect(100, 50, 900, 650); grabbed != want || cap.DisplayNum != 0 {
+		t.Errorf("captured %v on display %d, want %v on display 0", grabbed, cap.DisplayNum, want)
+	}
+
+	// Regions may span displays but not leave them
+	if _, err := c.CaptureRegion(image.Rect(1800, 0, 2400, 500)); err != nil {
+		t.Errorf("CaptureRegion across displays failed: %v", err)
+	}
+	for _, r := range []image.Rectangle{image.Rect(3000, 900, 4000, 1200), image.Rect(-10, 0, 100, 100), {}} {
+		if _, err := c.CaptureRegion(r); err == nil {
+			t.Errorf("CaptureRegion(%v) succeeded, want an error", r)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package capture
+
+import "image"
+
+// Fingerprint returns a small luminance thumbnail of img for comparing
+// frames with Similarity
+func Fingerprint(img image.Image) []uint8 {
+	return fingerprint(img)
+}
+
+// Similarity compares two fingerprints, from 0 (inverted) to 1
+// (identical thumbnails). Fingerprints of different sizes score 0.
+func Similarity(a, b []uint8) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var diff int
+	for i := range a {
+		d := int(a[i]) - int(b[i])
+		if d < 0 {
+			d = -d
+		}
+		diff += d
+	}
+	return 1 - float64(diff)/float64(255*len(a))
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package capture
+
+import (
+	"errors"
+	"time"
+)
+
+// IdleTime returns how long ago the user last provided input. It is only
+// implemented on Windows; elsewhere it always fails.
+func IdleTime() (time.Duration, error) {
+	return 0, errors.New("idle time is not supported on this platform")
+}
@@ -0,0 +1,35 @@
+package capture
+
+import (
+	"fmt"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	user32DLL            = windows.NewLazySystemDLL("user32.dll")
+	kernel32DLL          = windows.NewLazySystemDLL("kernel32.dll")
+	procGetLastInputInfo = user32DLL.NewProc("GetLastInputInfo")
+	procGetTickCount     = kernel32DLL.NewProc("GetTickCount")
+)
+
+// lastInputInfo mirrors LASTINPUTINFO
+type lastInputInfo struct {
+	cbSize uint32
+	dwTime uint32
+}
+
+// IdleTime returns how long ago the user last pressed a key or moved the mouse
+func IdleTime() (time.Duration, error) {
+	info := lastInputInfo{cbSize: uint32(unsafe.Sizeof(lastInputInfo{}))}
+	ret, _, err := procGetLastInputInfo.Call(uintptr(unsafe.Pointer(&info)))
+	if ret == 0 {
+		return 0, fmt.Errorf("GetLastInputInfo failed: %v", err)
+	}
+
+	// Both are 32-bit tick counts, so the subtraction survives wraparound
+	now, _, _ := procGetTickCount.Call()
+	return time.Duration(uint32(now)-info.dwTime) * time.Millisecond, nil
+}
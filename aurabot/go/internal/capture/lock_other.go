@@ -0,0 +1,9 @@
+//go:build !windows
+
+package capture
+
+// IsSessionLocked reports whether the user session is locked. Lock
+// detection is only implemented on Windows; elsewhere it's never locked.
+func IsSessionLocked() (bool, error) {
+	return false, nil
+}
@@ -0,0 +1,49 @@
+package capture
+
+import (
+	"encoding/binary"
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	wtsapi32DLL                     = windows.NewLazySystemDLL("wtsapi32.dll")
+	procWTSQuerySessionInformationW = wtsapi32DLL.NewProc("WTSQuerySessionInformationW")
+	procWTSFreeMemory               = wtsapi32DLL.NewProc("WTSFreeMemory")
+)
+
+const (
+	wtsCurrentSession = 0xFFFFFFFF
+	wtsSessionInfoEx  = 25
+	wtsSessionLock    = 0
+)
+
+// IsSessionLocked reports whether the current Windows session is locked
+func IsSessionLocked() (bool, error) {
+	var buf *byte
+	var size uint32
+
+	ret, _, err := procWTSQuerySessionInformationW.Call(
+		0, // WTS_CURRENT_SERVER_HANDLE
+		uintptr(wtsCurrentSession),
+		uintptr(wtsSessionInfoEx),
+		uintptr(unsafe.Pointer(&buf)),
+		uintptr(unsafe.Pointer(&size)),
+	)
+	if ret == 0 {
+		return false, fmt.Errorf("WTSQuerySessionInformation failed: %v", err)
+	}
+	defer procWTSFreeMemory.Call(uintptr(unsafe.Pointer(buf)))
+
+	// WTSINFOEXW: Level (DWORD) followed by WTSINFOEX_LEVEL1_W
+	// { SessionId, SessionState, SessionFlags, ... }
+	if size < 16 {
+		return false, fmt.Errorf("unexpected WTSINFOEX size: %d", size)
+	}
+	info := unsafe.Slice(buf, 16)
+	flags := binary.LittleEndian.Uint32(info[12:16])
+
+	return flags == wtsSessionLock, nil
+}
@@ -0,0 +1,23 @@
+package capture
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// NormalizeImage converts any image to an opaque RGBA image anchored at
+// the origin. Paletted, grayscale or CMYK sources and sub-images with an
+// offset origin then take the same resize and JPEG path as a screenshot;
+// transparent areas are flattened onto white since JPEG has no alpha.
+func NormalizeImage(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	if rgba, ok := img.(*image.RGBA); ok && b.Min == (image.Point{}) && rgba.Opaque() {
+		return rgba
+	}
+
+	out := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(out, out.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(out, out.Bounds(), img, b.Min, draw.Over)
+	return out
+}
@@ -0,0 +1,58 @@
+package capture
+
+import (
+	"fmt"
+	"image"
+	"time"
+)
+
+// configuredRegion returns the configured capture region, if any
+func (c *Capturer) configuredRegion() (image.Rectangle, bool) {
+	c.mu.RLock()
+	r := c.config.Region
+	c.mu.RUnlock()
+
+	if r.Width <= 0 || r.Height <= 0 {
+		return image.Rectangle{}, false
+	}
+	return image.Rect(r.X, r.Y, r.X+r.Width, r.Y+r.Height), true
+}
+
+// CaptureRegion captures a rectangle of the virtual screen, which must
+// lie within the active displays
+func (c *Capturer) CaptureRegion(rect image.Rectangle) (*Capture, error) {
+	if rect.Empty() {
+		return nil, fmt.Errorf("capture region %v is empty", rect)
+	}
+
+	n := c.numDisplays()
+	if n == 0 {
+		return nil, fmt.Errorf("no active displays found")
+	}
+	displays := make([]image.Rectangle, n)
+	var screen image.Rectangle
+	for i := range displays {
+		displays[i] = c.displayBounds(i)
+		screen = screen.Union(displays[i])
+	}
+	if !rect.In(screen) {
+		return nil, fmt.Errorf("capture region %v is outside the displays %v", rect, screen)
+	}
+
+	img, err := c.grabRect(rect)
+	if err != nil {
+		return nil, fmt.Errorf("capturing region %v: %w", rect, err)
+	}
+	compressed, err := c.compress(img)
+	if err != nil {
+		return nil, fmt.Errorf("compressing: %w", err)
+	}
+
+	return &Capture{
+		Timestamp:  time.Now(),
+		Image:      img,
+		Compressed: compressed,
+		MIMEType:   MIMEType(compressed),
+		DisplayNum: largestOverlap(rect, displays),
+	}, nil
+}
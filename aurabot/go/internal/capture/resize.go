@@ -0,0 +1,104 @@
+package capture
+
+import (
+	"image"
+	"strings"
+)
+
+// Resize algorithms, as used in CaptureConfig.ResizeAlgorithm
+const (
+	// ResizeNearest is the fastest, but small text comes out jagged
+	ResizeNearest = "nearest"
+	// ResizeBilinear blends neighbouring pixels, keeping small UI labels
+	// readable for the vision model at some extra CPU cost
+	ResizeBilinear = "bilinear"
+)
+
+// resizeImage scales down image if it exceeds max dimensions while maintaining aspect ratio
+func resizeImage(img image.Image, maxWidth, maxHeight int, algorithm string) image.Image {
+	if maxWidth <= 0 || maxHeight <= 0 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	width := bounds.Dx()
+	height := bounds.Dy()
+
+	// Check if resizing is needed
+	if width <= maxWidth && height <= maxHeight {
+		return img
+	}
+
+	// Calculate scaling factor to fit within bounds while maintaining aspect ratio
+	scaleX := float64(maxWidth) / float64(width)
+	scaleY := float64(maxHeight) / float64(height)
+	scale := scaleX
+	if scaleY < scaleX {
+		scale = scaleY
+	}
+
+	newWidth := int(float64(width) * scale)
+	newHeight := int(float64(height) * scale)
+
+	if strings.EqualFold(algorithm, ResizeBilinear) {
+		return resizeBilinear(NormalizeImage(img), newWidth, newHeight)
+	}
+
+	// Simple nearest-neighbor resize
+	resized := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
+	for y := 0; y < newHeight; y++ {
+		for x := 0; x < newWidth; x++ {
+			srcX := int(float64(x) / scale)
+			srcY := int(float64(y) / scale)
+			resized.Set(x, y, img.At(srcX, srcY))
+		}
+	}
+	return resized
+}
+
+// resizeBilinear samples each destination pixel centre from the four
+// nearest source pixels, weighted by distance
+func resizeBilinear(src *image.RGBA, newWidth, newHeight int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
+	width, height := src.Bounds().Dx(), src.Bounds().Dy()
+	ratioX := float64(width) / float64(newWidth)
+	ratioY := float64(height) / float64(newHeight)
+
+	// Column positions are the same for every row
+	cols := make([]struct{ i0, i1, w int }, newWidth)
+	for x := range cols {
+		x0, x1, fx := sampleRange((float64(x)+0.5)*ratioX-0.5, width)
+		cols[x].i0, cols[x].i1, cols[x].w = x0*4, x1*4, int(fx*256)
+	}
+
+	for y := 0; y < newHeight; y++ {
+		y0, y1, fy := sampleRange((float64(y)+0.5)*ratioY-0.5, height)
+		row0 := src.Pix[y0*src.Stride:]
+		row1 := src.Pix[y1*src.Stride:]
+		out := dst.Pix[y*dst.Stride:]
+		wy := int(fy * 256)
+
+		for x, col := range cols {
+			for c := 0; c < 4; c++ {
+				// 8-bit fixed point weights
+				top := int(row0[col.i0+c])*(256-col.w) + int(row0[col.i1+c])*col.w
+				bottom := int(row1[col.i0+c])*(256-col.w) + int(row1[col.i1+c])*col.w
+				out[x*4+c] = uint8((top*(256-wy) + bottom*wy + 1<<15) >> 16)
+			}
+		}
+	}
+	return dst
+}
+
+// sampleRange returns the two source indices around position pos, clamped
+// to [0, size), and the weight of the second
+func sampleRange(pos float64, size int) (int, int, float64) {
+	if pos <= 0 {
+		return 0, 0, 0
+	}
+	i := int(pos)
+	if i >= size-1 {
+		return size - 1, size - 1, 0
+	}
+	return i, i + 1, pos - float64(i)
+}
@@ -0,0 +1,56 @@
+package capture
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestResizeImage_Algorithms(t *testing.T) {
+	// Alternating black and white columns
+	src := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 400; x++ {
+			v := uint8(0)
+			if x%2 == 1 {
+				v = 255
+			}
+			src.Set(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+
+	for _, algorithm := range []string{ResizeNearest, ResizeBilinear, ""} {
+		out := resizeImage(src, 200, 200, algorithm)
+		if b := out.Bounds(); b.Dx() != 200 || b.Dy() != 100 {
+			t.Errorf("%q: size = %dx%d, want 200x100", algorithm, b.Dx(), b.Dy())
+		}
+	}
+
+	// Nearest picks one column of each pair; bilinear averages them
+	r, _, _, _ := resizeImage(src, 200, 200, ResizeNearest).At(50, 50).RGBA()
+	if r>>8 != 0 && r>>8 != 255 {
+		t.Errorf("nearest pixel = %d, want pure black or white", r>>8)
+	}
+	r, _, _, _ = resizeImage(src, 200, 200, ResizeBilinear).At(50, 50).RGBA()
+	if r>>8 < 120 || r>>8 > 135 {
+		t.Errorf("bilinear pixel = %d, want mid grey", r>>8)
+	}
+
+	if out := resizeImage(src, 800, 800, ResizeBilinear); out != image.Image(src) {
+		t.Error("image within limits was resized")
+	}
+}
+
+func benchmarkResize4K(b *testing.B, algorithm string) {
+	src := image.NewRGBA(image.Rect(0, 0, 3840, 2160))
+	for i := range src.Pix {
+		src.Pix[i] = uint8(i * 7)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		resizeImage(src, 1280, 720, algorithm)
+	}
+}
+
+func BenchmarkResize4KNearest(b *testing.B)  { benchmarkResize4K(b, ResizeNearest) }
+func BenchmarkResize4KBilinear(b *testing.B) { benchmarkResize4K(b, ResizeBilinear) }
@@ -0,0 +1,164 @@
+package capture
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+)
+
+// SSIM search bounds for adaptive JPEG quality
+const (
+	ssimMinQuality = 10
+	ssimMaxQuality = 95
+	ssimMaxSteps   = 6
+	ssimBlockSize  = 8
+)
+
+// fingerprintSize is the edge of the thumbnail used to spot similar frames
+const fingerprintSize = 16
+
+// fingerprintTolerance is the mean absolute luminance difference below which
+// two frames are considered similar enough to reuse the cached quality
+const fingerprintTolerance = 6
+
+// toGray converts an image to 8-bit luminance
+func toGray(img image.Image) *image.Gray {
+	if g, ok := img.(*image.Gray); ok {
+		return g
+	}
+	b := img.Bounds()
+	gray := image.NewGray(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			gray.Set(x, y, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return gray
+}
+
+// ssim computes the mean structural similarity of two equally sized
+// grayscale images over non-overlapping 8x8 windows
+func ssim(a, b *image.Gray) float64 {
+	const (
+		c1 = (0.01 * 255) * (0.01 * 255)
+		c2 = (0.03 * 255) * (0.03 * 255)
+	)
+
+	w, h := a.Bounds().Dx(), a.Bounds().Dy()
+	if b.Bounds().Dx() != w || b.Bounds().Dy() != h || w < ssimBlockSize || h < ssimBlockSize {
+		return 0
+	}
+
+	var total float64
+	var blocks int
+	n := float64(ssimBlockSize * ssimBlockSize)
+
+	for by := 0; by+ssimBlockSize <= h; by += ssimBlockSize {
+		for bx := 0; bx+ssimBlockSize <= w; bx += ssimBlockSize {
+			var sumA, sumB, sumAA, sumBB, sumAB float64
+			for y := by; y < by+ssimBlockSize; y++ {
+				rowA := a.Pix[y*a.Stride:]
+				rowB := b.Pix[y*b.Stride:]
+				for x := bx; x < bx+ssimBlockSize; x++ {
+					pa, pb := float64(rowA[x]), float64(rowB[x])
+					sumA += pa
+					sumB += pb
+					sumAA += pa * pa
+					sumBB += pb * pb
+					sumAB += pa * pb
+				}
+			}
+
+			meanA, meanB := sumA/n, sumB/n
+			varA := sumAA/n - meanA*meanA
+			varB := sumBB/n - meanB*meanB
+			cov := sumAB/n - meanA*meanB
+
+			total += ((2*meanA*meanB + c1) * (2*cov + c2)) /
+				((meanA*meanA + meanB*meanB + c1) * (varA + varB + c2))
+			blocks++
+		}
+	}
+
+	return total / float64(blocks)
+}
+
+// encodedSSIM encodes img at quality and returns the JPEG with its SSIM
+// against the original luminance
+func encodedSSIM(img image.Image, gray *image.Gray, quality int) ([]byte, float64, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, 0, err
+	}
+	decoded, err := jpeg.Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		return nil, 0, err
+	}
+	return buf.Bytes(), ssim(gray, toGray(decoded)), nil
+}
+
+// searchQuality binary-searches for the lowest JPEG quality whose output
+// still meets the target SSIM, returning that quality and its encoding
+func searchQuality(img image.Image, target float64) (int, []byte, error) {
+	gray := toGray(img)
+
+	lo, hi := ssimMinQuality, ssimMaxQuality
+	bestQuality := ssimMaxQuality
+	var best []byte
+
+	for step := 0; step < ssimMaxSteps && lo <= hi; step++ {
+		mid := (lo + hi) / 2
+		data, score, err := encodedSSIM(img, gray, mid)
+		if err != nil {
+			return 0, nil, err
+		}
+		if score >= target {
+			bestQuality, best = mid, data
+			hi = mid - 1
+		} else {
+			lo = mid + 1
+		}
+	}
+
+	if best == nil {
+		// Target unreachable within the step budget: use the ceiling
+		data, _, err := encodedSSIM(img, gray, ssimMaxQuality)
+		if err != nil {
+			return 0, nil, err
+		}
+		best = data
+	}
+
+	return bestQuality, best, nil
+}
+
+// fingerprint returns a small luminance thumbnail used to compare frames
+func fingerprint(img image.Image) []uint8 {
+	b := img.Bounds()
+	fp := make([]uint8, fingerprintSize*fingerprintSize)
+	for y := 0; y < fingerprintSize; y++ {
+		for x := 0; x < fingerprintSize; x++ {
+			px := b.Min.X + x*b.Dx()/fingerprintSize
+			py := b.Min.Y + y*b.Dy()/fingerprintSize
+			r, g, bl, _ := img.At(px, py).RGBA()
+			fp[y*fingerprintSize+x] = uint8((19595*r + 38470*g + 7471*bl + 1<<15) >> 24)
+		}
+	}
+	return fp
+}
+
+// similarFingerprints reports whether two thumbnails are close enough
+func similarFingerprints(a, b []uint8) bool {
+	if len(a) != len(b) || len(a) == 0 {
+		return false
+	}
+	var diff int
+	for i := range a {
+		d := int(a[i]) - int(b[i])
+		if d < 0 {
+			d = -d
+		}
+		diff += d
+	}
+	return diff/len(a) < fingerprintTolerance
+}
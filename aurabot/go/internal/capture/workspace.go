@@ -0,0 +1,161 @@
+package capture
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"strings"
+	"time"
+
+	"github.com/kbinani/screenshot"
+)
+
+// errNoWorkspace means no workspace window could be found, either because
+// none is open or because windows can't be listed on this platform
+var errNoWorkspace = errors.New("no workspace windows")
+
+// Window is a top-level window as seen by workspace capture
+type Window struct {
+	Title string
+	// App is the executable name, e.g. "code.exe"
+	App       string
+	Bounds    image.Rectangle
+	Minimized bool
+}
+
+// workspaceWindows returns the bounds of the visible windows whose title
+// contains one of titles or whose executable is one of apps (both case
+// insensitive)
+func workspaceWindows(windows []Window, titles, apps []string) []image.Rectangle {
+	var rects []image.Rectangle
+	for _, w := range windows {
+		if w.Minimized || w.Bounds.Empty() {
+			continue
+		}
+		if matchesAny(w.Title, titles, strings.Contains) ||
+			matchesAny(exeName(w.App), apps, strings.EqualFold) {
+			rects = append(rects, w.Bounds)
+		}
+	}
+	return rects
+}
+
+// exeName returns the file name of an executable path, whichever
+// separator it uses
+func exeName(path string) string {
+	return path[strings.LastIndexAny(path, `\/`)+1:]
+}
+
+// matchesAny reports whether match(value, p) holds for a pattern p,
+// comparing lowercased strings
+func matchesAny(value string, patterns []string, match func(s, p string) bool) bool {
+	value = strings.ToLower(value)
+	for _, p := range patterns {
+		if p != "" && match(value, strings.ToLower(p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// workspaceBounds returns the box enclosing all windows, with each window
+// first clipped to the displays so off-screen parts don't stretch it.
+// Overlapping windows simply share the box. ok is false when no window
+// is on a display.
+func workspaceBounds(windows, displays []image.Rectangle) (bounds image.Rectangle, ok bool) {
+	for _, w := range windows {
+		for _, d := range displays {
+			visible := w.Intersect(d)
+			if visible.Empty() {
+				continue
+			}
+			if !ok {
+				bounds, ok = visible, true
+				continue
+			}
+			bounds = bounds.Union(visible)
+		}
+	}
+	return bounds, ok
+}
+
+// largestOverlap returns the index of the display sharing the most area
+// with r
+func largestOverlap(r image.Rectangle, displays []image.Rectangle) int {
+	best, bestArea := 0, -1
+	for i, d := range displays {
+		overlap := r.Intersect(d)
+		if area := overlap.Dx() * overlap.Dy(); area > bestArea {
+			best, bestArea = i, area
+		}
+	}
+	return best
+}
+
+// workspaceConfigured reports whether a workspace capture is set up
+func (c *Capturer) workspaceConfigured() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.config.WorkspaceWindows) > 0 || len(c.config.WorkspaceApps) > 0
+}
+
+// CaptureWorkspace captures the box enclosing the configured workspace
+// windows as one image
+func (c *Capturer) CaptureWorkspace() (*Capture, error) {
+	c.mu.RLock()
+	titles, apps := c.config.WorkspaceWindows, c.config.WorkspaceApps
+	c.mu.RUnlock()
+
+	windows, err := c.listWindows()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errNoWorkspace, err)
+	}
+	rects := workspaceWindows(windows, titles, apps)
+
+	displays := make([]image.Rectangle, c.numDisplays())
+	for i := range displays {
+		displays[i] = c.displayBounds(i)
+	}
+	bounds, ok := workspaceBounds(rects, displays)
+	if !ok {
+		return nil, errNoWorkspace
+	}
+
+	img, err := c.grabRect(bounds)
+	if err != nil {
+		return nil, fmt.Errorf("capturing workspace %v: %w", bounds, err)
+	}
+	compressed, err := c.compress(img)
+	if err != nil {
+		return nil, fmt.Errorf("compressing: %w", err)
+	}
+
+	return &Capture{
+		Timestamp:  time.Now(),
+		Image:      img,
+		Compressed: compressed,
+		MIMEType:   MIMEType(compressed),
+		DisplayNum: largestOverlap(bounds, displays),
+	}, nil
+}
+
+// Capture takes the frame to analyze: the configured workspace while any
+// of its windows are open, otherwise the configured region or the
+// primary display
+func (c *Capturer) Capture() (*Capture, error) {
+	if c.workspaceConfigured() {
+		cap, err := c.CaptureWorkspace()
+		if !errors.Is(err, errNoWorkspace) {
+			return cap, err
+		}
+	}
+	if rect, ok := c.configuredRegion(); ok {
+		return c.CaptureRegion(rect)
+	}
+	return c.CapturePrimary()
+}
+
+// grabScreenRect captures an arbitrary rectangle of the virtual screen
+func grabScreenRect(r image.Rectangle) (image.Image, error) {
+	return screenshot.CaptureRect(r)
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package capture
+
+import "errors"
+
+// listWindows enumerates top-level windows. It is only implemented on
+// Windows; elsewhere workspace capture falls back to the primary display.
+func listWindows() ([]Window, error) {
+	return nil, errors.New("window listing is not supported on this platform")
+}
@@ -0,0 +1,68 @@
+package capture
+
+import (
+	"image"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	procGetWindowTextW = user32DLL.NewProc("GetWindowTextW")
+	procGetWindowRect  = user32DLL.NewProc("GetWindowRect")
+	procIsIconic       = user32DLL.NewProc("IsIconic")
+)
+
+// listWindows enumerates the visible top-level windows
+func listWindows() ([]Window, error) {
+	var list []Window
+	callback := windows.NewCallback(func(hwnd windows.HWND, _ uintptr) uintptr {
+		if !windows.IsWindowVisible(hwnd) {
+			return 1 // continue
+		}
+
+		var title [256]uint16
+		n, _, _ := procGetWindowTextW.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&title[0])), uintptr(len(title)))
+		if n == 0 {
+			return 1 // untitled windows are tool windows, not workspaces
+		}
+
+		var rect windows.Rect
+		procGetWindowRect.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&rect)))
+		iconic, _, _ := procIsIconic.Call(uintptr(hwnd))
+
+		list = append(list, Window{
+			Title:     windows.UTF16ToString(title[:n]),
+			App:       windowApp(hwnd),
+			Bounds:    image.Rect(int(rect.Left), int(rect.Top), int(rect.Right), int(rect.Bottom)),
+			Minimized: iconic != 0,
+		})
+		return 1
+	})
+
+	if err := windows.EnumWindows(callback, nil); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// windowApp returns the path of the executable owning hwnd, or "" when it
+// can't be read
+func windowApp(hwnd windows.HWND) string {
+	var pid uint32
+	if _, err := windows.GetWindowThreadProcessId(hwnd, &pid); err != nil {
+		return ""
+	}
+	process, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return ""
+	}
+	defer windows.CloseHandle(process)
+
+	var buf [windows.MAX_PATH]uint16
+	size := uint32(len(buf))
+	if err := windows.QueryFullProcessImageName(process, 0, &buf[0], &size); err != nil {
+		return ""
+	}
+	return windows.UTF16ToString(buf[:size])
+}
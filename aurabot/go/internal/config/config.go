@@ -1,20 +1,43 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"sync"
 
 	"github.com/joho/godotenv"
+	"github.com/robfig/cron/v3"
 	"gopkg.in/yaml.v3"
 )
 
 // Config holds all application configuration
 type Config struct {
-	Capture   CaptureConfig   `yaml:"capture"`
-	LLM       LLMConfig       `yaml:"llm"`
-	Memory    MemoryConfig    `yaml:"memory"`
-	App       AppConfig       `yaml:"app"`
-	Extension ExtensionConfig `yaml:"extension"`
+	Capture      CaptureConfig      `yaml:"capture"`
+	LLM          LLMConfig          `yaml:"llm"`
+	Memory       MemoryConfig       `yaml:"memory"`
+	App          AppConfig          `yaml:"app"`
+	Extension    ExtensionConfig    `yaml:"extension"`
+	QuickEnhance QuickEnhanceConfig `yaml:"quick_enhance"`
+	Enhancer     EnhancerConfig     `yaml:"enhancer"`
+	Events       EventsConfig       `yaml:"events"`
+}
+
+// Values of CaptureConfig.Displays besides a display index
+const (
+	DisplaysPrimary = "primary"
+	DisplaysAll     = "all"
+)
+
+// DefaultRedactPatterns mask credit card numbers and email addresses
+var DefaultRedactPatterns = []string{
+	`\b(?:\d[ -]?){12,18}\d\b`,
+	`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`,
 }
 
 // CaptureConfig holds screen capture settings
@@ -24,6 +47,67 @@ type CaptureConfig struct {
 	MaxWidth        int  `yaml:"max_width"`
 	MaxHeight       int  `yaml:"max_height"`
 	Enabled         bool `yaml:"enabled"`
+	// Format is the encoding sent to the vision model: "jpeg" or "png"
+	// (lossless, sharper text for OCR, larger); Quality and TargetSSIM
+	// only apply to JPEG
+	Format string `yaml:"format"`
+	// ResizeAlgorithm is used when downscaling to MaxWidth/MaxHeight:
+	// "nearest" (fastest) or "bilinear" (smoother small text)
+	ResizeAlgorithm string `yaml:"resize_algorithm"`
+	// TargetSSIM, when set (e.g. 0.95), picks the lowest JPEG quality whose
+	// output keeps this structural similarity instead of a fixed Quality
+	TargetSSIM float64 `yaml:"target_ssim"`
+	// ExcludeDisplays lists display indices that are never captured
+	ExcludeDisplays []int `yaml:"exclude_displays"`
+	// Displays picks what each capture takes: "primary" (the workspace,
+	// region or primary display), "all" (every display not excluded, each
+	// analyzed and stored separately) or a display index such as "1"
+	Displays string `yaml:"displays"`
+	// ArchiveDir, when set, keeps the screenshot of each stored memory
+	// there for review; ArchiveMaxFiles and ArchiveMaxAgeDays bound it
+	// (0 = no limit)
+	ArchiveDir        string `yaml:"archive_dir"`
+	ArchiveMaxFiles   int    `yaml:"archive_max_files"`
+	ArchiveMaxAgeDays int    `yaml:"archive_max_age_days"`
+	// Blocklist entries are matched case-insensitively against the active
+	// window's title; "*" matches any run of characters, so URL patterns
+	// like "*.bank.com" work for browsers showing the address. Matching
+	// captures are neither analyzed nor stored.
+	Blocklist []string `yaml:"blocklist"`
+	// RedactPatterns are regular expressions masked out of memory text
+	// before it is stored
+	RedactPatterns []string `yaml:"redact_patterns"`
+	// StartDelaySeconds postpones the first capture after startup
+	StartDelaySeconds int `yaml:"start_delay_seconds"`
+	// InputCooldownMs delays a capture until this long after the last
+	// keyboard or mouse input, so menus and dialogs can settle
+	InputCooldownMs int `yaml:"input_cooldown_ms"`
+	// IdleThresholdSeconds skips captures while there has been no
+	// keyboard or mouse input for this long; 0 disables it
+	IdleThresholdSeconds int `yaml:"idle_threshold_seconds"`
+	// RetryOnFailure re-enumerates displays and retries a failed capture
+	// once, recovering from docking and resolution changes
+	RetryOnFailure bool `yaml:"retry_on_failure"`
+	// WorkspaceWindows (title substrings) and WorkspaceApps (executable
+	// names) select windows captured together as one image, in place of
+	// the primary display, whenever any of them is open (Windows only)
+	WorkspaceWindows []string `yaml:"workspace_windows"`
+	WorkspaceApps    []string `yaml:"workspace_apps"`
+	// DedupThreshold (0-1) skips analyzing a capture whose thumbnail is at
+	// least this similar to the last analyzed one, e.g. 0.98; 0 disables
+	DedupThreshold float64 `yaml:"dedup_threshold"`
+	// Region, when it has a width and height, is captured in place of
+	// the primary display
+	Region RegionConfig `yaml:"region"`
+}
+
+// RegionConfig is a rectangle of the virtual screen, in pixels from the
+// primary display's top-left corner
+type RegionConfig struct {
+	X      int `yaml:"x"`
+	Y      int `yaml:"y"`
+	Width  int `yaml:"width"`
+	Height int `yaml:"height"`
 }
 
 // LLMConfig holds LLM API settings
@@ -34,8 +118,51 @@ type LLMConfig struct {
 	Temperature    float32 `yaml:"temperature"`
 	TimeoutSeconds int     `yaml:"timeout_seconds"`
 	// Cerebras config for chat/LLM tasks
-	CerebrasAPIKey string  `yaml:"cerebras_api_key"`
-	CerebrasModel  string  `yaml:"cerebras_model"`
+	CerebrasAPIKey string `yaml:"cerebras_api_key"`
+	CerebrasModel  string `yaml:"cerebras_model"`
+	// FallbackOnQuota answers chats with the local model when Cerebras
+	// reports an exhausted quota or billing problem
+	FallbackOnQuota bool `yaml:"fallback_on_quota"`
+	// ChatFallbackLocal retries a chat once with the local model when
+	// Cerebras fails with a network error, 5xx or rate limit
+	ChatFallbackLocal bool `yaml:"chat_fallback_local"`
+	// KeepWarmMinutes pings the vision model this often so LM Studio
+	// doesn't unload it; 0 disables. Pings are only sent between
+	// KeepWarmStartHour and KeepWarmEndHour (local time, 0-24) when set.
+	KeepWarmMinutes   int `yaml:"keep_warm_minutes"`
+	KeepWarmStartHour int `yaml:"keep_warm_start_hour"`
+	KeepWarmEndHour   int `yaml:"keep_warm_end_hour"`
+	// RetryAttempts caps the attempts per analysis or chat call; network
+	// errors, 5xx and 429 responses are retried after RetryBaseDelayMs,
+	// doubling each time, within TimeoutSeconds
+	RetryAttempts    int `yaml:"retry_attempts"`
+	RetryBaseDelayMs int `yaml:"retry_base_delay_ms"`
+	// FieldAliases maps other key names the vision model uses to the
+	// analysis fields (e.g. "task": "user_intent"), on top of the built-in
+	// ones; case and separators are ignored
+	FieldAliases map[string]string `yaml:"field_aliases"`
+	// PromptTemplate replaces the vision analysis prompt; it's a Go
+	// template where {{.Schema}} is the JSON example built from
+	// VisionSchema and {{.Fields}} its fields. VisionSchema replaces the
+	// requested fields, e.g. to add a "sensitivity" field; fields outside
+	// the built-in ones are kept in the analysis metadata. Both empty
+	// keep the built-in prompt for the analysis mode.
+	PromptTemplate string              `yaml:"prompt_template"`
+	VisionSchema   []VisionFieldConfig `yaml:"vision_schema"`
+	// LogPrompts logs every chat prompt, memories included, at debug
+	// level; off by default since they hold screen-derived context
+	LogPrompts bool `yaml:"log_prompts"`
+	// EmbeddingModel is the LM Studio model used for embeddings, e.g.
+	// for enhancer.rerank
+	EmbeddingModel string `yaml:"embedding_model"`
+}
+
+// VisionFieldConfig is one field the vision model is asked to return
+type VisionFieldConfig struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	// List asks for an array of strings rather than a string
+	List bool `yaml:"list"`
 }
 
 // MemoryConfig holds Mem0 settings
@@ -44,19 +171,214 @@ type MemoryConfig struct {
 	BaseURL        string `yaml:"base_url"`
 	UserID         string `yaml:"user_id"`
 	CollectionName string `yaml:"collection_name"`
+	// ExtraCollections are searched alongside CollectionName
+	ExtraCollections []CollectionConfig `yaml:"extra_collections"`
+	// RecencyWeight (0-1) blends relevance with recency when ranking
+	// search results; 0 ranks by relevance alone
+	RecencyWeight        float64 `yaml:"recency_weight"`
+	RecencyHalfLifeHours float64 `yaml:"recency_half_life_hours"`
+	// StructuredFields sends context, activities and key elements as
+	// separate fields that the backend can index and filter on
+	StructuredFields bool `yaml:"structured_fields"`
+	// Backend selects how memories are scoped on the wire: "mem0"
+	// (user_id/agent_id) or "supermemory" (container_tag)
+	Backend string `yaml:"backend"`
+	// MaxResponseBytes caps how much of a search or listing response is
+	// decoded; larger responses fail instead of being buffered (0 = no cap)
+	MaxResponseBytes int64 `yaml:"max_response_bytes"`
+	// BatchConcurrency bounds how many AddBatch items are stored at once;
+	// 1 stores them sequentially
+	BatchConcurrency int `yaml:"batch_concurrency"`
+	// UnknownTimeFallback is the creation time given to memories whose
+	// timestamps are missing or unreadable: "zero" (unknown; no recency
+	// credit, skipped by date filters) or "now"
+	UnknownTimeFallback string `yaml:"unknown_time_fallback"`
+	// LogResponses logs raw search response bodies at debug level
+	LogResponses bool `yaml:"log_responses"`
+	// EncryptionPassphrase, when set, encrypts memory content sent to the
+	// backend and archived screenshots with a key derived from it and
+	// UserID. Changing either makes earlier memories unreadable.
+	EncryptionPassphrase string `yaml:"encryption_passphrase"`
+}
+
+// CollectionConfig names a Mem0 collection. BaseURL and APIKey, when set,
+// override the MemoryConfig values for requests to that collection.
+type CollectionConfig struct {
+	Name    string `yaml:"name"`
+	BaseURL string `yaml:"base_url"`
+	APIKey  string `yaml:"api_key"`
+}
+
+// UnmarshalYAML also accepts a bare collection name
+func (c *CollectionConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		c.Name = value.Value
+		return nil
+	}
+	type plain CollectionConfig
+	return value.Decode((*plain)(c))
 }
 
 // AppConfig holds general app settings
 type AppConfig struct {
 	Verbose          bool `yaml:"verbose"`
 	ProcessOnCapture bool `yaml:"process_on_capture"`
-	MemoryWindow     int  `yaml:"memory_window"`
+	// CaptureSource is "screen" (periodic capture), "manual" (only on
+	// request) or "none" (no screen capture at all)
+	CaptureSource string `yaml:"capture_source"`
+	// CaptureCron, a five-field cron expression such as "0 * * * *" or
+	// "0 9 * * 1-5", schedules captures in place of Capture.IntervalSeconds
+	CaptureCron string `yaml:"capture_cron"`
+	// HandleSignals makes the desktop app clean up on SIGINT/SIGTERM
+	HandleSignals bool `yaml:"handle_signals"`
+	// MergeWindowSeconds merges a capture into the previous memory when
+	// it has the same context and came at most this long after; 0 disables
+	MergeWindowSeconds int `yaml:"merge_window_seconds"`
+	// DryRun analyzes captures and logs the results without storing them,
+	// for tuning the vision prompt
+	DryRun bool `yaml:"dry_run"`
+	// WatchConfig reloads config.yaml when it changes, applying the
+	// capture interval, quality and enabled flag and the memory window
+	// without a restart
+	WatchConfig  bool `yaml:"watch_config"`
+	MemoryWindow int  `yaml:"memory_window"`
+	// AnalysisMode selects the vision JSON schema: "full" or "minimal"
+	// (summary + context only, easier for small local models)
+	AnalysisMode string `yaml:"analysis_mode"`
+	// OCRMergeStrategy controls how OCR text is combined with the vision
+	// summary: "dedup" (only uncovered lines), "append" or "metadata"
+	OCRMergeStrategy string `yaml:"ocr_merge_strategy"`
+	// ConfidenceThreshold asks the model to rate its analysis and applies
+	// LowConfidenceAction ("skip" or "flag") below it; 0 disables the gate
+	ConfidenceThreshold float64 `yaml:"confidence_threshold"`
+	LowConfidenceAction string  `yaml:"low_confidence_action"`
+	// StateFile keeps the last analyzed frame across restarts so an
+	// unchanged screen isn't analyzed again; empty disables persistence
+	StateFile string `yaml:"state_file"`
+	// The user profile (recurring activities, tools and contexts) is
+	// rebuilt every ProfileRefreshMinutes and after ProfileEveryMemories
+	// new memories; 0 disables each trigger
+	ProfileRefreshMinutes int `yaml:"profile_refresh_minutes"`
+	ProfileEveryMemories  int `yaml:"profile_every_memories"`
+	// CompactOnShutdown summarizes the session's captures into one memory
+	// on a clean shutdown; CompactPrune also deletes the originals. The
+	// pass is abandoned after CompactTimeoutSeconds.
+	CompactOnShutdown     bool `yaml:"compact_on_shutdown"`
+	CompactPrune          bool `yaml:"compact_prune"`
+	CompactTimeoutSeconds int  `yaml:"compact_timeout_seconds"`
+	// LogRepeatWindowSeconds collapses identical log lines repeated within
+	// this window into one "(repeated N times)" line; 0 logs every line
+	LogRepeatWindowSeconds int `yaml:"log_repeat_window_seconds"`
+	// LogLevel is "debug", "info", "warn" or "error"; empty derives it
+	// from Verbose (debug when set, info otherwise). LogFormat is "text"
+	// or "json" (one object per line, for log collectors).
+	LogLevel  string `yaml:"log_level"`
+	LogFormat string `yaml:"log_format"`
 }
 
 // ExtensionConfig holds browser extension API settings
 type ExtensionConfig struct {
 	Enabled bool `yaml:"enabled"`
 	Port    int  `yaml:"port"`
+	// BindAddress is the interface the server listens on; 0.0.0.0 exposes
+	// it to the local network
+	BindAddress string `yaml:"bind_address"`
+	// WebUI serves a minimal search/enhance page at / on the same port
+	WebUI bool `yaml:"web_ui"`
+	// AdminToken enables the admin endpoints (e.g. searching every
+	// user's memories) for requests bearing it; empty disables them
+	AdminToken string `yaml:"admin_token"`
+	// APIKey is required as a bearer token on every /api/* route except
+	// health; empty leaves the API open to any local process
+	APIKey string `yaml:"api_key"`
+	// RateLimitPerSec caps /api/* requests per client each second; 0
+	// disables the limit
+	RateLimitPerSec float64 `yaml:"rate_limit_per_sec"`
+}
+
+// QuickEnhanceConfig holds global hotkey enhancement settings
+type QuickEnhanceConfig struct {
+	TimeoutSeconds int `yaml:"timeout_seconds"`
+	// Hotkeys are tried in order until one registers, e.g. "Ctrl+Alt+E"
+	Hotkeys         []string `yaml:"hotkeys"`
+	HotkeyAttempts  int      `yaml:"hotkey_attempts"`
+	HotkeyBackoffMs int      `yaml:"hotkey_backoff_ms"`
+
+	Overlay OverlayConfig `yaml:"overlay"`
+}
+
+// OverlayConfig places the floating enhance button. Sizes are in pixels
+// at 96 DPI and scaled by the display's DPI; zero uses the default.
+type OverlayConfig struct {
+	// HideRadius hides the button once the cursor moves this far from it
+	HideRadius int `yaml:"hide_radius"`
+	// OffsetX and OffsetY place the button relative to the cursor
+	OffsetX    int `yaml:"offset_x"`
+	OffsetY    int `yaml:"offset_y"`
+	ButtonSize int `yaml:"button_size"`
+}
+
+// EnhancerConfig holds prompt enhancement settings
+type EnhancerConfig struct {
+	// RequireStrong only enhances when at least one memory is highly
+	// relevant; otherwise the prompt is returned unchanged
+	RequireStrong bool `yaml:"require_strong"`
+	// FailOpen returns the prompt unchanged when the memory backend can't
+	// be searched; false makes enhancement fail instead
+	FailOpen bool `yaml:"fail_open"`
+	// HighRelevanceThreshold is the search score above which a memory is
+	// highly relevant; tune it to the backend's score distribution.
+	// MaxHighRelevance and MaxContextual cap how many of each a
+	// contextual enhancement includes.
+	HighRelevanceThreshold float64 `yaml:"high_relevance_threshold"`
+	MaxHighRelevance       int     `yaml:"max_high_relevance"`
+	MaxContextual          int     `yaml:"max_contextual"`
+	// FetchAllowedHosts enables fetching the page URL sent with an
+	// enhance request to enrich the search; only these hosts are contacted
+	FetchAllowedHosts []string `yaml:"fetch_allowed_hosts"`
+	FetchTimeoutMs    int      `yaml:"fetch_timeout_ms"`
+	// Reranker reorders search results before enhancement: "score",
+	// "recency" or "usage"; empty keeps the backend's order
+	Reranker string `yaml:"reranker"`
+	// Rerank re-scores search results by the cosine similarity of their
+	// embeddings (from LLM.EmbeddingModel) to the prompt's before the
+	// reranker runs; it costs an embeddings call per enhancement
+	Rerank bool `yaml:"rerank"`
+	// MaxMemoryChars truncates each memory in an enhanced prompt, at a
+	// sentence boundary where possible; 0 means no limit
+	MaxMemoryChars int `yaml:"max_memory_chars"`
+	// MaxPromptTokens caps an enhanced prompt's estimated tokens (about
+	// four characters each) by dropping the least relevant memories; 0
+	// means no limit
+	MaxPromptTokens int `yaml:"max_prompt_tokens"`
+	// IncludeProfile adds the user profile to every enhancement
+	IncludeProfile bool `yaml:"include_profile"`
+	// IncludeNow adds the current local date and time to every enhanced
+	// prompt, at NowPosition ("append" or "prepend") in NowFormat (a Go
+	// time layout; empty for e.g. "Monday, January 2, 2006 15:04 MST")
+	IncludeNow  bool   `yaml:"include_now"`
+	NowPosition string `yaml:"now_position"`
+	NowFormat   string `yaml:"now_format"`
+	// StoreEnhancements records every enhancement as a memory (source
+	// "enhancement") with its type; the prompt text and the page's site
+	// are only kept with StoreEnhancementPrompts and StoreEnhancementSites
+	StoreEnhancements       bool `yaml:"store_enhancements"`
+	StoreEnhancementPrompts bool `yaml:"store_enhancement_prompts"`
+	StoreEnhancementSites   bool `yaml:"store_enhancement_sites"`
+}
+
+// EventsConfig holds event delivery settings
+type EventsConfig struct {
+	// Webhooks receive a JSON POST for every event, e.g. "memory.stored"
+	Webhooks              []string `yaml:"webhooks"`
+	WebhookTimeoutSeconds int      `yaml:"webhook_timeout_seconds"`
+	// WebhookMaxAttempts caps the attempts per delivery; retries wait
+	// WebhookBackoffMs, doubling up to WebhookMaxBackoffMs, with jitter
+	WebhookMaxAttempts  int `yaml:"webhook_max_attempts"`
+	WebhookBackoffMs    int `yaml:"webhook_backoff_ms"`
+	WebhookMaxBackoffMs int `yaml:"webhook_max_backoff_ms"`
+	// FlushTimeoutSeconds bounds how long shutdown waits for pending deliveries
+	FlushTimeoutSeconds int `yaml:"flush_timeout_seconds"`
 }
 
 // Load reads config from file or creates default
@@ -68,9 +390,15 @@ func Load() (*Config, error) {
 		Capture: CaptureConfig{
 			IntervalSeconds: 30,
 			Quality:         60,
-			MaxWidth:        1280,  // 720p width - LFM-2 works best with this
-			MaxHeight:       720,   // 720p height
+			MaxWidth:        1280, // 720p width - LFM-2 works best with this
+			MaxHeight:       720,  // 720p height
 			Enabled:         true,
+			Format:          "jpeg",
+			ResizeAlgorithm: "nearest",
+			RetryOnFailure:  true,
+			Displays:        DisplaysPrimary,
+			ArchiveMaxFiles: 1000,
+			RedactPatterns:  slices.Clone(DefaultRedactPatterns),
 		},
 		LLM: LLMConfig{
 			BaseURL:        "http://localhost:1234/v1",
@@ -80,21 +408,75 @@ func Load() (*Config, error) {
 			TimeoutSeconds: 30,
 			CerebrasAPIKey: os.Getenv("CEREBRAS_API_KEY"),
 			CerebrasModel:  "llama3.1-70b",
+
+			FallbackOnQuota:   true,
+			ChatFallbackLocal: true,
+
+			RetryAttempts:    3,
+			RetryBaseDelayMs: 500,
 		},
 		Memory: MemoryConfig{
 			APIKey:         "",
 			BaseURL:        "http://localhost:8000",
 			UserID:         "default_user",
 			CollectionName: "screen_memories_v3",
+
+			RecencyHalfLifeHours: 72,
+			MaxResponseBytes:     8 << 20,
+			BatchConcurrency:     1,
+			UnknownTimeFallback:  "zero",
 		},
 		App: AppConfig{
 			Verbose:          false,
 			ProcessOnCapture: true,
+			CaptureSource:    "screen",
+			HandleSignals:    true,
+			WatchConfig:      true,
 			MemoryWindow:     10,
+			AnalysisMode:     "full",
+			OCRMergeStrategy: "dedup",
+
+			LowConfidenceAction: "skip",
+			StateFile:           "capture_state.json",
+
+			ProfileRefreshMinutes:  60,
+			ProfileEveryMemories:   20,
+			CompactTimeoutSeconds:  30,
+			LogRepeatWindowSeconds: 60,
+			LogFormat:              "text",
 		},
 		Extension: ExtensionConfig{
-			Enabled: true,
-			Port:    7345,
+			Enabled:         true,
+			Port:            7345,
+			BindAddress:     "127.0.0.1",
+			RateLimitPerSec: 10,
+		},
+		QuickEnhance: QuickEnhanceConfig{
+			TimeoutSeconds:  10,
+			Hotkeys:         []string{"Ctrl+Alt+E", "Win+Shift+E"},
+			HotkeyAttempts:  3,
+			HotkeyBackoffMs: 200,
+			Overlay: OverlayConfig{
+				HideRadius: 100,
+				OffsetX:    10,
+				OffsetY:    10,
+				ButtonSize: 48,
+			},
+		},
+		Enhancer: EnhancerConfig{
+			FailOpen:               true,
+			HighRelevanceThreshold: 0.85,
+			MaxHighRelevance:       3,
+			MaxContextual:          2,
+			FetchTimeoutMs:         2000,
+			NowPosition:            "append",
+		},
+		Events: EventsConfig{
+			WebhookTimeoutSeconds: 5,
+			WebhookMaxAttempts:    3,
+			WebhookBackoffMs:      500,
+			WebhookMaxBackoffMs:   30000,
+			FlushTimeoutSeconds:   5,
 		},
 	}
 
@@ -122,15 +504,148 @@ func Load() (*Config, error) {
 	if val := os.Getenv("CEREBRAS_API_KEY"); val != "" {
 		cfg.LLM.CerebrasAPIKey = val
 	}
+	if val := os.Getenv("AURABOT_ADMIN_TOKEN"); val != "" {
+		cfg.Extension.AdminToken = val
+	}
+	if val := os.Getenv("AURABOT_API_KEY"); val != "" {
+		cfg.Extension.APIKey = val
+	}
+	if val := os.Getenv("AURABOT_ENCRYPTION_PASSPHRASE"); val != "" {
+		cfg.Memory.EncryptionPassphrase = val
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
 
 	return cfg, nil
 }
 
-// Save writes current config to file
+// Validate checks that values are in range, reporting every bad one in a
+// single error
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Capture.IntervalSeconds < 1 {
+		invalid("invalid capture.interval_seconds %d: want at least 1", c.Capture.IntervalSeconds)
+	}
+	if c.Capture.Quality < 1 || c.Capture.Quality > 100 {
+		invalid("invalid capture.quality %d: want 1-100", c.Capture.Quality)
+	}
+	switch c.Capture.Displays {
+	case "", DisplaysPrimary, DisplaysAll:
+	default:
+		if n, err := strconv.Atoi(c.Capture.Displays); err != nil || n < 0 {
+			invalid("invalid capture.displays %q: want primary, all or a display index", c.Capture.Displays)
+		}
+	}
+	if c.Capture.ArchiveMaxFiles < 0 {
+		invalid("invalid capture.archive_max_files %d: want 0 or more", c.Capture.ArchiveMaxFiles)
+	}
+	if c.Capture.ArchiveMaxAgeDays < 0 {
+		invalid("invalid capture.archive_max_age_days %d: want 0 or more", c.Capture.ArchiveMaxAgeDays)
+	}
+	for _, p := range c.Capture.RedactPatterns {
+		if _, err := regexp.Compile(p); err != nil {
+			invalid("invalid capture.redact_patterns entry %q: %v", p, err)
+		}
+	}
+	if err := validateURL(c.LLM.BaseURL); err != nil {
+		invalid("invalid llm.base_url %q: %v", c.LLM.BaseURL, err)
+	}
+	if err := validateURL(c.Memory.BaseURL); err != nil {
+		invalid("invalid memory.base_url %q: %v", c.Memory.BaseURL, err)
+	}
+	if c.Extension.Enabled && (c.Extension.Port < 1 || c.Extension.Port > 65535) {
+		invalid("invalid extension.port %d: want 1-65535", c.Extension.Port)
+	}
+
+	if c.App.CaptureCron != "" {
+		if _, err := cron.ParseStandard(c.App.CaptureCron); err != nil {
+			invalid("invalid app.capture_cron %q: %w", c.App.CaptureCron, err)
+		}
+	}
+	switch c.App.LogLevel {
+	case "", "debug", "info", "warn", "error":
+	default:
+		invalid("invalid app.log_level %q: want debug, info, warn or error", c.App.LogLevel)
+	}
+	switch c.App.LogFormat {
+	case "", "text", "json":
+	default:
+		invalid("invalid app.log_format %q: want text or json", c.App.LogFormat)
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateURL checks that a base URL is set and absolute
+func validateURL(raw string) error {
+	if raw == "" {
+		return errors.New("must be set")
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return errors.New("want an absolute URL such as http://localhost:8000")
+	}
+	return nil
+}
+
+// saveMu serializes Save calls so concurrent saves can't interleave
+var saveMu sync.Mutex
+
+// writeTemp writes the new config to the temporary file; replaceable in
+// tests to simulate a failed write
+var writeTemp = func(f *os.File, data []byte) error {
+	_, err := f.Write(data)
+	return err
+}
+
+// Save writes current config to file. It writes a temporary file in the
+// same directory and renames it into place, so a crash mid-write leaves
+// the old file intact rather than a half-written one.
 func (c *Config) Save(path string) error {
 	data, err := yaml.Marshal(c)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, data, 0644)
+
+	saveMu.Lock()
+	defer saveMu.Unlock()
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("saving config: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := writeTemp(tmp, data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("saving config: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("saving config: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("saving config: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("saving config: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("saving config: %w", err)
+	}
+	return nil
 }
@@ -1,7 +1,10 @@
 package config
 
 import (
+	"errors"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"gopkg.in/yaml.v3"
@@ -86,3 +89,175 @@ capture:
 		t.Errorf("Expected quality 90, got %d", cfg.Capture.Quality)
 	}
 }
+
+func TestLoad_InvalidCaptureCron(t *testing.T) {
+	if err := os.WriteFile("config.yaml", []byte("app:\n  capture_cron: \"every monday\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove("config.yaml")
+
+	if _, err := Load(); err == nil {
+		t.Error("Load accepted an invalid capture_cron")
+	}
+
+	if err := os.WriteFile("config.yaml", []byte("app:\n  capture_cron: \"0 9 * * 1-5\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.App.CaptureCron != "0 9 * * 1-5" {
+		t.Errorf("CaptureCron = %q", cfg.App.CaptureCron)
+	}
+}
+
+func TestLoad_InvalidLogLevel(t *testing.T) {
+	if err := os.WriteFile("config.yaml", []byte("app:\n  log_level: \"trace\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove("config.yaml")
+
+	if _, err := Load(); err == nil {
+		t.Error("Load accepted an invalid log_level")
+	}
+}
+
+func TestValidate_RejectsInvalidValues(t *testing.T) {
+	valid := func() *Config {
+		return &Config{
+			Capture:   CaptureConfig{IntervalSeconds: 30, Quality: 60},
+			LLM:       LLMConfig{BaseURL: "http://localhost:1234/v1"},
+			Memory:    MemoryConfig{BaseURL: "http://localhost:8000"},
+			Extension: ExtensionConfig{Enabled: true, Port: 7345},
+		}
+	}
+	if err := valid().Validate(); err != nil {
+		t.Fatalf("Validate rejected a valid config: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		field  string
+	}{
+		{"zero interval", func(c *Config) { c.Capture.IntervalSeconds = 0 }, "capture.interval_seconds"},
+		{"negative quality", func(c *Config) { c.Capture.Quality = -5 }, "capture.quality"},
+		{"quality over 100", func(c *Config) { c.Capture.Quality = 101 }, "capture.quality"},
+		{"unknown displays", func(c *Config) { c.Capture.Displays = "secondary" }, "capture.displays"},
+		{"negative display", func(c *Config) { c.Capture.Displays = "-1" }, "capture.displays"},
+		{"negative archive max files", func(c *Config) { c.Capture.ArchiveMaxFiles = -1 }, "capture.archive_max_files"},
+		{"negative archive max age", func(c *Config) { c.Capture.ArchiveMaxAgeDays = -1 }, "capture.archive_max_age_days"},
+		{"bad redact pattern", func(c *Config) { c.Capture.RedactPatterns = []string{"(unclosed"} }, "capture.redact_patterns"},
+		{"blank llm url", func(c *Config) { c.LLM.BaseURL = "" }, "llm.base_url"},
+		{"relative llm url", func(c *Config) { c.LLM.BaseURL = "localhost:1234" }, "llm.base_url"},
+		{"blank memory url", func(c *Config) { c.Memory.BaseURL = "" }, "memory.base_url"},
+		{"port zero", func(c *Config) { c.Extension.Port = 0 }, "extension.port"},
+		{"port too large", func(c *Config) { c.Extension.Port = 70000 }, "extension.port"},
+		{"bad cron", func(c *Config) { c.App.CaptureCron = "every monday" }, "app.capture_cron"},
+		{"bad log level", func(c *Config) { c.App.LogLevel = "trace" }, "app.log_level"},
+		{"bad log format", func(c *Config) { c.App.LogFormat = "xml" }, "app.log_format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := valid()
+			tt.modify(c)
+			err := c.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.field) {
+				t.Errorf("Validate() = %v, want an error naming %s", err, tt.field)
+			}
+		})
+	}
+
+	// The port doesn't matter with the extension server off
+	c := valid()
+	c.Extension = ExtensionConfig{}
+	if err := c.Validate(); err != nil {
+		t.Errorf("Validate rejected an unset port with the server off: %v", err)
+	}
+
+	// Every problem is reported at once
+	c = valid()
+	c.Capture.IntervalSeconds = 0
+	c.Memory.BaseURL = ""
+	err := c.Validate()
+	if err == nil || !strings.Contains(err.Error(), "capture.interval_seconds") || !strings.Contains(err.Error(), "memory.base_url") {
+		t.Errorf("Validate() = %v, want both problems", err)
+	}
+}
+
+func TestCollectionConfig_NameOrMapping(t *testing.T) {
+	data := []byte(`
+extra_collections:
+  - shared
+  - name: work
+    base_url: http://work:8000
+    api_key: secret
+`)
+	var cfg MemoryConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	want := []CollectionConfig{
+		{Name: "shared"},
+		{Name: "work", BaseURL: "http://work:8000", APIKey: "secret"},
+	}
+	if len(cfg.ExtraCollections) != len(want) {
+		t.Fatalf("collections = %+v, want %+v", cfg.ExtraCollections, want)
+	}
+	for i := range want {
+		if cfg.ExtraCollections[i] != want[i] {
+			t.Errorf("collection %d = %+v, want %+v", i, cfg.ExtraCollections[i], want[i])
+		}
+	}
+}
+
+func TestSave_InterruptedWriteKeepsOriginal(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	original := "capture:\n  interval_seconds: 60\n"
+	if err := os.WriteFile(path, []byte(original), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// The write dies halfway through
+	defer func(orig func(*os.File, []byte) error) { writeTemp = orig }(writeTemp)
+	writeTemp = func(f *os.File, data []byte) error {
+		f.Write(data[:len(data)/2])
+		return errors.New("disk full")
+	}
+
+	cfg := &Config{Capture: CaptureConfig{IntervalSeconds: 5}}
+	if err := cfg.Save(path); err == nil {
+		t.Fatal("Save succeeded, want the write error")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != original {
+		t.Errorf("config = %q after a failed save, want the original", data)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("%d files in the config dir, want the temporary file removed", len(entries))
+	}
+
+	// A completed save replaces the file and keeps its permissions
+	writeTemp = func(f *os.File, data []byte) error {
+		_, err := f.Write(data)
+		return err
+	}
+	if err := cfg.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	saved := &Config{}
+	data, _ = os.ReadFile(path)
+	if err := yaml.Unmarshal(data, saved); err != nil || saved.Capture.IntervalSeconds != 5 {
+		t.Errorf("saved config = %+v (%v), want interval 5", saved.Capture, err)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600 kept", info.Mode().Perm())
+	}
+}
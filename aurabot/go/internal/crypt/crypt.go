@@ -0,0 +1,111 @@
+// Package crypt encrypts memory content and archived screenshots with
+// AES-GCM under a key derived from a passphrase, so backends and disks
+// only ever hold ciphertext.
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// scrypt cost parameters, as recommended for interactive logins
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+	keyLen  = 32 // AES-256
+)
+
+// Prefix marks encrypted strings, so plaintext stored before encryption
+// was enabled can still be told apart and read
+const Prefix = "enc:v1:"
+
+// ErrDecrypt is returned for ciphertext that was tampered with or sealed
+// under a different key
+var ErrDecrypt = errors.New("decrypting: wrong key or corrupted data")
+
+// Cipher seals and opens data with AES-256-GCM
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// New derives a key from passphrase and salt with scrypt. The same
+// passphrase and salt always give the same key.
+func New(passphrase, salt string) (*Cipher, error) {
+	if passphrase == "" {
+		return nil, errors.New("empty passphrase")
+	}
+	key, err := scrypt.Key([]byte(passphrase), []byte(salt), scryptN, scryptR, scryptP, keyLen)
+	if err != nil {
+		return nil, fmt.Errorf("deriving key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// Seal encrypts data, prepending a random nonce
+func (c *Cipher) Seal(data []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generating nonce: %w", err)
+	}
+	return c.aead.Seal(nonce, nonce, data, nil), nil
+}
+
+// Open decrypts data sealed by Seal
+func (c *Cipher) Open(data []byte) ([]byte, error) {
+	n := c.aead.NonceSize()
+	if len(data) < n {
+		return nil, ErrDecrypt
+	}
+	plain, err := c.aead.Open(nil, data[:n], data[n:], nil)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plain, nil
+}
+
+// SealString encrypts s as Prefix followed by base64
+func (c *Cipher) SealString(s string) (string, error) {
+	sealed, err := c.Seal([]byte(s))
+	if err != nil {
+		return "", err
+	}
+	return Prefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// OpenString decrypts a string from SealString. Strings without Prefix
+// are returned unchanged.
+func (c *Cipher) OpenString(s string) (string, error) {
+	if !IsSealed(s) {
+		return s, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(s, Prefix))
+	if err != nil {
+		return "", ErrDecrypt
+	}
+	plain, err := c.Open(sealed)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// IsSealed reports whether s was produced by SealString
+func IsSealed(s string) bool {
+	return strings.HasPrefix(s, Prefix)
+}
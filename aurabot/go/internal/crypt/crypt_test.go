@@ -0,0 +1,48 @@
+package crypt
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCipher_RoundTrip(t *testing.T) {
+	c, err := New("correct horse", "default_user")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	sealed, err := c.SealString("password: hunter2")
+	if err != nil {
+		t.Fatalf("SealString: %v", err)
+	}
+	if !IsSealed(sealed) || strings.Contains(sealed, "hunter2") {
+		t.Fatalf("sealed = %q, want prefixed ciphertext", sealed)
+	}
+	if plain, err := c.OpenString(sealed); err != nil || plain != "password: hunter2" {
+		t.Errorf("OpenString = %q, %v", plain, err)
+	}
+
+	// Plaintext from before encryption was enabled passes through
+	if plain, err := c.OpenString("Editing main.go"); err != nil || plain != "Editing main.go" {
+		t.Errorf("OpenString(plaintext) = %q, %v", plain, err)
+	}
+
+	// The same passphrase and salt derive the same key
+	again, _ := New("correct horse", "default_user")
+	frame := []byte{0xff, 0xd8, 0xff}
+	sealedFrame, _ := c.Seal(frame)
+	if got, err := again.Open(sealedFrame); err != nil || !bytes.Equal(got, frame) {
+		t.Errorf("Open with re-derived key = %v, %v", got, err)
+	}
+
+	other, _ := New("battery staple", "default_user")
+	if _, err := other.OpenString(sealed); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("OpenString with another key: err = %v, want ErrDecrypt", err)
+	}
+
+	if _, err := New("", "default_user"); err == nil {
+		t.Error("New accepted an empty passphrase")
+	}
+}
@@ -0,0 +1,48 @@
+package enhancer
+
+import "unicode/utf8"
+
+// SetMaxPromptTokens caps the estimated size of an enhanced prompt; the
+// least relevant memories are dropped until it fits. 0 means no limit.
+func (e *Enhancer) SetMaxPromptTokens(n int) {
+	e.maxPromptTokens = n
+}
+
+// estimateTokens roughly counts tokens at four characters each, which is
+// close enough for English text with common tokenizers
+func estimateTokens(s string) int {
+	return (utf8.RuneCountInString(s) + 3) / 4
+}
+
+// dropLeastRelevant removes the least relevant memory from d, reporting
+// false when none are left. Buckets and lists are ordered best first.
+func (d *TemplateData) dropLeastRelevant() bool {
+	switch {
+	case len(d.Memories) > 0:
+		d.Memories = d.Memories[:len(d.Memories)-1]
+	case len(d.Contextual) > 0:
+		d.Contextual = d.Contextual[:len(d.Contextual)-1]
+	case len(d.HighRelevance) > 0:
+		d.HighRelevance = d.HighRelevance[:len(d.HighRelevance)-1]
+	default:
+		return false
+	}
+	return true
+}
+
+// fitBudget renders d with render, dropping memories until the result is
+// within the token budget, and returns it with how many were dropped. The
+// prompt is still returned if even no memories exceed the budget.
+func (e *Enhancer) fitBudget(d *TemplateData, render func(TemplateData) string) (string, int) {
+	prompt := render(*d)
+	if e.maxPromptTokens <= 0 {
+		return prompt, 0
+	}
+
+	dropped := 0
+	for estimateTokens(prompt) > e.maxPromptTokens && d.dropLeastRelevant() {
+		dropped++
+		prompt = render(*d)
+	}
+	return prompt, dropped
+}
@@ -0,0 +1,42 @@
+package enhancer
+
+import "screen-memory-assistant/internal/memory"
+
+// EnhancementTypes lists the values of EnhancementResult.EnhancementType
+var EnhancementTypes = []string{"contextual", "detailed", "minimal"}
+
+// Rerankers lists the names accepted by UseReranker
+var Rerankers = []string{memory.RerankScore, memory.RerankRecency, memory.RerankUsage}
+
+// Capabilities describes what the enhancer supports as configured
+type Capabilities struct {
+	EnhancementTypes []string `json:"enhancement_types"`
+	Rerankers        []string `json:"rerankers"`
+	// Reranker is the built-in reranker in use; empty keeps backend order
+	Reranker       string  `json:"reranker"`
+	RecencyBoost   float64 `json:"recency_boost"`
+	MaxMemoryChars int     `json:"max_memory_chars"`
+	PageFetch      bool    `json:"page_fetch"`
+	Profile        bool    `json:"profile"`
+	// EmbeddingRerank is set when results are re-scored by embeddings
+	EmbeddingRerank bool `json:"embedding_rerank"`
+	// HighRelevanceThreshold is the score above which a memory is
+	// highly relevant
+	HighRelevanceThreshold float64 `json:"high_relevance_threshold"`
+}
+
+// Capabilities reports the enhancer's current options
+func (e *Enhancer) Capabilities() Capabilities {
+	return Capabilities{
+		EnhancementTypes: EnhancementTypes,
+		Rerankers:        Rerankers,
+		Reranker:         e.rerankerName,
+		RecencyBoost:     e.RecencyBoostWeight(),
+		MaxMemoryChars:   e.maxMemoryChars,
+		PageFetch:        e.fetcher != nil,
+		Profile:          e.profile != nil,
+		EmbeddingRerank:  e.embedder != nil,
+
+		HighRelevanceThreshold: e.relevance.Threshold,
+	}
+}
@@ -0,0 +1,58 @@
+package enhancer
+
+import (
+	"context"
+
+	"screen-memory-assistant/internal/memory"
+)
+
+// ScoreDebug explains how a search result was scored
+type ScoreDebug struct {
+	// RawScore and Distance are as returned by the backend
+	RawScore float64 `json:"raw_score"`
+	Distance float64 `json:"distance"`
+	// NormalizedScore is RawScore relative to the best result (0-1)
+	NormalizedScore float64 `json:"normalized_score"`
+	// RecencyFactor is the recency multiplier applied; 1 when recency
+	// isn't part of the ranking
+	RecencyFactor float64 `json:"recency_factor"`
+}
+
+type scoreDebugKey struct{}
+
+// WithScoreDebug makes searches made with the returned context attach a
+// ScoreDebug to each result
+func WithScoreDebug(ctx context.Context) context.Context {
+	return context.WithValue(ctx, scoreDebugKey{}, true)
+}
+
+// resultInfos converts ranked search results, attaching score details
+// when the context asks for them
+func resultInfos(ctx context.Context, reranker memory.Reranker, results []memory.SearchResult) []MemoryInfo {
+	debug, _ := ctx.Value(scoreDebugKey{}).(bool)
+
+	best := 0.0
+	for _, r := range results {
+		if r.RawScore > best {
+			best = r.RawScore
+		}
+	}
+	recency, hasRecency := reranker.(memory.RecencyReranker)
+
+	var memories []MemoryInfo
+	for _, r := range results {
+		info := newResultInfo(r)
+		if debug {
+			d := &ScoreDebug{RawScore: r.RawScore, Distance: r.Distance, RecencyFactor: 1}
+			if best > 0 {
+				d.NormalizedScore = r.RawScore / best
+			}
+			if hasRecency {
+				d.RecencyFactor = recency.Factor(r.Memory)
+			}
+			info.Debug = d
+		}
+		memories = append(memories, info)
+	}
+	return memories
+}
@@ -0,0 +1,85 @@
+package enhancer
+
+import (
+	"context"
+	"log/slog"
+	"math"
+
+	"screen-memory-assistant/internal/memory"
+)
+
+// Embedder turns texts into embedding vectors, one per text
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// SetEmbedder enables re-scoring search results by the similarity of their
+// embeddings to the prompt's, before the reranker runs; nil disables it
+func (e *Enhancer) SetEmbedder(embedder Embedder) {
+	e.embedder = embedder
+}
+
+// embeddingReranker scores results by cosine similarity to a query. The
+// backend's order is kept if the embeddings can't be fetched.
+type embeddingReranker struct {
+	ctx      context.Context
+	embedder Embedder
+	query    string
+	logger   *slog.Logger
+}
+
+// Rerank returns the results re-scored and sorted by similarity
+func (r embeddingReranker) Rerank(results []memory.SearchResult) []memory.SearchResult {
+	if len(results) == 0 {
+		return results
+	}
+
+	texts := make([]string, 0, len(results)+1)
+	texts = append(texts, r.query)
+	for _, res := range results {
+		texts = append(texts, res.Memory.Content)
+	}
+	vectors, err := r.embedder.Embed(r.ctx, texts)
+	if err != nil {
+		r.logger.Warn("embedding rerank failed, keeping search order", "error", err)
+		return results
+	}
+
+	ranked := make([]memory.SearchResult, len(results))
+	copy(ranked, results)
+	for i := range ranked {
+		ranked[i].Score = cosineSimilarity(vectors[0], vectors[i+1])
+	}
+	return memory.ScoreReranker{}.Rerank(ranked)
+}
+
+// cosineSimilarity returns the cosine of the angle between a and b, or 0
+// when either is empty, zero or their lengths differ
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// chainReranker applies each non-nil reranker in turn
+type chainReranker []memory.Reranker
+
+// Rerank runs the results through every reranker in order
+func (c chainReranker) Rerank(results []memory.SearchResult) []memory.SearchResult {
+	for _, r := range c {
+		if r != nil {
+			results = r.Rerank(results)
+		}
+	}
+	return results
+}
@@ -3,8 +3,8 @@ package enhancer
 import (
 	"context"
 	"fmt"
-	"log"
-	"strings"
+	"log/slog"
+	"maps"
 	"sync"
 	"time"
 
@@ -13,48 +13,213 @@ import (
 
 // Enhancer handles prompt enhancement using stored memories
 type Enhancer struct {
-	memoryStore *memory.Store
+	memoryStore memory.MemoryStore
+
+	// requireStrong skips enhancement unless a memory is highly relevant
+	requireStrong bool
+
+	// relevance is the high-relevance cutoff and the per-bucket caps
+	relevance RelevanceOptions
+
+	// fetcher optionally pulls page content into the search query
+	fetcher *ContextFetcher
+
+	// reranker reorders search results before bucketing; nil keeps them as is
+	reranker     memory.Reranker
+	rerankerName string
+
+	// embedder, when set, re-scores results by similarity to the prompt
+	embedder Embedder
+
+	// templates format enhancements by page context
+	templates map[string]TemplateFunc
+
+	// failClosed makes Enhance fail when the memory store can't be
+	// searched instead of returning the prompt unchanged
+	failClosed bool
+
+	// maxMemoryChars caps each memory's length in the prompt; 0 is unlimited
+	maxMemoryChars int
+
+	// maxPromptTokens caps the enhanced prompt's estimated tokens; 0 is
+	// unlimited
+	maxPromptTokens int
+
+	// profile returns a short user profile added to every enhancement
+	profile func() string
+
+	// history, when set, records each enhancement as a memory
+	history *HistoryOptions
+
+	// nowPosition ("append", "prepend" or "" for off) and nowFormat place
+	// the current time in enhanced prompts; now is the clock
+	nowPosition string
+	nowFormat   string
+	now         func() time.Time
+
+	logger *slog.Logger
 
 	// Stats tracking
-	statsMu          sync.RWMutex
-	enhancementsMade int
-	lastEnhancement  time.Time
+	statsMu           sync.RWMutex
+	enhancementsMade  int
+	lastEnhancement   time.Time
+	memoriesIncluded  int
+	typeCounts        map[string]int
+	searchFailures    int
+	lastSearchFailure time.Time
+	useCounts         map[string]int
 }
 
 // EnhancementResult contains the enhanced prompt and metadata
 type EnhancementResult struct {
-	OriginalPrompt   string
-	EnhancedPrompt   string
-	MemoriesUsed     []string
-	EnhancementType  string // "contextual", "detailed", "minimal"
+	OriginalPrompt  string
+	EnhancedPrompt  string
+	MemoriesUsed    []string
+	EnhancementType string // "contextual", "detailed", "minimal"
+	Explanation     *Explanation
+	// DroppedForBudget counts memories left out to fit the token budget
+	DroppedForBudget int
+}
+
+// highRelevanceScore is the default search score above which a memory
+// counts as highly relevant rather than contextual
+const highRelevanceScore = 0.85
+
+// maxDetailedMemories caps the memories in a detailed enhancement
+const maxDetailedMemories = 4
+
+// Explanation describes why an enhancement came out the way it did
+type Explanation struct {
+	HighRelevance int     `json:"high_relevance"`
+	Contextual    int     `json:"contextual"`
+	Threshold     float64 `json:"threshold"`
+	Type          string  `json:"type"`
+	Reason        string  `json:"reason"`
+	// Included is how many memories made it into the prompt; the rest
+	// were trimmed by the per-type limits
+	Included int `json:"included"`
+	Trimmed  int `json:"trimmed"`
+	// DroppedForBudget is how many of the trimmed memories were left out
+	// to fit the token budget
+	DroppedForBudget int `json:"dropped_for_budget"`
 }
 
 // MemoryInfo represents a simplified memory for the extension
 type MemoryInfo struct {
-	ID       string    `json:"id"`
-	Content  string    `json:"content"`
-	Context  string    `json:"context"`
-	Score    float64   `json:"score"`
-	Date     time.Time `json:"date"`
+	ID      string    `json:"id"`
+	Content string    `json:"content"`
+	Context string    `json:"context"`
+	Source  string    `json:"source"`
+	Score   float64   `json:"score"`
+	Date    time.Time `json:"date"`
+	// Collection tells apart memories with equal IDs from different collections
+	Collection string `json:"collection,omitempty"`
+	// UserID names the owner; only set by SearchAllUsers
+	UserID string `json:"user_id,omitempty"`
+	// Debug is only set for searches made with WithScoreDebug
+	Debug *ScoreDebug `json:"debug,omitempty"`
 }
 
 // New creates a new prompt enhancer
-func New(memoryStore *memory.Store) *Enhancer {
+func New(memoryStore memory.MemoryStore) *Enhancer {
 	return &Enhancer{
 		memoryStore: memoryStore,
+		relevance:   defaultRelevance,
+		templates:   maps.Clone(defaultTemplates),
+		now:         time.Now,
+		logger:      slog.Default(),
 	}
 }
 
+// SetLogger replaces the logger, slog.Default() by default
+func (e *Enhancer) SetLogger(l *slog.Logger) {
+	e.logger = l
+}
+
+// SetRequireStrong makes Enhance return the prompt unchanged unless at
+// least one memory scores above the high-relevance threshold
+func (e *Enhancer) SetRequireStrong(require bool) {
+	e.requireStrong = require
+}
+
+// SetFailOpen sets whether Enhance returns the prompt unchanged when the
+// memory store can't be searched (the default) or fails
+func (e *Enhancer) SetFailOpen(failOpen bool) {
+	e.failClosed = !failOpen
+}
+
+// SetProfile sets a source for a short user profile that is appended to
+// every enhancement; nil disables it
+func (e *Enhancer) SetProfile(profile func() string) {
+	e.profile = profile
+}
+
 // Enhance takes a prompt and enhances it with relevant memories
 func (e *Enhancer) Enhance(ctx context.Context, prompt, pageContext string, maxMemories int) (*EnhancementResult, error) {
 	// Search for relevant memories based on the prompt
-	results, err := e.memoryStore.Search(prompt, maxMemories)
+	reranker := e.rerankerFor(ctx)
+	if e.embedder != nil {
+		reranker = chainReranker{embeddingReranker{ctx: ctx, embedder: e.embedder, query: prompt, logger: e.logger}, reranker}
+	}
+	results, err := e.memoryStore.Search(e.searchQuery(ctx, prompt), fetchLimit(maxMemories, reranker))
 	if err != nil {
-		return nil, fmt.Errorf("memory search failed: %w", err)
+		// A cancelled request stays an error; an unreachable backend
+		// shouldn't stop the user from sending their prompt
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("memory search failed: %w", err)
+		}
+		e.recordSearchFailure()
+		if e.failClosed {
+			return nil, fmt.Errorf("memory search failed: %w", err)
+		}
+		e.logger.Warn("memory search failed, leaving prompt unchanged", "error", err)
+		e.recordType("none")
+		return &EnhancementResult{
+			OriginalPrompt:  prompt,
+			EnhancedPrompt:  prompt,
+			MemoriesUsed:    []string{},
+			EnhancementType: "none",
+			Explanation: &Explanation{
+				Threshold: e.relevance.Threshold,
+				Type:      "none",
+				Reason:    "memory search failed",
+			},
+		}, nil
+	}
+	results = rank(reranker, results, maxMemories)
+
+	e.logger.Debug("found relevant memories for prompt", "count", len(results))
+
+	result := e.enhanceResults(prompt, pageContext, results)
+	e.recordType(result.EnhancementType)
+	if len(result.MemoriesUsed) == 0 {
+		return result, nil
 	}
 
-	log.Printf("[Enhancer] Found %d relevant memories for prompt", len(results))
+	// Update stats
+	e.recordUse(results)
+	e.statsMu.Lock()
+	e.enhancementsMade++
+	e.lastEnhancement = time.Now()
+	e.memoriesIncluded += result.Explanation.Included
+	e.statsMu.Unlock()
+
+	e.logger.Info("enhanced prompt", "memories", len(result.MemoriesUsed), "type", result.EnhancementType)
+
+	e.storeEnhancement(ctx, result, pageContext)
 
+	return result, nil
+}
+
+// EnhanceWithMemories enhances prompt with the given memories, in order,
+// instead of searching the store. Stats are not updated, so it can be
+// used to try out the enhancer's templating.
+func (e *Enhancer) EnhanceWithMemories(prompt, pageContext string, memories []memory.SearchResult) *EnhancementResult {
+	return e.enhanceResults(prompt, pageContext, memories)
+}
+
+// enhanceResults builds the enhancement for prompt from ranked results
+func (e *Enhancer) enhanceResults(prompt, pageContext string, results []memory.SearchResult) *EnhancementResult {
 	// If no memories found, return original prompt
 	if len(results) == 0 {
 		return &EnhancementResult{
@@ -62,7 +227,12 @@ func (e *Enhancer) Enhance(ctx context.Context, prompt, pageContext string, maxM
 			EnhancedPrompt:  prompt,
 			MemoriesUsed:    []string{},
 			EnhancementType: "none",
-		}, nil
+			Explanation: &Explanation{
+				Threshold: e.relevance.Threshold,
+				Type:      "none",
+				Reason:    "no memories matched the prompt",
+			},
+		}
 	}
 
 	// Extract memory contents and build contextual enhancement
@@ -73,174 +243,320 @@ func (e *Enhancer) Enhance(ctx context.Context, prompt, pageContext string, maxM
 
 	for _, result := range results {
 		memoriesUsed = append(memoriesUsed, result.Memory.Content)
-		
+
+		// Long memories would eat the prompt's budget
+		content := truncateMemory(result.Memory.Content, e.maxMemoryChars)
+
 		// Categorize memories by relevance score
-		if result.Score > 0.85 {
-			highRelevanceMemories = append(highRelevanceMemories, result.Memory.Content)
+		if result.Score > e.relevance.Threshold {
+			highRelevanceMemories = append(highRelevanceMemories, content)
 		} else {
-			contextualMemories = append(contextualMemories, result.Memory.Content)
+			contextualMemories = append(contextualMemories, content)
 		}
-		
+
 		// Build formatted memory content with metadata
-		content := result.Memory.Content
 		if result.Memory.Metadata.Context != "" {
 			content = fmt.Sprintf("[%s] %s", result.Memory.Metadata.Context, content)
 		}
 		memoryContents = append(memoryContents, content)
 	}
 
+	// A weak enhancement can be worse than none
+	if e.requireStrong && len(highRelevanceMemories) == 0 {
+		e.logger.Debug("no strong memories, leaving prompt unchanged")
+		return &EnhancementResult{
+			OriginalPrompt:  prompt,
+			EnhancedPrompt:  prompt,
+			MemoriesUsed:    []string{},
+			EnhancementType: "none",
+			Explanation: &Explanation{
+				Contextual: len(contextualMemories),
+				Threshold:  e.relevance.Threshold,
+				Type:       "none",
+				Reason:     "strong memories required but none scored above the threshold",
+				Trimmed:    len(results),
+			},
+		}
+	}
+
 	// Determine enhancement type based on relevance and context
-	enhancementType := e.determineEnhancementType(len(highRelevanceMemories), len(contextualMemories), pageContext)
+	enhancementType, reason := e.determineEnhancementType(len(highRelevanceMemories), len(contextualMemories), pageContext)
 
 	// Build enhanced prompt based on enhancement type
-	enhancedPrompt := e.buildEnhancedPrompt(prompt, highRelevanceMemories, contextualMemories, memoryContents, enhancementType)
-
-	// Update stats
-	e.statsMu.Lock()
-	e.enhancementsMade++
-	e.lastEnhancement = time.Now()
-	e.statsMu.Unlock()
-
-	log.Printf("[Enhancer] Enhanced prompt using %d memories (type: %s)", len(memoriesUsed), enhancementType)
+	enhancedPrompt, included, dropped := e.buildEnhancedPrompt(prompt, highRelevanceMemories, contextualMemories, memoryContents, enhancementType, pageContext)
 
 	return &EnhancementResult{
 		OriginalPrompt:  prompt,
 		EnhancedPrompt:  enhancedPrompt,
 		MemoriesUsed:    memoriesUsed,
 		EnhancementType: enhancementType,
-	}, nil
+		Explanation: &Explanation{
+			HighRelevance:    len(highRelevanceMemories),
+			Contextual:       len(contextualMemories),
+			Threshold:        e.relevance.Threshold,
+			Type:             enhancementType,
+			Reason:           reason,
+			Included:         included,
+			Trimmed:          len(results) - included,
+			DroppedForBudget: dropped,
+		},
+		DroppedForBudget: dropped,
+	}
 }
 
-// determineEnhancementType decides how to enhance the prompt
-func (e *Enhancer) determineEnhancementType(highRelevance, contextual int, pageContext string) string {
+// determineEnhancementType decides how to enhance the prompt and why
+func (e *Enhancer) determineEnhancementType(highRelevance, contextual int, pageContext string) (string, string) {
 	if highRelevance >= 2 {
-		return "contextual"
+		return "contextual", fmt.Sprintf("%d highly relevant memories", highRelevance)
 	}
 	if highRelevance == 1 && contextual >= 2 {
-		return "detailed"
+		return "detailed", fmt.Sprintf("1 highly relevant and %d contextual memories", contextual)
 	}
 	if highRelevance == 0 && contextual > 0 {
-		return "minimal"
+		return "minimal", fmt.Sprintf("no highly relevant memories, %d contextual", contextual)
 	}
-	return "contextual"
+	return "contextual", fmt.Sprintf("default for %d highly relevant and %d contextual memories", highRelevance, contextual)
 }
 
-// buildEnhancedPrompt creates the enhanced prompt based on type
+// buildEnhancedPrompt creates the enhanced prompt based on type, in the
+// template for the page context, and returns how many memories it
+// included and how many it dropped to fit the token budget
 func (e *Enhancer) buildEnhancedPrompt(
 	originalPrompt string,
 	highRelevanceMemories []string,
 	contextualMemories []string,
 	allMemories []string,
 	enhancementType string,
-) string {
-	var builder strings.Builder
-
-	// Always include the original prompt
-	builder.WriteString(originalPrompt)
+	pageContext string,
+) (string, int, int) {
+	data := TemplateData{Type: enhancementType}
 
 	switch enhancementType {
 	case "contextual":
-		// Rich context enhancement for highly relevant scenarios
-		builder.WriteString("\n\n[Context from previous sessions]\n")
-		builder.WriteString("Based on my previous activities and context:\n")
-		
-		for i, memory := range highRelevanceMemories {
-			builder.WriteString(fmt.Sprintf("- %s\n", memory))
-			if i >= 2 { // Limit to top 3 high relevance
-				break
-			}
-		}
-		
-		if len(contextualMemories) > 0 {
-			builder.WriteString("\nAdditional context:\n")
-			for i, memory := range contextualMemories {
-				builder.WriteString(fmt.Sprintf("- %s\n", memory))
-				if i >= 1 { // Limit to 2 additional
-					break
-				}
-			}
-		}
-
+		data.HighRelevance = highRelevanceMemories[:min(len(highRelevanceMemories), e.relevance.MaxHighRelevance)]
+		data.Contextual = contextualMemories[:min(len(contextualMemories), e.relevance.MaxContextual)]
 	case "detailed":
-		// Moderate detail enhancement
-		builder.WriteString("\n\n[Relevant background]\n")
-		for i, memory := range allMemories {
-			builder.WriteString(fmt.Sprintf("- %s\n", memory))
-			if i >= 3 { // Limit total memories
-				break
+		data.Memories = allMemories[:min(len(allMemories), maxDetailedMemories)]
+	case "minimal":
+		data.Memories = allMemories[:min(len(allMemories), 1)]
+		data.More = len(allMemories) > 1
+	}
+
+	tmpl := e.templateFor(pageContext)
+	enhancedPrompt, dropped := e.fitBudget(&data, func(d TemplateData) string {
+		// Always include the original prompt
+		prompt := originalPrompt + tmpl(d)
+
+		// The profile gives baseline context without another search
+		if e.profile != nil {
+			if p := e.profile(); p != "" {
+				prompt += fmt.Sprintf("\n[About me: %s]", p)
 			}
 		}
+		return e.withNow(prompt)
+	})
+	return enhancedPrompt, data.included(), dropped
+}
 
-	case "minimal":
-		// Light touch - just add context note
-		if len(allMemories) > 0 {
-			builder.WriteString("\n\n[Note: Consider previous context: ")
-			builder.WriteString(allMemories[0])
-			if len(allMemories) > 1 {
-				builder.WriteString(" and related activities")
-			}
-			builder.WriteString("]")
+// SearchMemories performs a memory search and returns simplified results.
+// A non-empty source restricts results to memories from that origin.
+func (e *Enhancer) SearchMemories(ctx context.Context, query, source string, limit int) ([]MemoryInfo, error) {
+	return e.SearchMemoriesPaged(ctx, query, source, limit, 0)
+}
+
+// SearchMemoriesPaged is SearchMemories starting offset results down the
+// ranking. Plain searches page on the backend; source filters and
+// reranking need the results before the page, so those are fetched too.
+func (e *Enhancer) SearchMemoriesPaged(ctx context.Context, query, source string, limit, offset int) ([]MemoryInfo, error) {
+	if offset < 0 {
+		return nil, memory.ErrNegativeOffset
+	}
+
+	reranker := e.rerankerFor(ctx)
+	if source == "" && reranker == nil {
+		results, err := e.memoryStore.SearchPaged(query, limit, offset)
+		if err != nil {
+			return nil, err
 		}
+		return resultInfos(ctx, nil, results), nil
+	}
+
+	results, err := e.memoryStore.SearchSource(query, source, fetchLimit(offset+limit, reranker))
+	if err != nil {
+		return nil, err
+	}
+	results = rank(reranker, results, offset+limit)
+	if offset >= len(results) {
+		return nil, nil
+	}
+
+	return resultInfos(ctx, reranker, results[offset:]), nil
+}
+
+// SearchAllUsers searches the memories of every user, naming each
+// result's owner. The memory store must have admin rights.
+func (e *Enhancer) SearchAllUsers(ctx context.Context, query string, limit int) ([]MemoryInfo, error) {
+	results, err := e.memoryStore.SearchAllUsers(query, limit)
+	if err != nil {
+		return nil, err
 	}
 
-	return builder.String()
+	memories := resultInfos(ctx, nil, results)
+	for i, r := range results {
+		memories[i].UserID = r.Memory.UserID
+	}
+	return memories, nil
 }
 
-// SearchMemories performs a memory search and returns simplified results
-func (e *Enhancer) SearchMemories(ctx context.Context, query string, limit int) ([]MemoryInfo, error) {
-	results, err := e.memoryStore.Search(query, limit)
+// SearchMemoriesRelative searches memories within a natural time window
+// such as "today" or "last 7 days", optionally restricted to a source
+func (e *Enhancer) SearchMemoriesRelative(ctx context.Context, query, source, window string, limit int) ([]MemoryInfo, error) {
+	reranker := e.rerankerFor(ctx)
+	results, err := e.memoryStore.SearchRelative(query, source, window, fetchLimit(limit, reranker))
+	if err != nil {
+		return nil, err
+	}
+	results = rank(reranker, results, limit)
+
+	return resultInfos(ctx, reranker, results), nil
+}
+
+// SimilarMemories returns the memories most similar to the one with the
+// given ID, excluding that memory
+func (e *Enhancer) SimilarMemories(ctx context.Context, memoryID string, limit int) ([]MemoryInfo, error) {
+	results, err := e.memoryStore.Similar(memoryID, limit)
 	if err != nil {
 		return nil, err
 	}
 
 	var memories []MemoryInfo
 	for _, result := range results {
-		memories = append(memories, MemoryInfo{
-			ID:      result.Memory.ID,
-			Content: result.Memory.Content,
-			Context: result.Memory.Metadata.Context,
-			Score:   result.Score,
-			Date:    result.Memory.CreatedAt,
-		})
+		memories = append(memories, newResultInfo(result))
 	}
 
 	return memories, nil
 }
 
-// GetRecentMemories returns the most recent memories
-func (e *Enhancer) GetRecentMemories(limit int) ([]MemoryInfo, error) {
-	memories, err := e.memoryStore.GetRecent(limit)
+// GetRecentMemories returns the most recent memories, optionally
+// restricted to a source
+func (e *Enhancer) GetRecentMemories(source string, limit int) ([]MemoryInfo, error) {
+	memories, err := e.memoryStore.GetRecentSource(source, limit)
 	if err != nil {
 		return nil, err
 	}
 
 	var result []MemoryInfo
 	for _, m := range memories {
-		result = append(result, MemoryInfo{
-			ID:      m.ID,
-			Content: m.Content,
-			Context: m.Metadata.Context,
-			Date:    m.CreatedAt,
-		})
+		result = append(result, newMemoryInfo(m, 0))
 	}
 
 	return result, nil
 }
 
+// AddMemory stores a memory created outside the capture loop, stamped
+// with its source (e.g. the extension or a clipboard image)
+func (e *Enhancer) AddMemory(content, pageContext, source string) (*MemoryInfo, error) {
+	metadata := memory.Metadata{
+		Timestamp: time.Now().Format(time.RFC3339),
+		Context:   pageContext,
+		Source:    source,
+	}
+
+	m, err := e.memoryStore.Add(content, metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	info := newMemoryInfo(*m, 0)
+	return &info, nil
+}
+
+// DeleteMemory removes a memory from collection (MemoryInfo.Collection;
+// empty means the primary one); memory.ErrNotFound is returned when no
+// memory has the ID
+func (e *Enhancer) DeleteMemory(collection, memoryID string) error {
+	return e.memoryStore.Delete(collection, memoryID)
+}
+
+// newMemoryInfo converts a stored memory into the simplified form
+func newMemoryInfo(m memory.Memory, score float64) MemoryInfo {
+	return MemoryInfo{
+		ID:      m.ID,
+		Content: m.Content,
+		Context: m.Metadata.Context,
+		Source:  m.Source(),
+		Score:   score,
+		Date:    m.CreatedAt,
+		// Deletes route by it
+		Collection: m.Collection,
+	}
+}
+
+// newResultInfo converts a search result, keeping its score and collection
+func newResultInfo(r memory.SearchResult) MemoryInfo {
+	info := newMemoryInfo(r.Memory, r.Score)
+	info.Collection = r.Collection
+	return info
+}
+
 // Stats contains enhancer statistics
 type Stats struct {
 	EnhancementsMade int       `json:"enhancements_made"`
 	LastEnhancement  time.Time `json:"last_enhancement,omitempty"`
+	// AvgMemoriesUsed is the mean number of memories put in the prompt
+	// per enhancement made
+	AvgMemoriesUsed float64 `json:"avg_memories_used"`
+	// ByType counts enhance requests by the enhancement they got, with
+	// "none" for prompts left unchanged
+	ByType map[string]int `json:"by_type"`
+	// SearchFailures counts memory searches that failed, not counting
+	// cancelled requests
+	SearchFailures    int       `json:"search_failures"`
+	LastSearchFailure time.Time `json:"last_search_failure,omitempty"`
 }
 
 // GetStats returns current statistics
 func (e *Enhancer) GetStats() Stats {
 	e.statsMu.RLock()
 	defer e.statsMu.RUnlock()
+
+	byType := map[string]int{"none": 0}
+	for _, t := range EnhancementTypes {
+		byType[t] = 0
+	}
+	maps.Copy(byType, e.typeCounts)
+
+	var avg float64
+	if e.enhancementsMade > 0 {
+		avg = float64(e.memoriesIncluded) / float64(e.enhancementsMade)
+	}
+
 	return Stats{
-		EnhancementsMade: e.enhancementsMade,
-		LastEnhancement:  e.lastEnhancement,
+		EnhancementsMade:  e.enhancementsMade,
+		LastEnhancement:   e.lastEnhancement,
+		AvgMemoriesUsed:   avg,
+		ByType:            byType,
+		SearchFailures:    e.searchFailures,
+		LastSearchFailure: e.lastSearchFailure,
+	}
+}
+
+// recordType counts an enhance request by the enhancement it got
+func (e *Enhancer) recordType(enhancementType string) {
+	e.statsMu.Lock()
+	defer e.statsMu.Unlock()
+	if e.typeCounts == nil {
+		e.typeCounts = make(map[string]int)
 	}
+	e.typeCounts[enhancementType]++
+}
+
+// recordSearchFailure counts a failed memory search
+func (e *Enhancer) recordSearchFailure() {
+	e.statsMu.Lock()
+	defer e.statsMu.Unlock()
+	e.searchFailures++
+	e.lastSearchFailure = time.Now()
 }
 
 // QuickEnhance provides a one-line enhancement for simple prompts
@@ -0,0 +1,646 @@
+package enhancer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"screen-memory-assistant/internal/config"
+	"screen-memory-assistant/internal/memory"
+)
+
+func TestAddMemory_StampsSource(t *testing.T) {
+	var added []memory.Metadata
+	mem0 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Metadata memory.Metadata `json:"metadata"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		added = append(added, payload.Metadata)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer mem0.Close()
+
+	e := New(memory.NewStore(&config.MemoryConfig{BaseURL: mem0.URL}))
+
+	info, err := e.AddMemory("Architecture diagram of the ingest pipeline", "work", memory.SourceClipboard)
+	if err != nil {
+		t.Fatalf("AddMemory failed: %v", err)
+	}
+
+	if len(added) != 1 || added[0].Source != memory.SourceClipboard {
+		t.Errorf("stored metadata = %+v, want source %q", added, memory.SourceClipboard)
+	}
+	if info.Source != memory.SourceClipboard {
+		t.Errorf("MemoryInfo.Source = %q, want %q", info.Source, memory.SourceClipboard)
+	}
+}
+
+// searchResults serves a Mem0 search response with the given scores
+func searchResults(t *testing.T, scores ...float64) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var results []map[string]interface{}
+		for i, score := range scores {
+			results = append(results, map[string]interface{}{
+				"id":     fmt.Sprintf("m%d", i),
+				"memory": fmt.Sprintf("memory %d", i),
+				"score":  score,
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+	}))
+}
+
+func TestEnhance_Explanation(t *testing.T) {
+	tests := []struct {
+		name   string
+		scores []float64
+		want   Explanation
+	}{
+		{
+			name:   "contextual trims extra contextual memories",
+			scores: []float64{0.95, 0.9, 0.5, 0.4, 0.3},
+			want:   Explanation{HighRelevance: 2, Contextual: 3, Type: "contextual", Included: 4, Trimmed: 1},
+		},
+		{
+			name:   "detailed",
+			scores: []float64{0.9, 0.5, 0.4},
+			want:   Explanation{HighRelevance: 1, Contextual: 2, Type: "detailed", Included: 3},
+		},
+		{
+			name:   "minimal uses one memory",
+			scores: []float64{0.5, 0.4},
+			want:   Explanation{Contextual: 2, Type: "minimal", Included: 1, Trimmed: 1},
+		},
+		{
+			name: "no memories",
+			want: Explanation{Type: "none"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mem0 := searchResults(t, tt.scores...)
+			defer mem0.Close()
+
+			e := New(memory.NewStore(&config.MemoryConfig{BaseURL: mem0.URL}))
+			result, err := e.Enhance(context.Background(), "prompt", "", 5)
+			if err != nil {
+				t.Fatalf("Enhance failed: %v", err)
+			}
+
+			got := result.Explanation
+			if got == nil {
+				t.Fatal("Explanation is nil")
+			}
+			if got.Reason == "" {
+				t.Error("Explanation.Reason is empty")
+			}
+			if got.Threshold != highRelevanceScore {
+				t.Errorf("Threshold = %v, want %v", got.Threshold, highRelevanceScore)
+			}
+			got.Reason, got.Threshold = "", 0
+			if *got != tt.want {
+				t.Errorf("Explanation = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnhance_RelevanceOptions(t *testing.T) {
+	scores := []float64{0.9, 0.8, 0.7, 0.6, 0.5}
+	tests := []struct {
+		name string
+		opts RelevanceOptions
+		want Explanation
+	}{
+		{
+			name: "defaults",
+			want: Explanation{HighRelevance: 1, Contextual: 4, Type: "detailed", Included: 4, Trimmed: 1},
+		},
+		{
+			name: "lower threshold",
+			opts: RelevanceOptions{Threshold: 0.65},
+			want: Explanation{HighRelevance: 3, Contextual: 2, Type: "contextual", Included: 5},
+		},
+		{
+			name: "lower threshold with caps",
+			opts: RelevanceOptions{Threshold: 0.65, MaxHighRelevance: 2, MaxContextual: 1},
+			want: Explanation{HighRelevance: 3, Contextual: 2, Type: "contextual", Included: 3, Trimmed: 2},
+		},
+		{
+			name: "threshold above every score",
+			opts: RelevanceOptions{Threshold: 0.95},
+			want: Explanation{Contextual: 5, Type: "minimal", Included: 1, Trimmed: 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mem0 := searchResults(t, scores...)
+			defer mem0.Close()
+
+			e := New(memory.NewStore(&config.MemoryConfig{BaseURL: mem0.URL}))
+			e.SetRelevance(tt.opts)
+			result, err := e.Enhance(context.Background(), "prompt", "", 5)
+			if err != nil {
+				t.Fatalf("Enhance failed: %v", err)
+			}
+
+			got := *result.Explanation
+			if want := e.relevance.Threshold; got.Threshold != want {
+				t.Errorf("Threshold = %v, want %v", got.Threshold, want)
+			}
+			got.Reason, got.Threshold = "", 0
+			if got != tt.want {
+				t.Errorf("Explanation = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnhance_RequireStrong(t *testing.T) {
+	mem0 := searchResults(t, 0.6, 0.5)
+	defer mem0.Close()
+
+	e := New(memory.NewStore(&config.MemoryConfig{BaseURL: mem0.URL}))
+
+	weak, err := e.Enhance(context.Background(), "fix the flaky test", "", 5)
+	if err != nil {
+		t.Fatalf("Enhance failed: %v", err)
+	}
+	if weak.EnhancementType != "minimal" {
+		t.Fatalf("without require_strong type = %q, want minimal", weak.EnhancementType)
+	}
+
+	e.SetRequireStrong(true)
+	result, err := e.Enhance(context.Background(), "fix the flaky test", "", 5)
+	if err != nil {
+		t.Fatalf("Enhance failed: %v", err)
+	}
+	if result.EnhancedPrompt != "fix the flaky test" {
+		t.Errorf("EnhancedPrompt = %q, want the original prompt", result.EnhancedPrompt)
+	}
+	if result.EnhancementType != "none" {
+		t.Errorf("EnhancementType = %q, want none", result.EnhancementType)
+	}
+	if len(result.MemoriesUsed) != 0 {
+		t.Errorf("MemoriesUsed = %v, want none", result.MemoriesUsed)
+	}
+}
+
+func TestEnhance_FetchedPageContext(t *testing.T) {
+	tool := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body><h1>Project Falcon</h1><script>x()</script></body></html>")
+	}))
+	defer tool.Close()
+
+	// Mem0 only knows about Falcon, which the prompt alone doesn't mention
+	var queries []string
+	mem0 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Query string `json:"query"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		queries = append(queries, payload.Query)
+
+		results := []map[string]interface{}{}
+		if strings.Contains(payload.Query, "Project Falcon") {
+			results = append(results, map[string]interface{}{
+				"id": "falcon", "memory": "Falcon launch moved to March", "score": 0.9,
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+	}))
+	defer mem0.Close()
+
+	tests := []struct {
+		name    string
+		allowed []string
+		want    string
+	}{
+		{"allowed host", []string{"127.0.0.1"}, "contextual"},
+		{"host not allowed", []string{"example.com"}, "none"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queries = nil
+			e := New(memory.NewStore(&config.MemoryConfig{BaseURL: mem0.URL}))
+			e.SetContextFetcher(NewContextFetcher(tt.allowed, time.Second))
+
+			ctx := WithPageURL(context.Background(), tool.URL+"/page")
+			result, err := e.Enhance(ctx, "summarize the status", "", 5)
+			if err != nil {
+				t.Fatalf("Enhance failed: %v", err)
+			}
+			if result.EnhancementType != tt.want {
+				t.Errorf("type = %q (queries %q), want %q", result.EnhancementType, queries, tt.want)
+			}
+			for _, q := range queries {
+				if strings.Contains(q, "x()") {
+					t.Errorf("query %q contains script content", q)
+				}
+			}
+		})
+	}
+}
+
+func TestEnhance_AppliesReranker(t *testing.T) {
+	mem0 := searchResults(t, 0.9, 0.5)
+	defer mem0.Close()
+
+	e := New(memory.NewStore(&config.MemoryConfig{BaseURL: mem0.URL}))
+	if err := e.UseReranker("embedding"); err == nil {
+		t.Error("UseReranker accepted an unknown name")
+	}
+
+	// Invert the order so the weaker memory is bucketed first
+	e.SetReranker(rerankFunc(func(results []memory.SearchResult) []memory.SearchResult {
+		return []memory.SearchResult{results[1], results[0]}
+	}))
+
+	result, err := e.Enhance(context.Background(), "prompt", "", 5)
+	if err != nil {
+		t.Fatalf("Enhance failed: %v", err)
+	}
+	if len(result.MemoriesUsed) != 2 || result.MemoriesUsed[0] != "memory 1" {
+		t.Errorf("memories used = %q, want the reranked order", result.MemoriesUsed)
+	}
+}
+
+// fakeEmbedder embeds each text as the vector it maps to, failing with err
+type fakeEmbedder struct {
+	vectors map[string][]float32
+	err     error
+}
+
+func (f fakeEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		out[i] = f.vectors[text]
+	}
+	return out, nil
+}
+
+func TestEnhance_EmbeddingRerank(t *testing.T) {
+	mem0 := searchResults(t, 0.9, 0.5)
+	defer mem0.Close()
+
+	e := New(memory.NewStore(&config.MemoryConfig{BaseURL: mem0.URL}))
+	e.SetEmbedder(fakeEmbedder{vectors: map[string][]float32{
+		"prompt":   {1, 0},
+		"memory 0": {0, 1},
+		"memory 1": {1, 0.1},
+	}})
+
+	// The backend ranks memory 0 first; its embedding is unrelated
+	result, err := e.Enhance(context.Background(), "prompt", "", 5)
+	if err != nil {
+		t.Fatalf("Enhance failed: %v", err)
+	}
+	if len(result.MemoriesUsed) != 2 || result.MemoriesUsed[0] != "memory 1" {
+		t.Errorf("memories used = %q, want memory 1 first", result.MemoriesUsed)
+	}
+	if result.Explanation.HighRelevance != 1 {
+		t.Errorf("high relevance = %d, want 1 by cosine similarity", result.Explanation.HighRelevance)
+	}
+	if !e.Capabilities().EmbeddingRerank {
+		t.Error("capabilities don't report the embedding rerank")
+	}
+
+	// Without embeddings the backend's order stands
+	e.SetEmbedder(fakeEmbedder{err: errors.New("model not loaded")})
+	result, err = e.Enhance(context.Background(), "prompt", "", 5)
+	if err != nil {
+		t.Fatalf("Enhance failed: %v", err)
+	}
+	if len(result.MemoriesUsed) != 2 || result.MemoriesUsed[0] != "memory 0" {
+		t.Errorf("memories used = %q, want the search order on failure", result.MemoriesUsed)
+	}
+}
+
+type rerankFunc func([]memory.SearchResult) []memory.SearchResult
+
+func (f rerankFunc) Rerank(results []memory.SearchResult) []memory.SearchResult {
+	return f(results)
+}
+
+func TestTruncateMemory(t *testing.T) {
+	long := "Reviewed the ingest PR. Left comments on retries. Then spent the afternoon in a very long meeting about quarterly planning"
+
+	tests := []struct {
+		name    string
+		content string
+		limit   int
+		want    string
+	}{
+		{"short enough", "Short memory.", 50, "Short memory."},
+		{"no limit", long, 0, long},
+		{"sentence boundary", long, 60, "Reviewed the ingest PR. Left comments on retries.…"},
+		{"word boundary when no sentence fits", "Spent the afternoon in a very long meeting", 20, "Spent the afternoon…"},
+		{"multibyte", "Café déjà vu. Über naïve résumé text", 20, "Café déjà vu.…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateMemory(tt.content, tt.limit)
+			if got != tt.want {
+				t.Errorf("truncateMemory() = %q, want %q", got, tt.want)
+			}
+			if tt.limit > 0 && len([]rune(got)) > tt.limit {
+				t.Errorf("length %d exceeds limit %d", len([]rune(got)), tt.limit)
+			}
+		})
+	}
+}
+
+func TestEnhance_TruncatesLongMemories(t *testing.T) {
+	long := "Fixed the flaky upload test. " + strings.Repeat("Lots of verbose model output follows. ", 20)
+	mem0 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"results": []map[string]interface{}{{"id": "m1", "memory": long, "score": 0.9}},
+		})
+	}))
+	defer mem0.Close()
+
+	e := New(memory.NewStore(&config.MemoryConfig{BaseURL: mem0.URL}))
+	e.SetMaxMemoryChars(40)
+
+	result, err := e.Enhance(context.Background(), "prompt", "", 5)
+	if err != nil {
+		t.Fatalf("Enhance failed: %v", err)
+	}
+	if !strings.Contains(result.EnhancedPrompt, "Fixed the flaky upload test.…") {
+		t.Errorf("prompt doesn't hold the truncated memory:\n%s", result.EnhancedPrompt)
+	}
+	if strings.Contains(result.EnhancedPrompt, "verbose") {
+		t.Errorf("prompt holds text past the limit:\n%s", result.EnhancedPrompt)
+	}
+}
+
+func TestEnhance_IncludeNow(t *testing.T) {
+	now := time.Date(2024, 5, 15, 14, 30, 0, 0, time.UTC)
+	results := []memory.SearchResult{{Memory: memory.Memory{Content: "Planning the release"}, Score: 0.9}}
+
+	tests := []struct {
+		position string
+		format   string
+		check    func(string) bool
+	}{
+		{NowAppend, "", func(p string) bool {
+			return strings.HasSuffix(p, "\n[Current time: Wednesday, May 15, 2024 14:30 UTC]")
+		}},
+		{NowPrepend, "2006-01-02 15:04", func(p string) bool {
+			return strings.HasPrefix(p, "[Current time: 2024-05-15 14:30]\nwhat next?")
+		}},
+		{"", "", func(p string) bool { return !strings.Contains(p, "Current time") }},
+	}
+
+	for _, tt := range tests {
+		e := New(memory.NewStore(&config.MemoryConfig{}))
+		e.now = func() time.Time { return now }
+		e.SetIncludeNow(tt.position, tt.format)
+
+		result := e.EnhanceWithMemories("what next?", "", results)
+		if !tt.check(result.EnhancedPrompt) {
+			t.Errorf("position %q: enhanced prompt = %q", tt.position, result.EnhancedPrompt)
+		}
+	}
+}
+
+func TestEnhance_StoresHistory(t *testing.T) {
+	tests := []struct {
+		name    string
+		history *HistoryOptions
+		want    string
+	}{
+		{"disabled", nil, ""},
+		{"type only", &HistoryOptions{}, "Enhanced a prompt (contextual)"},
+		{"prompt and site", &HistoryOptions{Prompts: true, Sites: true}, "Enhanced a prompt (contextual) on chat.example.com: draft the release notes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var added []string
+			var sources []string
+			mem0 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.Contains(r.URL.Path, "/search/") {
+					json.NewEncoder(w).Encode(map[string]interface{}{"results": []map[string]interface{}{
+						{"id": "m0", "memory": "Release checklist", "score": 0.95},
+					}})
+					return
+				}
+				var payload struct {
+					Messages []struct {
+						Content string `json:"content"`
+					} `json:"messages"`
+					Metadata memory.Metadata `json:"metadata"`
+				}
+				json.NewDecoder(r.Body).Decode(&payload)
+				for _, m := range payload.Messages {
+					added = append(added, m.Content)
+				}
+				sources = append(sources, payload.Metadata.Source)
+				w.WriteHeader(http.StatusCreated)
+			}))
+			defer mem0.Close()
+
+			e := New(memory.NewStore(&config.MemoryConfig{BaseURL: mem0.URL}))
+			e.SetHistory(tt.history)
+
+			ctx := WithPageURL(context.Background(), "https://chat.example.com/c/123")
+			if _, err := e.Enhance(ctx, "draft the release notes", "work", 5); err != nil {
+				t.Fatalf("Enhance failed: %v", err)
+			}
+
+			if tt.want == "" {
+				if len(added) != 0 {
+					t.Errorf("stored %q with history disabled", added)
+				}
+				return
+			}
+			if len(added) != 1 || added[0] != tt.want || sources[0] != memory.SourceEnhancement {
+				t.Errorf("stored %q (sources %q), want %q from %q", added, sources, tt.want, memory.SourceEnhancement)
+			}
+		})
+	}
+}
+
+func TestEnhance_FailOpen(t *testing.T) {
+	mem0 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "backend down", http.StatusServiceUnavailable)
+	}))
+	defer mem0.Close()
+
+	e := New(memory.NewStore(&config.MemoryConfig{BaseURL: mem0.URL}))
+
+	result, err := e.Enhance(context.Background(), "draft the release notes", "", 5)
+	if err != nil {
+		t.Fatalf("Enhance failed with fail-open on: %v", err)
+	}
+	if result.EnhancedPrompt != "draft the release notes" || result.EnhancementType != "none" {
+		t.Errorf("result = %q (%s), want the prompt unchanged", result.EnhancedPrompt, result.EnhancementType)
+	}
+
+	// A cancelled request is still reported
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := e.Enhance(ctx, "draft the release notes", "", 5); err == nil {
+		t.Error("Enhance succeeded after cancellation, want an error")
+	}
+
+	e.SetFailOpen(false)
+	if _, err := e.Enhance(context.Background(), "draft the release notes", "", 5); err == nil {
+		t.Error("Enhance succeeded with fail-open off, want the search error")
+	}
+}
+
+func TestEnhance_ContextTemplates(t *testing.T) {
+	memories := []memory.SearchResult{
+		{Memory: memory.Memory{Content: "Migrating the billing service to Go"}, Score: 0.95},
+		{Memory: memory.Memory{Content: "Reviewed the payments schema"}, Score: 0.9},
+	}
+
+	tests := []struct {
+		pageContext string
+		want        string
+	}{
+		{"claude", "\n\n<context source=\"my previous sessions\">\n" +
+			"<memory relevance=\"high\">Migrating the billing service to Go</memory>\n" +
+			"<memory relevance=\"high\">Reviewed the payments schema</memory>\n" +
+			"</context>"},
+		{"ChatGPT", "\n\n### Context from my previous sessions\n" +
+			"- **Migrating the billing service to Go**\n" +
+			"- **Reviewed the payments schema**"},
+		{"perplexity", "\n\n[Context from previous sessions]\n" +
+			"Based on my previous activities and context:\n" +
+			"- Migrating the billing service to Go\n" +
+			"- Reviewed the payments schema\n"},
+	}
+
+	e := New(nil)
+	for _, tt := range tests {
+		t.Run(tt.pageContext, func(t *testing.T) {
+			result := e.EnhanceWithMemories("plan the rollout", tt.pageContext, memories)
+			if want := "plan the rollout" + tt.want; result.EnhancedPrompt != want {
+				t.Errorf("enhanced prompt =\n%q\nwant\n%q", result.EnhancedPrompt, want)
+			}
+		})
+	}
+
+	// A custom template replaces a built-in one, and nil restores the default
+	e.SetTemplate("Claude", func(d TemplateData) string {
+		return fmt.Sprintf(" (%d memories)", d.included())
+	})
+	if got := e.EnhanceWithMemories("plan", "claude", memories).EnhancedPrompt; got != "plan (2 memories)" {
+		t.Errorf("custom template prompt = %q", got)
+	}
+	e.SetTemplate("claude", nil)
+	if got := e.EnhanceWithMemories("plan", "claude", memories).EnhancedPrompt; !strings.Contains(got, "[Context from previous sessions]") {
+		t.Errorf("prompt after removing the template = %q, want the default", got)
+	}
+}
+
+func TestEnhance_MaxPromptTokens(t *testing.T) {
+	memories := []memory.SearchResult{
+		{Memory: memory.Memory{Content: "Migrating the billing service to Go"}, Score: 0.95},
+		{Memory: memory.Memory{Content: "Reviewed the payments schema"}, Score: 0.9},
+		{Memory: memory.Memory{Content: "Lunch with the platform team"}, Score: 0.5},
+	}
+
+	e := New(nil)
+	full := e.EnhanceWithMemories("plan the rollout", "", memories)
+	if full.DroppedForBudget != 0 {
+		t.Errorf("dropped %d memories without a budget", full.DroppedForBudget)
+	}
+
+	// Room for everything but the contextual memory
+	e.SetMaxPromptTokens(estimateTokens(full.EnhancedPrompt) - 5)
+	result := e.EnhanceWithMemories("plan the rollout", "", memories)
+	if result.DroppedForBudget != 1 || result.Explanation.DroppedForBudget != 1 {
+		t.Errorf("dropped %d memories, want 1", result.DroppedForBudget)
+	}
+	if strings.Contains(result.EnhancedPrompt, "Lunch") || !strings.Contains(result.EnhancedPrompt, "payments schema") {
+		t.Errorf("want the least relevant memory dropped:\n%s", result.EnhancedPrompt)
+	}
+	if got := estimateTokens(result.EnhancedPrompt); got > e.maxPromptTokens {
+		t.Errorf("prompt is %d tokens, over the budget of %d", got, e.maxPromptTokens)
+	}
+	if result.Explanation.Included != 2 || result.Explanation.Trimmed != 1 {
+		t.Errorf("included %d, trimmed %d; want 2 and 1", result.Explanation.Included, result.Explanation.Trimmed)
+	}
+
+	// A budget too small for any memory leaves the prompt as is
+	e.SetMaxPromptTokens(1)
+	result = e.EnhanceWithMemories("plan the rollout", "", memories)
+	if result.DroppedForBudget != 3 {
+		t.Errorf("dropped %d memories, want all 3", result.DroppedForBudget)
+	}
+	if !strings.HasPrefix(result.EnhancedPrompt, "plan the rollout") || strings.Contains(result.EnhancedPrompt, "- ") {
+		t.Errorf("prompt = %q, want no memories", result.EnhancedPrompt)
+	}
+}
+
+func TestGetStats_CountsByTypeAndFailures(t *testing.T) {
+	var results []map[string]interface{}
+	down := false
+	mem0 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down {
+			http.Error(w, "backend down", http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+	}))
+	defer mem0.Close()
+
+	e := New(memory.NewStore(&config.MemoryConfig{BaseURL: mem0.URL}))
+	enhance := func() {
+		t.Helper()
+		if _, err := e.Enhance(context.Background(), "plan the rollout", "", 5); err != nil {
+			t.Fatalf("Enhance failed: %v", err)
+		}
+	}
+
+	// Two contextual enhancements with 2 memories, one minimal with 1
+	results = []map[string]interface{}{
+		{"id": "m1", "memory": "Migrating billing to Go", "score": 0.95},
+		{"id": "m2", "memory": "Reviewed the payments schema", "score": 0.9},
+	}
+	enhance()
+	enhance()
+	results = []map[string]interface{}{{"id": "m3", "memory": "Lunch with the team", "score": 0.4}}
+	enhance()
+	results = nil
+	enhance()
+	down = true
+	enhance()
+
+	stats := e.GetStats()
+	if stats.EnhancementsMade != 3 {
+		t.Errorf("enhancements made = %d, want 3", stats.EnhancementsMade)
+	}
+	want := map[string]int{"contextual": 2, "detailed": 0, "minimal": 1, "none": 2}
+	for typ, n := range want {
+		if stats.ByType[typ] != n {
+			t.Errorf("by type %s = %d, want %d", typ, stats.ByType[typ], n)
+		}
+	}
+	if stats.AvgMemoriesUsed != 5.0/3 {
+		t.Errorf("avg memories used = %v, want %v", stats.AvgMemoriesUsed, 5.0/3)
+	}
+	if stats.SearchFailures != 1 || stats.LastSearchFailure.IsZero() {
+		t.Errorf("search failures = %d at %v, want 1 with a time", stats.SearchFailures, stats.LastSearchFailure)
+	}
+}
@@ -0,0 +1,132 @@
+package enhancer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Context fetch limits
+const (
+	defaultFetchTimeout  = 2 * time.Second
+	defaultFetchMaxBytes = 64 * 1024
+	// fetchQueryChars bounds how much fetched text joins the search query
+	fetchQueryChars = 500
+)
+
+var (
+	htmlTagPattern    = regexp.MustCompile(`(?s)<(script|style)[^>]*>.*?</(script|style)>|<[^>]*>`)
+	whitespacePattern = regexp.MustCompile(`\s+`)
+)
+
+// ContextFetcher fetches text for a page URL to enrich enhancement. Only
+// hosts on the allowlist are contacted.
+type ContextFetcher struct {
+	allowed  map[string]bool
+	client   *http.Client
+	maxBytes int64
+}
+
+// NewContextFetcher creates a fetcher for the allowed hosts (host names,
+// without ports). A zero timeout uses the default.
+func NewContextFetcher(allowedHosts []string, timeout time.Duration) *ContextFetcher {
+	if timeout <= 0 {
+		timeout = defaultFetchTimeout
+	}
+	allowed := make(map[string]bool, len(allowedHosts))
+	for _, h := range allowedHosts {
+		allowed[strings.ToLower(strings.TrimSpace(h))] = true
+	}
+	return &ContextFetcher{
+		allowed: allowed,
+		client: &http.Client{
+			Timeout: timeout,
+			// Redirects could leave the allowlist
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		maxBytes: defaultFetchMaxBytes,
+	}
+}
+
+// Fetch returns the text content at rawURL, with HTML tags stripped
+func (f *ContextFetcher) Fetch(ctx context.Context, rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("parsing url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	if !f.allowed[strings.ToLower(u.Hostname())] {
+		return "", fmt.Errorf("host %q is not allowed", u.Hostname())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("creating request: %w", err)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, f.maxBytes))
+	if err != nil {
+		return "", fmt.Errorf("reading response: %w", err)
+	}
+
+	text := string(body)
+	if strings.Contains(resp.Header.Get("Content-Type"), "html") {
+		text = htmlTagPattern.ReplaceAllString(text, " ")
+	}
+	return strings.TrimSpace(whitespacePattern.ReplaceAllString(text, " ")), nil
+}
+
+type pageURLKey struct{}
+
+// WithPageURL attaches the URL of the page a prompt was written on, so an
+// enhancer with a ContextFetcher can fetch it
+func WithPageURL(ctx context.Context, pageURL string) context.Context {
+	return context.WithValue(ctx, pageURLKey{}, pageURL)
+}
+
+// SetContextFetcher enables enriching the search query with the page's
+// content; nil disables it
+func (e *Enhancer) SetContextFetcher(f *ContextFetcher) {
+	e.fetcher = f
+}
+
+// searchQuery returns the prompt, extended with fetched page text when a
+// fetcher is set and the request carries a page URL
+func (e *Enhancer) searchQuery(ctx context.Context, prompt string) string {
+	pageURL, _ := ctx.Value(pageURLKey{}).(string)
+	if e.fetcher == nil || pageURL == "" {
+		return prompt
+	}
+
+	text, err := e.fetcher.Fetch(ctx, pageURL)
+	if err != nil {
+		e.logger.Warn("page context fetch skipped", "error", err)
+		return prompt
+	}
+	if len(text) > fetchQueryChars {
+		text = text[:fetchQueryChars]
+	}
+	if text == "" {
+		return prompt
+	}
+	return prompt + "\n" + text
+}
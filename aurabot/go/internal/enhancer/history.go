@@ -0,0 +1,56 @@
+package enhancer
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"screen-memory-assistant/internal/memory"
+)
+
+// historyPromptChars caps how much of a prompt is kept in its record
+const historyPromptChars = 500
+
+// HistoryOptions controls what an enhancement's memory records beyond
+// its type. Prompts can hold private text, so both are opt-in.
+type HistoryOptions struct {
+	// Prompts keeps the original prompt text
+	Prompts bool
+	// Sites keeps the host of the page the prompt was written on
+	Sites bool
+}
+
+// SetHistory stores every enhancement as a memory with source
+// "enhancement", so later enhancements can draw on past prompts; nil
+// disables it
+func (e *Enhancer) SetHistory(opts *HistoryOptions) {
+	e.history = opts
+}
+
+// storeEnhancement records result as a memory if history is enabled
+func (e *Enhancer) storeEnhancement(ctx context.Context, result *EnhancementResult, pageContext string) {
+	if e.history == nil {
+		return
+	}
+
+	content := fmt.Sprintf("Enhanced a prompt (%s)", result.EnhancementType)
+	if e.history.Sites {
+		pageURL, _ := ctx.Value(pageURLKey{}).(string)
+		if u, err := url.Parse(pageURL); err == nil && u.Hostname() != "" {
+			content += " on " + u.Hostname()
+		}
+	}
+	if e.history.Prompts {
+		content += ": " + truncateMemory(result.OriginalPrompt, historyPromptChars)
+	}
+
+	metadata := memory.Metadata{
+		Timestamp: e.now().Format(time.RFC3339),
+		Context:   pageContext,
+		Source:    memory.SourceEnhancement,
+	}
+	if _, err := e.memoryStore.Add(content, metadata); err != nil {
+		e.logger.Warn("failed to store enhancement", "error", err)
+	}
+}
@@ -0,0 +1,42 @@
+package enhancer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Where SetIncludeNow puts the current time in an enhanced prompt
+const (
+	NowAppend  = "append"
+	NowPrepend = "prepend"
+)
+
+// defaultNowFormat names the weekday, which matters for planning prompts
+const defaultNowFormat = "Monday, January 2, 2006 15:04 MST"
+
+// SetIncludeNow adds the current local time to every enhanced prompt, at
+// position NowAppend or NowPrepend, formatted with a Go time layout
+// (empty for the default). An empty position turns it off.
+func (e *Enhancer) SetIncludeNow(position, format string) {
+	position = strings.ToLower(strings.TrimSpace(position))
+	if position != "" && position != NowPrepend {
+		position = NowAppend
+	}
+	if format == "" {
+		format = defaultNowFormat
+	}
+	e.nowPosition = position
+	e.nowFormat = format
+}
+
+// withNow adds the current time to an enhanced prompt if configured
+func (e *Enhancer) withNow(prompt string) string {
+	if e.nowPosition == "" {
+		return prompt
+	}
+	stamp := fmt.Sprintf("[Current time: %s]", e.now().Format(e.nowFormat))
+	if e.nowPosition == NowPrepend {
+		return stamp + "\n" + prompt
+	}
+	return prompt + "\n" + stamp
+}
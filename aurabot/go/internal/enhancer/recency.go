@@ -0,0 +1,51 @@
+package enhancer
+
+import (
+	"context"
+
+	"screen-memory-assistant/internal/memory"
+)
+
+// DefaultRecencyBoost is the weight used when a request turns the recency
+// boost on without a weight and none is configured
+const DefaultRecencyBoost = 0.5
+
+// recencyOverfetch is how many times more results are fetched when
+// re-ranking
+const recencyOverfetch = 3
+
+type recencyKey struct{}
+
+// WithRecencyBoost overrides the configured recency weight for searches
+// and enhancements made with the returned context; 0 ranks by relevance
+// alone
+func WithRecencyBoost(ctx context.Context, weight float64) context.Context {
+	return context.WithValue(ctx, recencyKey{}, weight)
+}
+
+// RecencyBoostWeight returns the weight a request gets when it turns the
+// recency boost on: the configured weight, or DefaultRecencyBoost if none
+func (e *Enhancer) RecencyBoostWeight() float64 {
+	if w := e.memoryStore.RecencyWeight(); w > 0 {
+		return w
+	}
+	return DefaultRecencyBoost
+}
+
+// recencyWeight returns the request's recency weight, falling back to
+// the configured one
+func (e *Enhancer) recencyWeight(ctx context.Context) float64 {
+	if w, ok := ctx.Value(recencyKey{}).(float64); ok {
+		return w
+	}
+	return e.memoryStore.RecencyWeight()
+}
+
+// fetchLimit widens a search when results will be re-ranked, so newer or
+// more used but slightly less relevant memories can surface
+func fetchLimit(limit int, r memory.Reranker) int {
+	if r != nil && limit > 0 {
+		return limit * recencyOverfetch
+	}
+	return limit
+}
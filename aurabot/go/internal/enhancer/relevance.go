@@ -0,0 +1,34 @@
+package enhancer
+
+// RelevanceOptions sets how search results are bucketed. Score
+// distributions differ between backends, so the cutoff is tunable.
+type RelevanceOptions struct {
+	// Threshold is the score above which a memory is highly relevant
+	Threshold float64
+	// MaxHighRelevance and MaxContextual cap how many of each a
+	// contextual enhancement includes
+	MaxHighRelevance int
+	MaxContextual    int
+}
+
+// defaultRelevance is used for options left at zero
+var defaultRelevance = RelevanceOptions{
+	Threshold:        highRelevanceScore,
+	MaxHighRelevance: 3,
+	MaxContextual:    2,
+}
+
+// SetRelevance sets the high-relevance threshold and the per-bucket caps;
+// zero fields keep the defaults
+func (e *Enhancer) SetRelevance(opts RelevanceOptions) {
+	if opts.Threshold == 0 {
+		opts.Threshold = defaultRelevance.Threshold
+	}
+	if opts.MaxHighRelevance <= 0 {
+		opts.MaxHighRelevance = defaultRelevance.MaxHighRelevance
+	}
+	if opts.MaxContextual <= 0 {
+		opts.MaxContextual = defaultRelevance.MaxContextual
+	}
+	e.relevance = opts
+}
@@ -0,0 +1,78 @@
+package enhancer
+
+import (
+	"context"
+	"fmt"
+
+	"screen-memory-assistant/internal/memory"
+)
+
+// defaultUsageWeight scales the usage reranker's boost
+const defaultUsageWeight = 0.2
+
+// SetReranker sets the reranker applied to search results before they
+// are bucketed; nil keeps the backend's order. A request's recency boost
+// takes precedence over it.
+func (e *Enhancer) SetReranker(r memory.Reranker) {
+	e.reranker = r
+	e.rerankerName = ""
+}
+
+// UseReranker selects a built-in reranker by name: "score", "recency" or
+// "usage". An empty name keeps the backend's order.
+func (e *Enhancer) UseReranker(name string) error {
+	switch name {
+	case "":
+		e.SetReranker(nil)
+	case memory.RerankScore:
+		e.SetReranker(memory.ScoreReranker{})
+	case memory.RerankRecency:
+		e.SetReranker(e.memoryStore.RecencyReranker(e.RecencyBoostWeight()))
+	case memory.RerankUsage:
+		e.SetReranker(memory.UsageReranker{Weight: defaultUsageWeight, Uses: e.uses})
+	default:
+		return fmt.Errorf("unknown reranker %q", name)
+	}
+	e.rerankerName = name
+	return nil
+}
+
+// rerankerFor returns the reranker for a request: recency when the
+// request or config sets a recency weight, otherwise the configured one
+func (e *Enhancer) rerankerFor(ctx context.Context) memory.Reranker {
+	if w := e.recencyWeight(ctx); w > 0 {
+		return e.memoryStore.RecencyReranker(w)
+	}
+	return e.reranker
+}
+
+// rank applies r, if any, and keeps at most limit results
+func rank(r memory.Reranker, results []memory.SearchResult, limit int) []memory.SearchResult {
+	if r == nil {
+		return results
+	}
+	results = r.Rerank(results)
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// recordUse counts a memory's inclusion in an enhancement
+func (e *Enhancer) recordUse(results []memory.SearchResult) {
+	e.statsMu.Lock()
+	defer e.statsMu.Unlock()
+	if e.useCounts == nil {
+		e.useCounts = make(map[string]int)
+	}
+	for _, r := range results {
+		e.useCounts[r.Memory.ID]++
+	}
+}
+
+// uses returns how many enhancements have used the memory
+func (e *Enhancer) uses(id string) int {
+	e.statsMu.RLock()
+	defer e.statsMu.RUnlock()
+	return e.useCounts[id]
+}
@@ -0,0 +1,145 @@
+package enhancer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TemplateData holds the memories an enhancement includes, already
+// trimmed to the limits of its type
+type TemplateData struct {
+	// Type is "contextual", "detailed" or "minimal"
+	Type string
+	// HighRelevance and Contextual are the buckets of a contextual
+	// enhancement
+	HighRelevance []string
+	Contextual    []string
+	// Memories are the memories of a detailed or minimal enhancement,
+	// with their context prefixed
+	Memories []string
+	// More is set when a minimal enhancement left related memories out
+	More bool
+}
+
+// TemplateFunc formats the memories appended to a prompt
+type TemplateFunc func(d TemplateData) string
+
+// defaultTemplates format enhancements for the AI chat named by the page
+// context; other contexts use defaultTemplate
+var defaultTemplates = map[string]TemplateFunc{
+	"chatgpt": chatGPTTemplate,
+	"claude":  claudeTemplate,
+}
+
+// SetTemplate formats enhancements for pageContext with tmpl; nil reverts
+// that context to the default template
+func (e *Enhancer) SetTemplate(pageContext string, tmpl TemplateFunc) {
+	key := strings.ToLower(pageContext)
+	if tmpl == nil {
+		delete(e.templates, key)
+		return
+	}
+	e.templates[key] = tmpl
+}
+
+// templateFor picks the template for a page context
+func (e *Enhancer) templateFor(pageContext string) TemplateFunc {
+	if tmpl, ok := e.templates[strings.ToLower(pageContext)]; ok {
+		return tmpl
+	}
+	return defaultTemplate
+}
+
+// defaultTemplate uses bracketed sections that read well in any chat
+func defaultTemplate(d TemplateData) string {
+	var builder strings.Builder
+
+	switch d.Type {
+	case "contextual":
+		// Rich context enhancement for highly relevant scenarios
+		builder.WriteString("\n\n[Context from previous sessions]\n")
+		builder.WriteString("Based on my previous activities and context:\n")
+		for _, memory := range d.HighRelevance {
+			builder.WriteString(fmt.Sprintf("- %s\n", memory))
+		}
+		if len(d.Contextual) > 0 {
+			builder.WriteString("\nAdditional context:\n")
+			for _, memory := range d.Contextual {
+				builder.WriteString(fmt.Sprintf("- %s\n", memory))
+			}
+		}
+
+	case "detailed":
+		// Moderate detail enhancement
+		builder.WriteString("\n\n[Relevant background]\n")
+		for _, memory := range d.Memories {
+			builder.WriteString(fmt.Sprintf("- %s\n", memory))
+		}
+
+	case "minimal":
+		// Light touch - just add context note
+		if len(d.Memories) > 0 {
+			builder.WriteString("\n\n[Note: Consider previous context: ")
+			builder.WriteString(d.Memories[0])
+			if d.More {
+				builder.WriteString(" and related activities")
+			}
+			builder.WriteString("]")
+		}
+	}
+
+	return builder.String()
+}
+
+// claudeTemplate wraps memories in XML tags, which Claude is trained to
+// treat as structured context
+func claudeTemplate(d TemplateData) string {
+	var builder strings.Builder
+	writeMemories := func(relevance string, memories []string) {
+		for _, memory := range memories {
+			builder.WriteString(fmt.Sprintf("<memory relevance=%q>%s</memory>\n", relevance, memory))
+		}
+	}
+
+	builder.WriteString("\n\n<context source=\"my previous sessions\">\n")
+	switch d.Type {
+	case "contextual":
+		writeMemories("high", d.HighRelevance)
+		writeMemories("related", d.Contextual)
+	case "detailed":
+		writeMemories("background", d.Memories)
+	case "minimal":
+		writeMemories("related", d.Memories)
+	}
+	builder.WriteString("</context>")
+
+	return builder.String()
+}
+
+// chatGPTTemplate renders memories as a Markdown section, which ChatGPT
+// formats in the message box
+func chatGPTTemplate(d TemplateData) string {
+	var builder strings.Builder
+
+	builder.WriteString("\n\n### Context from my previous sessions\n")
+	switch d.Type {
+	case "contextual":
+		for _, memory := range d.HighRelevance {
+			builder.WriteString(fmt.Sprintf("- **%s**\n", memory))
+		}
+		for _, memory := range d.Contextual {
+			builder.WriteString(fmt.Sprintf("- %s\n", memory))
+		}
+	default:
+		for _, memory := range d.Memories {
+			builder.WriteString(fmt.Sprintf("- %s\n", memory))
+		}
+	}
+
+	return strings.TrimSuffix(builder.String(), "\n")
+}
+
+// included counts the memories a template formats
+func (d TemplateData) included() int {
+	return len(d.HighRelevance) + len(d.Contextual) + len(d.Memories)
+}
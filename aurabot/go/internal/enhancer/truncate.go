@@ -0,0 +1,51 @@
+package enhancer
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ellipsis marks truncated memory content
+const ellipsis = "…"
+
+// SetMaxMemoryChars limits how many characters of each memory go into an
+// enhanced prompt; 0 means no limit
+func (e *Enhancer) SetMaxMemoryChars(n int) {
+	e.maxMemoryChars = n
+}
+
+// truncateMemory shortens content to at most limit characters, ending
+// with an ellipsis. It keeps the leading text, cutting after the last
+// complete sentence when that keeps at least half the limit, and at a
+// word boundary otherwise.
+func truncateMemory(content string, limit int) string {
+	runes := []rune(content)
+	if limit <= 0 || len(runes) <= limit {
+		return content
+	}
+	if limit <= 1 {
+		return ellipsis
+	}
+
+	// Leave room for the ellipsis
+	head := runes[:limit-1]
+
+	for i := len(head) - 1; i >= len(head)/2; i-- {
+		if isSentenceEnd(head[i]) && (i+1 == len(head) || unicode.IsSpace(head[i+1])) {
+			return string(head[:i+1]) + ellipsis
+		}
+	}
+
+	// The cut already falls between words
+	if unicode.IsSpace(runes[len(head)]) {
+		return strings.TrimRightFunc(string(head), unicode.IsSpace) + ellipsis
+	}
+	if i := strings.LastIndexFunc(string(head), unicode.IsSpace); i > 0 {
+		return strings.TrimRightFunc(string(head)[:i], unicode.IsSpace) + ellipsis
+	}
+	return string(head) + ellipsis
+}
+
+func isSentenceEnd(r rune) bool {
+	return r == '.' || r == '!' || r == '?'
+}
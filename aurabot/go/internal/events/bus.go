@@ -0,0 +1,111 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Event types
+const (
+	MemoryStored = "memory.stored"
+)
+
+// defaultQueueSize bounds events waiting for dispatch
+const defaultQueueSize = 256
+
+// Event is something that happened in the app, delivered to subscribers
+type Event struct {
+	Type string      `json:"type"`
+	Time time.Time   `json:"time"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+// Handler receives dispatched events
+type Handler func(Event)
+
+// Bus delivers published events to subscribers on a background goroutine
+type Bus struct {
+	queue    chan Event
+	done     chan struct{}
+	mu       sync.RWMutex
+	handlers []Handler
+	closed   bool
+	dropped  atomic.Int64
+}
+
+// NewBus creates a bus holding up to size queued events (0 for the default)
+func NewBus(size int) *Bus {
+	if size <= 0 {
+		size = defaultQueueSize
+	}
+	b := &Bus{
+		queue: make(chan Event, size),
+		done:  make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// Subscribe registers h for every event published afterwards
+func (b *Bus) Subscribe(h Handler) {
+	b.mu.Lock()
+	b.handlers = append(b.handlers, h)
+	b.mu.Unlock()
+}
+
+// Publish queues an event without blocking. It returns false when the
+// bus is closed or its queue is full.
+func (b *Bus) Publish(eventType string, data interface{}) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return false
+	}
+
+	select {
+	case b.queue <- Event{Type: eventType, Time: time.Now(), Data: data}:
+		return true
+	default:
+		b.dropped.Add(1)
+		return false
+	}
+}
+
+// Dropped returns how many events were discarded because the queue was full
+func (b *Bus) Dropped() int64 {
+	return b.dropped.Load()
+}
+
+// Close stops accepting events and waits until the queued ones have been
+// dispatched or ctx is done. It returns how many were left undelivered.
+func (b *Bus) Close(ctx context.Context) int {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.queue)
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-b.done:
+		return 0
+	case <-ctx.Done():
+		return len(b.queue)
+	}
+}
+
+// run dispatches queued events until the bus is closed and drained
+func (b *Bus) run() {
+	defer close(b.done)
+	for e := range b.queue {
+		b.mu.RLock()
+		handlers := b.handlers
+		b.mu.RUnlock()
+
+		for _, h := range handlers {
+			h(e)
+		}
+	}
+}
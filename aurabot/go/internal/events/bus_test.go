@@ -0,0 +1,57 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBus_CloseDrainsQueue(t *testing.T) {
+	bus := NewBus(10)
+
+	var mu sync.Mutex
+	var got []string
+	release := make(chan struct{})
+	bus.Subscribe(func(e Event) {
+		<-release
+		mu.Lock()
+		got = append(got, e.Type)
+		mu.Unlock()
+	})
+
+	for i := 0; i < 3; i++ {
+		if !bus.Publish(MemoryStored, i) {
+			t.Fatalf("Publish %d rejected", i)
+		}
+	}
+	close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if n := bus.Close(ctx); n != 0 {
+		t.Errorf("Close left %d undelivered", n)
+	}
+	if len(got) != 3 {
+		t.Errorf("delivered %d events, want 3", len(got))
+	}
+	if bus.Publish(MemoryStored, nil) {
+		t.Error("Publish after Close was accepted")
+	}
+}
+
+func TestBus_CloseTimeout(t *testing.T) {
+	bus := NewBus(10)
+	block := make(chan struct{})
+	defer close(block)
+	bus.Subscribe(func(Event) { <-block })
+
+	bus.Publish(MemoryStored, 1)
+	bus.Publish(MemoryStored, 2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if n := bus.Close(ctx); n != 1 {
+		t.Errorf("Close reported %d undelivered, want 1", n)
+	}
+}
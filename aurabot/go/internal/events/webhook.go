@@ -0,0 +1,157 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Default webhook delivery settings
+const (
+	webhookAttempts   = 3
+	webhookBackoff    = 500 * time.Millisecond
+	webhookMaxBackoff = 30 * time.Second
+)
+
+// Webhooks posts events as JSON to a set of URLs. Deliveries run in the
+// background and are retried with jittered exponential backoff; Flush
+// waits for them.
+type Webhooks struct {
+	urls       []string
+	client     *http.Client
+	attempts   int
+	backoff    time.Duration
+	maxBackoff time.Duration
+
+	// jitter picks a random duration in [0, d); replaceable in tests
+	jitter func(d time.Duration) time.Duration
+
+	wg      sync.WaitGroup
+	pending atomic.Int64
+	dropped atomic.Int64
+}
+
+// NewWebhooks creates a sender for urls with a per-request timeout
+func NewWebhooks(urls []string, timeout time.Duration) *Webhooks {
+	return &Webhooks{
+		urls:       urls,
+		client:     &http.Client{Timeout: timeout},
+		attempts:   webhookAttempts,
+		backoff:    webhookBackoff,
+		maxBackoff: webhookMaxBackoff,
+		jitter: func(d time.Duration) time.Duration {
+			return time.Duration(rand.Int63n(int64(d)))
+		},
+	}
+}
+
+// SetRetry sets how many attempts a delivery gets, the delay before the
+// first retry and the cap the doubling delay grows to. Zero or negative
+// values keep the current setting.
+func (w *Webhooks) SetRetry(attempts int, backoff, maxBackoff time.Duration) {
+	if attempts > 0 {
+		w.attempts = attempts
+	}
+	if backoff > 0 {
+		w.backoff = backoff
+	}
+	if maxBackoff > 0 {
+		w.maxBackoff = maxBackoff
+	}
+}
+
+// Dropped returns how many deliveries failed every attempt
+func (w *Webhooks) Dropped() int64 {
+	return w.dropped.Load()
+}
+
+// Handle delivers e to every URL; it is a bus Handler
+func (w *Webhooks) Handle(e Event) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("[Webhooks] Failed to encode %s event: %v", e.Type, err)
+		return
+	}
+
+	for _, url := range w.urls {
+		w.wg.Add(1)
+		w.pending.Add(1)
+		go func(url string) {
+			defer w.wg.Done()
+			defer w.pending.Add(-1)
+			if err := w.deliver(url, body); err != nil {
+				w.dropped.Add(1)
+				log.Printf("[Webhooks] Dropping %s delivery to %s after %d attempts: %v", e.Type, url, w.attempts, err)
+			}
+		}(url)
+	}
+}
+
+// deliver posts body to url, retrying failed attempts
+func (w *Webhooks) deliver(url string, body []byte) error {
+	var err error
+	for attempt := 1; attempt <= w.attempts; attempt++ {
+		if err = w.post(url, body); err == nil {
+			return nil
+		}
+		if attempt < w.attempts {
+			time.Sleep(w.retryDelay(attempt))
+		}
+	}
+	return err
+}
+
+// retryDelay is the wait after the given failed attempt: the backoff
+// doubled per attempt up to maxBackoff, of which a random half is taken
+// off so failing deliveries don't retry in lockstep
+func (w *Webhooks) retryDelay(attempt int) time.Duration {
+	delay := w.backoff
+	for i := 1; i < attempt && delay < w.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > w.maxBackoff {
+		delay = w.maxBackoff
+	}
+	if half := delay / 2; half > 0 {
+		delay = half + w.jitter(half)
+	}
+	return delay
+}
+
+// post makes a single delivery attempt
+func (w *Webhooks) post(url string, body []byte) error {
+	resp, err := w.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Flush waits for in-flight deliveries, including their retries, until
+// ctx is done. It returns how many were still pending.
+func (w *Webhooks) Flush(ctx context.Context) int {
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return 0
+	case <-ctx.Done():
+		return int(w.pending.Load())
+	}
+}
@@ -0,0 +1,66 @@
+package events
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWebhooks_RetriesUntilDelivered(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	w := NewWebhooks([]string{server.URL}, time.Second)
+	w.SetRetry(3, time.Millisecond, 4*time.Millisecond)
+	w.Handle(Event{Type: MemoryStored})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if n := w.Flush(ctx); n != 0 {
+		t.Fatalf("%d deliveries still pending", n)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("server called %d times, want 3", got)
+	}
+	if w.Dropped() != 0 {
+		t.Errorf("Dropped = %d, want 0", w.Dropped())
+	}
+
+	// One more failure than the budget allows drops the delivery
+	calls.Store(0)
+	w.SetRetry(2, 0, 0)
+	w.Handle(Event{Type: MemoryStored})
+	w.Flush(ctx)
+	if got := calls.Load(); got != 2 || w.Dropped() != 1 {
+		t.Errorf("server called %d times, dropped %d; want 2 and 1", got, w.Dropped())
+	}
+}
+
+func TestWebhooks_RetryDelay(t *testing.T) {
+	w := NewWebhooks(nil, time.Second)
+	w.SetRetry(10, 100*time.Millisecond, time.Second)
+
+	// Without jitter the delay is half the doubled backoff
+	w.jitter = func(time.Duration) time.Duration { return 0 }
+	for attempt, want := range map[int]time.Duration{1: 50, 2: 100, 3: 200, 4: 400, 5: 500, 9: 500} {
+		if got := w.retryDelay(attempt); got != want*time.Millisecond {
+			t.Errorf("retryDelay(%d) = %v, want %vms", attempt, got, want)
+		}
+	}
+
+	// Jitter stays within the other half
+	w.jitter = func(d time.Duration) time.Duration { return d - 1 }
+	if got := w.retryDelay(2); got >= 200*time.Millisecond {
+		t.Errorf("retryDelay(2) = %v, want under the full 200ms", got)
+	}
+}
@@ -1,10 +1,15 @@
 package llm
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,11 +19,52 @@ import (
 
 // Client wraps the OpenAI-compatible LLM API
 type Client struct {
-	visionClient *openai.Client  // LM Studio for vision
-	chatClient   *openai.Client  // Cerebras for chat/text
+	visionClient *openai.Client // LM Studio for vision
+	chatClient   *openai.Client // Cerebras for chat/text
 	config       *config.LLMConfig
+	analysisMode string
+	// askConfidence adds a self-reported confidence field to the schema
+	askConfidence bool
+	logger        *slog.Logger
 }
 
+// Analysis modes select which JSON schema the vision model is asked for
+const (
+	AnalysisModeFull    = "full"
+	AnalysisModeMinimal = "minimal"
+)
+
+// fullAnalysisPrompt asks for the five-field analysis schema
+const fullAnalysisPrompt = `You are a personal AI assistant observing the user's screen. Analyze what you see and provide:
+1. A brief summary of what's on screen
+2. The context (work, entertainment, communication, etc.)
+3. Activities the user might be doing
+4. Key UI elements visible
+5. What the user likely intends to do
+
+Respond in this exact JSON format:
+{
+  "summary": "brief description",
+  "context": "work/entertainment/social/etc",
+  "activities": ["activity1", "activity2"],
+  "key_elements": ["element1", "element2"],
+  "user_intent": "what user is trying to accomplish"
+}`
+
+// compactAnalysisPrompt asks for a two-field schema that small models emit reliably
+const compactAnalysisPrompt = `You are a personal AI assistant observing the user's screen. Describe what you see.
+
+Respond in this exact JSON format:
+{
+  "summary": "brief description",
+  "context": "work/entertainment/social/etc"
+}`
+
+// confidenceInstruction asks the model to rate its own analysis
+const confidenceInstruction = `
+
+Also include "confidence": a number from 0 to 1 saying how sure you are that the description matches the screen. Use a low value if the image is unclear or you are guessing.`
+
 // VisionMessage represents a message with image content
 type VisionMessage struct {
 	Role        string
@@ -33,6 +79,14 @@ type AnalysisResult struct {
 	Activities  []string `json:"activities"`
 	KeyElements []string `json:"key_elements"`
 	UserIntent  string   `json:"user_intent"`
+	// OCRText holds on-screen text when the backend returns an OCR pass
+	OCRText string `json:"ocr_text,omitempty"`
+	// Confidence is the model's self-reported certainty (0-1); nil when
+	// the model didn't provide one
+	Confidence *float64 `json:"confidence,omitempty"`
+	// Metadata holds fields the model returned that aren't part of the
+	// schema, so they aren't silently lost
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
 }
 
 // NewClient creates a new LLM client
@@ -40,12 +94,16 @@ func NewClient(cfg *config.LLMConfig) *Client {
 	// Vision client (LM Studio) - for image analysis
 	visionConfig := openai.DefaultConfig("")
 	visionConfig.BaseURL = cfg.BaseURL
+	// Rate-limited responses keep their Retry-After for error messages
+	httpClient := &http.Client{Transport: retryAfterTransport{http.DefaultTransport}}
+	visionConfig.HTTPClient = httpClient
 
 	// Chat client (Cerebras) - for text/chat
 	var chatClient *openai.Client
 	if cfg.CerebrasAPIKey != "" {
 		chatConfig := openai.DefaultConfig(cfg.CerebrasAPIKey)
 		chatConfig.BaseURL = "https://api.cerebras.ai/v1"
+		chatConfig.HTTPClient = httpClient
 		chatClient = openai.NewClientWithConfig(chatConfig)
 	} else {
 		// Fallback to LM Studio if no Cerebras key
@@ -56,39 +114,66 @@ func NewClient(cfg *config.LLMConfig) *Client {
 		visionClient: openai.NewClientWithConfig(visionConfig),
 		chatClient:   chatClient,
 		config:       cfg,
+		analysisMode: AnalysisModeFull,
+		logger:       slog.Default(),
+	}
+}
+
+// SetAnalysisMode selects the analysis schema ("full" or "minimal")
+func (c *Client) SetAnalysisMode(mode string) {
+	if mode == AnalysisModeMinimal {
+		c.analysisMode = AnalysisModeMinimal
+		return
+	}
+	c.analysisMode = AnalysisModeFull
+}
+
+// SetRequestConfidence asks the model to include a confidence score
+func (c *Client) SetRequestConfidence(enabled bool) {
+	c.askConfidence = enabled
+}
+
+// SetLogger replaces the logger, slog.Default() by default
+func (c *Client) SetLogger(l *slog.Logger) {
+	c.logger = l
+}
+
+// imageMIMEType tells PNG captures from the default JPEG ones
+func imageMIMEType(data []byte) string {
+	if bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")) {
+		return "image/png"
 	}
+	return "image/jpeg"
 }
 
-// AnalyzeScreen sends a screen capture to the LLM for analysis
+// AnalyzeScreen sends a screen capture (JPEG or PNG) to the LLM for analysis
 func (c *Client) AnalyzeScreen(ctx context.Context, imageData []byte, previousContext string) (*AnalysisResult, error) {
+	return c.AnalyzeCapture(ctx, imageData, previousContext, "")
+}
+
+// AnalyzeCapture is AnalyzeScreen with the title of the window that was
+// in the foreground, which tells the model a lot about the context
+func (c *Client) AnalyzeCapture(ctx context.Context, imageData []byte, previousContext, windowTitle string) (*AnalysisResult, error) {
 	ctx, cancel := context.WithTimeout(ctx, time.Duration(c.config.TimeoutSeconds)*time.Second)
 	defer cancel()
 
 	base64Image := base64.StdEncoding.EncodeToString(imageData)
-	dataURL := fmt.Sprintf("data:image/jpeg;base64,%s", base64Image)
+	dataURL := fmt.Sprintf("data:%s;base64,%s", imageMIMEType(imageData), base64Image)
 
 	// Build system prompt
-	systemPrompt := `You are a personal AI assistant observing the user's screen. Analyze what you see and provide:
-1. A brief summary of what's on screen
-2. The context (work, entertainment, communication, etc.)
-3. Activities the user might be doing
-4. Key UI elements visible
-5. What the user likely intends to do
-
-Respond in this exact JSON format:
-{
-  "summary": "brief description",
-  "context": "work/entertainment/social/etc",
-  "activities": ["activity1", "activity2"],
-  "key_elements": ["element1", "element2"],
-  "user_intent": "what user is trying to accomplish"
-}`
+	systemPrompt, err := c.analysisPrompt()
+	if err != nil {
+		return nil, err
+	}
 
 	// Add previous context if available
 	userPrompt := "Analyze this screenshot:"
 	if previousContext != "" {
 		userPrompt = fmt.Sprintf("Previous context: %s\n\nAnalyze this new screenshot:", previousContext)
 	}
+	if windowTitle != "" {
+		userPrompt = fmt.Sprintf("Active window: %s\n\n%s", windowTitle, userPrompt)
+	}
 
 	req := openai.ChatCompletionRequest{
 		Model: c.config.Model,
@@ -118,9 +203,14 @@ Respond in this exact JSON format:
 		Temperature: c.config.Temperature,
 	}
 
-	resp, err := c.visionClient.CreateChatCompletion(ctx, req)
+	ctx, wait := withRetryAfter(ctx)
+	var resp openai.ChatCompletionResponse
+	err = c.withRetry(ctx, func() (err error) {
+		resp, err = c.visionClient.CreateChatCompletion(ctx, req)
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("LLM API error: %w", err)
+		return nil, classifyError(err, providerLocal, req.Model, wait.get())
 	}
 
 	if len(resp.Choices) == 0 {
@@ -131,38 +221,68 @@ Respond in this exact JSON format:
 	return c.parseResponse(resp.Choices[0].Message.Content), nil
 }
 
+// Chat backends, as reported in ChatResponse.Backend
+const (
+	BackendCerebras = "cerebras"
+	BackendLocal    = "local"
+)
+
+// ChatResponse is a chat answer and how it was produced
+type ChatResponse struct {
+	Content string `json:"content"`
+	Model   string `json:"model"`
+	// Backend is the service that answered: BackendCerebras or BackendLocal
+	Backend string `json:"backend"`
+	// Degraded is set when the local model answered because the chat
+	// provider's quota was exhausted or it was unavailable
+	Degraded bool `json:"degraded"`
+}
+
 // GenerateResponse generates a conversational response based on context
 func (c *Client) GenerateResponse(ctx context.Context, prompt string, memories []string) (string, error) {
-	ctx, cancel := context.WithTimeout(ctx, time.Duration(c.config.TimeoutSeconds)*time.Second)
-	defer cancel()
+	resp, err := c.Chat(ctx, prompt, memories)
+	if err != nil {
+		return "", err
+	}
+	return resp.Content, nil
+}
 
-	systemPrompt := "You are a helpful AI assistant that knows the user well through their screen activity history. Answer based ONLY on the provided memory context. If the information isn't in the memories, say you don't know. Be concise."
+// Chat generates a conversational response based on context. When
+// Cerebras reports an exhausted quota or stays unavailable, the local
+// model may answer instead; see openChat.
+func (c *Client) Chat(ctx context.Context, prompt string, memories []string) (*ChatResponse, error) {
+	systemPrompt, userPrompt := chatPrompts(prompt, memories)
 
-	// Include memories as context
-	userPrompt := prompt
-	if len(memories) > 0 {
-		memoryContext := "Based on your activity history:\n"
-		for _, m := range memories {
-			memoryContext += "- " + m + "\n"
-		}
-		userPrompt = memoryContext + "\nUser question: " + prompt + "\n\nAnswer based only on the activity history above."
+	c.logPrompt(systemPrompt, userPrompt)
+
+	req := c.chatRequest(systemPrompt, userPrompt)
+
+	var resp openai.ChatCompletionResponse
+	chat, release, err := c.openChat(ctx, req, func(ctx context.Context, client *openai.Client, req openai.ChatCompletionRequest) (err error) {
+		resp, err = client.CreateChatCompletion(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
+	release()
 
-	// DEBUG: Print the full prompt
-	fmt.Println("\n" + strings.Repeat("=", 70))
-	fmt.Println("FULL PROMPT SENT TO LLM:")
-	fmt.Println(strings.Repeat("=", 70))
-	fmt.Printf("System:\n%s\n\n", systemPrompt)
-	fmt.Printf("User:\n%s\n", userPrompt)
-	fmt.Println(strings.Repeat("=", 70))
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("no response from LLM")
+	}
+	chat.Content = resp.Choices[0].Message.Content
+	return chat, nil
+}
 
+// chatRequest builds a chat request for the chat model
+func (c *Client) chatRequest(systemPrompt, userPrompt string) openai.ChatCompletionRequest {
 	// Use Cerebras model for chat
 	model := c.config.CerebrasModel
 	if model == "" {
 		model = c.config.Model
 	}
 
-	req := openai.ChatCompletionRequest{
+	return openai.ChatCompletionRequest{
 		Model: model,
 		Messages: []openai.ChatCompletionMessage{
 			{
@@ -177,20 +297,103 @@ func (c *Client) GenerateResponse(ctx context.Context, prompt string, memories [
 		MaxTokens:   c.config.MaxTokens,
 		Temperature: c.config.Temperature,
 	}
+}
+
+// openChat sends req to the chat provider with send, retrying transient
+// failures. When Cerebras reports an exhausted quota (FallbackOnQuota) or
+// stays unavailable or times out (ChatFallbackLocal), the local model gets
+// the request instead and the answer is marked degraded. Each backend
+// gets the full TimeoutSeconds, so a fallback isn't left with what the
+// retries spent.
+//
+// The returned ChatResponse names the backend that answered, without
+// content. release ends that backend's deadline; call it once the
+// response, or the stream send opened, has been read.
+func (c *Client) openChat(ctx context.Context, req openai.ChatCompletionRequest, send func(ctx context.Context, client *openai.Client, req openai.ChatCompletionRequest) error) (*ChatResponse, context.CancelFunc, error) {
+	attempt := func(client *openai.Client, retry bool) (context.CancelFunc, time.Duration, error) {
+		ctx, cancel := context.WithTimeout(ctx, time.Duration(c.config.TimeoutSeconds)*time.Second)
+		ctx, wait := withRetryAfter(ctx)
+		call := func() error { return send(ctx, client, req) }
+		var err error
+		if retry {
+			err = c.withRetry(ctx, call)
+		} else {
+			err = call()
+		}
+		if err != nil {
+			cancel()
+			return nil, wait.get(), err
+		}
+		return cancel, 0, nil
+	}
 
-	resp, err := c.chatClient.CreateChatCompletion(ctx, req)
+	provider := c.chatProvider()
+	degraded := false
+	release, wait, err := attempt(c.chatClient, true)
+	switch {
+	case err == nil || provider != providerCerebras:
+	case c.config.FallbackOnQuota && isQuotaError(err):
+		c.logger.Warn("chat quota exhausted, falling back to local model", "error", err, "model", c.config.Model)
+		req.Model = c.config.Model
+		degraded = true
+		provider = providerLocal
+		release, wait, err = attempt(c.visionClient, true)
+	case c.config.ChatFallbackLocal && (isRetryable(err) || (errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil)):
+		// Cerebras is down, overloaded or timed out; LM Studio gets one try
+		c.logger.Warn("chat provider unavailable, falling back to local model", "error", err, "model", c.config.Model)
+		req.Model = c.config.Model
+		degraded = true
+		provider = providerLocal
+		release, wait, err = attempt(c.visionClient, false)
+	}
 	if err != nil {
-		return "", fmt.Errorf("LLM API error: %w", err)
+		c.logger.Warn("chat request failed", "provider", provider, "model", req.Model, "error", err)
+		return nil, nil, classifyError(err, provider, req.Model, wait)
 	}
 
-	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("no response from LLM")
+	backend := BackendLocal
+	if provider == providerCerebras {
+		backend = BackendCerebras
 	}
+	return &ChatResponse{Model: req.Model, Backend: backend, Degraded: degraded}, release, nil
+}
 
-	return resp.Choices[0].Message.Content, nil
+// chatProvider names the service answering chats
+func (c *Client) chatProvider() string {
+	if c.config.CerebrasAPIKey != "" {
+		return providerCerebras
+	}
+	return providerLocal
+}
+
+// logPrompt logs a chat prompt at debug level when LogPrompts is set;
+// prompts hold memory contents, so they're never logged by default
+func (c *Client) logPrompt(systemPrompt, userPrompt string) {
+	if c.config.LogPrompts {
+		c.logger.Debug("chat prompt", "system", systemPrompt, "user", userPrompt)
+	}
 }
 
-// parseResponse extracts structured data from LLM text response
+// chatPrompts builds the system and user prompts for answering prompt
+// from memories
+func chatPrompts(prompt string, memories []string) (string, string) {
+	systemPrompt := "You are a helpful AI assistant that knows the user well through their screen activity history. Answer based ONLY on the provided memory context. If the information isn't in the memories, say you don't know. Be concise."
+
+	// Include memories as context
+	userPrompt := prompt
+	if len(memories) > 0 {
+		memoryContext := "Based on your activity history:\n"
+		for _, m := range memories {
+			memoryContext += "- " + m + "\n"
+		}
+		userPrompt = memoryContext + "\nUser question: " + prompt + "\n\nAnswer based only on the activity history above."
+	}
+	return systemPrompt, userPrompt
+}
+
+// parseResponse extracts structured data from LLM text response.
+// Both the full and the compact schema are accepted; fields missing
+// from the compact shape keep their defaults.
 func (c *Client) parseResponse(content string) *AnalysisResult {
 	// Store the full LLM output without truncation
 	result := &AnalysisResult{
@@ -204,36 +407,92 @@ func (c *Client) parseResponse(content string) *AnalysisResult {
 	// Try to parse JSON response if structured
 	// This allows LFM-2 to return proper JSON that we can extract fields from
 	var jsonResult map[string]interface{}
-	if err := json.Unmarshal([]byte(content), &jsonResult); err == nil {
-		if summary, ok := jsonResult["summary"].(string); ok {
+	if err := json.Unmarshal([]byte(extractJSON(content)), &jsonResult); err == nil {
+		fields, extras := normalizeFields(jsonResult, c.config.FieldAliases)
+		if summary, ok := fields["summary"].(string); ok {
 			result.Summary = summary
 		}
-		if context, ok := jsonResult["context"].(string); ok {
+		if context, ok := fields["context"].(string); ok {
 			result.Context = context
 		}
-		if userIntent, ok := jsonResult["user_intent"].(string); ok {
+		if userIntent, ok := fields["user_intent"].(string); ok {
 			result.UserIntent = userIntent
 		}
-		// Handle arrays
-		if activities, ok := jsonResult["activities"].([]interface{}); ok {
-			for _, a := range activities {
-				if s, ok := a.(string); ok {
-					result.Activities = append(result.Activities, s)
-				}
-			}
+		if ocrText, ok := fields["ocr_text"].(string); ok {
+			result.OCRText = ocrText
 		}
-		if keyElements, ok := jsonResult["key_elements"].([]interface{}); ok {
-			for _, e := range keyElements {
-				if s, ok := e.(string); ok {
-					result.KeyElements = append(result.KeyElements, s)
-				}
-			}
+		if confidence, ok := parseConfidence(fields["confidence"]); ok {
+			result.Confidence = &confidence
 		}
+		// Handle arrays (or a lone string)
+		result.Activities = append(result.Activities, stringList(fields["activities"])...)
+		result.KeyElements = append(result.KeyElements, stringList(fields["key_elements"])...)
+		result.Metadata = extras
 	}
 
 	return result
 }
 
+// parseConfidence reads a confidence given as a number or numeric string,
+// accepting percentages (e.g. 85) by scaling them to 0-1
+func parseConfidence(v interface{}) (float64, bool) {
+	var f float64
+	switch val := v.(type) {
+	case float64:
+		f = val
+	case string:
+		parsed, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(val), "%"), 64)
+		if err != nil {
+			return 0, false
+		}
+		f = parsed
+	default:
+		return 0, false
+	}
+
+	if f > 1 && f <= 100 {
+		f /= 100
+	}
+	if f < 0 || f > 1 {
+		return 0, false
+	}
+	return f, true
+}
+
+// extractJSON strips markdown code fences and surrounding prose that
+// small models tend to wrap around their JSON output
+func extractJSON(content string) string {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end <= start {
+		return content
+	}
+	return content[start : end+1]
+}
+
+// Warmup sends a one-token completion to the vision model so the local
+// server keeps it loaded
+func (c *Client) Warmup(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(c.config.TimeoutSeconds)*time.Second)
+	defer cancel()
+
+	req := openai.ChatCompletionRequest{
+		Model: c.config.Model,
+		Messages: []openai.ChatCompletionMessage{
+			{
+				Role:    openai.ChatMessageRoleUser,
+				Content: "Hi",
+			},
+		},
+		MaxTokens: 1,
+	}
+
+	if _, err := c.visionClient.CreateChatCompletion(ctx, req); err != nil {
+		return fmt.Errorf("warmup: %w", err)
+	}
+	return nil
+}
+
 // CheckHealth verifies the LLM endpoints are available
 func (c *Client) CheckHealth(ctx context.Context) error {
 	// Check vision client (LM Studio)
@@ -1,8 +1,20 @@
 package llm
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/sashabaranov/go-openai"
 	"screen-memory-assistant/internal/config"
 )
 
@@ -84,3 +96,788 @@ func TestParseResponse_Truncation(t *testing.T) {
 		t.Error("Summary doesn't end with '...'")
 	}
 }
+
+func TestParseResponse_FullSchema(t *testing.T) {
+	client := NewClient(&config.LLMConfig{})
+
+	input := `{
+  "summary": "Editing Go code",
+  "context": "work",
+  "activities": ["coding", "testing"],
+  "key_elements": ["VS Code"],
+  "user_intent": "fix a bug"
+}`
+
+	result := client.parseResponse(input)
+
+	if result.Summary != "Editing Go code" {
+		t.Errorf("Summary = %s, want 'Editing Go code'", result.Summary)
+	}
+	if result.Context != "work" {
+		t.Errorf("Context = %s, want 'work'", result.Context)
+	}
+	if len(result.Activities) != 2 {
+		t.Errorf("Activities length = %d, want 2", len(result.Activities))
+	}
+	if len(result.KeyElements) != 1 {
+		t.Errorf("KeyElements length = %d, want 1", len(result.KeyElements))
+	}
+	if result.UserIntent != "fix a bug" {
+		t.Errorf("UserIntent = %s, want 'fix a bug'", result.UserIntent)
+	}
+}
+
+func TestParseResponse_CompactSchema(t *testing.T) {
+	client := NewClient(&config.LLMConfig{})
+	client.SetAnalysisMode(AnalysisModeMinimal)
+
+	input := "```json\n{\"summary\": \"Watching a video\", \"context\": \"entertainment\"}\n```"
+
+	result := client.parseResponse(input)
+
+	if result.Summary != "Watching a video" {
+		t.Errorf("Summary = %s, want 'Watching a video'", result.Summary)
+	}
+	if result.Context != "entertainment" {
+		t.Errorf("Context = %s, want 'entertainment'", result.Context)
+	}
+	if result.UserIntent != "unknown" {
+		t.Errorf("UserIntent = %s, want 'unknown'", result.UserIntent)
+	}
+	if result.Activities == nil || len(result.Activities) != 0 {
+		t.Error("Activities should be an empty slice for compact schema")
+	}
+}
+
+func TestParseResponse_Confidence(t *testing.T) {
+	client := NewClient(&config.LLMConfig{})
+
+	tests := []struct {
+		input string
+		want  float64
+		ok    bool
+	}{
+		{`{"summary": "Editing code", "confidence": 0.42}`, 0.42, true},
+		{`{"summary": "Editing code", "confidence": "85%"}`, 0.85, true},
+		{`{"summary": "Editing code", "confidence": "unsure"}`, 0, false},
+		{`{"summary": "Editing code"}`, 0, false},
+	}
+
+	for _, tt := range tests {
+		result := client.parseResponse(tt.input)
+		if (result.Confidence != nil) != tt.ok {
+			t.Errorf("%s: confidence present = %v, want %v", tt.input, result.Confidence != nil, tt.ok)
+			continue
+		}
+		if tt.ok && *result.Confidence != tt.want {
+			t.Errorf("%s: confidence = %f, want %f", tt.input, *result.Confidence, tt.want)
+		}
+	}
+}
+
+func TestParseResponse_KeyVariantsAndExtras(t *testing.T) {
+	client := NewClient(&config.LLMConfig{FieldAliases: map[string]string{"Task": "user_intent"}})
+
+	result := client.parseResponse(`{
+  "Summary": "Reviewing a pull request",
+  "context": "work",
+  "keyElements": ["GitHub", "diff view"],
+  "activity": "code review",
+  "userIntent": "approve the PR",
+  "language": "Go",
+  "window_count": 3
+}`)
+
+	if result.Summary != "Reviewing a pull request" {
+		t.Errorf("Summary = %q", result.Summary)
+	}
+	if result.UserIntent != "approve the PR" {
+		t.Errorf("UserIntent = %q, want 'approve the PR'", result.UserIntent)
+	}
+	if len(result.KeyElements) != 2 {
+		t.Errorf("KeyElements = %v, want 2 from keyElements", result.KeyElements)
+	}
+	if len(result.Activities) != 1 || result.Activities[0] != "code review" {
+		t.Errorf("Activities = %v, want [code review]", result.Activities)
+	}
+	if len(result.Metadata) != 2 || result.Metadata["language"] != "Go" || result.Metadata["window_count"] != float64(3) {
+		t.Errorf("Metadata = %v, want language and window_count", result.Metadata)
+	}
+
+	// An exact key wins; its variant is kept as an extra, and configured
+	// aliases apply
+	result = client.parseResponse(`{"summary": "a", "user_intent": "exact", "USER-INTENT": "variant"}`)
+	if result.UserIntent != "exact" || result.Metadata["USER-INTENT"] != "variant" {
+		t.Errorf("UserIntent = %q, Metadata = %v", result.UserIntent, result.Metadata)
+	}
+	result = client.parseResponse(`{"summary": "a", "task": "ship it"}`)
+	if result.UserIntent != "ship it" || result.Metadata != nil {
+		t.Errorf("UserIntent = %q, Metadata = %v; want the configured alias", result.UserIntent, result.Metadata)
+	}
+}
+
+func TestSetAnalysisMode(t *testing.T) {
+	client := NewClient(&config.LLMConfig{})
+
+	if client.analysisMode != AnalysisModeFull {
+		t.Errorf("default analysisMode = %s, want %s", client.analysisMode, AnalysisModeFull)
+	}
+
+	client.SetAnalysisMode("minimal")
+	if client.analysisMode != AnalysisModeMinimal {
+		t.Errorf("analysisMode = %s, want %s", client.analysisMode, AnalysisModeMinimal)
+	}
+
+	client.SetAnalysisMode("bogus")
+	if client.analysisMode != AnalysisModeFull {
+		t.Errorf("analysisMode = %s, want fallback %s", client.analysisMode, AnalysisModeFull)
+	}
+}
+
+func TestAnalysisPrompt(t *testing.T) {
+	// Defaults keep the built-in prompts
+	client := NewClient(&config.LLMConfig{})
+	if got, _ := client.analysisPrompt(); got != fullAnalysisPrompt {
+		t.Errorf("default prompt = %q", got)
+	}
+	client.SetAnalysisMode(AnalysisModeMinimal)
+	client.SetRequestConfidence(true)
+	if got, _ := client.analysisPrompt(); got != compactAnalysisPrompt+confidenceInstruction {
+		t.Errorf("minimal prompt = %q", got)
+	}
+
+	// A custom schema with the default template lists its fields
+	schema := []config.VisionFieldConfig{
+		{Name: "summary", Description: "brief description"},
+		{Name: "activities", Description: "activity", List: true},
+		{Name: "sensitivity", Description: "public/private/secret"},
+	}
+	client = NewClient(&config.LLMConfig{VisionSchema: schema})
+	got, err := client.analysisPrompt()
+	if err != nil {
+		t.Fatalf("analysisPrompt failed: %v", err)
+	}
+	for _, want := range []string{"3. sensitivity: public/private/secret", `"activities": ["activity"],`, `"sensitivity": "public/private/secret"` + "\n}"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("prompt missing %q:\n%s", want, got)
+		}
+	}
+
+	// A translated template gets the built-in schema
+	client = NewClient(&config.LLMConfig{PromptTemplate: "Antworte nur mit JSON:\n{{.Schema}}"})
+	got, _ = client.analysisPrompt()
+	if !strings.HasPrefix(got, "Antworte nur mit JSON:\n{\n  \"summary\"") || !strings.Contains(got, `"user_intent"`) {
+		t.Errorf("template prompt = %q", got)
+	}
+
+	client = NewClient(&config.LLMConfig{PromptTemplate: "{{.Schema"})
+	if _, err := client.analysisPrompt(); err == nil {
+		t.Error("analysisPrompt accepted a broken template")
+	}
+}
+
+func TestStreamChat(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{"Busy ", "day."} {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":%q}}]}\n\n", chunk)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	client := NewClient(&config.LLMConfig{BaseURL: server.URL, Model: "test-model", TimeoutSeconds: 5})
+
+	var chunks []string
+	full, err := client.StreamChat(context.Background(), "system", "user", func(chunk string) {
+		chunks = append(chunks, chunk)
+	})
+	if err != nil {
+		t.Fatalf("StreamChat failed: %v", err)
+	}
+	if len(chunks) != 2 || full != "Busy day." {
+		t.Errorf("chunks = %q, full = %q", chunks, full)
+	}
+}
+
+func TestChat_LogsPromptOnlyWhenEnabled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":"ok"}}]}`)
+	}))
+	defer server.Close()
+
+	for _, logPrompts := range []bool{false, true} {
+		var buf bytes.Buffer
+		client := NewClient(&config.LLMConfig{BaseURL: server.URL, Model: "test-model", TimeoutSeconds: 5, LogPrompts: logPrompts})
+		client.SetLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+		if _, err := client.Chat(context.Background(), "what was I doing?", []string{"Reading bank statement"}); err != nil {
+			t.Fatalf("Chat failed: %v", err)
+		}
+		if logged := strings.Contains(buf.String(), "bank statement"); logged != logPrompts {
+			t.Errorf("LogPrompts %v: prompt logged = %v: %s", logPrompts, logged, buf.String())
+		}
+	}
+}
+
+func TestChat_FallsBackOnQuotaError(t *testing.T) {
+	cerebras := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"error":{"message":"You exceeded your current quota","type":"insufficient_quota","code":"quota_exceeded"}}`)
+	}))
+	defer cerebras.Close()
+
+	var localModel string
+	local := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		localModel = req.Model
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":"local answer"}}]}`)
+	}))
+	defer local.Close()
+
+	newClient := func(fallback bool) *Client {
+		cfg := &config.LLMConfig{
+			BaseURL:         local.URL,
+			Model:           "local-model",
+			TimeoutSeconds:  5,
+			CerebrasAPIKey:  "key",
+			CerebrasModel:   "cerebras-model",
+			FallbackOnQuota: fallback,
+		}
+		client := NewClient(cfg)
+		chatConfig := openai.DefaultConfig("key")
+		chatConfig.BaseURL = cerebras.URL
+		client.chatClient = openai.NewClientWithConfig(chatConfig)
+		return client
+	}
+
+	resp, err := newClient(true).Chat(context.Background(), "what did I do?", nil)
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if resp.Content != "local answer" || !resp.Degraded || localModel != "local-model" {
+		t.Errorf("response = %+v (local model %q), want degraded local answer", resp, localModel)
+	}
+
+	if _, err := newClient(false).Chat(context.Background(), "what did I do?", nil); err == nil {
+		t.Error("Chat succeeded with fallback disabled")
+	}
+}
+
+func TestChat_FallsBackLocallyWhenCerebrasIsDown(t *testing.T) {
+	var cerebrasCalls int
+	cerebras := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cerebrasCalls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer cerebras.Close()
+
+	var localCalls int
+	local := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		localCalls++
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":"local answer"}}]}`)
+	}))
+	defer local.Close()
+
+	newClient := func(fallback bool) *Client {
+		client := NewClient(&config.LLMConfig{
+			BaseURL:           local.URL,
+			Model:             "local-model",
+			TimeoutSeconds:    5,
+			CerebrasAPIKey:    "key",
+			CerebrasModel:     "cerebras-model",
+			ChatFallbackLocal: fallback,
+			RetryAttempts:     2,
+			RetryBaseDelayMs:  1,
+		})
+		chatConfig := openai.DefaultConfig("key")
+		chatConfig.BaseURL = cerebras.URL
+		client.chatClient = openai.NewClientWithConfig(chatConfig)
+		return client
+	}
+
+	resp, err := newClient(true).Chat(context.Background(), "what did I do?", nil)
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if resp.Content != "local answer" || resp.Backend != BackendLocal || resp.Model != "local-model" || !resp.Degraded {
+		t.Errorf("response = %+v, want a degraded local answer", resp)
+	}
+	if cerebrasCalls != 2 || localCalls != 1 {
+		t.Errorf("calls: cerebras %d, local %d; want Cerebras retried, then one local try", cerebrasCalls, localCalls)
+	}
+
+	if _, err := newClient(false).Chat(context.Background(), "what did I do?", nil); err == nil {
+		t.Error("Chat succeeded with fallback disabled")
+	}
+	if localCalls != 1 {
+		t.Errorf("local model called %d times, want no fallback when disabled", localCalls)
+	}
+}
+
+func TestChat_FallbackGetsItsOwnDeadline(t *testing.T) {
+	// Slow failures use up most of Cerebras' timeout across two attempts
+	cerebras := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(400 * time.Millisecond)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer cerebras.Close()
+	local := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":"local answer"}}]}`)
+	}))
+	defer local.Close()
+
+	client := NewClient(&config.LLMConfig{
+		BaseURL:           local.URL,
+		Model:             "local-model",
+		TimeoutSeconds:    1,
+		CerebrasAPIKey:    "key",
+		CerebrasModel:     "cerebras-model",
+		ChatFallbackLocal: true,
+		RetryAttempts:     2,
+		RetryBaseDelayMs:  1,
+	})
+	chatConfig := openai.DefaultConfig("key")
+	chatConfig.BaseURL = cerebras.URL
+	client.chatClient = openai.NewClientWithConfig(chatConfig)
+
+	resp, err := client.Chat(context.Background(), "what did I do?", nil)
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if resp.Backend != BackendLocal || !resp.Degraded {
+		t.Errorf("response = %+v, want a degraded local answer", resp)
+	}
+}
+
+func TestChat_FallsBackWhenCerebrasTimesOut(t *testing.T) {
+	hung := make(chan struct{})
+	cerebras := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-hung
+	}))
+	defer cerebras.Close()
+	defer close(hung)
+	local := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":"local answer"}}]}`)
+	}))
+	defer local.Close()
+
+	client := NewClient(&config.LLMConfig{
+		BaseURL:           local.URL,
+		Model:             "local-model",
+		TimeoutSeconds:    1,
+		CerebrasAPIKey:    "key",
+		CerebrasModel:     "cerebras-model",
+		ChatFallbackLocal: true,
+	})
+	chatConfig := openai.DefaultConfig("key")
+	chatConfig.BaseURL = cerebras.URL
+	client.chatClient = openai.NewClientWithConfig(chatConfig)
+
+	resp, err := client.Chat(context.Background(), "what did I do?", nil)
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if resp.Content != "local answer" || resp.Backend != BackendLocal {
+		t.Errorf("response = %+v, want the local answer", resp)
+	}
+}
+
+func TestIsQuotaError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&openai.APIError{HTTPStatusCode: http.StatusPaymentRequired}, true},
+		{&openai.APIError{HTTPStatusCode: http.StatusTooManyRequests, Message: "Quota exceeded"}, true},
+		{&openai.APIError{HTTPStatusCode: http.StatusTooManyRequests, Message: "Too many requests, slow down"}, false},
+		{&openai.RequestError{HTTPStatusCode: http.StatusTooManyRequests, Body: []byte("billing hard limit reached")}, true},
+		{&openai.APIError{HTTPStatusCode: http.StatusInternalServerError, Message: "quota service down"}, false},
+		{fmt.Errorf("dial tcp: connection refused"), false},
+	}
+	for _, tt := range tests {
+		if got := isQuotaError(fmt.Errorf("wrapped: %w", tt.err)); got != tt.want {
+			t.Errorf("isQuotaError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyzeScreen_ImageDataURL(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"jpeg", []byte{0xff, 0xd8, 0xff, 0xe0}, "data:image/jpeg;base64,"},
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00"), "data:image/png;base64,"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				raw, _ := io.ReadAll(r.Body)
+				body = string(raw)
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":"{\"summary\":\"ok\",\"context\":\"work\"}"}}]}`)
+			}))
+			defer server.Close()
+
+			client := NewClient(&config.LLMConfig{BaseURL: server.URL, Model: "test-model", TimeoutSeconds: 5})
+			if _, err := client.AnalyzeScreen(context.Background(), tt.data, ""); err != nil {
+				t.Fatalf("AnalyzeScreen failed: %v", err)
+			}
+			if !strings.Contains(body, tt.want) {
+				t.Errorf("request doesn't contain %q", tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyzeScreen_RetriesTransientErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		wantErr  bool
+		wantHits int
+	}{
+		{"recovers from 503 and 429", []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}, false, 3},
+		{"gives up after the attempt budget", []int{500, 502, 503, 504}, true, 3},
+		{"does not retry a bad request", []int{http.StatusBadRequest}, true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				hits++
+				w.Header().Set("Content-Type", "application/json")
+				if hits <= len(tt.statuses) {
+					w.WriteHeader(tt.statuses[hits-1])
+					fmt.Fprint(w, `{"error":{"message":"model is loading"}}`)
+					return
+				}
+				fmt.Fprint(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":"{\"summary\":\"ok\",\"context\":\"work\"}"}}]}`)
+			}))
+			defer server.Close()
+
+			client := NewClient(&config.LLMConfig{
+				BaseURL:          server.URL,
+				Model:            "test-model",
+				TimeoutSeconds:   5,
+				RetryAttempts:    3,
+				RetryBaseDelayMs: 1,
+			})
+			_, err := client.AnalyzeScreen(context.Background(), []byte{0xff, 0xd8}, "")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("AnalyzeScreen error = %v, want error %v", err, tt.wantErr)
+			}
+			if hits != tt.wantHits {
+				t.Errorf("server hit %d times, want %d", hits, tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestWithRetry_RespectsDeadline(t *testing.T) {
+	client := NewClient(&config.LLMConfig{RetryAttempts: 5, RetryBaseDelayMs: 1000})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	calls := 0
+	start := time.Now()
+	err := client.withRetry(ctx, func() error {
+		calls++
+		return fmt.Errorf("dial tcp: connection refused")
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("withRetry made %d calls (err %v), want 1 failed call", calls, err)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("withRetry waited %v past a deadline it couldn't meet", elapsed)
+	}
+
+	if isRetryable(context.Canceled) || isRetryable(fmt.Errorf("wrapped: %w", context.DeadlineExceeded)) {
+		t.Error("context errors are retryable")
+	}
+}
+
+func TestGenerateResponseStream(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{"You ", "coded."} {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":%q}}]}\n\n", chunk)
+		}
+		w.(http.Flusher).Flush()
+		switch r.URL.Query().Get("mode") {
+		case "broken":
+			fmt.Fprint(w, "data: {not json\n\n")
+		case "hang":
+			select {
+			case <-r.Context().Done():
+			case <-release:
+			}
+			return
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+	defer close(release)
+
+	collect := func(ctx context.Context, mode string, cancelAfter int) (string, error) {
+		chatConfig := openai.DefaultConfig("")
+		chatConfig.BaseURL = server.URL
+		chatConfig.HTTPClient = &http.Client{Transport: queryTransport{mode}}
+		client := NewClient(&config.LLMConfig{Model: "test-model", TimeoutSeconds: 5})
+		client.chatClient = openai.NewClientWithConfig(chatConfig)
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		chunks, err := client.GenerateResponseStream(ctx, "what did I do?", []string{"coding"})
+		if err != nil {
+			return "", err
+		}
+		var text string
+		var streamErr error
+		n := 0
+		for chunk := range chunks {
+			if chunk.Err != nil {
+				streamErr = chunk.Err
+				continue
+			}
+			text += chunk.Text
+			if n++; n == cancelAfter {
+				cancel()
+			}
+		}
+		return text, streamErr
+	}
+
+	if text, err := collect(context.Background(), "", 0); err != nil || text != "You coded." {
+		t.Errorf("stream = %q, %v; want full text", text, err)
+	}
+	if text, err := collect(context.Background(), "broken", 0); err == nil || text != "You coded." {
+		t.Errorf("broken stream = %q, %v; want the text so far and an error", text, err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := collect(context.Background(), "hang", 2); err != nil {
+			t.Errorf("cancelled stream surfaced %v, want a clean close", err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream channel not closed after cancellation")
+	}
+}
+
+func TestGenerateResponseStream_FallsBackLocallyWhenCerebrasIsDown(t *testing.T) {
+	var cerebrasCalls int
+	cerebras := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cerebrasCalls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer cerebras.Close()
+
+	local := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{"Local ", "answer."} {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":%q}}]}\n\n", chunk)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer local.Close()
+
+	client := NewClient(&config.LLMConfig{
+		BaseURL:           local.URL,
+		Model:             "local-model",
+		TimeoutSeconds:    5,
+		CerebrasAPIKey:    "key",
+		CerebrasModel:     "cerebras-model",
+		ChatFallbackLocal: true,
+		RetryAttempts:     2,
+		RetryBaseDelayMs:  1,
+	})
+	chatConfig := openai.DefaultConfig("key")
+	chatConfig.BaseURL = cerebras.URL
+	client.chatClient = openai.NewClientWithConfig(chatConfig)
+
+	chunks, err := client.GenerateResponseStream(context.Background(), "what did I do?", nil)
+	if err != nil {
+		t.Fatalf("GenerateResponseStream failed: %v", err)
+	}
+	var got []Chunk
+	for chunk := range chunks {
+		got = append(got, chunk)
+	}
+	if len(got) != 2 || got[0].Text+got[1].Text != "Local answer." || got[0].Err != nil || got[1].Err != nil {
+		t.Fatalf("chunks = %+v, want the local model's answer", got)
+	}
+	if got[0].Backend != BackendLocal || !got[0].Degraded {
+		t.Errorf("first chunk = %+v, want it to name the degraded local backend", got[0])
+	}
+	if cerebrasCalls != 2 {
+		t.Errorf("cerebras called %d times, want the stream retried before falling back", cerebrasCalls)
+	}
+}
+
+func TestStreamChat_FallsBackOnQuotaError(t *testing.T) {
+	cerebras := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusPaymentRequired)
+		fmt.Fprint(w, `{"error":{"message":"Payment required","type":"billing"}}`)
+	}))
+	defer cerebras.Close()
+
+	local := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"Quiet day.\"}}]}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer local.Close()
+
+	newClient := func(fallback bool) *Client {
+		client := NewClient(&config.LLMConfig{
+			BaseURL:         local.URL,
+			Model:           "local-model",
+			TimeoutSeconds:  5,
+			CerebrasAPIKey:  "key",
+			CerebrasModel:   "cerebras-model",
+			FallbackOnQuota: fallback,
+		})
+		chatConfig := openai.DefaultConfig("key")
+		chatConfig.BaseURL = cerebras.URL
+		client.chatClient = openai.NewClientWithConfig(chatConfig)
+		return client
+	}
+
+	full, err := newClient(true).StreamChat(context.Background(), "system", "user", nil)
+	if err != nil || full != "Quiet day." {
+		t.Errorf("StreamChat = %q, %v; want the local model's answer", full, err)
+	}
+	if _, err := newClient(false).StreamChat(context.Background(), "system", "user", nil); err == nil {
+		t.Error("StreamChat succeeded with fallback disabled")
+	}
+}
+
+// queryTransport adds a mode query parameter to every request
+type queryTransport struct{ mode string }
+
+func (q queryTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	values := r.URL.Query()
+	values.Set("mode", q.mode)
+	r.URL.RawQuery = values.Encode()
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestEmbed_OrdersByIndex(t *testing.T) {
+	var model string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		model = req.Model
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(&config.LLMConfig{BaseURL: server.URL, TimeoutSeconds: 5, EmbeddingModel: "embed-model"})
+	vectors, err := client.Embed(context.Background(), []string{"first", "second"})
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if model != "embed-model" {
+		t.Errorf("model = %q, want %q", model, "embed-model")
+	}
+	if len(vectors) != 2 || vectors[0][0] != 1 || vectors[1][1] != 1 {
+		t.Errorf("vectors = %v, want them in input order", vectors)
+	}
+
+	if _, err := NewClient(&config.LLMConfig{BaseURL: server.URL}).Embed(context.Background(), []string{"x"}); err == nil {
+		t.Error("Embed without an embedding model should fail")
+	}
+}
+
+func TestChat_ClassifiesErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		header     string
+		body       string
+		wantKind   error
+		wantText   string
+		retryAfter time.Duration
+	}{
+		{"bad key", http.StatusUnauthorized, "", `{"error":{"message":"Wrong API Key","type":"invalid_request_error"}}`,
+			ErrAuth, "Check your Cerebras API key", 0},
+		{"rate limited", http.StatusTooManyRequests, "20", `{"error":{"message":"Too many requests","type":"rate_limit"}}`,
+			ErrRateLimited, "Try again in 20s", 20 * time.Second},
+		{"unknown model", http.StatusNotFound, "", `{"error":{"message":"Model does not exist","code":"model_not_found"}}`,
+			ErrModelNotFound, `no model "cerebras-model"`, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.header != "" {
+					w.Header().Set("Retry-After", tt.header)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			client := NewClient(&config.LLMConfig{BaseURL: server.URL, Model: "local-model", TimeoutSeconds: 5,
+				CerebrasAPIKey: "key", CerebrasModel: "cerebras-model"})
+			chatConfig := openai.DefaultConfig("key")
+			chatConfig.BaseURL = server.URL
+			chatConfig.HTTPClient = &http.Client{Transport: retryAfterTransport{http.DefaultTransport}}
+			client.chatClient = openai.NewClientWithConfig(chatConfig)
+
+			_, err := client.Chat(context.Background(), "hi", nil)
+			if !errors.Is(err, tt.wantKind) {
+				t.Fatalf("err = %v, want %v", err, tt.wantKind)
+			}
+			if !strings.Contains(err.Error(), tt.wantText) {
+				t.Errorf("message = %q, want it to contain %q", err.Error(), tt.wantText)
+			}
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.RetryAfter != tt.retryAfter {
+				t.Errorf("APIError = %+v, want retry after %s", apiErr, tt.retryAfter)
+			}
+		})
+	}
+
+	// Timeouts and unclassified failures
+	if err := classifyError(fmt.Errorf("post: %w", context.DeadlineExceeded), providerLocal, "m", 0); !errors.Is(err, ErrTimeout) ||
+		!errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("deadline exceeded classified as %v, want ErrTimeout wrapping it", err)
+	}
+	serverErr := &openai.APIError{HTTPStatusCode: http.StatusInternalServerError, Message: "boom"}
+	if err := classifyError(serverErr, providerCerebras, "m", 0); !strings.HasPrefix(err.Error(), "LLM API error") {
+		t.Errorf("500 classified as %q, want a plain LLM API error", err)
+	}
+
+	now := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	if got := parseRetryAfter(now.Add(90*time.Second).Format(http.TimeFormat), now); got != 90*time.Second {
+		t.Errorf("parseRetryAfter(date) = %s, want 1m30s", got)
+	}
+}
@@ -0,0 +1,49 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Embed returns an embedding per text from LLM.EmbeddingModel on the
+// vision endpoint, in the order of texts
+func (c *Client) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if c.config.EmbeddingModel == "" {
+		return nil, errors.New("no embedding model configured")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(c.config.TimeoutSeconds)*time.Second)
+	defer cancel()
+
+	req := openai.EmbeddingRequest{
+		Input: texts,
+		Model: openai.EmbeddingModel(c.config.EmbeddingModel),
+	}
+
+	var resp openai.EmbeddingResponse
+	err := c.withRetry(ctx, func() (err error) {
+		resp, err = c.visionClient.CreateEmbeddings(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("embeddings API error: %w", err)
+	}
+
+	embeddings := make([][]float32, len(texts))
+	for _, d := range resp.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding index %d out of range", d.Index)
+		}
+		embeddings[d.Index] = d.Embedding
+	}
+	for i, e := range embeddings {
+		if e == nil {
+			return nil, fmt.Errorf("no embedding returned for input %d", i)
+		}
+	}
+	return embeddings, nil
+}
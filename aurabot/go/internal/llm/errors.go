@@ -0,0 +1,173 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Kinds of LLM failure, matched with errors.Is on the errors the client
+// returns
+var (
+	ErrAuth          = errors.New("LLM authentication failed")
+	ErrRateLimited   = errors.New("LLM rate limited")
+	ErrModelNotFound = errors.New("LLM model not found")
+	ErrTimeout       = errors.New("LLM request timed out")
+)
+
+// Providers named in error messages
+const (
+	providerCerebras = "Cerebras"
+	providerLocal    = "LM Studio"
+)
+
+// APIError is a classified LLM failure. Its message says what to do
+// about it; Err holds the provider's response.
+type APIError struct {
+	// Kind is ErrAuth, ErrRateLimited, ErrModelNotFound or ErrTimeout
+	Kind     error
+	Provider string
+	Model    string
+	// RetryAfter is how long the provider asked to wait when rate
+	// limited; 0 if it didn't say
+	RetryAfter time.Duration
+	// Quota is set when the rate limit is an exhausted quota or billing
+	// problem rather than a burst
+	Quota bool
+	Err   error
+}
+
+func (e *APIError) Error() string {
+	switch e.Kind {
+	case ErrAuth:
+		if e.Provider == providerCerebras {
+			return "Cerebras rejected the API key. Check your Cerebras API key (llm.cerebras_api_key or CEREBRAS_API_KEY)."
+		}
+		return fmt.Sprintf("%s rejected the request as unauthorized. Check its API key settings.", e.Provider)
+	case ErrRateLimited:
+		if e.Quota {
+			return fmt.Sprintf("%s quota is exhausted. Check your plan and billing, or enable llm.fallback_on_quota.", e.Provider)
+		}
+		if e.RetryAfter > 0 {
+			return fmt.Sprintf("%s is rate limiting requests. Try again in %s.", e.Provider, e.RetryAfter.Round(time.Second))
+		}
+		return fmt.Sprintf("%s is rate limiting requests. Try again shortly.", e.Provider)
+	case ErrModelNotFound:
+		setting := "llm.model"
+		if e.Provider == providerCerebras {
+			setting = "llm.cerebras_model"
+		}
+		return fmt.Sprintf("%s has no model %q. Check %s.", e.Provider, e.Model, setting)
+	case ErrTimeout:
+		return fmt.Sprintf("%s didn't answer in time. Try again, or raise llm.timeout_seconds.", e.Provider)
+	}
+	return fmt.Sprintf("LLM API error: %v", e.Err)
+}
+
+// Unwrap exposes both the kind and the underlying error to errors.Is/As
+func (e *APIError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// classifyError turns a failed API call into an *APIError when its cause
+// is one users can act on, and into a plain "LLM API error" otherwise
+func classifyError(err error, provider, model string, retryAfter time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	apiErr := &APIError{Provider: provider, Model: model, Err: err}
+
+	var status int
+	var code string
+	var oaErr *openai.APIError
+	var reqErr *openai.RequestError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &oaErr):
+		status = oaErr.HTTPStatusCode
+		code, _ = oaErr.Code.(string)
+	case errors.As(err, &reqErr):
+		status = reqErr.HTTPStatusCode
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		apiErr.Kind = ErrTimeout
+		return apiErr
+	}
+
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		apiErr.Kind = ErrAuth
+	case status == http.StatusTooManyRequests || status == http.StatusPaymentRequired:
+		apiErr.Kind = ErrRateLimited
+		apiErr.RetryAfter = retryAfter
+		apiErr.Quota = isQuotaError(err)
+	case status == http.StatusNotFound || code == "model_not_found":
+		apiErr.Kind = ErrModelNotFound
+	case status == http.StatusRequestTimeout || status == http.StatusGatewayTimeout:
+		apiErr.Kind = ErrTimeout
+	default:
+		return fmt.Errorf("LLM API error: %w", err)
+	}
+	return apiErr
+}
+
+// retryAfterKey carries a *retryAfter through a request's context
+type retryAfterKey struct{}
+
+// retryAfter records the Retry-After of the last rate-limited response
+// to a call, which go-openai's errors don't expose
+type retryAfter struct {
+	nanos atomic.Int64
+}
+
+// withRetryAfter returns a context whose rate-limited responses record
+// their Retry-After in the returned holder
+func withRetryAfter(ctx context.Context) (context.Context, *retryAfter) {
+	r := &retryAfter{}
+	return context.WithValue(ctx, retryAfterKey{}, r), r
+}
+
+// get returns the recorded delay, or 0
+func (r *retryAfter) get() time.Duration {
+	return time.Duration(r.nanos.Load())
+}
+
+// retryAfterTransport records Retry-After headers of 429 responses for
+// requests made with withRetryAfter
+type retryAfterTransport struct {
+	base http.RoundTripper
+}
+
+func (t retryAfterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+		return resp, err
+	}
+	if holder, ok := req.Context().Value(retryAfterKey{}).(*retryAfter); ok {
+		holder.nanos.Store(int64(parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())))
+	}
+	return resp, err
+}
+
+// parseRetryAfter reads a Retry-After header in seconds or as an HTTP
+// date; 0 if it is missing or unreadable
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,99 @@
+package llm
+
+import (
+	"sort"
+	"strings"
+)
+
+// analysisFields are the keys parseResponse reads from the model's JSON
+var analysisFields = []string{"summary", "context", "activities", "key_elements", "user_intent", "ocr_text", "confidence"}
+
+// defaultFieldAliases maps other names models use for the analysis fields,
+// in folded form (see foldKey)
+var defaultFieldAliases = map[string]string{
+	"description": "summary",
+	"activity":    "activities",
+	"elements":    "key_elements",
+	"uielements":  "key_elements",
+	"intent":      "user_intent",
+	"goal":        "user_intent",
+	"ocr":         "ocr_text",
+	"screentext":  "ocr_text",
+}
+
+// foldKey lowercases a key and drops separators, so "userIntent",
+// "UserIntent" and "user-intent" all match "user_intent"
+func foldKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '_', '-', ' ', '.':
+			return -1
+		}
+		return r
+	}, strings.ToLower(key))
+}
+
+// normalizeFields renames the keys of a model's JSON to the analysis
+// field names, tolerating case variants and aliases (the defaults plus
+// configured ones, which win). Keys that don't map to a field, or map to
+// one already taken, are returned as extras rather than dropped. An
+// exact key wins over a variant of it.
+func normalizeFields(raw map[string]interface{}, aliases map[string]string) (fields, extras map[string]interface{}) {
+	lookup := make(map[string]string, len(analysisFields)+len(defaultFieldAliases)+len(aliases))
+	for _, field := range analysisFields {
+		lookup[foldKey(field)] = field
+	}
+	for alias, field := range defaultFieldAliases {
+		lookup[alias] = field
+	}
+	for alias, field := range aliases {
+		lookup[foldKey(alias)] = field
+	}
+
+	// Exact keys first, then the rest in a stable order
+	keys := make([]string, 0, len(raw))
+	for key := range raw {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ei, ej := lookup[foldKey(keys[i])] == keys[i], lookup[foldKey(keys[j])] == keys[j]
+		if ei != ej {
+			return ei
+		}
+		return keys[i] < keys[j]
+	})
+
+	fields = make(map[string]interface{})
+	for _, key := range keys {
+		field, ok := lookup[foldKey(key)]
+		if _, taken := fields[field]; ok && !taken {
+			fields[field] = raw[key]
+			continue
+		}
+		if extras == nil {
+			extras = make(map[string]interface{})
+		}
+		extras[key] = raw[key]
+	}
+	return fields, extras
+}
+
+// stringList reads a JSON array of strings, or a single string as a
+// one-element list
+func stringList(v interface{}) []string {
+	switch val := v.(type) {
+	case string:
+		if val = strings.TrimSpace(val); val != "" {
+			return []string{val}
+		}
+	case []interface{}:
+		var list []string
+		for _, item := range val {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"screen-memory-assistant/internal/config"
+)
+
+// VisionSchema is the list of fields the vision model is asked to return
+type VisionSchema []config.VisionFieldConfig
+
+// Built-in schemas for the analysis modes, used when LLM.VisionSchema is empty
+var (
+	fullVisionSchema = VisionSchema{
+		{Name: "summary", Description: "brief description"},
+		{Name: "context", Description: "work/entertainment/social/etc"},
+		{Name: "activities", Description: "activity", List: true},
+		{Name: "key_elements", Description: "UI element", List: true},
+		{Name: "user_intent", Description: "what user is trying to accomplish"},
+	}
+	compactVisionSchema = VisionSchema{
+		{Name: "summary", Description: "brief description"},
+		{Name: "context", Description: "work/entertainment/social/etc"},
+	}
+)
+
+// defaultPromptTemplate is used with a custom schema but no template
+const defaultPromptTemplate = `You are a personal AI assistant observing the user's screen. Analyze what you see and provide:
+{{range $i, $f := .Fields}}{{inc $i}}. {{$f.Name}}: {{$f.Description}}
+{{end}}
+Respond in this exact JSON format:
+{{.Schema}}`
+
+// promptData is what a prompt template can refer to: .Schema is the JSON
+// example and .Fields the fields it lists
+type promptData struct {
+	Schema string
+	Fields VisionSchema
+}
+
+var promptFuncs = template.FuncMap{"inc": func(i int) int { return i + 1 }}
+
+// ParsePromptTemplate parses an analysis prompt template
+func ParsePromptTemplate(text string) (*template.Template, error) {
+	return template.New("prompt").Funcs(promptFuncs).Parse(text)
+}
+
+// JSON renders the schema as the example object the model should return
+func (s VisionSchema) JSON() string {
+	var b strings.Builder
+	b.WriteString("{\n")
+	for i, f := range s {
+		name, _ := json.Marshal(f.Name)
+		desc, _ := json.Marshal(f.Description)
+		if f.List {
+			fmt.Fprintf(&b, "  %s: [%s]", name, desc)
+		} else {
+			fmt.Fprintf(&b, "  %s: %s", name, desc)
+		}
+		if i < len(s)-1 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// analysisPrompt builds the vision system prompt. Without a configured
+// template or schema it is the built-in prompt for the analysis mode.
+func (c *Client) analysisPrompt() (string, error) {
+	schema := VisionSchema(c.config.VisionSchema)
+	text := c.config.PromptTemplate
+
+	var prompt string
+	switch {
+	case text == "" && len(schema) == 0 && c.analysisMode == AnalysisModeMinimal:
+		prompt = compactAnalysisPrompt
+	case text == "" && len(schema) == 0:
+		prompt = fullAnalysisPrompt
+	default:
+		if len(schema) == 0 {
+			schema = fullVisionSchema
+			if c.analysisMode == AnalysisModeMinimal {
+				schema = compactVisionSchema
+			}
+		}
+		if text == "" {
+			text = defaultPromptTemplate
+		}
+		tmpl, err := ParsePromptTemplate(text)
+		if err != nil {
+			return "", fmt.Errorf("parsing prompt template: %w", err)
+		}
+		var b strings.Builder
+		if err := tmpl.Execute(&b, promptData{Schema: schema.JSON(), Fields: schema}); err != nil {
+			return "", fmt.Errorf("rendering prompt template: %w", err)
+		}
+		prompt = b.String()
+	}
+
+	if c.askConfidence {
+		prompt += confidenceInstruction
+	}
+	return prompt, nil
+}
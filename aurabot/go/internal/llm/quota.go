@@ -0,0 +1,47 @@
+package llm
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// quotaMarkers identify a rate limit caused by an exhausted quota or
+// billing problem rather than a short burst
+var quotaMarkers = []string{"quota", "billing", "insufficient", "credit", "payment"}
+
+// isQuotaError reports whether err is a 402, or a 429 whose body names a
+// quota or billing problem
+func isQuotaError(err error) bool {
+	var status int
+	var detail string
+
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	switch {
+	case errors.As(err, &apiErr):
+		status = apiErr.HTTPStatusCode
+		code, _ := apiErr.Code.(string)
+		detail = strings.Join([]string{apiErr.Message, apiErr.Type, code}, " ")
+	case errors.As(err, &reqErr):
+		status = reqErr.HTTPStatusCode
+		detail = string(reqErr.Body)
+	default:
+		return false
+	}
+
+	switch status {
+	case http.StatusPaymentRequired:
+		return true
+	case http.StatusTooManyRequests:
+		detail = strings.ToLower(detail)
+		for _, marker := range quotaMarkers {
+			if strings.Contains(detail, marker) {
+				return true
+			}
+		}
+	}
+	return false
+}
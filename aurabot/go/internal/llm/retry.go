@@ -0,0 +1,74 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// defaultRetryBaseDelay is the first retry delay when none is configured
+const defaultRetryBaseDelay = 500 * time.Millisecond
+
+// isRetryable reports whether a failed API call may succeed when repeated:
+// network errors, 5xx and short-burst 429s are; cancellation, quota
+// exhaustion and other 4xx are not
+func isRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var status int
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	switch {
+	case errors.As(err, &apiErr):
+		status = apiErr.HTTPStatusCode
+	case errors.As(err, &reqErr):
+		status = reqErr.HTTPStatusCode
+	default:
+		// No response at all, e.g. LM Studio is still loading the model
+		return true
+	}
+
+	if status == http.StatusTooManyRequests {
+		return !isQuotaError(err)
+	}
+	return status >= 500
+}
+
+// withRetry runs call up to RetryAttempts times, doubling the delay from
+// RetryBaseDelayMs between attempts. It gives up early when ctx is done
+// or its deadline would pass before the next attempt.
+func (c *Client) withRetry(ctx context.Context, call func() error) error {
+	attempts := c.config.RetryAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := time.Duration(c.config.RetryBaseDelayMs) * time.Millisecond
+	if delay <= 0 {
+		delay = defaultRetryBaseDelay
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = call(); err == nil || attempt == attempts || !isRetryable(err) {
+			return err
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return err
+		}
+
+		c.logger.Warn("LLM call failed, retrying", "attempt", attempt, "attempts", attempts, "error", err, "delay", delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
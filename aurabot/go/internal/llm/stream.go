@@ -0,0 +1,138 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// StreamChat sends a chat completion and calls onChunk with each piece of
+// text as it arrives. It returns the accumulated text; when ctx is
+// cancelled mid-stream the partial text is returned with ctx's error.
+// Opening the stream falls back like Chat.
+func (c *Client) StreamChat(ctx context.Context, systemPrompt, userPrompt string, onChunk func(string)) (string, error) {
+	_, full, err := c.streamChat(ctx, systemPrompt, userPrompt, nil, func(chunk string) bool {
+		if onChunk != nil {
+			onChunk(chunk)
+		}
+		return true
+	})
+	if err != nil {
+		return full, err
+	}
+	if full == "" {
+		return "", fmt.Errorf("no response from LLM")
+	}
+	return full, nil
+}
+
+// streamChat opens a chat stream through openChat, calls opened with the
+// backend serving it, then calls onChunk with each piece of text until
+// the stream ends or onChunk returns false. It returns the backend and
+// the text read; a failure to open returns no backend.
+func (c *Client) streamChat(ctx context.Context, systemPrompt, userPrompt string, opened func(*ChatResponse), onChunk func(string) bool) (*ChatResponse, string, error) {
+	req := c.chatRequest(systemPrompt, userPrompt)
+	req.Stream = true
+
+	var stream *openai.ChatCompletionStream
+	chat, release, err := c.openChat(ctx, req, func(ctx context.Context, client *openai.Client, req openai.ChatCompletionRequest) (err error) {
+		stream, err = client.CreateChatCompletionStream(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	defer release()
+	defer stream.Close()
+	if opened != nil {
+		opened(chat)
+	}
+
+	var full strings.Builder
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return chat, full.String(), ctx.Err()
+			}
+			return chat, full.String(), fmt.Errorf("reading stream: %w", err)
+		}
+		if len(resp.Choices) == 0 || resp.Choices[0].Delta.Content == "" {
+			continue
+		}
+
+		chunk := resp.Choices[0].Delta.Content
+		full.WriteString(chunk)
+		if !onChunk(chunk) {
+			break
+		}
+	}
+	return chat, full.String(), nil
+}
+
+// Chunk is a piece of a streamed response. The first chunk names the
+// backend answering, as in ChatResponse; the last chunk of a failed
+// stream carries Err and no text.
+type Chunk struct {
+	Text     string
+	Backend  string
+	Degraded bool
+	Err      error
+}
+
+// GenerateResponseStream answers prompt like GenerateResponse, sending the
+// text on the returned channel as it is generated. The channel is closed
+// when the answer is complete, after a chunk carrying a stream error, or
+// when ctx is cancelled (check ctx.Err()). An error opening the stream,
+// after any fallback, is returned directly.
+func (c *Client) GenerateResponseStream(ctx context.Context, prompt string, memories []string) (<-chan Chunk, error) {
+	systemPrompt, userPrompt := chatPrompts(prompt, memories)
+	c.logPrompt(systemPrompt, userPrompt)
+
+	chunks := make(chan Chunk)
+	opened := make(chan error, 1)
+	go func() {
+		defer close(chunks)
+
+		send := func(chunk Chunk) bool {
+			select {
+			case chunks <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		var first *ChatResponse
+		chat, _, err := c.streamChat(ctx, systemPrompt, userPrompt, func(chat *ChatResponse) {
+			first = chat
+			opened <- nil
+		}, func(text string) bool {
+			chunk := Chunk{Text: text}
+			if first != nil {
+				chunk.Backend, chunk.Degraded = first.Backend, first.Degraded
+				first = nil
+			}
+			return send(chunk)
+		})
+		if chat == nil {
+			opened <- err
+			return
+		}
+		if err != nil && ctx.Err() == nil {
+			send(Chunk{Err: err})
+		}
+	}()
+
+	if err := <-opened; err != nil {
+		return nil, err
+	}
+	return chunks, nil
+}
@@ -0,0 +1,44 @@
+// Package logging builds the leveled, structured logger shared by the
+// service, the enhancer and the memory store.
+package logging
+
+import (
+	"io"
+	"log/slog"
+
+	"screen-memory-assistant/internal/config"
+)
+
+// Log formats, as used in App.LogFormat
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Level returns the configured level. Without App.LogLevel, Verbose
+// selects debug and otherwise info, matching what it used to print.
+func Level(cfg *config.AppConfig) slog.Level {
+	switch cfg.LogLevel {
+	case "debug":
+		return slog.LevelDebug
+	case "info":
+		return slog.LevelInfo
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	}
+	if cfg.Verbose {
+		return slog.LevelDebug
+	}
+	return slog.LevelInfo
+}
+
+// New returns a logger writing to w at the configured level and format
+func New(w io.Writer, cfg *config.AppConfig) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: Level(cfg)}
+	if cfg.LogFormat == FormatJSON {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
@@ -0,0 +1,47 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"screen-memory-assistant/internal/config"
+)
+
+func TestLevel(t *testing.T) {
+	tests := []struct {
+		cfg  config.AppConfig
+		want slog.Level
+	}{
+		{config.AppConfig{}, slog.LevelInfo},
+		{config.AppConfig{Verbose: true}, slog.LevelDebug},
+		{config.AppConfig{Verbose: true, LogLevel: "warn"}, slog.LevelWarn},
+		{config.AppConfig{LogLevel: "error"}, slog.LevelError},
+	}
+	for _, tt := range tests {
+		if got := Level(&tt.cfg); got != tt.want {
+			t.Errorf("Level(%+v) = %v, want %v", tt.cfg, got, tt.want)
+		}
+	}
+}
+
+func TestNew_JSONHidesDebug(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, &config.AppConfig{LogFormat: FormatJSON})
+
+	logger.Debug("memory search response", "body", "private screen contents")
+	logger.Info("memory stored", "id", "m1")
+
+	if strings.Contains(buf.String(), "private screen contents") {
+		t.Errorf("debug line written at info level: %s", buf.String())
+	}
+	var line map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("output is not one JSON object: %v: %s", err, buf.String())
+	}
+	if line["msg"] != "memory stored" || line["level"] != "INFO" || line["id"] != "m1" {
+		t.Errorf("line = %v", line)
+	}
+}
@@ -0,0 +1,35 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrAdminRequired is returned by SearchAllUsers on a store without admin rights
+var ErrAdminRequired = errors.New("searching all users requires an admin store")
+
+// SetAdmin allows this store to search across users. Only stores behind
+// an authenticated admin path should have it.
+func (s *Store) SetAdmin(enabled bool) {
+	s.admin = enabled
+}
+
+// SearchAllUsers retrieves relevant memories of every user in the
+// configured collections; each result's Memory.UserID names its owner
+func (s *Store) SearchAllUsers(query string, limit int) ([]SearchResult, error) {
+	if !s.admin {
+		return nil, ErrAdminRequired
+	}
+	// A Supermemory container tag always includes the user
+	if s.backend.Name() != BackendMem0 {
+		return nil, fmt.Errorf("searching all users is not supported by the %s backend", s.backend.Name())
+	}
+	return s.search(query, limit, 0, nil, true)
+}
+
+// searchScope returns the scope a search of collection runs in
+func (s *Store) searchScope(collection string, allUsers bool) Scope {
+	scope := s.scope(collection)
+	scope.AllUsers = allUsers
+	return scope
+}
@@ -0,0 +1,68 @@
+package memory
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"screen-memory-assistant/internal/config"
+)
+
+func TestSearchAllUsers_DropsUserScope(t *testing.T) {
+	var payloads []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		payloads = append(payloads, payload)
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": []map[string]interface{}{
+			{"id": "m1", "memory": "Roadmap review", "user_id": "alice", "score": 0.9},
+			{"id": "m2", "memory": "Roadmap draft", "user_id": "bob", "score": 0.8},
+		}})
+	}))
+	defer server.Close()
+
+	store := NewStore(&config.MemoryConfig{BaseURL: server.URL, UserID: "alice", CollectionName: "team"})
+
+	if _, err := store.SearchAllUsers("roadmap", 5); !errors.Is(err, ErrAdminRequired) {
+		t.Fatalf("SearchAllUsers without admin = %v, want ErrAdminRequired", err)
+	}
+	if len(payloads) != 0 {
+		t.Fatal("non-admin SearchAllUsers reached the backend")
+	}
+
+	if _, err := store.Search("roadmap", 5); err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	store.SetAdmin(true)
+	results, err := store.SearchAllUsers("roadmap", 5)
+	if err != nil {
+		t.Fatalf("SearchAllUsers failed: %v", err)
+	}
+	// Admin rights don't widen ordinary searches
+	if _, err := store.Search("roadmap", 5); err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+
+	if len(payloads) != 3 {
+		t.Fatalf("backend saw %d searches, want 3", len(payloads))
+	}
+	for i, wantUser := range []bool{true, false, true} {
+		if _, ok := payloads[i]["user_id"]; ok != wantUser {
+			t.Errorf("search %d: user_id sent = %v, want %v (%v)", i, ok, wantUser, payloads[i])
+		}
+		if payloads[i]["agent_id"] != "team" {
+			t.Errorf("search %d: agent_id = %v, want team", i, payloads[i]["agent_id"])
+		}
+	}
+	if len(results) != 2 || results[0].Memory.UserID != "alice" || results[1].Memory.UserID != "bob" {
+		t.Errorf("results = %+v, want each owner named", results)
+	}
+
+	store = NewStore(&config.MemoryConfig{BaseURL: server.URL, Backend: BackendSupermemory})
+	store.SetAdmin(true)
+	if _, err := store.SearchAllUsers("roadmap", 5); err == nil {
+		t.Error("SearchAllUsers succeeded on a backend that scopes every search by user")
+	}
+}
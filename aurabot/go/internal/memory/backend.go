@@ -0,0 +1,114 @@
+package memory
+
+import (
+	"fmt"
+	"net/url"
+
+	"screen-memory-assistant/internal/config"
+)
+
+// Backend names, as used in config
+const (
+	BackendMem0        = "mem0"
+	BackendSupermemory = "supermemory"
+)
+
+// Scope identifies whose memories an operation touches, independent of
+// how a backend names its scoping fields
+type Scope struct {
+	User       string
+	Collection string
+	// AllUsers leaves the user out of a search; only admin searches set it
+	AllUsers bool
+}
+
+// MemoryBackend translates a logical scope into a backend's wire fields.
+// Adds, searches and listings all go through it so they stay scoped
+// the same way whichever backend is used.
+type MemoryBackend interface {
+	Name() string
+	// ScopeFields returns the JSON fields scoping an add or search
+	ScopeFields(scope Scope) map[string]interface{}
+	// ScopeQuery returns the query parameters scoping a listing
+	ScopeQuery(scope Scope) url.Values
+}
+
+// NewBackend returns the backend with the given name; empty means Mem0
+func NewBackend(name string) (MemoryBackend, error) {
+	switch name {
+	case "", BackendMem0:
+		return mem0Backend{}, nil
+	case BackendSupermemory:
+		return supermemoryBackend{}, nil
+	default:
+		return nil, fmt.Errorf("unknown memory backend %q", name)
+	}
+}
+
+// Open returns a store for the backend named by cfg.Backend. Unlike
+// NewStore, which falls back to Mem0 and only refuses writes when the
+// encryption key can't be derived, both are errors here.
+func Open(cfg *config.MemoryConfig) (*Store, error) {
+	if _, err := NewBackend(cfg.Backend); err != nil {
+		return nil, err
+	}
+	store := NewStore(cfg)
+	if store.cipherErr != nil {
+		return nil, store.cipherErr
+	}
+	return store, nil
+}
+
+// mem0Backend scopes by user_id and agent_id (the collection)
+type mem0Backend struct{}
+
+func (mem0Backend) Name() string { return BackendMem0 }
+
+func (mem0Backend) ScopeFields(scope Scope) map[string]interface{} {
+	if scope.AllUsers {
+		return map[string]interface{}{"agent_id": scope.Collection}
+	}
+	return map[string]interface{}{
+		"user_id":  scope.User,
+		"agent_id": scope.Collection,
+	}
+}
+
+func (mem0Backend) ScopeQuery(scope Scope) url.Values {
+	return url.Values{
+		"user_id":  {scope.User},
+		"agent_id": {scope.Collection},
+	}
+}
+
+// supermemoryBackend scopes by a single container tag combining the user
+// and collection, so switching backends keeps memories separated
+type supermemoryBackend struct{}
+
+func (supermemoryBackend) Name() string { return BackendSupermemory }
+
+func (supermemoryBackend) ScopeFields(scope Scope) map[string]interface{} {
+	return map[string]interface{}{
+		"container_tag": containerTag(scope),
+	}
+}
+
+func (supermemoryBackend) ScopeQuery(scope Scope) url.Values {
+	return url.Values{
+		"container_tag": {containerTag(scope)},
+	}
+}
+
+// containerTag joins the scope into one tag; the collection is left out
+// when empty
+func containerTag(scope Scope) string {
+	if scope.Collection == "" {
+		return scope.User
+	}
+	return scope.User + "_" + scope.Collection
+}
+
+// scope returns the logical scope for collection
+func (s *Store) scope(collection string) Scope {
+	return Scope{User: s.config.UserID, Collection: collection}
+}
@@ -0,0 +1,99 @@
+package memory
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"screen-memory-assistant/internal/config"
+)
+
+func TestBackend_ScopesAddSearchAndListAlike(t *testing.T) {
+	tests := []struct {
+		backend string
+		want    map[string]string
+		absent  []string
+	}{
+		{"", map[string]string{"user_id": "alice", "agent_id": "screen"}, []string{"container_tag"}},
+		{BackendSupermemory, map[string]string{"container_tag": "alice_screen"}, []string{"user_id", "agent_id"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.backend, func(t *testing.T) {
+			var added, searched map[string]interface{}
+			var listed map[string][]string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodGet {
+					listed = r.URL.Query()
+					json.NewEncoder(w).Encode([]Memory{})
+					return
+				}
+				var payload map[string]interface{}
+				json.NewDecoder(r.Body).Decode(&payload)
+				if strings.HasSuffix(r.URL.Path, "/search/") {
+					searched = payload
+					json.NewEncoder(w).Encode(map[string]interface{}{"results": []interface{}{}})
+					return
+				}
+				added = payload
+				w.WriteHeader(http.StatusCreated)
+			}))
+			defer server.Close()
+
+			store := NewStore(&config.MemoryConfig{
+				BaseURL:        server.URL,
+				UserID:         "alice",
+				CollectionName: "screen",
+				Backend:        tt.backend,
+			})
+			if _, err := store.Add("note", Metadata{}); err != nil {
+				t.Fatalf("Add failed: %v", err)
+			}
+			if _, err := store.Search("note", 5); err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+			if _, err := store.GetRecent(5); err != nil {
+				t.Fatalf("GetRecent failed: %v", err)
+			}
+
+			for field, value := range tt.want {
+				if added[field] != value {
+					t.Errorf("add %s = %v, want %q", field, added[field], value)
+				}
+				if searched[field] != value {
+					t.Errorf("search %s = %v, want %q", field, searched[field], value)
+				}
+				if got := listed[field]; len(got) != 1 || got[0] != value {
+					t.Errorf("list %s = %v, want %q", field, got, value)
+				}
+			}
+			for _, field := range tt.absent {
+				if _, ok := added[field]; ok {
+					t.Errorf("add sent %s for backend %q", field, tt.backend)
+				}
+				if _, ok := searched[field]; ok {
+					t.Errorf("search sent %s for backend %q", field, tt.backend)
+				}
+			}
+		})
+	}
+}
+
+func TestNewBackend_Unknown(t *testing.T) {
+	if _, err := NewBackend("pinecone"); err == nil {
+		t.Error("expected an error for an unknown backend")
+	}
+	if _, err := Open(&config.MemoryConfig{Backend: "pinecone"}); err == nil {
+		t.Error("Open accepted an unknown backend")
+	}
+
+	store, err := Open(&config.MemoryConfig{Backend: BackendSupermemory})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if store.backend.Name() != BackendSupermemory {
+		t.Errorf("backend = %s, want %s", store.backend.Name(), BackendSupermemory)
+	}
+}
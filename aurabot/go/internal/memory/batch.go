@@ -0,0 +1,63 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// BatchItem is one memory to store with AddBatch
+type BatchItem struct {
+	Content  string
+	Metadata Metadata
+}
+
+// BatchResult is the outcome of storing one BatchItem
+type BatchResult struct {
+	Memory *Memory
+	Err    error
+}
+
+// AddBatch stores items with at most concurrency requests in flight;
+// concurrency <= 0 uses the configured BatchConcurrency, and 1 stores
+// them one after another. Results are in the same order as items
+// whatever order the requests complete in. The returned error joins the
+// per-item errors, which are also set on the results.
+func (s *Store) AddBatch(items []BatchItem, concurrency int) ([]BatchResult, error) {
+	if concurrency <= 0 {
+		concurrency = s.config.BatchConcurrency
+	}
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	if concurrency > len(items) {
+		concurrency = len(items)
+	}
+
+	results := make([]BatchResult, len(items))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				m, err := s.Add(items[i].Content, items[i].Metadata)
+				results[i] = BatchResult{Memory: m, Err: err}
+			}
+		}()
+	}
+	for i := range items {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var errs []error
+	for i, r := range results {
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("item %d: %w", i, r.Err))
+		}
+	}
+	return results, errors.Join(errs...)
+}
@@ -0,0 +1,66 @@
+package memory
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"screen-memory-assistant/internal/config"
+)
+
+func TestAddBatch_PreservesOrder(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			peak := maxInFlight.Load()
+			if n <= peak || maxInFlight.CompareAndSwap(peak, n) {
+				break
+			}
+		}
+
+		var payload struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		// Earlier items finish last
+		if strings.HasSuffix(payload.Messages[0].Content, "0") {
+			time.Sleep(50 * time.Millisecond)
+		}
+		if payload.Messages[0].Content == "fail 3" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	store := NewStore(&config.MemoryConfig{BaseURL: server.URL})
+	items := []BatchItem{{Content: "item 0"}, {Content: "item 1"}, {Content: "item 2"}, {Content: "fail 3"}}
+
+	results, err := store.AddBatch(items, 3)
+	if err == nil || !strings.Contains(err.Error(), "item 3") {
+		t.Errorf("error = %v, want item 3's failure", err)
+	}
+	if len(results) != len(items) {
+		t.Fatalf("got %d results, want %d", len(results), len(items))
+	}
+	for i, r := range results[:3] {
+		if r.Err != nil || r.Memory == nil || r.Memory.Content != items[i].Content {
+			t.Errorf("result %d = %+v, want %q", i, r, items[i].Content)
+		}
+	}
+	if results[3].Err == nil {
+		t.Error("result 3 should carry its error")
+	}
+	if got := maxInFlight.Load(); got < 2 || got > 3 {
+		t.Errorf("max concurrent requests = %d, want 2-3", got)
+	}
+}
@@ -0,0 +1,121 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// collections returns the collections searched: the primary collection
+// followed by any extra ones, without repeats
+func (s *Store) collections() []string {
+	seen := map[string]bool{s.config.CollectionName: true}
+	collections := []string{s.config.CollectionName}
+	for _, c := range s.config.ExtraCollections {
+		if c.Name == "" || seen[c.Name] {
+			continue
+		}
+		seen[c.Name] = true
+		collections = append(collections, c.Name)
+	}
+	return collections
+}
+
+// endpoint returns the base URL and API key for requests to collection,
+// preferring the collection's own settings over the top-level ones
+func (s *Store) endpoint(collection string) (string, string) {
+	baseURL, apiKey := s.config.BaseURL, s.config.APIKey
+	for _, c := range s.config.ExtraCollections {
+		if c.Name != collection {
+			continue
+		}
+		if c.BaseURL != "" {
+			baseURL = c.BaseURL
+		}
+		if c.APIKey != "" {
+			apiKey = c.APIKey
+		}
+		break
+	}
+	return baseURL, apiKey
+}
+
+// collectionOrPrimary returns collection, or the primary collection when
+// it is empty
+func (s *Store) collectionOrPrimary(collection string) string {
+	if collection == "" {
+		return s.config.CollectionName
+	}
+	return collection
+}
+
+// ErrNegativeOffset is returned by SearchPaged for an offset below 0
+var ErrNegativeOffset = errors.New("offset must not be negative")
+
+// resultKey identifies a memory across collections; IDs are only unique
+// within a collection
+type resultKey struct {
+	collection string
+	id         string
+}
+
+// Search retrieves relevant memories based on query. With extra
+// collections configured, each one is searched and the results are merged
+// by score, keeping one result per (collection, ID).
+func (s *Store) Search(query string, limit int) ([]SearchResult, error) {
+	return s.search(query, limit, 0, nil, false)
+}
+
+// SearchPaged is Search starting offset results down the ranking (0 is
+// the top), for paging through older relevant results
+func (s *Store) SearchPaged(query string, limit, offset int) ([]SearchResult, error) {
+	if offset < 0 {
+		return nil, ErrNegativeOffset
+	}
+	return s.search(query, limit, offset, nil, false)
+}
+
+// search runs Search, passing filters to the backend when non-nil. With
+// allUsers the configured user's scope is dropped.
+func (s *Store) search(query string, limit, offset int, filters map[string]interface{}, allUsers bool) ([]SearchResult, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+
+	collections := s.collections()
+	if len(collections) == 1 {
+		return s.searchCollection(query, s.searchScope(collections[0], allUsers), limit, offset, filters)
+	}
+
+	// Merged rankings can only be paged after merging, so every collection
+	// is searched from the top
+	seen := make(map[resultKey]bool)
+	var merged []SearchResult
+	for _, collection := range collections {
+		results, err := s.searchCollection(query, s.searchScope(collection, allUsers), offset+limit, 0, filters)
+		if err != nil {
+			return nil, fmt.Errorf("searching %s: %w", collection, err)
+		}
+		for _, r := range results {
+			key := resultKey{collection: r.Collection, id: r.Memory.ID}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			merged = append(merged, r)
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Score > merged[j].Score
+	})
+	if offset >= len(merged) {
+		return nil, nil
+	}
+	merged = merged[offset:]
+	if len(merged) > limit {
+		merged = merged[:limit]
+	}
+
+	return merged, nil
+}
@@ -0,0 +1,241 @@
+package memory
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"screen-memory-assistant/internal/config"
+)
+
+func TestSearch_MultipleCollections(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			AgentID string `json:"agent_id"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+
+		// Both collections hold a memory with ID "m1"; "work" repeats it
+		results := map[string][]map[string]interface{}{
+			"screen": {
+				{"id": "m1", "memory": "screen memory", "score": 0.7},
+			},
+			"work": {
+				{"id": "m1", "memory": "work memory", "score": 0.9},
+				{"id": "m1", "memory": "work memory", "score": 0.9},
+			},
+		}[payload.AgentID]
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+	}))
+	defer server.Close()
+
+	store := NewStore(&config.MemoryConfig{
+		BaseURL:          server.URL,
+		CollectionName:   "screen",
+		ExtraCollections: []config.CollectionConfig{{Name: "work"}, {Name: "screen"}},
+	})
+
+	results, err := store.Search("q", 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want one per collection: %+v", len(results), results)
+	}
+	want := []struct{ collection, content string }{
+		{"work", "work memory"},
+		{"screen", "screen memory"},
+	}
+	for i, w := range want {
+		if results[i].Collection != w.collection || results[i].Memory.Content != w.content {
+			t.Errorf("result %d = %s/%q, want %s/%q", i,
+				results[i].Collection, results[i].Memory.Content, w.collection, w.content)
+		}
+	}
+}
+
+func TestSearch_SingleCollectionTagged(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"results": []map[string]interface{}{{"id": "m1", "memory": "a"}},
+		})
+	}))
+	defer server.Close()
+
+	store := NewStore(&config.MemoryConfig{BaseURL: server.URL, CollectionName: "screen"})
+
+	results, err := store.Search("q", 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Collection != "screen" {
+		t.Errorf("results = %+v, want one result tagged 'screen'", results)
+	}
+}
+
+func TestSearch_PerCollectionEndpoint(t *testing.T) {
+	var defaultAuth string
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defaultAuth = r.Header.Get("Authorization")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"results": []map[string]interface{}{{"id": "p1", "memory": "personal", "score": 0.5}},
+		})
+	}))
+	defer primary.Close()
+
+	var workAuth string
+	work := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		workAuth = r.Header.Get("Authorization")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"results": []map[string]interface{}{{"id": "w1", "memory": "work", "score": 0.9}},
+		})
+	}))
+	defer work.Close()
+
+	store := NewStore(&config.MemoryConfig{
+		BaseURL:        primary.URL,
+		APIKey:         "personal-key",
+		CollectionName: "screen",
+		ExtraCollections: []config.CollectionConfig{
+			{Name: "work", BaseURL: work.URL, APIKey: "work-key"},
+		},
+	})
+
+	results, err := store.Search("q", 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 2 || results[0].Collection != "work" || results[1].Collection != "screen" {
+		t.Fatalf("results = %+v, want work then screen", results)
+	}
+	if defaultAuth != "Bearer personal-key" {
+		t.Errorf("primary auth = %q, want top-level key", defaultAuth)
+	}
+	if workAuth != "Bearer work-key" {
+		t.Errorf("work auth = %q, want collection key", workAuth)
+	}
+}
+
+func TestDelete_RoutesByCollection(t *testing.T) {
+	var primaryCalls int
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			primaryCalls++
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": []interface{}{}})
+	}))
+	defer primary.Close()
+
+	var workAuth, workPath string
+	work := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			workAuth, workPath = r.Header.Get("Authorization"), r.URL.Path
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"results": []map[string]interface{}{{"id": "w1", "memory": "work", "score": 0.9}},
+		})
+	}))
+	defer work.Close()
+
+	store := NewStore(&config.MemoryConfig{
+		BaseURL:        primary.URL,
+		APIKey:         "personal-key",
+		CollectionName: "screen",
+		ExtraCollections: []config.CollectionConfig{
+			{Name: "work", BaseURL: work.URL, APIKey: "work-key"},
+		},
+	})
+
+	results, err := store.Search("q", 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	var found *Memory
+	for i := range results {
+		if results[i].Memory.ID == "w1" {
+			found = &results[i].Memory
+		}
+	}
+	if found == nil || found.Collection != "work" {
+		t.Fatalf("results = %+v, want w1 tagged with its collection", results)
+	}
+
+	if err := store.Delete(found.Collection, found.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if workPath != "/v1/memories/w1/" || workAuth != "Bearer work-key" {
+		t.Errorf("work server got %q with auth %q, want the delete with the collection key", workPath, workAuth)
+	}
+	if primaryCalls != 0 {
+		t.Errorf("primary server called %d times, want none", primaryCalls)
+	}
+
+	// No collection means the primary one
+	if err := store.Delete("", "p1"); !errors.Is(err, ErrNotFound) || primaryCalls != 1 {
+		t.Errorf("Delete without a collection: err = %v, primary calls = %d; want the primary server's 404", err, primaryCalls)
+	}
+}
+
+func TestSearchPaged(t *testing.T) {
+	var payloads []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		payloads = append(payloads, payload)
+
+		results := map[string][]map[string]interface{}{
+			"screen": {
+				{"id": "s1", "memory": "a", "score": 0.9},
+				{"id": "s2", "memory": "c", "score": 0.5},
+			},
+			"work": {
+				{"id": "w1", "memory": "b", "score": 0.7},
+				{"id": "w2", "memory": "d", "score": 0.3},
+			},
+		}[payload["agent_id"].(string)]
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+	}))
+	defer server.Close()
+
+	// One collection: the offset goes to the backend, and only when set
+	store := NewStore(&config.MemoryConfig{BaseURL: server.URL, CollectionName: "screen"})
+	if _, err := store.Search("q", 2); err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if _, err := store.SearchPaged("q", 2, 4); err != nil {
+		t.Fatalf("SearchPaged failed: %v", err)
+	}
+	if _, ok := payloads[0]["offset"]; ok {
+		t.Errorf("Search sent an offset: %v", payloads[0])
+	}
+	if payloads[1]["offset"] != float64(4) {
+		t.Errorf("offset = %v, want 4", payloads[1]["offset"])
+	}
+
+	if _, err := store.SearchPaged("q", 2, -1); !errors.Is(err, ErrNegativeOffset) {
+		t.Errorf("negative offset error = %v, want ErrNegativeOffset", err)
+	}
+
+	// Several collections: merged first, then paged
+	store = NewStore(&config.MemoryConfig{
+		BaseURL:          server.URL,
+		CollectionName:   "screen",
+		ExtraCollections: []config.CollectionConfig{{Name: "work"}},
+	})
+	results, err := store.SearchPaged("q", 2, 1)
+	if err != nil {
+		t.Fatalf("SearchPaged failed: %v", err)
+	}
+	if len(results) != 2 || results[0].Memory.ID != "w1" || results[1].Memory.ID != "s2" {
+		t.Errorf("page = %+v, want w1, s2", results)
+	}
+	if results, _ := store.SearchPaged("q", 2, 10); len(results) != 0 {
+		t.Errorf("page past the end = %+v, want none", results)
+	}
+}
@@ -0,0 +1,167 @@
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"screen-memory-assistant/internal/config"
+	"screen-memory-assistant/internal/crypt"
+)
+
+// SetCipher encrypts memory content and the descriptive metadata on Add
+// and Update and decrypts them in search results and listings; nil sends
+// plaintext. NewStore sets it from
+// EncryptionPassphrase.
+func (s *Store) SetCipher(c *crypt.Cipher) {
+	s.cipher = c
+	s.cipherErr = nil
+}
+
+// Cipher returns the store's cipher, or nil when content is plaintext
+func (s *Store) Cipher() *crypt.Cipher {
+	return s.cipher
+}
+
+// deriveCipher makes a cipher from a passphrase and salt; tests replace it
+var deriveCipher = crypt.New
+
+// newCipher derives the configured cipher. The user ID salts the key, so
+// each user's passphrase yields a different key.
+func newCipher(cfg *config.MemoryConfig) (*crypt.Cipher, error) {
+	if cfg.EncryptionPassphrase == "" {
+		return nil, nil
+	}
+	c, err := deriveCipher(cfg.EncryptionPassphrase, cfg.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key: %w", err)
+	}
+	return c, nil
+}
+
+// seal encrypts content for the backend, if a cipher is set. A configured
+// passphrase that yielded no cipher refuses the write rather than sending
+// plaintext.
+func (s *Store) seal(content string) (string, error) {
+	if s.cipherErr != nil {
+		return "", s.cipherErr
+	}
+	if s.cipher == nil {
+		return content, nil
+	}
+	return s.cipher.SealString(content)
+}
+
+// open decrypts content from the backend. Content stored before
+// encryption was enabled is returned as is; ok is false for content that
+// can't be decrypted, e.g. sealed under a previous passphrase.
+func (s *Store) open(id, content string) (string, bool) {
+	if s.cipher == nil || !crypt.IsSealed(content) {
+		return content, true
+	}
+	plain, err := s.cipher.OpenString(content)
+	if err != nil {
+		s.logger.Warn("skipping memory that can't be decrypted", "id", id, "error", err)
+		return "", false
+	}
+	return plain, true
+}
+
+// sealedExtraKey holds the encrypted Extra fields in place of the fields
+const sealedExtraKey = "sealed"
+
+// sealMetadata encrypts the metadata fields that describe what was on
+// screen, if a cipher is set. Timestamps, the display, the source, the
+// confidence flag and the image path stay readable so the backend can
+// still filter on them.
+func (s *Store) sealMetadata(m Metadata) (Metadata, error) {
+	if s.cipherErr != nil {
+		return Metadata{}, s.cipherErr
+	}
+	if s.cipher == nil {
+		return m, nil
+	}
+
+	var err error
+	seal := func(v string) string {
+		if err != nil || v == "" {
+			return v
+		}
+		var sealed string
+		sealed, err = s.cipher.SealString(v)
+		return sealed
+	}
+	sealAll := func(values []string) []string {
+		if values == nil {
+			return nil
+		}
+		sealed := make([]string, len(values))
+		for i, v := range values {
+			sealed[i] = seal(v)
+		}
+		return sealed
+	}
+
+	m.Context = seal(m.Context)
+	m.Activities = sealAll(m.Activities)
+	m.KeyElements = sealAll(m.KeyElements)
+	m.UserIntent = seal(m.UserIntent)
+	m.OCRText = seal(m.OCRText)
+	m.WindowTitle = seal(m.WindowTitle)
+	if len(m.Extra) > 0 {
+		extra, jsonErr := json.Marshal(m.Extra)
+		if jsonErr != nil {
+			return Metadata{}, jsonErr
+		}
+		m.Extra = map[string]interface{}{sealedExtraKey: seal(string(extra))}
+	}
+	if err != nil {
+		return Metadata{}, err
+	}
+	return m, nil
+}
+
+// openMetadata decrypts the fields sealMetadata encrypted. Plaintext
+// fields are returned as is; ok is false when a field can't be decrypted.
+func (s *Store) openMetadata(id string, m Metadata) (Metadata, bool) {
+	if s.cipher == nil {
+		return m, true
+	}
+
+	var err error
+	open := func(v string) string {
+		if err != nil || !crypt.IsSealed(v) {
+			return v
+		}
+		var plain string
+		plain, err = s.cipher.OpenString(v)
+		return plain
+	}
+	openAll := func(values []string) []string {
+		if values == nil {
+			return nil
+		}
+		plain := make([]string, len(values))
+		for i, v := range values {
+			plain[i] = open(v)
+		}
+		return plain
+	}
+
+	m.Context = open(m.Context)
+	m.Activities = openAll(m.Activities)
+	m.KeyElements = openAll(m.KeyElements)
+	m.UserIntent = open(m.UserIntent)
+	m.OCRText = open(m.OCRText)
+	m.WindowTitle = open(m.WindowTitle)
+	if sealed, ok := m.Extra[sealedExtraKey].(string); ok && len(m.Extra) == 1 && crypt.IsSealed(sealed) {
+		var extra map[string]interface{}
+		if err = json.Unmarshal([]byte(open(sealed)), &extra); err == nil {
+			m.Extra = extra
+		}
+	}
+	if err != nil {
+		s.logger.Warn("skipping memory whose metadata can't be decrypted", "id", id, "error", err)
+		return Metadata{}, false
+	}
+	return m, true
+}
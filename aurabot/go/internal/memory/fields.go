@@ -0,0 +1,90 @@
+package memory
+
+import "strings"
+
+// FieldFilter restricts a search to memories with matching structured
+// fields. Empty fields match every memory; matching ignores case.
+type FieldFilter struct {
+	Context    string
+	Activity   string
+	KeyElement string
+}
+
+// IsZero reports whether the filter matches every memory
+func (f FieldFilter) IsZero() bool {
+	return f == FieldFilter{}
+}
+
+// Matches reports whether m satisfies every set field
+func (f FieldFilter) Matches(m Memory) bool {
+	if f.Context != "" && !strings.EqualFold(m.Metadata.Context, f.Context) {
+		return false
+	}
+	if f.Activity != "" && !containsFold(m.Metadata.Activities, f.Activity) {
+		return false
+	}
+	if f.KeyElement != "" && !containsFold(m.Metadata.KeyElements, f.KeyElement) {
+		return false
+	}
+	return true
+}
+
+// backendFilters converts the filter to the fields sent to the backend
+func (f FieldFilter) backendFilters() map[string]interface{} {
+	filters := make(map[string]interface{})
+	if f.Context != "" {
+		filters["context"] = f.Context
+	}
+	if f.Activity != "" {
+		filters["activities"] = f.Activity
+	}
+	if f.KeyElement != "" {
+		filters["key_elements"] = f.KeyElement
+	}
+	return filters
+}
+
+func containsFold(values []string, want string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// SearchFields retrieves relevant memories matching filter. With
+// structured fields enabled the filter is also sent to the backend;
+// results are always checked here, since not every backend filters.
+func (s *Store) SearchFields(query string, filter FieldFilter, limit int) ([]SearchResult, error) {
+	if filter.IsZero() {
+		return s.Search(query, limit)
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+
+	// Encrypted fields can only be matched here, after decryption
+	var filters map[string]interface{}
+	if s.config.StructuredFields && s.cipher == nil {
+		filters = filter.backendFilters()
+	}
+
+	results, err := s.search(query, limit*rangeOverfetch, 0, filters, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var filtered []SearchResult
+	for _, r := range results {
+		if !filter.Matches(r.Memory) {
+			continue
+		}
+		filtered = append(filtered, r)
+		if len(filtered) >= limit {
+			break
+		}
+	}
+
+	return filtered, nil
+}
@@ -0,0 +1,92 @@
+package memory
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"screen-memory-assistant/internal/config"
+)
+
+func TestStructuredFields_SentAndFilterable(t *testing.T) {
+	var added map[string]interface{}
+	var filters map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+
+		if !strings.HasSuffix(r.URL.Path, "/search/") {
+			added = payload
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+
+		filters, _ = payload["filters"].(map[string]interface{})
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"results": []map[string]interface{}{
+				{"id": "a", "memory": "debugging", "metadata": map[string]interface{}{
+					"activities": []string{"coding"}, "key_elements": []string{"VS Code"}}},
+				{"id": "b", "memory": "reading", "metadata": map[string]interface{}{
+					"activities": []string{"reading"}, "key_elements": []string{"Firefox"}}},
+			},
+		})
+	}))
+	defer server.Close()
+
+	store := NewStore(&config.MemoryConfig{BaseURL: server.URL, StructuredFields: true})
+
+	_, err := store.Add("debugging", Metadata{
+		Context:     "work",
+		Activities:  []string{"coding"},
+		KeyElements: []string{"VS Code"},
+	})
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if added["context"] != "work" {
+		t.Errorf("context field = %v, want %q", added["context"], "work")
+	}
+	if acts, _ := added["activities"].([]interface{}); len(acts) != 1 || acts[0] != "coding" {
+		t.Errorf("activities field = %v, want [coding]", added["activities"])
+	}
+	if elems, _ := added["key_elements"].([]interface{}); len(elems) != 1 || elems[0] != "VS Code" {
+		t.Errorf("key_elements field = %v, want [VS Code]", added["key_elements"])
+	}
+
+	results, err := store.SearchFields("q", FieldFilter{KeyElement: "vs code"}, 10)
+	if err != nil {
+		t.Fatalf("SearchFields failed: %v", err)
+	}
+	if filters["key_elements"] != "vs code" {
+		t.Errorf("backend filters = %v, want key_elements", filters)
+	}
+	if len(results) != 1 || results[0].Memory.ID != "a" {
+		t.Errorf("results = %+v, want only a", results)
+	}
+}
+
+func TestStructuredFields_Disabled(t *testing.T) {
+	var payloads []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		payloads = append(payloads, payload)
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": []interface{}{}})
+	}))
+	defer server.Close()
+
+	store := NewStore(&config.MemoryConfig{BaseURL: server.URL})
+	store.Add("x", Metadata{Activities: []string{"coding"}})
+	store.SearchFields("q", FieldFilter{Activity: "coding"}, 10)
+
+	for _, p := range payloads {
+		if _, ok := p["activities"]; ok {
+			t.Errorf("activities sent as a field while disabled: %v", p)
+		}
+		if _, ok := p["filters"]; ok {
+			t.Errorf("filters sent while disabled: %v", p)
+		}
+	}
+}
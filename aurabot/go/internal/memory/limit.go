@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// ErrResponseTooLarge is returned when a response exceeds MaxResponseBytes
+var ErrResponseTooLarge = errors.New("response too large")
+
+// cappedReader fails once more than max bytes have been read, so a
+// streaming decoder stops instead of silently decoding a truncated body
+type cappedReader struct {
+	r   io.Reader
+	max int64
+	n   int64
+}
+
+func (c *cappedReader) Read(p []byte) (int, error) {
+	if c.n > c.max {
+		return 0, fmt.Errorf("%w: over %d bytes", ErrResponseTooLarge, c.max)
+	}
+	// Read at most one byte past the cap to detect overflow
+	if remaining := c.max - c.n + 1; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	if c.n > c.max {
+		return n, fmt.Errorf("%w: over %d bytes", ErrResponseTooLarge, c.max)
+	}
+	return n, err
+}
+
+// debugBody logs a response body at debug level when LogResponses is set.
+// Bodies hold memory contents, so they're only read up front then.
+func (s *Store) debugBody(msg string, body io.Reader, attrs ...any) io.Reader {
+	if !s.config.LogResponses || !s.logger.Enabled(context.Background(), slog.LevelDebug) {
+		return body
+	}
+	raw, err := io.ReadAll(body)
+	s.logger.Debug(msg, append(attrs, "body", string(raw))...)
+	if err != nil {
+		return io.MultiReader(bytes.NewReader(raw), errReader{err})
+	}
+	return bytes.NewReader(raw)
+}
+
+// errReader fails every read with err, replaying a failed body read
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
+
+// responseBody wraps body with the configured size cap
+func (s *Store) responseBody(body io.Reader) io.Reader {
+	if s.config.MaxResponseBytes <= 0 {
+		return body
+	}
+	return &cappedReader{r: body, max: s.config.MaxResponseBytes}
+}
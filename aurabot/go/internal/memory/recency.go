@@ -0,0 +1,25 @@
+package memory
+
+import "time"
+
+// defaultRecencyHalfLife is used when no half-life is configured
+const defaultRecencyHalfLife = 72 * time.Hour
+
+// RecencyWeight returns the configured recency weight; 0 ranks by
+// relevance alone
+func (s *Store) RecencyWeight() float64 {
+	return s.config.RecencyWeight
+}
+
+// recencyHalfLife returns the age at which a memory's recency credit halves
+func (s *Store) recencyHalfLife() time.Duration {
+	if s.config.RecencyHalfLifeHours <= 0 {
+		return defaultRecencyHalfLife
+	}
+	return time.Duration(s.config.RecencyHalfLifeHours * float64(time.Hour))
+}
+
+// RecencyReranker returns a reranker using the configured half-life
+func (s *Store) RecencyReranker(weight float64) RecencyReranker {
+	return RecencyReranker{Weight: weight, HalfLife: s.recencyHalfLife(), Now: s.now}
+}
@@ -0,0 +1,97 @@
+package memory
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Reranker reorders search results, possibly adjusting their scores
+type Reranker interface {
+	Rerank(results []SearchResult) []SearchResult
+}
+
+// Built-in reranker names, as used in config
+const (
+	RerankScore   = "score"
+	RerankRecency = "recency"
+	RerankUsage   = "usage"
+)
+
+// sortByScore orders results by descending score, keeping ties in place
+func sortByScore(results []SearchResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+}
+
+// ScoreReranker orders results by relevance score alone
+type ScoreReranker struct{}
+
+// Rerank returns the results sorted by score
+func (ScoreReranker) Rerank(results []SearchResult) []SearchResult {
+	ranked := make([]SearchResult, len(results))
+	copy(ranked, results)
+	sortByScore(ranked)
+	return ranked
+}
+
+// RecencyReranker blends relevance with age:
+// score * ((1-Weight) + Weight*0.5^(age/HalfLife)). Memories with no
+// known time get no recency credit.
+type RecencyReranker struct {
+	Weight   float64
+	HalfLife time.Duration
+	Now      func() time.Time
+}
+
+// Rerank returns the results re-scored and sorted by recency-weighted score
+func (r RecencyReranker) Rerank(results []SearchResult) []SearchResult {
+	weight := math.Min(r.Weight, 1)
+	now := r.Now()
+
+	ranked := make([]SearchResult, len(results))
+	copy(ranked, results)
+	for i := range ranked {
+		ranked[i].Score *= r.factor(ranked[i].Memory, weight, now)
+	}
+
+	sortByScore(ranked)
+	return ranked
+}
+
+// Factor returns the multiplier Rerank applies to m's score
+func (r RecencyReranker) Factor(m Memory) float64 {
+	return r.factor(m, math.Min(r.Weight, 1), r.Now())
+}
+
+func (r RecencyReranker) factor(m Memory, weight float64, now time.Time) float64 {
+	decay := 0.0
+	if t := m.Time(); !t.IsZero() {
+		age := now.Sub(t)
+		if age < 0 {
+			age = 0
+		}
+		decay = math.Pow(0.5, float64(age)/float64(r.HalfLife))
+	}
+	return (1 - weight) + weight*decay
+}
+
+// UsageReranker favors memories that have been used often:
+// score * (1 + Weight*ln(1+uses))
+type UsageReranker struct {
+	Weight float64
+	Uses   func(id string) int
+}
+
+// Rerank returns the results re-scored and sorted by usage-weighted score
+func (r UsageReranker) Rerank(results []SearchResult) []SearchResult {
+	ranked := make([]SearchResult, len(results))
+	copy(ranked, results)
+	for i := range ranked {
+		ranked[i].Score *= 1 + r.Weight*math.Log1p(float64(r.Uses(ranked[i].Memory.ID)))
+	}
+
+	sortByScore(ranked)
+	return ranked
+}
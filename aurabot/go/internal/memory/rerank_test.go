@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"testing"
+	"time"
+)
+
+// sampleResults has an old strong match, a fresh weaker one and one
+// without a time
+func sampleResults(now time.Time) []SearchResult {
+	at := func(age time.Duration) Metadata {
+		return Metadata{Timestamp: now.Add(-age).Format(time.RFC3339)}
+	}
+	return []SearchResult{
+		{Memory: Memory{ID: "fresh", Metadata: at(time.Hour)}, Score: 0.6},
+		{Memory: Memory{ID: "old", Metadata: at(30 * 24 * time.Hour)}, Score: 0.9},
+		{Memory: Memory{ID: "undated"}, Score: 0.7},
+	}
+}
+
+func ids(results []SearchResult) []string {
+	var out []string
+	for _, r := range results {
+		out = append(out, r.Memory.ID)
+	}
+	return out
+}
+
+func TestRerankers(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	uses := map[string]int{"fresh": 20}
+
+	tests := []struct {
+		name     string
+		reranker Reranker
+		want     []string
+	}{
+		{"score", ScoreReranker{}, []string{"old", "undated", "fresh"}},
+		{"recency", RecencyReranker{Weight: 0.8, HalfLife: 72 * time.Hour, Now: func() time.Time { return now }},
+			[]string{"fresh", "old", "undated"}},
+		{"usage", UsageReranker{Weight: 0.2, Uses: func(id string) int { return uses[id] }},
+			[]string{"fresh", "old", "undated"}},
+		{"usage without uses", UsageReranker{Weight: 0.2, Uses: func(string) int { return 0 }},
+			[]string{"old", "undated", "fresh"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := sampleResults(now)
+			got := ids(tt.reranker.Rerank(input))
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+			if input[0].Memory.ID != "fresh" || input[0].Score != 0.6 {
+				t.Errorf("input was modified: %+v", input[0])
+			}
+		})
+	}
+}
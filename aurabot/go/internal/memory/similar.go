@@ -0,0 +1,68 @@
+package memory
+
+import (
+	"errors"
+	"sort"
+)
+
+// ErrNotFound is returned when no memory has the requested ID
+var ErrNotFound = errors.New("memory not found")
+
+// getScanLimit bounds how many memories Get scans; the Mem0 server has
+// no lookup by ID, so the memory is found in the full listing
+const getScanLimit = 1000
+
+// Get retrieves a single memory by ID
+func (s *Store) Get(memoryID string) (*Memory, error) {
+	memories, err := s.GetRecent(getScanLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range memories {
+		if m.ID == memoryID {
+			return &m, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+// Similar finds the memories most similar to the given one by searching
+// with its content. The memory itself is excluded and results are ordered
+// by descending score.
+func (s *Store) Similar(memoryID string, limit int) ([]SearchResult, error) {
+	if limit <= 0 {
+		limit = 5
+	}
+
+	source, err := s.Get(memoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	// One extra result makes room for the source memory itself
+	results, err := s.Search(source.Content, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	var similar []SearchResult
+	for _, r := range results {
+		// Get only looks in the primary collection; an equal ID elsewhere
+		// is a different memory
+		if r.Memory.ID == memoryID && r.Collection == s.config.CollectionName {
+			continue
+		}
+		similar = append(similar, r)
+	}
+
+	sort.SliceStable(similar, func(i, j int) bool {
+		return similar[i].Score > similar[j].Score
+	})
+	if len(similar) > limit {
+		similar = similar[:limit]
+	}
+
+	return similar, nil
+}
@@ -0,0 +1,75 @@
+package memory
+
+// Memory sources identify where a memory was created
+const (
+	SourceCapture   = "capture"
+	SourceManual    = "manual"
+	SourceExtension = "extension"
+	SourceClipboard = "clipboard"
+	// SourceEnhancement marks a record of a past prompt enhancement
+	SourceEnhancement = "enhancement"
+)
+
+// Source returns where the memory came from. Memories stored before
+// sources were recorded could only come from screen capture.
+func (m Memory) Source() string {
+	if m.Metadata.Source == "" {
+		return SourceCapture
+	}
+	return m.Metadata.Source
+}
+
+// SearchSource retrieves relevant memories created by source.
+// An empty source matches every memory.
+func (s *Store) SearchSource(query, source string, limit int) ([]SearchResult, error) {
+	if source == "" {
+		return s.Search(query, limit)
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+
+	results, err := s.Search(query, limit*rangeOverfetch)
+	if err != nil {
+		return nil, err
+	}
+
+	var filtered []SearchResult
+	for _, r := range results {
+		if r.Memory.Source() != source {
+			continue
+		}
+		filtered = append(filtered, r)
+		if len(filtered) >= limit {
+			break
+		}
+	}
+
+	return filtered, nil
+}
+
+// GetRecentSource retrieves the most recent memories created by source.
+// An empty source matches every memory.
+func (s *Store) GetRecentSource(source string, limit int) ([]Memory, error) {
+	if source == "" {
+		return s.GetRecent(limit)
+	}
+
+	memories, err := s.GetRecent(limit * rangeOverfetch)
+	if err != nil {
+		return nil, err
+	}
+
+	var filtered []Memory
+	for _, m := range memories {
+		if m.Source() != source {
+			continue
+		}
+		filtered = append(filtered, m)
+		if len(filtered) >= limit {
+			break
+		}
+	}
+
+	return filtered, nil
+}
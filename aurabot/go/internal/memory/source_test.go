@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"screen-memory-assistant/internal/config"
+)
+
+func TestSearchSource_Filters(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"results": []map[string]interface{}{
+				{"id": "legacy", "memory": "a"},
+				{"id": "ext", "memory": "b", "metadata": map[string]string{"source": SourceExtension}},
+				{"id": "note", "memory": "c", "metadata": map[string]string{"source": SourceManual}},
+			},
+		})
+	}))
+	defer server.Close()
+
+	store := NewStore(&config.MemoryConfig{BaseURL: server.URL})
+
+	tests := []struct {
+		source string
+		want   []string
+	}{
+		{"", []string{"legacy", "ext", "note"}},
+		{SourceCapture, []string{"legacy"}},
+		{SourceExtension, []string{"ext"}},
+		{SourceManual, []string{"note"}},
+		{SourceClipboard, nil},
+	}
+
+	for _, tt := range tests {
+		results, err := store.SearchSource("q", tt.source, 10)
+		if err != nil {
+			t.Fatalf("SearchSource(%q) failed: %v", tt.source, err)
+		}
+		var got []string
+		for _, r := range results {
+			got = append(got, r.Memory.ID)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("SearchSource(%q) = %v, want %v", tt.source, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("SearchSource(%q) = %v, want %v", tt.source, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestMemory_Source(t *testing.T) {
+	if got := (Memory{}).Source(); got != SourceCapture {
+		t.Errorf("legacy memory source = %q, want %q", got, SourceCapture)
+	}
+	m := Memory{Metadata: Metadata{Source: SourceClipboard}}
+	if got := m.Source(); got != SourceClipboard {
+		t.Errorf("source = %q, want %q", got, SourceClipboard)
+	}
+}
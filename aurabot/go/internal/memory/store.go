@@ -4,10 +4,13 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 
 	"screen-memory-assistant/internal/config"
+	"screen-memory-assistant/internal/crypt"
 )
 
 // Memory represents a stored memory
@@ -17,50 +20,120 @@ type Memory struct {
 	UserID    string    `json:"user_id"`
 	Metadata  Metadata  `json:"metadata"`
 	CreatedAt time.Time `json:"created_at"`
+	// Collection is where the memory was read from; Update and Delete
+	// take it to reach the right server
+	Collection string `json:"collection,omitempty"`
 }
 
 // Metadata contains additional context about the memory
 type Metadata struct {
-	Timestamp   string   `json:"timestamp"`
-	Context     string   `json:"context"`
-	Activities  []string `json:"activities"`
-	KeyElements []string `json:"key_elements"`
-	UserIntent  string   `json:"user_intent"`
-	DisplayNum  int      `json:"display_num"`
+	Timestamp string `json:"timestamp"`
+	// EndTimestamp is set when later captures were merged into the memory
+	EndTimestamp string   `json:"end_timestamp,omitempty"`
+	Context      string   `json:"context"`
+	Activities   []string `json:"activities"`
+	KeyElements  []string `json:"key_elements"`
+	UserIntent   string   `json:"user_intent"`
+	DisplayNum   int      `json:"display_num"`
+	OCRText      string   `json:"ocr_text,omitempty"`
+	Source       string   `json:"source,omitempty"`
+	// LowConfidence marks analyses the model itself was unsure about
+	LowConfidence bool `json:"low_confidence,omitempty"`
+	// Extra holds analysis fields outside the schema, as the model named them
+	Extra map[string]interface{} `json:"extra,omitempty"`
+	// WindowTitle is the foreground window's title at capture time
+	WindowTitle string `json:"window_title,omitempty"`
+	// ImagePath is the archived screenshot the memory was made from
+	ImagePath string `json:"image_path,omitempty"`
 }
 
 // SearchResult represents a memory search result
 type SearchResult struct {
-	Memory    Memory  `json:"memory"`
-	Score     float64 `json:"score"`
-	Distance  float64 `json:"distance"`
+	Memory   Memory  `json:"memory"`
+	Score    float64 `json:"score"`
+	Distance float64 `json:"distance"`
+	// RawScore is the backend's score, before any reranking
+	RawScore float64 `json:"raw_score"`
+	// Collection is the Mem0 collection the memory was found in
+	Collection string `json:"collection"`
 }
 
-// parseTime parses an ISO8601 time string, returning zero time on error
-func parseTime(s string) time.Time {
-	t, _ := time.Parse(time.RFC3339, s)
-	return t
+// MemoryStore is the set of memory operations the service and enhancer
+// depend on, whichever backend (Mem0 or Supermemory) a Store talks to
+type MemoryStore interface {
+	Add(content string, metadata Metadata) (*Memory, error)
+	Update(collection, memoryID, content string, metadata Metadata) error
+	Delete(collection, memoryID string) error
+
+	Search(query string, limit int) ([]SearchResult, error)
+	SearchPaged(query string, limit, offset int) ([]SearchResult, error)
+	SearchSource(query, source string, limit int) ([]SearchResult, error)
+	SearchRelative(query, source, window string, limit int) ([]SearchResult, error)
+	SearchAllUsers(query string, limit int) ([]SearchResult, error)
+	Similar(memoryID string, limit int) ([]SearchResult, error)
+	GetRecent(limit int) ([]Memory, error)
+	GetRecentSource(source string, limit int) ([]Memory, error)
+
+	// RecencyWeight is the configured recency boost, 0 when off
+	RecencyWeight() float64
+	RecencyReranker(weight float64) RecencyReranker
+
+	// Cipher is nil when content is stored as plaintext
+	Cipher() *crypt.Cipher
+	SetLogger(l *slog.Logger)
+	CheckHealth() error
 }
 
-// Store handles Mem0 operations
+var _ MemoryStore = (*Store)(nil)
+
+// Store handles memory operations against the configured backend
 type Store struct {
 	config     *config.MemoryConfig
+	backend    MemoryBackend
 	httpClient *http.Client
+	now        func() time.Time
+	// admin allows SearchAllUsers
+	admin  bool
+	logger *slog.Logger
+	// cipher encrypts content at rest in the backend; nil sends plaintext
+	cipher *crypt.Cipher
+	// cipherErr is why the configured cipher couldn't be made; writes
+	// fail with it instead of falling back to plaintext
+	cipherErr error
 }
 
 // NewStore creates a new memory store
 func NewStore(cfg *config.MemoryConfig) *Store {
+	// Unknown names fall back to Mem0; callers that write memories
+	// reject them up front with NewBackend
+	backend, err := NewBackend(cfg.Backend)
+	if err != nil {
+		backend = mem0Backend{}
+	}
+	cipher, cipherErr := newCipher(cfg)
 	return &Store{
-		config: cfg,
+		config:  cfg,
+		backend: backend,
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
+		now:       time.Now,
+		logger:    slog.Default(),
+		cipher:    cipher,
+		cipherErr: cipherErr,
 	}
 }
 
+// SetLogger replaces the logger, slog.Default() by default. With
+// LogResponses, search response bodies are logged at debug level.
+func (s *Store) SetLogger(l *slog.Logger) {
+	s.logger = l
+}
+
 // Add stores a new memory
 func (s *Store) Add(content string, metadata Metadata) (*Memory, error) {
-	url := fmt.Sprintf("%s/v1/memories/", s.config.BaseURL)
+	baseURL, apiKey := s.endpoint(s.config.CollectionName)
+	url := fmt.Sprintf("%s/v1/memories/", baseURL)
 
 	memory := &Memory{
 		Content:   content,
@@ -69,16 +142,37 @@ func (s *Store) Add(content string, metadata Metadata) (*Memory, error) {
 		CreatedAt: time.Now(),
 	}
 
+	sealed, err := s.seal(content)
+	if err != nil {
+		return nil, fmt.Errorf("encrypting memory: %w", err)
+	}
+	sealedMetadata, err := s.sealMetadata(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("encrypting memory: %w", err)
+	}
+
 	payload := map[string]interface{}{
 		"messages": []map[string]string{
 			{
 				"role":    "user",
-				"content": content,
+				"content": sealed,
 			},
 		},
-		"user_id":   s.config.UserID,
-		"metadata":  metadata,
-		"agent_id":  s.config.CollectionName,
+		"metadata": sealedMetadata,
+	}
+	// Ciphertext must be stored verbatim rather than rewritten into facts
+	if s.cipher != nil {
+		payload["infer"] = false
+	}
+	for k, v := range s.backend.ScopeFields(s.scope(s.config.CollectionName)) {
+		payload[k] = v
+	}
+	// Top-level fields let backends index them rather than bury them in
+	// metadata; encrypted, there is nothing to index
+	if s.config.StructuredFields && s.cipher == nil {
+		payload["context"] = metadata.Context
+		payload["activities"] = metadata.Activities
+		payload["key_elements"] = metadata.KeyElements
 	}
 
 	jsonData, err := json.Marshal(payload)
@@ -92,8 +186,8 @@ func (s *Store) Add(content string, metadata Metadata) (*Memory, error) {
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	if s.config.APIKey != "" {
-		req.Header.Set("Authorization", "Bearer "+s.config.APIKey)
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
 	}
 
 	resp, err := s.httpClient.Do(req)
@@ -109,19 +203,27 @@ func (s *Store) Add(content string, metadata Metadata) (*Memory, error) {
 	return memory, nil
 }
 
-// Search retrieves relevant memories based on query
-func (s *Store) Search(query string, limit int) ([]SearchResult, error) {
-	url := fmt.Sprintf("%s/v1/memories/search/", s.config.BaseURL)
-
-	if limit <= 0 {
-		limit = 10
-	}
+// searchCollection retrieves relevant memories from the scope's collection,
+// skipping the first offset. Non-nil filters are sent for backends that
+// filter on structured fields.
+func (s *Store) searchCollection(query string, scope Scope, limit, offset int, filters map[string]interface{}) ([]SearchResult, error) {
+	collection := scope.Collection
+	baseURL, apiKey := s.endpoint(collection)
+	url := fmt.Sprintf("%s/v1/memories/search/", baseURL)
 
 	payload := map[string]interface{}{
-		"query":     query,
-		"user_id":   s.config.UserID,
-		"agent_id":  s.config.CollectionName,
-		"limit":     limit,
+		"query": query,
+		"limit": limit,
+	}
+	if offset > 0 {
+		payload["offset"] = offset
+	}
+	// Scoped exactly like Add, so searches find what was added
+	for k, v := range s.backend.ScopeFields(scope) {
+		payload[k] = v
+	}
+	if filters != nil {
+		payload["filters"] = filters
 	}
 
 	jsonData, err := json.Marshal(payload)
@@ -135,8 +237,8 @@ func (s *Store) Search(query string, limit int) ([]SearchResult, error) {
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	if s.config.APIKey != "" {
-		req.Header.Set("Authorization", "Bearer "+s.config.APIKey)
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
 	}
 
 	resp, err := s.httpClient.Do(req)
@@ -151,50 +253,65 @@ func (s *Store) Search(query string, limit int) ([]SearchResult, error) {
 
 	var result struct {
 		Results []struct {
-			Memory   string    `json:"memory"`
-			ID       string    `json:"id"`
-			UserID   string    `json:"user_id"`
-			Score    float64   `json:"score"`
-			Distance float64   `json:"distance"`
-			Metadata Metadata  `json:"metadata"`
+			Memory    string   `json:"memory"`
+			ID        string   `json:"id"`
+			UserID    string   `json:"user_id"`
+			Score     float64  `json:"score"`
+			Distance  float64  `json:"distance"`
+			Metadata  Metadata `json:"metadata"`
 			CreatedAt string   `json:"created_at"`
 		} `json:"results"`
 	}
-	
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+
+	body := s.debugBody("memory search response", s.responseBody(resp.Body), "collection", collection)
+	if err := json.NewDecoder(body).Decode(&result); err != nil {
 		return nil, fmt.Errorf("decoding response: %w", err)
 	}
-	
+
 	var searchResults []SearchResult
 	for _, r := range result.Results {
+		content, ok := s.open(r.ID, r.Memory)
+		if !ok {
+			continue
+		}
+		metadata, ok := s.openMetadata(r.ID, r.Metadata)
+		if !ok {
+			continue
+		}
 		searchResults = append(searchResults, SearchResult{
-			Memory: Memory{
-				ID:        r.ID,
-				Content:   r.Memory,
-				UserID:    r.UserID,
-				Metadata:  r.Metadata,
-				CreatedAt: parseTime(r.CreatedAt),
-			},
-			Score:    r.Score,
-			Distance: r.Distance,
+			Memory: s.withKnownTime(Memory{
+				ID:         r.ID,
+				Content:    content,
+				UserID:     r.UserID,
+				Metadata:   metadata,
+				CreatedAt:  parseTime(r.CreatedAt),
+				Collection: collection,
+			}),
+			Score:      r.Score,
+			Distance:   r.Distance,
+			RawScore:   r.Score,
+			Collection: collection,
 		})
 	}
 
 	return searchResults, nil
 }
 
-// GetRecent retrieves the most recent memories
+// GetRecent retrieves the most recent memories from the primary
+// collection, where captures are stored
 func (s *Store) GetRecent(limit int) ([]Memory, error) {
-	url := fmt.Sprintf("%s/v1/memories/?user_id=%s&agent_id=%s&limit=%d",
-		s.config.BaseURL, s.config.UserID, s.config.CollectionName, limit)
+	baseURL, apiKey := s.endpoint(s.config.CollectionName)
+	query := s.backend.ScopeQuery(s.scope(s.config.CollectionName))
+	query.Set("limit", strconv.Itoa(limit))
+	url := fmt.Sprintf("%s/v1/memories/?%s", baseURL, query.Encode())
 
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("creating request: %w", err)
 	}
 
-	if s.config.APIKey != "" {
-		req.Header.Set("Authorization", "Bearer "+s.config.APIKey)
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
 	}
 
 	resp, err := s.httpClient.Do(req)
@@ -207,25 +324,107 @@ func (s *Store) GetRecent(limit int) ([]Memory, error) {
 		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
 	}
 
-	var memories []Memory
-	if err := json.NewDecoder(resp.Body).Decode(&memories); err != nil {
+	var body json.RawMessage
+	if err := json.NewDecoder(s.responseBody(resp.Body)).Decode(&body); err != nil {
 		return nil, fmt.Errorf("decoding response: %w", err)
 	}
+	memories, err := decodeMemories(body)
+	if err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	readable := memories[:0]
+	for _, m := range memories {
+		content, ok := s.open(m.ID, m.Content)
+		if !ok {
+			continue
+		}
+		metadata, ok := s.openMetadata(m.ID, m.Metadata)
+		if !ok {
+			continue
+		}
+		m.Content, m.Metadata = content, metadata
+		m.Collection = s.config.CollectionName
+		readable = append(readable, s.withKnownTime(m))
+	}
+
+	return readable, nil
+}
+
+// decodeMemories reads a listing, either wrapped like search responses
+// ({"results": [...]}) or as a bare array
+func decodeMemories(data []byte) ([]Memory, error) {
+	var memories []Memory
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err := json.Unmarshal(trimmed, &memories)
+		return memories, err
+	}
 
-	return memories, nil
+	var envelope struct {
+		Results []Memory `json:"results"`
+	}
+	err := json.Unmarshal(data, &envelope)
+	return envelope.Results, err
 }
 
-// Delete removes a memory by ID
-func (s *Store) Delete(memoryID string) error {
-	url := fmt.Sprintf("%s/v1/memories/%s/", s.config.BaseURL, memoryID)
+// Update replaces a memory's content and metadata. collection is the
+// memory's Collection; empty means the primary collection.
+func (s *Store) Update(collection, memoryID, content string, metadata Metadata) error {
+	baseURL, apiKey := s.endpoint(s.collectionOrPrimary(collection))
+	url := fmt.Sprintf("%s/v1/memories/%s/", baseURL, memoryID)
+
+	sealed, err := s.seal(content)
+	if err != nil {
+		return fmt.Errorf("encrypting memory: %w", err)
+	}
+	sealedMetadata, err := s.sealMetadata(metadata)
+	if err != nil {
+		return fmt.Errorf("encrypting memory: %w", err)
+	}
+
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"text":     sealed,
+		"metadata": sealedMetadata,
+	})
+	if err != nil {
+		return fmt.Errorf("marshaling update: %w", err)
+	}
+
+	req, err := http.NewRequest("PUT", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Delete removes a memory by ID from collection; empty means the
+// primary collection
+func (s *Store) Delete(collection, memoryID string) error {
+	baseURL, apiKey := s.endpoint(s.collectionOrPrimary(collection))
+	url := fmt.Sprintf("%s/v1/memories/%s/", baseURL, memoryID)
 
 	req, err := http.NewRequest("DELETE", url, nil)
 	if err != nil {
 		return fmt.Errorf("creating request: %w", err)
 	}
 
-	if s.config.APIKey != "" {
-		req.Header.Set("Authorization", "Bearer "+s.config.APIKey)
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
 	}
 
 	resp, err := s.httpClient.Do(req)
@@ -234,6 +433,9 @@ func (s *Store) Delete(memoryID string) error {
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
 		return fmt.Errorf("unexpected status: %d", resp.StatusCode)
 	}
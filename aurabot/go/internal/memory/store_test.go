@@ -1,10 +1,20 @@
 package memory
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"screen-memory-assistant/internal/config"
+	"screen-memory-assistant/internal/crypt"
 )
 
 func TestNewStore(t *testing.T) {
@@ -106,3 +116,262 @@ func TestStore_buildPayload(t *testing.T) {
 		t.Error("CollectionName not set correctly")
 	}
 }
+
+func TestSearch_ResponseSizeCap(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		results := make([]map[string]interface{}, 200)
+		for i := range results {
+			results[i] = map[string]interface{}{"id": fmt.Sprint(i), "memory": strings.Repeat("x", 1000)}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+	}))
+	defer server.Close()
+
+	store := NewStore(&config.MemoryConfig{BaseURL: server.URL, MaxResponseBytes: 64 << 10})
+	_, err := store.Search("query", 200)
+	if !errors.Is(err, ErrResponseTooLarge) {
+		t.Fatalf("Search error = %v, want ErrResponseTooLarge", err)
+	}
+
+	store = NewStore(&config.MemoryConfig{BaseURL: server.URL, MaxResponseBytes: 1 << 20})
+	results, err := store.Search("query", 200)
+	if err != nil {
+		t.Fatalf("Search under the cap failed: %v", err)
+	}
+	if len(results) != 200 {
+		t.Errorf("got %d results, want 200", len(results))
+	}
+}
+
+func TestGetRecent_ResponseShapes(t *testing.T) {
+	bodies := map[string]string{
+		"wrapped": `{"results": [
+			{"id": "m1", "memory": "Reviewing the Q3 budget", "user_id": "u1",
+			 "metadata": {"context": "work"}, "created_at": "2024-05-17T09:30:00Z"}
+		]}`,
+		"bare": `[{"id": "m1", "content": "Reviewing the Q3 budget", "user_id": "u1",
+			"metadata": {"context": "work"}, "created_at": "2024-05-17T09:30:00Z"}]`,
+	}
+
+	for name, body := range bodies {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(body))
+		}))
+
+		store := NewStore(&config.MemoryConfig{BaseURL: server.URL, UserID: "u1"})
+		memories, err := store.GetRecent(5)
+		server.Close()
+		if err != nil {
+			t.Fatalf("%s: GetRecent failed: %v", name, err)
+		}
+		if len(memories) != 1 {
+			t.Fatalf("%s: got %d memories, want 1", name, len(memories))
+		}
+		m := memories[0]
+		if m.ID != "m1" || m.Content != "Reviewing the Q3 budget" || m.Metadata.Context != "work" {
+			t.Errorf("%s: memory = %+v", name, m)
+		}
+		if want := time.Date(2024, 5, 17, 9, 30, 0, 0, time.UTC); !m.CreatedAt.Equal(want) {
+			t.Errorf("%s: created at %v, want %v", name, m.CreatedAt, want)
+		}
+	}
+}
+
+func TestSearch_LogsBodyOnlyWhenEnabled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"results": []map[string]interface{}{{"id": "m1", "memory": "bank statement"}},
+		})
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		level        slog.Level
+		logResponses bool
+		wantLogged   bool
+	}{
+		{slog.LevelDebug, false, false},
+		{slog.LevelInfo, true, false},
+		{slog.LevelDebug, true, true},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		store := NewStore(&config.MemoryConfig{BaseURL: server.URL, LogResponses: tt.logResponses})
+		store.SetLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: tt.level})))
+
+		results, err := store.Search("q", 5)
+		if err != nil {
+			t.Fatalf("%+v: Search failed: %v", tt, err)
+		}
+		if len(results) != 1 || results[0].Memory.Content != "bank statement" {
+			t.Errorf("%+v: results = %+v", tt, results)
+		}
+		if logged := strings.Contains(buf.String(), "bank statement"); logged != tt.wantLogged {
+			t.Errorf("%+v: body logged = %v: %s", tt, logged, buf.String())
+		}
+	}
+}
+
+func TestStore_EncryptsContent(t *testing.T) {
+	var stored []string
+	var infer interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/v1/memories/" && r.Method == "POST":
+			var payload struct {
+				Messages []map[string]string    `json:"messages"`
+				Infer    interface{}            `json:"infer"`
+				Metadata map[string]interface{} `json:"metadata"`
+			}
+			json.NewDecoder(r.Body).Decode(&payload)
+			stored = append(stored, payload.Messages[0]["content"])
+			infer = payload.Infer
+			w.Write([]byte(`{}`))
+		case r.URL.Path == "/v1/memories/search/":
+			var results []map[string]interface{}
+			for i, content := range stored {
+				results = append(results, map[string]interface{}{"id": fmt.Sprint(i), "memory": content})
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+		default:
+			var listing []map[string]interface{}
+			for i, content := range stored {
+				listing = append(listing, map[string]interface{}{"id": fmt.Sprint(i), "memory": content})
+			}
+			json.NewEncoder(w).Encode(listing)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.MemoryConfig{BaseURL: server.URL, UserID: "u", CollectionName: "c", EncryptionPassphrase: "correct horse"}
+	store := NewStore(cfg)
+	store.SetLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if store.Cipher() == nil {
+		t.Fatal("no cipher derived from the passphrase")
+	}
+
+	m, err := store.Add("password: hunter2", Metadata{Context: "work"})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if m.Content != "password: hunter2" {
+		t.Errorf("returned content = %q, want plaintext", m.Content)
+	}
+	if strings.Contains(stored[0], "hunter2") || !strings.HasPrefix(stored[0], "enc:") {
+		t.Errorf("backend received %q, want ciphertext", stored[0])
+	}
+	if infer != false {
+		t.Errorf("infer = %v, want false so ciphertext is kept verbatim", infer)
+	}
+
+	// Plaintext from before encryption is still read; content sealed under
+	// another passphrase is skipped
+	stored = append(stored, "Editing main.go")
+	other := NewStore(&config.MemoryConfig{BaseURL: server.URL, UserID: "u", CollectionName: "c", EncryptionPassphrase: "battery staple"})
+	foreign, _ := other.seal("someone else's secret")
+	stored = append(stored, foreign)
+
+	results, err := store.Search("password", 10)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	var contents []string
+	for _, r := range results {
+		contents = append(contents, r.Memory.Content)
+	}
+	if len(contents) != 2 || contents[0] != "password: hunter2" || contents[1] != "Editing main.go" {
+		t.Errorf("search contents = %q, want the decrypted and the plaintext memory", contents)
+	}
+
+	recent, err := store.GetRecent(10)
+	if err != nil {
+		t.Fatalf("GetRecent: %v", err)
+	}
+	if len(recent) != 2 || recent[0].Content != "password: hunter2" {
+		t.Errorf("recent = %+v, want the two readable memories decrypted", recent)
+	}
+}
+
+func TestStore_EncryptsMetadata(t *testing.T) {
+	var stored json.RawMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == "POST" && r.URL.Path == "/v1/memories/" {
+			var payload struct {
+				Metadata json.RawMessage `json:"metadata"`
+			}
+			json.NewDecoder(r.Body).Decode(&payload)
+			stored = payload.Metadata
+			w.Write([]byte(`{}`))
+			return
+		}
+		fmt.Fprintf(w, `{"results":[{"id":"1","memory":"x","metadata":%s}]}`, stored)
+	}))
+	defer server.Close()
+
+	store := NewStore(&config.MemoryConfig{BaseURL: server.URL, UserID: "u", CollectionName: "c", EncryptionPassphrase: "correct horse"})
+	metadata := Metadata{
+		Timestamp:   "2026-10-18T09:00:00Z",
+		Source:      SourceCapture,
+		Context:     "banking",
+		Activities:  []string{"paying rent"},
+		OCRText:     "PIN 4321",
+		WindowTitle: "Chat with Alex",
+		Extra:       map[string]interface{}{"account": "12-3456"},
+	}
+	if _, err := store.Add("paid rent", metadata); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	for _, secret := range []string{"banking", "paying rent", "4321", "Alex", "12-3456"} {
+		if strings.Contains(string(stored), secret) {
+			t.Errorf("backend metadata contains %q in the clear: %s", secret, stored)
+		}
+	}
+	for _, clear := range []string{metadata.Timestamp, SourceCapture} {
+		if !strings.Contains(string(stored), clear) {
+			t.Errorf("backend metadata lacks %q, want it readable for filtering: %s", clear, stored)
+		}
+	}
+
+	results, err := store.Search("rent", 1)
+	if err != nil || len(results) != 1 {
+		t.Fatalf("Search = %v, %v", results, err)
+	}
+	got := results[0].Memory.Metadata
+	if got.Context != "banking" || got.OCRText != "PIN 4321" || got.WindowTitle != "Chat with Alex" ||
+		len(got.Activities) != 1 || got.Activities[0] != "paying rent" || got.Extra["account"] != "12-3456" {
+		t.Errorf("metadata = %+v, want it decrypted", got)
+	}
+}
+
+func TestEncryption_NoCipherRefusesPlaintext(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	derive := deriveCipher
+	deriveCipher = func(passphrase, salt string) (*crypt.Cipher, error) { return nil, errors.New("no entropy") }
+	defer func() { deriveCipher = derive }()
+
+	cfg := &config.MemoryConfig{BaseURL: server.URL, UserID: "u", EncryptionPassphrase: "correct horse"}
+	if _, err := Open(cfg); err == nil {
+		t.Error("Open succeeded without the configured cipher")
+	}
+
+	store := NewStore(cfg)
+	if _, err := store.Add("password: hunter2", Metadata{}); err == nil {
+		t.Error("Add succeeded without the configured cipher")
+	}
+	if err := store.Update("", "m1", "password: hunter2", Metadata{}); err == nil {
+		t.Error("Update succeeded without the configured cipher")
+	}
+	if requests != 0 {
+		t.Errorf("backend received %d requests, want none", requests)
+	}
+}
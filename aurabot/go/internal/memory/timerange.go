@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rangeOverfetch is how many extra results are requested from Mem0 when
+// filtering client-side, since the backend can't filter by date or source
+const rangeOverfetch = 5
+
+// SearchRange retrieves relevant memories captured within [start, end)
+func (s *Store) SearchRange(query string, start, end time.Time, limit int) ([]SearchResult, error) {
+	return s.SearchRangeSource(query, "", start, end, limit)
+}
+
+// SearchRangeSource retrieves relevant memories created by source and
+// captured within [start, end). An empty source matches every memory.
+func (s *Store) SearchRangeSource(query, source string, start, end time.Time, limit int) ([]SearchResult, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+
+	results, err := s.Search(query, limit*rangeOverfetch)
+	if err != nil {
+		return nil, err
+	}
+
+	var filtered []SearchResult
+	for _, r := range results {
+		if source != "" && r.Memory.Source() != source {
+			continue
+		}
+		t := r.Memory.Time()
+		if t.IsZero() || t.Before(start) || !t.Before(end) {
+			continue
+		}
+		filtered = append(filtered, r)
+		if len(filtered) >= limit {
+			break
+		}
+	}
+
+	return filtered, nil
+}
+
+// SearchRelative retrieves relevant memories within a natural time window
+// such as "today", "yesterday", "this week" or "last 7 days", optionally
+// restricted to a source
+func (s *Store) SearchRelative(query, source, window string, limit int) ([]SearchResult, error) {
+	start, end, err := RelativeWindow(window, s.now())
+	if err != nil {
+		return nil, err
+	}
+	return s.SearchRangeSource(query, source, start, end, limit)
+}
+
+// Time returns when the memory was captured, falling back to its creation time
+func (m Memory) Time() time.Time {
+	if t := parseTime(m.Metadata.Timestamp); !t.IsZero() {
+		return t
+	}
+	return m.CreatedAt
+}
+
+// RelativeWindow converts a phrase like "today" or "last 3 days" into
+// concrete [start, end) bounds relative to now
+func RelativeWindow(window string, now time.Time) (time.Time, time.Time, error) {
+	phrase := strings.ToLower(strings.Join(strings.Fields(window), " "))
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch phrase {
+	case "today":
+		return midnight, midnight.AddDate(0, 0, 1), nil
+	case "yesterday":
+		return midnight.AddDate(0, 0, -1), midnight, nil
+	case "this week":
+		// Weeks start on Monday
+		offset := (int(now.Weekday()) + 6) % 7
+		thisWeek := midnight.AddDate(0, 0, -offset)
+		return thisWeek, thisWeek.AddDate(0, 0, 7), nil
+	case "last week":
+		offset := (int(now.Weekday()) + 6) % 7
+		thisWeek := midnight.AddDate(0, 0, -offset)
+		return thisWeek.AddDate(0, 0, -7), thisWeek, nil
+	case "this month":
+		thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		return thisMonth, thisMonth.AddDate(0, 1, 0), nil
+	}
+
+	// "last N hours" / "last N days" / "last N weeks"
+	parts := strings.Fields(phrase)
+	if len(parts) == 3 && (parts[0] == "last" || parts[0] == "past") {
+		n, err := strconv.Atoi(parts[1])
+		if err != nil || n <= 0 {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid window %q", window)
+		}
+		switch strings.TrimSuffix(parts[2], "s") {
+		case "hour":
+			return now.Add(-time.Duration(n) * time.Hour), now, nil
+		case "day":
+			return now.AddDate(0, 0, -n), now, nil
+		case "week":
+			return now.AddDate(0, 0, -7*n), now, nil
+		}
+	}
+
+	return time.Time{}, time.Time{}, fmt.Errorf("unsupported window %q", window)
+}
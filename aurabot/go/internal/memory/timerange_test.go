@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"screen-memory-assistant/internal/config"
+)
+
+func TestRelativeWindow(t *testing.T) {
+	// Wednesday afternoon
+	now := time.Date(2024, 5, 15, 14, 30, 0, 0, time.UTC)
+	day := func(d int) time.Time { return time.Date(2024, 5, d, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		window string
+		start  time.Time
+		end    time.Time
+	}{
+		{"today", day(15), day(16)},
+		{"Yesterday", day(14), day(15)},
+		{"this week", day(13), day(20)},
+		{"last week", day(6), day(13)},
+		{"this month", day(1), time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{"last 7 days", now.AddDate(0, 0, -7), now},
+		{"past 1 day", now.AddDate(0, 0, -1), now},
+		{"last 3 hours", now.Add(-3 * time.Hour), now},
+		{"last 2 weeks", now.AddDate(0, 0, -14), now},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.window, func(t *testing.T) {
+			start, end, err := RelativeWindow(tt.window, now)
+			if err != nil {
+				t.Fatalf("RelativeWindow(%q) error: %v", tt.window, err)
+			}
+			if !start.Equal(tt.start) || !end.Equal(tt.end) {
+				t.Errorf("RelativeWindow(%q) = [%v, %v), want [%v, %v)", tt.window, start, end, tt.start, tt.end)
+			}
+		})
+	}
+
+	for _, bad := range []string{"", "soon", "last zero days", "last 3 fortnights"} {
+		if _, _, err := RelativeWindow(bad, now); err == nil {
+			t.Errorf("RelativeWindow(%q) expected error", bad)
+		}
+	}
+}
+
+func TestSearchRelative_FiltersByWindow(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"results": []map[string]interface{}{
+				{"id": "today", "memory": "a", "metadata": map[string]string{"timestamp": "2024-05-15T09:00:00Z"}},
+				{"id": "yesterday", "memory": "b", "metadata": map[string]string{"timestamp": "2024-05-14T09:00:00Z"}},
+				{"id": "undated", "memory": "c"},
+			},
+		})
+	}))
+	defer server.Close()
+
+	store := NewStore(&config.MemoryConfig{BaseURL: server.URL})
+	store.now = func() time.Time { return time.Date(2024, 5, 15, 14, 30, 0, 0, time.UTC) }
+
+	results, err := store.SearchRelative("anything", "", "yesterday", 5)
+	if err != nil {
+		t.Fatalf("SearchRelative failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Memory.ID != "yesterday" {
+		t.Errorf("results = %+v, want only 'yesterday'", results)
+	}
+}
@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"screen-memory-assistant/internal/ratelog"
+)
+
+// Fallbacks for memories without a usable time, as used in
+// MemoryConfig.UnknownTimeFallback
+const (
+	// TimeFallbackZero leaves the time unknown: no recency credit, and the
+	// memory is left out of date filters
+	TimeFallbackZero = "zero"
+	// TimeFallbackNow treats the memory as created when it was read
+	TimeFallbackNow = "now"
+)
+
+// timeLayouts are tried in order; the zone-less ones (as some Mem0
+// versions return) are read as UTC
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// timeWarnings keeps a backend with a broken timestamp format from
+// flooding the log
+var timeWarnings = ratelog.New(time.Minute)
+
+// parseTime parses an ISO8601 timestamp, with or without a zone, or Unix
+// seconds or milliseconds. Unparseable values are logged and give the
+// zero time.
+func parseTime(s string) time.Time {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}
+	}
+
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+
+	if n, err := strconv.ParseFloat(s, 64); err == nil && n > 0 {
+		// Millisecond timestamps are past the year 33658 in seconds
+		if n >= 1e12 {
+			n /= 1000
+		}
+		sec := int64(n)
+		return time.Unix(sec, int64((n-float64(sec))*1e9)).UTC()
+	}
+
+	timeWarnings.Printf("[Memory] Unrecognized timestamp %q, treating it as unknown", s)
+	return time.Time{}
+}
+
+// UnmarshalJSON decodes a memory, accepting created_at in any format
+// parseTime understands
+func (m *Memory) UnmarshalJSON(data []byte) error {
+	type plain Memory
+	var raw struct {
+		*plain
+		CreatedAt json.RawMessage `json:"created_at"`
+		// Mem0 names the content "memory"
+		Text string `json:"memory"`
+	}
+	raw.plain = (*plain)(m)
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if m.Content == "" {
+		m.Content = raw.Text
+	}
+
+	m.CreatedAt = time.Time{}
+	if len(raw.CreatedAt) > 0 && string(raw.CreatedAt) != "null" {
+		var s string
+		if err := json.Unmarshal(raw.CreatedAt, &s); err != nil {
+			// Numbers are Unix timestamps
+			s = string(raw.CreatedAt)
+		}
+		m.CreatedAt = parseTime(s)
+	}
+	return nil
+}
+
+// withKnownTime applies the configured fallback to a memory with no
+// creation time
+func (s *Store) withKnownTime(m Memory) Memory {
+	if m.CreatedAt.IsZero() && s.config.UnknownTimeFallback == TimeFallbackNow {
+		m.CreatedAt = s.now()
+	}
+	return m
+}
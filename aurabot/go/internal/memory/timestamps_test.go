@@ -0,0 +1,88 @@
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"screen-memory-assistant/internal/config"
+)
+
+func TestParseTime(t *testing.T) {
+	want := time.Date(2024, 5, 15, 14, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		input string
+		want  time.Time
+	}{
+		{"2024-05-15T14:30:00Z", want},
+		{"2024-05-15T16:30:00+02:00", want},
+		{"2024-05-15T14:30:00.250Z", want.Add(250 * time.Millisecond)},
+		{"2024-05-15T14:30:00.123456", want.Add(123456 * time.Microsecond)},
+		{"2024-05-15 14:30:00", want},
+		{"1715783400", want},
+		{"1715783400000", want},
+		{"1715783400.5", want.Add(500 * time.Millisecond)},
+		{"", time.Time{}},
+		{"yesterday-ish", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		if got := parseTime(tt.input); !got.Equal(tt.want) {
+			t.Errorf("parseTime(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestMemory_UnmarshalCreatedAt(t *testing.T) {
+	want := time.Date(2024, 5, 15, 14, 30, 0, 0, time.UTC)
+
+	for _, raw := range []string{`"2024-05-15T14:30:00"`, `1715783400`, `"garbage"`, `null`} {
+		var m Memory
+		data := fmt.Sprintf(`{"id":"m1","content":"hello","created_at":%s}`, raw)
+		if err := json.Unmarshal([]byte(data), &m); err != nil {
+			t.Fatalf("Unmarshal(%s) failed: %v", raw, err)
+		}
+		if m.ID != "m1" || m.Content != "hello" {
+			t.Errorf("Unmarshal(%s) lost fields: %+v", raw, m)
+		}
+		known := raw != `"garbage"` && raw != `null`
+		if known && !m.CreatedAt.Equal(want) {
+			t.Errorf("Unmarshal(%s) CreatedAt = %v, want %v", raw, m.CreatedAt, want)
+		}
+		if !known && !m.CreatedAt.IsZero() {
+			t.Errorf("Unmarshal(%s) CreatedAt = %v, want zero", raw, m.CreatedAt)
+		}
+	}
+}
+
+func TestSearch_UnknownTimeFallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"results":[{"id":"m1","memory":"hello","created_at":"not a time"}]}`)
+	}))
+	defer server.Close()
+
+	now := time.Date(2024, 5, 15, 14, 30, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		fallback string
+		want     time.Time
+	}{
+		{TimeFallbackZero, time.Time{}},
+		{TimeFallbackNow, now},
+	} {
+		store := NewStore(&config.MemoryConfig{BaseURL: server.URL, UnknownTimeFallback: tt.fallback})
+		store.now = func() time.Time { return now }
+
+		results, err := store.Search("hello", 5)
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if len(results) != 1 || !results[0].Memory.CreatedAt.Equal(tt.want) {
+			t.Errorf("fallback %q: results = %+v, want CreatedAt %v", tt.fallback, results, tt.want)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+//go:build !windows
+
+package overlay
+
+import "screen-memory-assistant/internal/config"
+
+// Overlay is a no-op off Windows, where the floating button isn't
+// implemented
+type Overlay struct{}
+
+// NewOverlay creates an overlay that never shows
+func NewOverlay(onClick func(), cfg config.OverlayConfig) (*Overlay, error) {
+	return &Overlay{}, nil
+}
+
+// Start does nothing
+func (o *Overlay) Start() error { return nil }
+
+// Close does nothing
+func (o *Overlay) Close() {}
+
+// Stop does nothing
+func (o *Overlay) Stop() {}
+
+// Show does nothing
+func (o *Overlay) Show(x, y int) {}
+
+// Hide does nothing
+func (o *Overlay) Hide() {}
+
+// IsVisible always reports false
+func (o *Overlay) IsVisible() bool { return false }
+
+// SetOnClick does nothing; the button is never clicked
+func (o *Overlay) SetOnClick(handler func()) {}
@@ -0,0 +1,517 @@
+package overlay
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"screen-memory-assistant/internal/config"
+)
+
+// Point represents a point
+type Point struct {
+	X int32
+	Y int32
+}
+
+// Rect represents a rectangle
+type Rect struct {
+	Left   int32
+	Top    int32
+	Right  int32
+	Bottom int32
+}
+
+// Overlay creates a system-wide floating button that appears near text selections
+type Overlay struct {
+	hwnd    uintptr
+	visible bool
+	mu      sync.RWMutex
+	lifeMu  sync.Mutex // serializes Start and Close
+	onClick func()
+	cancel  context.CancelFunc
+	lastPos Point
+	cfg     config.OverlayConfig
+	scale   func(v int) int32 // logical pixels to the window's DPI
+	done    chan struct{}     // closed once the window thread has cleaned up
+}
+
+const (
+	wsExToolWindow = 0x00000080
+	wsExNoActivate = 0x08000000
+	wsExTopMost    = 0x00000008
+	wsExLayered    = 0x00080000
+	wsPopup        = 0x80000000
+	wsVisible      = 0x10000000
+	cwUseDefault   = 0x80000000
+	swShow         = 5
+	swHide         = 0
+	wmPaint        = 0x000F
+	wmClose        = 0x0010
+	wmLButtonUp    = 0x0202
+	colorWindow    = 5
+)
+
+var (
+	user32DLL                      = windows.NewLazySystemDLL("user32.dll")
+	kernel32DLL                    = windows.NewLazySystemDLL("kernel32.dll")
+	gdi32DLL                       = windows.NewLazySystemDLL("gdi32.dll")
+	procCreateWindowEx             = user32DLL.NewProc("CreateWindowExW")
+	procShowWindow                 = user32DLL.NewProc("ShowWindow")
+	procUpdateWindow               = user32DLL.NewProc("UpdateWindow")
+	procDefWindowProc              = user32DLL.NewProc("DefWindowProcW")
+	procPeekMessage                = user32DLL.NewProc("PeekMessageW")
+	procTranslateMessage           = user32DLL.NewProc("TranslateMessage")
+	procDispatchMessage            = user32DLL.NewProc("DispatchMessageW")
+	procGetCursorPos               = user32DLL.NewProc("GetCursorPos")
+	procSetWindowPos               = user32DLL.NewProc("SetWindowPos")
+	procInvalidateRect             = user32DLL.NewProc("InvalidateRect")
+	procBeginPaint                 = user32DLL.NewProc("BeginPaint")
+	procEndPaint                   = user32DLL.NewProc("EndPaint")
+	procFillRect                   = user32DLL.NewProc("FillRect")
+	procCreateSolidBrush           = gdi32DLL.NewProc("CreateSolidBrush")
+	procDeleteObject               = gdi32DLL.NewProc("DeleteObject")
+	procSetLayeredWindowAttributes = user32DLL.NewProc("SetLayeredWindowAttributes")
+	procGetModuleHandle            = kernel32DLL.NewProc("GetModuleHandleW")
+	procGetSysColorBrush           = user32DLL.NewProc("GetSysColorBrush")
+	procRegisterClassEx            = user32DLL.NewProc("RegisterClassExW")
+	procPostMessage                = user32DLL.NewProc("PostMessageW")
+	procDestroyWindow              = user32DLL.NewProc("DestroyWindow")
+	procUnregisterClass            = user32DLL.NewProc("UnregisterClassW")
+	procGetDpiForWindow            = user32DLL.NewProc("GetDpiForWindow")
+)
+
+// Defaults for unset OverlayConfig fields, in pixels at 96 DPI
+const (
+	defaultHideRadius = 100
+	defaultOffset     = 10
+	defaultButtonSize = 48
+	baseDPI           = 96
+)
+
+const overlayClassName = "AuraBotOverlay"
+
+// The window procedure callback is created once: callbacks made by
+// windows.NewCallback are never freed, so a new one per Start would leak.
+// It dispatches to the overlay that owns the window.
+var (
+	wndProcOnce sync.Once
+	wndProcPtr  uintptr
+	overlaysMu  sync.RWMutex
+	overlays    = make(map[uintptr]*Overlay)
+)
+
+func sharedWndProc() uintptr {
+	wndProcOnce.Do(func() {
+		wndProcPtr = windows.NewCallback(func(hwnd uintptr, msg uint32, wParam uintptr, lParam uintptr) uintptr {
+			overlaysMu.RLock()
+			o := overlays[hwnd]
+			overlaysMu.RUnlock()
+			if o != nil {
+				return o.windowProc(hwnd, msg, wParam, lParam)
+			}
+			ret, _, _ := procDefWindowProc.Call(hwnd, uintptr(msg), wParam, lParam)
+			return ret
+		})
+	})
+	return wndProcPtr
+}
+
+// WndClassEx structure
+type WndClassEx struct {
+	CbSize        uint32
+	Style         uint32
+	LpfnWndProc   uintptr
+	CbClsExtra    int32
+	CbWndExtra    int32
+	HInstance     uintptr
+	HIcon         uintptr
+	HCursor       uintptr
+	HbrBackground uintptr
+	LpszMenuName  *uint16
+	LpszClassName *uint16
+	HIconSm       uintptr
+}
+
+// PaintStruct structure
+type PaintStruct struct {
+	Hdc         uintptr
+	FErase      int32
+	RcPaint     Rect
+	FRestore    int32
+	FIncUpdate  int32
+	RgbReserved [32]byte
+}
+
+// Msg structure
+type Msg struct {
+	Hwnd    uintptr
+	Message uint32
+	WParam  uintptr
+	LParam  uintptr
+	Time    uint32
+	Pt      Point
+}
+
+// NewOverlay creates a new system overlay placed by cfg. Zero fields use
+// the defaults (the offsets only when both are zero).
+func NewOverlay(onClick func(), cfg config.OverlayConfig) (*Overlay, error) {
+	if cfg.HideRadius <= 0 {
+		cfg.HideRadius = defaultHideRadius
+	}
+	if cfg.OffsetX == 0 && cfg.OffsetY == 0 {
+		cfg.OffsetX, cfg.OffsetY = defaultOffset, defaultOffset
+	}
+	if cfg.ButtonSize <= 0 {
+		cfg.ButtonSize = defaultButtonSize
+	}
+	return &Overlay{
+		onClick: onClick,
+		cfg:     cfg,
+		scale:   func(v int) int32 { return int32(v) },
+	}, nil
+}
+
+// dpiScale returns a function scaling 96-DPI pixels to the window's DPI.
+// GetDpiForWindow needs Windows 10 1607; older systems stay unscaled.
+func dpiScale(hwnd uintptr) func(v int) int32 {
+	dpi := uintptr(baseDPI)
+	if procGetDpiForWindow.Find() == nil {
+		if d, _, _ := procGetDpiForWindow.Call(hwnd); d != 0 {
+			dpi = d
+		}
+	}
+	return func(v int) int32 { return int32(v * int(dpi) / baseDPI) }
+}
+
+// size returns the button's size in pixels at the window's DPI
+func (o *Overlay) size() int32 {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.scale(o.cfg.ButtonSize)
+}
+
+// Start creates the overlay window and starts its message loop. Calling
+// it again while running is a no-op; after Close it creates a new window.
+func (o *Overlay) Start() error {
+	o.lifeMu.Lock()
+	defer o.lifeMu.Unlock()
+	if o.done != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	ready := make(chan error, 1)
+	go o.windowThread(ctx, ready, done)
+	if err := <-ready; err != nil {
+		cancel()
+		return fmt.Errorf("failed to create overlay window: %w", err)
+	}
+
+	o.cancel, o.done = cancel, done
+	go o.positionTracker(ctx)
+
+	log.Println("[Overlay] System overlay started")
+	return nil
+}
+
+// Close destroys the overlay window and unregisters its class, waiting
+// for the window thread to finish. The overlay can be started again.
+func (o *Overlay) Close() {
+	o.lifeMu.Lock()
+	defer o.lifeMu.Unlock()
+	cancel, done := o.cancel, o.done
+	o.cancel, o.done = nil, nil
+	if done == nil {
+		return
+	}
+
+	cancel()
+	<-done
+
+	o.mu.Lock()
+	o.visible = false
+	o.mu.Unlock()
+}
+
+// Stop closes the overlay; see Close
+func (o *Overlay) Stop() {
+	o.Close()
+}
+
+// windowThread owns the overlay window: windows belong to the thread that
+// created them, so creation, the message loop and DestroyWindow all run
+// here on one locked OS thread.
+func (o *Overlay) windowThread(ctx context.Context, ready chan<- error, done chan<- struct{}) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if err := o.createWindow(); err != nil {
+		close(done)
+		ready <- err
+		return
+	}
+	ready <- nil
+
+	o.messageLoop(ctx)
+	o.destroyWindow()
+	close(done)
+}
+
+// createWindow creates the overlay window
+func (o *Overlay) createWindow() error {
+	className, _ := windows.UTF16PtrFromString(overlayClassName)
+	windowName, _ := windows.UTF16PtrFromString("AuraBot Overlay")
+
+	// Get module handle
+	modHandle, _, _ := procGetModuleHandle.Call(0)
+
+	// Register window class
+	var wc WndClassEx
+	wc.CbSize = uint32(unsafe.Sizeof(wc))
+	wc.LpfnWndProc = sharedWndProc()
+	wc.HInstance = modHandle
+	wc.HbrBackground, _, _ = procGetSysColorBrush.Call(colorWindow)
+	wc.LpszClassName = className
+
+	// Fails harmlessly if the class is still registered by another overlay
+	procRegisterClassEx.Call(uintptr(unsafe.Pointer(&wc)))
+
+	// Create layered, transparent, topmost window (square button)
+	ret, _, err := procCreateWindowEx.Call(
+		uintptr(wsExToolWindow|wsExNoActivate|wsExTopMost|wsExLayered),
+		uintptr(unsafe.Pointer(className)),
+		uintptr(unsafe.Pointer(windowName)),
+		uintptr(wsPopup),
+		uintptr(cwUseDefault),
+		uintptr(cwUseDefault),
+		uintptr(o.cfg.ButtonSize),
+		uintptr(o.cfg.ButtonSize),
+		uintptr(0),
+		uintptr(0),
+		modHandle,
+		uintptr(unsafe.Pointer(o)),
+	)
+
+	if ret == 0 {
+		return fmt.Errorf("CreateWindowEx failed: %v", err)
+	}
+
+	o.mu.Lock()
+	o.hwnd = ret
+	o.scale = dpiScale(ret)
+	o.mu.Unlock()
+	overlaysMu.Lock()
+	overlays[ret] = o
+	overlaysMu.Unlock()
+
+	// Make window fully transparent background with opaque content
+	procSetLayeredWindowAttributes.Call(
+		ret,
+		0,
+		255,
+		0x00000001, // LWA_ALPHA
+	)
+
+	return nil
+}
+
+// destroyWindow destroys the window and unregisters the class; it must
+// run on the thread that created the window
+func (o *Overlay) destroyWindow() {
+	o.mu.Lock()
+	hwnd := o.hwnd
+	o.hwnd = 0
+	o.mu.Unlock()
+	if hwnd == 0 {
+		return
+	}
+
+	procDestroyWindow.Call(hwnd)
+	overlaysMu.Lock()
+	delete(overlays, hwnd)
+	overlaysMu.Unlock()
+
+	className, _ := windows.UTF16PtrFromString(overlayClassName)
+	modHandle, _, _ := procGetModuleHandle.Call(0)
+	procUnregisterClass.Call(uintptr(unsafe.Pointer(className)), modHandle)
+}
+
+// windowProc handles Windows messages
+func (o *Overlay) windowProc(hwnd uintptr, msg uint32, wParam uintptr, lParam uintptr) uintptr {
+	switch msg {
+	case wmPaint:
+		o.paint(hwnd)
+		return 0
+
+	case wmLButtonUp:
+		if o.onClick != nil {
+			go o.onClick()
+		}
+		return 0
+
+	case wmClose:
+		procShowWindow.Call(hwnd, uintptr(swHide))
+		o.mu.Lock()
+		o.visible = false
+		o.mu.Unlock()
+		return 0
+	}
+
+	ret, _, _ := procDefWindowProc.Call(
+		hwnd,
+		uintptr(msg),
+		wParam,
+		lParam,
+	)
+	return ret
+}
+
+// paint draws the floating button
+func (o *Overlay) paint(hwnd uintptr) {
+	var ps PaintStruct
+
+	procBeginPaint.Call(hwnd, uintptr(unsafe.Pointer(&ps)))
+	defer procEndPaint.Call(hwnd, uintptr(unsafe.Pointer(&ps)))
+
+	// Create gradient brush (purple - 0x8B5CF6)
+	brush, _, _ := procCreateSolidBrush.Call(0xF56E3C) // Orange-ish color for visibility
+	defer procDeleteObject.Call(brush)
+
+	// Fill entire window
+	size := o.size()
+	rect := Rect{Left: 0, Top: 0, Right: size, Bottom: size}
+	procFillRect.Call(ps.Hdc, uintptr(unsafe.Pointer(&rect)), brush)
+}
+
+// Show displays the overlay at the specified position
+func (o *Overlay) Show(x, y int) {
+	o.mu.RLock()
+	hwnd := o.hwnd
+	size := o.scale(o.cfg.ButtonSize)
+	offsetX, offsetY := o.scale(o.cfg.OffsetX), o.scale(o.cfg.OffsetY)
+	o.mu.RUnlock()
+	if hwnd == 0 {
+		return
+	}
+
+	// Offset so it doesn't cover the text
+	x += int(offsetX)
+	y += int(offsetY)
+
+	const (
+		swpShowWindow = 0x0040
+		swpNoActivate = 0x0010
+		hwndTopMost   = ^uintptr(0) // -1 as uintptr
+	)
+
+	procSetWindowPos.Call(
+		hwnd,
+		uintptr(hwndTopMost),
+		uintptr(x),
+		uintptr(y),
+		uintptr(size),
+		uintptr(size),
+		uintptr(swpShowWindow|swpNoActivate),
+	)
+
+	procShowWindow.Call(hwnd, uintptr(swShow))
+	procInvalidateRect.Call(hwnd, 0, 1)
+
+	o.mu.Lock()
+	o.visible = true
+	o.lastPos.X = int32(x)
+	o.lastPos.Y = int32(y)
+	o.mu.Unlock()
+}
+
+// Hide hides the overlay
+func (o *Overlay) Hide() {
+	o.mu.RLock()
+	hwnd := o.hwnd
+	o.mu.RUnlock()
+	if hwnd == 0 {
+		return
+	}
+
+	procShowWindow.Call(hwnd, uintptr(swHide))
+
+	o.mu.Lock()
+	o.visible = false
+	o.mu.Unlock()
+}
+
+// IsVisible returns whether the overlay is visible
+func (o *Overlay) IsVisible() bool {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.visible
+}
+
+// messageLoop runs the Windows message loop
+func (o *Overlay) messageLoop(ctx context.Context) {
+	var msg Msg
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		ret, _, _ := procPeekMessage.Call(
+			uintptr(unsafe.Pointer(&msg)),
+			0, 0, 0, 1,
+		)
+
+		if ret != 0 {
+			procTranslateMessage.Call(uintptr(unsafe.Pointer(&msg)))
+			procDispatchMessage.Call(uintptr(unsafe.Pointer(&msg)))
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// positionTracker tracks cursor position to auto-hide when cursor moves away
+func (o *Overlay) positionTracker(ctx context.Context) {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			o.mu.RLock()
+			visible := o.visible
+			lastX := o.lastPos.X
+			lastY := o.lastPos.Y
+			radius := o.scale(o.cfg.HideRadius)
+			o.mu.RUnlock()
+
+			if visible {
+				var pt Point
+				procGetCursorPos.Call(uintptr(unsafe.Pointer(&pt)))
+
+				// Hide if cursor moves far from button
+				dx := pt.X - lastX
+				dy := pt.Y - lastY
+				if dx*dx+dy*dy > radius*radius {
+					o.Hide()
+				}
+			}
+		}
+	}
+}
+
+// SetOnClick sets the click handler
+func (o *Overlay) SetOnClick(handler func()) {
+	o.onClick = handler
+}
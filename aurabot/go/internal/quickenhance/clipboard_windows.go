@@ -0,0 +1,268 @@
+package quickenhance
+
+import (
+	"context"
+	"image"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	procIsClipboardFormatAvailable = user32DLL.NewProc("IsClipboardFormatAvailable")
+	procGlobalSize                 = kernel32DLL.NewProc("GlobalSize")
+	procGetAsyncKeyState           = user32DLL.NewProc("GetAsyncKeyState")
+	procEnumClipboardFormats       = user32DLL.NewProc("EnumClipboardFormats")
+	procGetClipboardSequenceNumber = user32DLL.NewProc("GetClipboardSequenceNumber")
+)
+
+// Clipboard formats whose data is a GDI or owner handle rather than
+// global memory, so they can't be copied byte for byte. CF_BITMAP is
+// synthesized again from CF_DIB when the snapshot is restored.
+const (
+	cfMetafilePict    = 3
+	cfPalette         = 9
+	cfEnhMetafile     = 14
+	cfOwnerDisplay    = 0x0080
+	cfDspBitmap       = 0x0082
+	cfDspMetafilePict = 0x0083
+	cfDspEnhMetafile  = 0x008E
+	cfPrivateFirst    = 0x0200
+	cfGdiObjLast      = 0x03FF
+)
+
+// gmemMoveable allocates movable global memory, as SetClipboardData requires
+const gmemMoveable = 0x0002
+
+// copyableFormat reports whether a format's data is global memory
+func copyableFormat(format uint32) bool {
+	switch format {
+	case cfBitmap, cfMetafilePict, cfPalette, cfEnhMetafile,
+		cfOwnerDisplay, cfDspBitmap, cfDspMetafilePict, cfDspEnhMetafile:
+		return false
+	}
+	// Private and GDI object ranges hold handles the owner frees
+	return format < cfPrivateFirst || format > cfGdiObjLast
+}
+
+// vkEscape is the virtual-key code for the Escape key
+const vkEscape = 0x1B
+
+// winPlatform implements Platform using the Win32 clipboard API
+type winPlatform struct{}
+
+// GetClipboardText gets text from clipboard
+func (winPlatform) GetClipboardText() string {
+	// Open clipboard
+	ret, _, _ := procOpenClipboard.Call(0)
+	if ret == 0 {
+		return ""
+	}
+	defer procCloseClipboard.Call()
+
+	// Get clipboard data
+	handle, _, _ := procGetClipboardData.Call(cfUnicodeText)
+	if handle == 0 {
+		return ""
+	}
+
+	// Lock memory
+	ptr, _, _ := procGlobalLock.Call(handle)
+	if ptr == 0 {
+		return ""
+	}
+	defer procGlobalUnlock.Call(handle)
+
+	// Convert to Go string (UTF-16)
+	return windows.UTF16PtrToString((*uint16)(unsafe.Pointer(ptr)))
+}
+
+// SetClipboardText sets text to clipboard
+func (winPlatform) SetClipboardText(text string) bool {
+	// Open clipboard
+	ret, _, _ := procOpenClipboard.Call(0)
+	if ret == 0 {
+		return false
+	}
+	defer procCloseClipboard.Call()
+
+	// Empty clipboard
+	procEmptyClipboard.Call()
+
+	if text == "" {
+		return true
+	}
+
+	// Convert to UTF-16
+	utf16Text, err := windows.UTF16FromString(text)
+	if err != nil {
+		return false
+	}
+
+	// Calculate size
+	size := len(utf16Text) * 2
+
+	// Allocate global memory
+	hGlobal, _, _ := procGlobalAlloc.Call(0x0042, uintptr(size)) // GHND = 0x0042
+	if hGlobal == 0 {
+		return false
+	}
+
+	// Lock memory
+	ptr, _, _ := procGlobalLock.Call(hGlobal)
+	if ptr == 0 {
+		procGlobalFree.Call(hGlobal)
+		return false
+	}
+
+	// Copy data
+	procRtlMoveMemory.Call(ptr, uintptr(unsafe.Pointer(&utf16Text[0])), uintptr(size))
+	procGlobalUnlock.Call(hGlobal)
+
+	// Set clipboard data
+	ret, _, _ = procSetClipboardData.Call(cfUnicodeText, hGlobal)
+	return ret != 0
+}
+
+// SaveClipboard copies out every global-memory format on the clipboard
+func (winPlatform) SaveClipboard() (ClipboardSnapshot, bool) {
+	ret, _, _ := procOpenClipboard.Call(0)
+	if ret == 0 {
+		return nil, false
+	}
+	defer procCloseClipboard.Call()
+
+	var snapshot ClipboardSnapshot
+	format := uintptr(0)
+	for {
+		format, _, _ = procEnumClipboardFormats.Call(format)
+		if format == 0 {
+			break
+		}
+		if !copyableFormat(uint32(format)) {
+			continue
+		}
+
+		handle, _, _ := procGetClipboardData.Call(format)
+		if handle == 0 {
+			continue
+		}
+		size, _, _ := procGlobalSize.Call(handle)
+		ptr, _, _ := procGlobalLock.Call(handle)
+		if ptr == 0 {
+			continue
+		}
+		data := make([]byte, size)
+		if size > 0 {
+			procRtlMoveMemory.Call(uintptr(unsafe.Pointer(&data[0])), ptr, size)
+		}
+		procGlobalUnlock.Call(handle)
+
+		snapshot = append(snapshot, ClipboardFormat{Format: uint32(format), Data: data})
+	}
+	return snapshot, true
+}
+
+// RestoreClipboard empties the clipboard and puts back each saved format
+func (winPlatform) RestoreClipboard(snapshot ClipboardSnapshot) bool {
+	ret, _, _ := procOpenClipboard.Call(0)
+	if ret == 0 {
+		return false
+	}
+	defer procCloseClipboard.Call()
+
+	procEmptyClipboard.Call()
+
+	ok := true
+	for _, f := range snapshot {
+		hGlobal, _, _ := procGlobalAlloc.Call(gmemMoveable, uintptr(len(f.Data)))
+		if hGlobal == 0 {
+			ok = false
+			continue
+		}
+		if len(f.Data) > 0 {
+			ptr, _, _ := procGlobalLock.Call(hGlobal)
+			if ptr == 0 {
+				procGlobalFree.Call(hGlobal)
+				ok = false
+				continue
+			}
+			procRtlMoveMemory.Call(ptr, uintptr(unsafe.Pointer(&f.Data[0])), uintptr(len(f.Data)))
+			procGlobalUnlock.Call(hGlobal)
+		}
+
+		// On success the clipboard owns the memory
+		if ret, _, _ := procSetClipboardData.Call(uintptr(f.Format), hGlobal); ret == 0 {
+			procGlobalFree.Call(hGlobal)
+			ok = false
+		}
+	}
+	return ok
+}
+
+// ClipboardSequence returns the clipboard sequence number
+func (winPlatform) ClipboardSequence() uint32 {
+	seq, _, _ := procGetClipboardSequenceNumber.Call()
+	return uint32(seq)
+}
+
+// GetClipboardImage reads a bitmap from the clipboard. Windows synthesizes
+// CF_DIB from CF_BITMAP, so both formats are read through the DIB path.
+func (winPlatform) GetClipboardImage() (image.Image, bool) {
+	dib, _, _ := procIsClipboardFormatAvailable.Call(cfDIB)
+	bitmap, _, _ := procIsClipboardFormatAvailable.Call(cfBitmap)
+	if dib == 0 && bitmap == 0 {
+		return nil, false
+	}
+
+	ret, _, _ := procOpenClipboard.Call(0)
+	if ret == 0 {
+		return nil, false
+	}
+	defer procCloseClipboard.Call()
+
+	handle, _, _ := procGetClipboardData.Call(cfDIB)
+	if handle == 0 {
+		return nil, false
+	}
+
+	size, _, _ := procGlobalSize.Call(handle)
+	ptr, _, _ := procGlobalLock.Call(handle)
+	if ptr == 0 || size == 0 {
+		return nil, false
+	}
+	defer procGlobalUnlock.Call(handle)
+
+	// Copy out of the locked global memory before decoding
+	data := make([]byte, size)
+	procRtlMoveMemory.Call(uintptr(unsafe.Pointer(&data[0])), ptr, size)
+
+	img, err := decodeDIB(data)
+	if err != nil {
+		return nil, false
+	}
+	return img, true
+}
+
+// watchEscape cancels the in-flight enhancement when Escape is pressed
+func (q *QuickEnhance) watchEscape(ctx context.Context) {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	// Clear the "pressed since last call" bit from earlier key presses
+	procGetAsyncKeyState.Call(vkEscape)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			state, _, _ := procGetAsyncKeyState.Call(vkEscape)
+			if state&0x8000 != 0 {
+				q.CancelEnhancement()
+				return
+			}
+		}
+	}
+}
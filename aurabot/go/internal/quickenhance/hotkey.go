@@ -1,78 +1,134 @@
 package quickenhance
 
 import (
+	"bytes"
 	"context"
-	"runtime"
+	"image"
+	"image/jpeg"
+	"log"
 	"sync"
 	"time"
-	"unsafe"
 
-	"golang.org/x/sys/windows"
+	"screen-memory-assistant/internal/capture"
+	"screen-memory-assistant/internal/config"
 	"screen-memory-assistant/internal/enhancer"
+	"screen-memory-assistant/internal/llm"
 	"screen-memory-assistant/internal/overlay"
 )
 
 // QuickEnhance provides global hotkey functionality for text enhancement
 type QuickEnhance struct {
-	enhancer    *enhancer.Enhancer
-	overlay     *overlay.Overlay
-	ctx         context.Context
-	cancel      context.CancelFunc
-	running     bool
-	mu          sync.RWMutex
-	callback    func(text string)
-	hotkeyID    int
+	enhancer   Enhancer
+	overlay    *overlay.Overlay
+	overlayCfg config.OverlayConfig
+	ctx        context.Context
+	cancel     context.CancelFunc
+	running    bool
+	mu         sync.RWMutex
+	callback   func(text string)
+	hotkeyID   int
+
+	platform      Platform
+	analyzer      ImageAnalyzer
+	imageCallback func(result *llm.AnalysisResult)
+
+	// In-flight enhancement, cancellable by a second hotkey press or Escape
+	timeout        time.Duration
+	inFlightID     int
+	inFlightCancel context.CancelFunc
+
+	// Clipboard contents waiting to be restored after a copy or paste
+	pendingRestore *ClipboardSnapshot
+	restoreTimer   *time.Timer
+
+	// Whether the last selection used CRLF line endings, restored on paste
+	selectionCRLF bool
+
+	// Hotkeys tried in order until one registers, and the one that did
+	hotkeys    HotkeyConfig
+	registered string
+
+	// How long a simulated Ctrl+C may take to change the clipboard
+	copyTimeout time.Duration
 }
 
+// defaultEnhanceTimeout bounds a quick enhancement when none is configured
+const defaultEnhanceTimeout = 10 * time.Second
+
+// defaultCopyTimeout bounds the wait for a copy; apps with nothing
+// selected leave the clipboard alone, so this is also the empty case
+const defaultCopyTimeout = 500 * time.Millisecond
+
+// clipboardPollInterval is how often the clipboard sequence is checked
+const clipboardPollInterval = 5 * time.Millisecond
+
 // EnhancementResult is an alias to the enhancer package type
 type EnhancementResult = enhancer.EnhancementResult
 
 // MemoryInfo is an alias to the enhancer package type
 type MemoryInfo = enhancer.MemoryInfo
 
-// Windows API constants
-const (
-	modAlt         = 0x0001
-	modControl     = 0x0002
-	modShift       = 0x0004
-	modWin         = 0x0008
-	vkE            = 0x45
-	wmHotkey       = 0x0312
-	cfUnicodeText  = 13
-)
-
-var (
-	user32DLL            = windows.NewLazySystemDLL("user32.dll")
-	kernel32DLL          = windows.NewLazySystemDLL("kernel32.dll")
-	procRegisterHotKey   = user32DLL.NewProc("RegisterHotKey")
-	procUnregisterHotKey = user32DLL.NewProc("UnregisterHotKey")
-	procPeekMessage      = user32DLL.NewProc("PeekMessageW")
-	procTranslateMessage = user32DLL.NewProc("TranslateMessage")
-	procDispatchMessage  = user32DLL.NewProc("DispatchMessageW")
-	procOpenClipboard    = user32DLL.NewProc("OpenClipboard")
-	procCloseClipboard   = user32DLL.NewProc("CloseClipboard")
-	procEmptyClipboard   = user32DLL.NewProc("EmptyClipboard")
-	procGetClipboardData = user32DLL.NewProc("GetClipboardData")
-	procSetClipboardData = user32DLL.NewProc("SetClipboardData")
-	procGlobalLock       = kernel32DLL.NewProc("GlobalLock")
-	procGlobalUnlock     = kernel32DLL.NewProc("GlobalUnlock")
-	procGlobalAlloc      = kernel32DLL.NewProc("GlobalAlloc")
-	procGlobalFree       = kernel32DLL.NewProc("GlobalFree")
-	procRtlMoveMemory    = kernel32DLL.NewProc("RtlMoveMemory")
-	procGetCursorPos     = user32DLL.NewProc("GetCursorPos")
-)
-
-// New creates a new QuickEnhance instance
-func New(enhancer *enhancer.Enhancer) *QuickEnhance {
+// New creates a new QuickEnhance instance registering one of hotkeys; with
+// none, DefaultHotkeys are tried
+func New(enhancer *enhancer.Enhancer, hotkeys HotkeyConfig) *QuickEnhance {
 	ctx, cancel := context.WithCancel(context.Background())
+	if len(hotkeys.Hotkeys) == 0 {
+		hotkeys.Hotkeys = DefaultHotkeyConfig().Hotkeys
+	}
 	return &QuickEnhance{
-		enhancer: enhancer,
-		ctx:      ctx,
-		cancel:   cancel,
-		hotkeyID: 1,
+		enhancer:    enhancer,
+		ctx:         ctx,
+		cancel:      cancel,
+		hotkeyID:    1,
+		platform:    newPlatform(),
+		timeout:     defaultEnhanceTimeout,
+		hotkeys:     hotkeys,
+		copyTimeout: defaultCopyTimeout,
 	}
 }
 
+// Hotkey returns the combination that registered, e.g. "Ctrl+Alt+E", or
+// "" while none is registered
+func (q *QuickEnhance) Hotkey() string {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.registered
+}
+
+// setRegistered records the registered hotkey's name; "" when none is
+func (q *QuickEnhance) setRegistered(name string) {
+	q.mu.Lock()
+	q.registered = name
+	q.mu.Unlock()
+}
+
+// SetTimeout sets how long a quick enhancement may run before it's abandoned
+func (q *QuickEnhance) SetTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = defaultEnhanceTimeout
+	}
+	q.mu.Lock()
+	q.timeout = timeout
+	q.mu.Unlock()
+}
+
+// SetOverlayConfig sets the floating button's placement, used from the
+// next Start
+func (q *QuickEnhance) SetOverlayConfig(cfg config.OverlayConfig) {
+	q.mu.Lock()
+	q.overlayCfg = cfg
+	q.mu.Unlock()
+}
+
+// SetImageAnalyzer enables analysis of images found on the clipboard.
+// The callback receives the analysis in place of the text callback.
+func (q *QuickEnhance) SetImageAnalyzer(analyzer ImageAnalyzer, callback func(result *llm.AnalysisResult)) {
+	q.mu.Lock()
+	q.analyzer = analyzer
+	q.imageCallback = callback
+	q.mu.Unlock()
+}
+
 // SetCallback sets the function to call when text is captured
 func (q *QuickEnhance) SetCallback(callback func(text string)) {
 	q.mu.Lock()
@@ -80,7 +136,8 @@ func (q *QuickEnhance) SetCallback(callback func(text string)) {
 	q.mu.Unlock()
 }
 
-// Start begins listening for the global hotkey and starts overlay
+// Start begins listening for the global hotkey and starts overlay. It can
+// be called again after Stop, or after a failed Start.
 func (q *QuickEnhance) Start() error {
 	q.mu.Lock()
 	if q.running {
@@ -88,34 +145,56 @@ func (q *QuickEnhance) Start() error {
 		return nil
 	}
 	q.running = true
+	// Stop cancels the context, so each start gets a fresh one
+	q.cancel()
+	ctx, cancel := context.WithCancel(context.Background())
+	q.ctx, q.cancel = ctx, cancel
+	overlayCfg := q.overlayCfg
 	q.mu.Unlock()
 
 	// Create and start overlay
-	ov, err := overlay.NewOverlay(q.handleOverlayClick)
+	ov, err := overlay.NewOverlay(q.handleOverlayClick, overlayCfg)
 	if err != nil {
+		q.setRunning(false)
 		return err
 	}
 	q.overlay = ov
-	
+
 	if err := ov.Start(); err != nil {
+		q.setRunning(false)
 		return err
 	}
 
-	// Start hotkey listener
-	go q.hotkeyListener()
+	// Start hotkey listener and wait for its registration result so a
+	// hotkey taken by another app is reported instead of failing silently
+	ready := make(chan error, 1)
+	go q.hotkeyListener(ctx, ready)
+	if err := <-ready; err != nil {
+		ov.Stop()
+		q.setRunning(false)
+		return err
+	}
 
 	return nil
 }
 
 // Stop stops the hotkey listener and overlay
 func (q *QuickEnhance) Stop() {
-	q.cancel()
+	q.mu.RLock()
+	cancel := q.cancel
+	q.mu.RUnlock()
+	cancel()
 	q.unregisterHotkey()
 	if q.overlay != nil {
 		q.overlay.Stop()
 	}
+	q.setRunning(false)
+}
+
+// setRunning records whether the hotkey listener and overlay are up
+func (q *QuickEnhance) setRunning(running bool) {
 	q.mu.Lock()
-	q.running = false
+	q.running = running
 	q.mu.Unlock()
 }
 
@@ -125,7 +204,7 @@ func (q *QuickEnhance) handleOverlayClick() {
 	q.mu.RLock()
 	callback := q.callback
 	q.mu.RUnlock()
-	
+
 	if callback != nil {
 		callback("")
 	}
@@ -136,13 +215,8 @@ func (q *QuickEnhance) ShowOverlay() {
 	if q.overlay == nil {
 		return
 	}
-	
-	var pt struct {
-		X int32
-		Y int32
-	}
-	procGetCursorPos.Call(uintptr(unsafe.Pointer(&pt)))
-	q.overlay.Show(int(pt.X), int(pt.Y))
+
+	q.overlay.Show(cursorPos())
 }
 
 // HideOverlay hides the floating button
@@ -152,257 +226,223 @@ func (q *QuickEnhance) HideOverlay() {
 	}
 }
 
-// hotkeyListener listens for the global hotkey
-func (q *QuickEnhance) hotkeyListener() {
-	runtime.LockOSThread()
-	defer runtime.UnlockOSThread()
+// unregisterHotkey unregisters the global hotkey
+func (q *QuickEnhance) unregisterHotkey() {
+	q.platform.UnregisterHotkey(q.hotkeyID)
+	q.setRegistered("")
+}
 
-	// Register hotkey: Ctrl+Alt+E
-	if !q.registerHotkey() {
+// handleHotkey processes the hotkey press
+func (q *QuickEnhance) handleHotkey() {
+	// A second press while an enhancement is running cancels it
+	if q.CancelEnhancement() {
 		return
 	}
-	defer q.unregisterHotkey()
-
-	// Message loop
-	var msg struct {
-		Hwnd    windows.HWND
-		Message uint32
-		WParam  uintptr
-		LParam  uintptr
-		Time    uint32
-		PtX     int32
-		PtY     int32
-	}
-	
-	for {
-		select {
-		case <-q.ctx.Done():
-			return
-		default:
-		}
 
-		// PeekMessage with PM_REMOVE = 1
-		ret, _, _ := procPeekMessage.Call(
-			uintptr(unsafe.Pointer(&msg)),
-			0, 0, 0, 1,
-		)
-
-		if ret != 0 {
-			if msg.Message == wmHotkey && int(msg.WParam) == q.hotkeyID {
-				go q.handleHotkey()
-			}
-			procTranslateMessage.Call(uintptr(unsafe.Pointer(&msg)))
-			procDispatchMessage.Call(uintptr(unsafe.Pointer(&msg)))
-		}
+	q.mu.RLock()
+	callback := q.callback
+	analyzer := q.analyzer
+	imageCallback := q.imageCallback
+	q.mu.RUnlock()
+	analyzeImages := analyzer != nil && imageCallback != nil
 
-		time.Sleep(10 * time.Millisecond)
+	// Grab any clipboard image first; copying the selection overwrites it
+	var img image.Image
+	if analyzeImages {
+		img, _ = q.platform.GetClipboardImage()
 	}
-}
 
-// registerHotkey registers the global hotkey
-func (q *QuickEnhance) registerHotkey() bool {
-	// Try Ctrl+Alt+E
-	mods := uint32(modControl | modAlt)
-	ret, _, _ := procRegisterHotKey.Call(0, uintptr(q.hotkeyID), uintptr(mods), uintptr(vkE))
-	
-	if ret == 0 {
-		// Try Win+Shift+E as fallback
-		mods = uint32(modWin | modShift)
-		ret, _, _ = procRegisterHotKey.Call(0, uintptr(q.hotkeyID), uintptr(mods), uintptr(vkE))
-		if ret == 0 {
-			return false
+	// Get selected text by copying it
+	text := q.getSelectedText()
+
+	// A freshly copied image (e.g. a selected picture) takes precedence
+	if analyzeImages && text == "" {
+		if copied, ok := q.platform.GetClipboardImage(); ok {
+			img = copied
 		}
 	}
-	
-	return true
-}
 
-// unregisterHotkey unregisters the global hotkey
-func (q *QuickEnhance) unregisterHotkey() {
-	procUnregisterHotKey.Call(0, uintptr(q.hotkeyID))
-}
-
-// handleHotkey processes the hotkey press
-func (q *QuickEnhance) handleHotkey() {
-	// Get selected text by copying it
-	text := q.getSelectedText()
-	
 	// Show overlay at cursor position
-	var pt struct {
-		X int32
-		Y int32
+	q.overlay.Show(cursorPos())
+
+	// Nothing selected but an image is on the clipboard: analyze it instead
+	if classifyClipboard(text, img) == contentImage {
+		q.analyzeImage(analyzer, img, imageCallback)
+		return
 	}
-	procGetCursorPos.Call(uintptr(unsafe.Pointer(&pt)))
-	q.overlay.Show(int(pt.X), int(pt.Y))
-	
+
 	// Call the callback with the captured text
-	q.mu.RLock()
-	callback := q.callback
-	q.mu.RUnlock()
-	
 	if callback != nil {
 		callback(text)
 	}
 }
 
+// analyzeImage sends a clipboard image through the vision analysis
+func (q *QuickEnhance) analyzeImage(analyzer ImageAnalyzer, img image.Image, callback func(result *llm.AnalysisResult)) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, capture.NormalizeImage(img), &jpeg.Options{Quality: 80}); err != nil {
+		log.Printf("[QuickEnhance] Failed to encode clipboard image: %v", err)
+		return
+	}
+
+	ctx, done := q.beginEnhancement()
+	defer done()
+
+	result, err := analyzer.AnalyzeScreen(ctx, buf.Bytes(), "")
+	if err != nil {
+		log.Printf("[QuickEnhance] Clipboard image analysis failed: %v", err)
+		return
+	}
+	callback(result)
+}
+
 // getSelectedText copies the current selection and returns it
 func (q *QuickEnhance) getSelectedText() string {
-	// Save current clipboard
-	savedClipboard := q.getClipboardText()
-	
-	// Small delay
-	time.Sleep(50 * time.Millisecond)
-	
-	// Clear clipboard
-	q.setClipboardText("")
-	time.Sleep(20 * time.Millisecond)
-	
-	// Send Ctrl+C using keybd_event
-	q.sendCtrlC()
-	
-	// Wait for clipboard
-	time.Sleep(100 * time.Millisecond)
-	
-	// Read clipboard
-	text := q.getClipboardText()
-	
-	// Restore original clipboard after delay
-	go func() {
-		time.Sleep(200 * time.Millisecond)
-		q.setClipboardText(savedClipboard)
-	}()
-	
-	return text
-}
+	// Finish any pending restore so we don't save our own temporary contents
+	q.flushClipboardRestore()
 
-// sendCtrlC simulates Ctrl+C
-func (q *QuickEnhance) sendCtrlC() {
-	// Use keybd_event to send Ctrl+C
-	// VK_CONTROL = 0x11, VK_C = 0x43
-	keybdEvent := user32DLL.NewProc("keybd_event")
-	
-	// Press Ctrl
-	keybdEvent.Call(0x11, 0, 0, 0)
-	// Press C
-	keybdEvent.Call(0x43, 0, 0, 0)
-	// Release C
-	keybdEvent.Call(0x43, 0, 2, 0)
-	// Release Ctrl
-	keybdEvent.Call(0x11, 0, 2, 0)
-}
+	// Save the whole clipboard, not just its text, so images and files
+	// copied before the hotkey survive
+	saved, ok := q.platform.SaveClipboard()
 
-// getClipboardText gets text from clipboard
-func (q *QuickEnhance) getClipboardText() string {
-	// Open clipboard
-	ret, _, _ := procOpenClipboard.Call(0)
-	if ret == 0 {
-		return ""
-	}
-	defer procCloseClipboard.Call()
+	// Clear clipboard so a copy of nothing doesn't return the old text
+	q.platform.SetClipboardText("")
+	seq := q.platform.ClipboardSequence()
 
-	// Get clipboard data
-	handle, _, _ := procGetClipboardData.Call(cfUnicodeText)
-	if handle == 0 {
-		return ""
-	}
+	// Send Ctrl+C using keybd_event and wait for it to land
+	q.platform.SendCopy()
+	q.waitClipboardChange(seq)
 
-	// Lock memory
-	ptr, _, _ := procGlobalLock.Call(handle)
-	if ptr == 0 {
-		return ""
+	// Read clipboard, remembering its line endings for the paste
+	text, crlf := normalizeNewlines(q.platform.GetClipboardText())
+	q.mu.Lock()
+	q.selectionCRLF = crlf
+	q.mu.Unlock()
+
+	// Restore original clipboard after delay
+	if ok {
+		q.scheduleClipboardRestore(saved, 200*time.Millisecond)
 	}
-	defer procGlobalUnlock.Call(handle)
 
-	// Convert to Go string (UTF-16)
-	return windows.UTF16PtrToString((*uint16)(unsafe.Pointer(ptr)))
+	return text
 }
 
-// setClipboardText sets text to clipboard
-func (q *QuickEnhance) setClipboardText(text string) bool {
-	// Open clipboard
-	ret, _, _ := procOpenClipboard.Call(0)
-	if ret == 0 {
-		return false
+// waitClipboardChange waits until the clipboard sequence moves past seq,
+// giving up after the copy timeout
+func (q *QuickEnhance) waitClipboardChange(seq uint32) {
+	timeout := q.copyTimeout
+	if timeout <= 0 {
+		timeout = defaultCopyTimeout
 	}
-	defer procCloseClipboard.Call()
+	deadline := time.Now().Add(timeout)
+	for q.platform.ClipboardSequence() == seq {
+		if time.Now().After(deadline) {
+			return
+		}
+		time.Sleep(clipboardPollInterval)
+	}
+}
 
-	// Empty clipboard
-	procEmptyClipboard.Call()
+// EnhancePrompt enhances the given prompt
+func (q *QuickEnhance) EnhancePrompt(prompt string) (*EnhancementResult, error) {
+	ctx, done := q.beginEnhancement()
+	defer done()
 
-	if text == "" {
-		return true
-	}
+	return q.enhancer.Enhance(ctx, prompt, "", 5)
+}
 
-	// Convert to UTF-16
-	utf16Text, err := windows.UTF16FromString(text)
-	if err != nil {
-		return false
+// beginEnhancement creates the context for an in-flight enhancement. It is
+// cancelled by the configured timeout, CancelEnhancement or the Escape key.
+func (q *QuickEnhance) beginEnhancement() (context.Context, func()) {
+	q.mu.Lock()
+	ctx, cancel := context.WithTimeout(q.ctx, q.timeout)
+	if q.inFlightCancel != nil {
+		q.inFlightCancel() // a new enhancement supersedes the old one
 	}
+	q.inFlightID++
+	id := q.inFlightID
+	q.inFlightCancel = cancel
+	q.mu.Unlock()
 
-	// Calculate size
-	size := len(utf16Text) * 2
+	go q.watchEscape(ctx)
 
-	// Allocate global memory
-	hGlobal, _, _ := procGlobalAlloc.Call(0x0042, uintptr(size)) // GHND = 0x0042
-	if hGlobal == 0 {
-		return false
+	return ctx, func() {
+		cancel()
+		q.mu.Lock()
+		if q.inFlightID == id {
+			q.inFlightCancel = nil
+		}
+		q.mu.Unlock()
 	}
+}
 
-	// Lock memory
-	ptr, _, _ := procGlobalLock.Call(hGlobal)
-	if ptr == 0 {
-		procGlobalFree.Call(hGlobal)
+// CancelEnhancement aborts the in-flight enhancement, hides the overlay and
+// restores the clipboard. It reports whether anything was cancelled.
+func (q *QuickEnhance) CancelEnhancement() bool {
+	q.mu.Lock()
+	cancel := q.inFlightCancel
+	q.inFlightCancel = nil
+	q.mu.Unlock()
+
+	if cancel == nil {
 		return false
 	}
 
-	// Copy data
-	procRtlMoveMemory.Call(ptr, uintptr(unsafe.Pointer(&utf16Text[0])), uintptr(size))
-	procGlobalUnlock.Call(hGlobal)
+	cancel()
+	q.HideOverlay()
+	q.flushClipboardRestore()
+	return true
+}
+
+// scheduleClipboardRestore puts saved back on the clipboard after delay
+func (q *QuickEnhance) scheduleClipboardRestore(saved ClipboardSnapshot, delay time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
 
-	// Set clipboard data
-	ret, _, _ = procSetClipboardData.Call(cfUnicodeText, hGlobal)
-	return ret != 0
+	if q.restoreTimer != nil {
+		q.restoreTimer.Stop()
+	}
+	q.pendingRestore = &saved
+	q.restoreTimer = time.AfterFunc(delay, q.flushClipboardRestore)
 }
 
-// EnhancePrompt enhances the given prompt
-func (q *QuickEnhance) EnhancePrompt(prompt string) (*EnhancementResult, error) {
-	ctx, cancel := context.WithTimeout(q.ctx, 10*time.Second)
-	defer cancel()
-	
-	return q.enhancer.Enhance(ctx, prompt, "", 5)
+// flushClipboardRestore performs a pending clipboard restore immediately
+func (q *QuickEnhance) flushClipboardRestore() {
+	q.mu.Lock()
+	saved := q.pendingRestore
+	q.pendingRestore = nil
+	if q.restoreTimer != nil {
+		q.restoreTimer.Stop()
+		q.restoreTimer = nil
+	}
+	q.mu.Unlock()
+
+	if saved != nil {
+		q.platform.RestoreClipboard(*saved)
+	}
 }
 
 // PasteEnhanced pastes the enhanced text
 func (q *QuickEnhance) PasteEnhanced(text string) {
+	q.flushClipboardRestore()
+
 	// Save current clipboard
-	savedClipboard := q.getClipboardText()
-	
-	// Set enhanced text
-	q.setClipboardText(text)
+	saved, ok := q.platform.SaveClipboard()
+
+	// Set enhanced text with the selection's line endings
+	q.mu.RLock()
+	crlf := q.selectionCRLF
+	q.mu.RUnlock()
+	q.platform.SetClipboardText(restoreNewlines(text, crlf))
 	time.Sleep(50 * time.Millisecond)
-	
+
 	// Send Ctrl+V
-	q.sendCtrlV()
-	
-	// Restore original clipboard
-	go func() {
-		time.Sleep(500 * time.Millisecond)
-		q.setClipboardText(savedClipboard)
-	}()
-}
+	q.platform.SendPaste()
 
-// sendCtrlV simulates Ctrl+V
-func (q *QuickEnhance) sendCtrlV() {
-	keybdEvent := user32DLL.NewProc("keybd_event")
-	
-	// Press Ctrl
-	keybdEvent.Call(0x11, 0, 0, 0)
-	// Press V
-	keybdEvent.Call(0x56, 0, 0, 0)
-	// Release V
-	keybdEvent.Call(0x56, 0, 2, 0)
-	// Release Ctrl
-	keybdEvent.Call(0x11, 0, 2, 0)
+	// Restore original clipboard
+	if ok {
+		q.scheduleClipboardRestore(saved, 500*time.Millisecond)
+	}
 }
 
 // GetSelectedText gets currently selected text (public method for app.go)
@@ -0,0 +1,392 @@
+package quickenhance
+
+import (
+	"context"
+	"errors"
+	"image"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"screen-memory-assistant/internal/enhancer"
+)
+
+// fakePlatform records clipboard writes and hotkey registration attempts
+type fakePlatform struct {
+	mu        sync.Mutex
+	clipboard string
+	writes    []string
+	// formats holds non-text clipboard formats, e.g. an image
+	formats ClipboardSnapshot
+	// seq counts clipboard changes; copyDelay makes SendCopy land late
+	seq       uint32
+	copyDelay time.Duration
+
+	// busy holds how many more registrations of each hotkey fail;
+	// a negative count never succeeds
+	busy     map[string]int
+	attempts []string
+
+	// selection is copied by SendCopy; pasted records what SendPaste pasted
+	selection string
+	pasted    []string
+}
+
+func (f *fakePlatform) GetClipboardText() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.clipboard
+}
+
+func (f *fakePlatform) SetClipboardText(text string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.clipboard = text
+	f.formats = nil
+	f.seq++
+	f.writes = append(f.writes, text)
+	return true
+}
+
+// fakeTextFormat stands in for CF_UNICODETEXT in the fake's snapshots
+const fakeTextFormat = cfUnicodeText
+
+func (f *fakePlatform) SaveClipboard() (ClipboardSnapshot, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	snapshot := append(ClipboardSnapshot{}, f.formats...)
+	if f.clipboard != "" {
+		snapshot = append(snapshot, ClipboardFormat{Format: fakeTextFormat, Data: []byte(f.clipboard)})
+	}
+	return snapshot, true
+}
+
+func (f *fakePlatform) RestoreClipboard(snapshot ClipboardSnapshot) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.clipboard, f.formats = "", nil
+	f.seq++
+	for _, format := range snapshot {
+		if format.Format == fakeTextFormat {
+			f.clipboard = string(format.Data)
+			continue
+		}
+		f.formats = append(f.formats, format)
+	}
+	return true
+}
+
+func (f *fakePlatform) GetClipboardImage() (image.Image, bool) {
+	return nil, false
+}
+
+func (f *fakePlatform) RegisterHotkey(id int, hk Hotkey) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.attempts = append(f.attempts, hk.Name)
+	if n := f.busy[hk.Name]; n != 0 {
+		f.busy[hk.Name] = n - 1
+		return false
+	}
+	return true
+}
+
+func (f *fakePlatform) UnregisterHotkey(id int) {}
+
+func (f *fakePlatform) ClipboardSequence() uint32 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.seq
+}
+
+func (f *fakePlatform) SendCopy() {
+	copySelection := func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.clipboard = f.selection
+		f.formats = nil
+		f.seq++
+	}
+	if f.copyDelay > 0 {
+		time.AfterFunc(f.copyDelay, copySelection)
+		return
+	}
+	copySelection()
+}
+
+func (f *fakePlatform) SendPaste() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pasted = append(f.pasted, f.clipboard)
+}
+
+// blockingEnhancer blocks until its context is done
+type blockingEnhancer struct {
+	started chan struct{}
+}
+
+func (b *blockingEnhancer) Enhance(ctx context.Context, prompt, pageContext string, maxMemories int) (*enhancer.EnhancementResult, error) {
+	close(b.started)
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// textSnapshot is a clipboard snapshot holding only text
+func textSnapshot(text string) ClipboardSnapshot {
+	return ClipboardSnapshot{{Format: fakeTextFormat, Data: []byte(text)}}
+}
+
+func newTestQuickEnhance(enh Enhancer, platform Platform) *QuickEnhance {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &QuickEnhance{
+		enhancer: enh,
+		platform: platform,
+		ctx:      ctx,
+		cancel:   cancel,
+		timeout:  time.Minute,
+	}
+}
+
+func TestCancelEnhancement(t *testing.T) {
+	platform := &fakePlatform{clipboard: "temporary"}
+	enh := &blockingEnhancer{started: make(chan struct{})}
+	q := newTestQuickEnhance(enh, platform)
+	defer q.cancel()
+
+	// A copy is in progress and the user's clipboard is waiting to be restored
+	q.scheduleClipboardRestore(textSnapshot("original"), time.Hour)
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := q.EnhancePrompt("slow prompt")
+		errCh <- err
+	}()
+
+	<-enh.started
+	if !q.CancelEnhancement() {
+		t.Fatal("CancelEnhancement() = false, want true while in flight")
+	}
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("EnhancePrompt error = %v, want context.Canceled", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("EnhancePrompt did not return after cancellation")
+	}
+
+	if got := platform.GetClipboardText(); got != "original" {
+		t.Errorf("clipboard = %q after cancel, want %q", got, "original")
+	}
+
+	if q.CancelEnhancement() {
+		t.Error("CancelEnhancement() = true with nothing in flight")
+	}
+}
+
+func TestEnhancePrompt_Timeout(t *testing.T) {
+	enh := &blockingEnhancer{started: make(chan struct{})}
+	q := newTestQuickEnhance(enh, &fakePlatform{})
+	defer q.cancel()
+	q.SetTimeout(20 * time.Millisecond)
+
+	_, err := q.EnhancePrompt("slow prompt")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("EnhancePrompt error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestStart_RestartsAfterStopOrFailure(t *testing.T) {
+	enh := &blockingEnhancer{started: make(chan struct{})}
+	q := newTestQuickEnhance(enh, &fakePlatform{})
+	q.Stop()
+
+	err := q.Start()
+	defer q.Stop()
+	if q.ctx.Err() != nil {
+		t.Error("context still cancelled after Stop and Start")
+	}
+	if err == nil {
+		return
+	}
+
+	// A failed start (no global hotkeys on this platform) must not leave
+	// the feature marked running, or every later Start is a no-op
+	q.mu.RLock()
+	running := q.running
+	q.mu.RUnlock()
+	if running {
+		t.Error("running = true after a failed Start")
+	}
+	if again := q.Start(); again == nil || again.Error() != err.Error() {
+		t.Errorf("second Start = %v, want %v again", again, err)
+	}
+}
+
+func TestRegisterFirst_TriesInOrder(t *testing.T) {
+	hotkeys, err := parseHotkeys([]string{"Ctrl+Alt+E", "Win+Shift+E", "Ctrl+Shift+F9"})
+	if err != nil {
+		t.Fatalf("parseHotkeys: %v", err)
+	}
+
+	platform := &fakePlatform{busy: map[string]int{
+		"Ctrl+Alt+E":  -1,
+		"Win+Shift+E": 1,
+	}}
+
+	hk, err := registerFirst(platform, 1, hotkeys, 2, 0)
+	if err != nil {
+		t.Fatalf("registerFirst: %v", err)
+	}
+	if hk.Name != "Win+Shift+E" {
+		t.Errorf("registered %q, want Win+Shift+E", hk.Name)
+	}
+
+	want := "Ctrl+Alt+E,Ctrl+Alt+E,Win+Shift+E,Win+Shift+E"
+	if got := strings.Join(platform.attempts, ","); got != want {
+		t.Errorf("attempts = %s, want %s", got, want)
+	}
+}
+
+func TestRegisterFirst_AllInUse(t *testing.T) {
+	hotkeys, _ := parseHotkeys(DefaultHotkeys)
+	platform := &fakePlatform{busy: map[string]int{
+		"Ctrl+Alt+E":  -1,
+		"Win+Shift+E": -1,
+	}}
+
+	_, err := registerFirst(platform, 1, hotkeys, 1, 0)
+	if !errors.Is(err, ErrHotkeyUnavailable) {
+		t.Fatalf("err = %v, want ErrHotkeyUnavailable", err)
+	}
+	if !strings.Contains(err.Error(), "Ctrl+Alt+E, Win+Shift+E") {
+		t.Errorf("error %q should name the hotkeys tried", err)
+	}
+}
+
+func TestParseHotkeyConfig(t *testing.T) {
+	cfg, err := ParseHotkeyConfig([]string{"Ctrl+Alt+Space", "Win+Shift+F9"}, 3, time.Second)
+	if err != nil {
+		t.Fatalf("ParseHotkeyConfig: %v", err)
+	}
+	if len(cfg.Hotkeys) != 2 || cfg.Hotkeys[0].Key != 0x20 || cfg.Hotkeys[1].Modifiers != modWin|modShift ||
+		cfg.Attempts != 3 || cfg.Backoff != time.Second {
+		t.Errorf("ParseHotkeyConfig = %+v", cfg)
+	}
+
+	cfg, err = ParseHotkeyConfig(nil, 1, 0)
+	if err != nil || len(cfg.Hotkeys) != len(DefaultHotkeys) || cfg.Hotkeys[0].Name != DefaultHotkeys[0] {
+		t.Errorf("ParseHotkeyConfig(nil) = %+v, %v; want the defaults", cfg, err)
+	}
+	if _, err := ParseHotkeyConfig([]string{"Ctrl+Alt+Enter"}, 1, 0); err == nil {
+		t.Error("ParseHotkeyConfig accepted Enter")
+	}
+
+	if q := New(nil, HotkeyConfig{}); len(q.hotkeys.Hotkeys) != len(DefaultHotkeys) || q.Hotkey() != "" {
+		t.Errorf("New without hotkeys: hotkeys = %+v, registered = %q; want the defaults, none registered", q.hotkeys, q.Hotkey())
+	}
+}
+
+func TestParseHotkey(t *testing.T) {
+	hk, err := ParseHotkey("ctrl + Alt + f12")
+	if err != nil {
+		t.Fatalf("ParseHotkey: %v", err)
+	}
+	if hk.Modifiers != modControl|modAlt || hk.Key != vkF1+11 {
+		t.Errorf("ParseHotkey = %+v", hk)
+	}
+
+	hk, err = ParseHotkey("Ctrl+Alt+Space")
+	if err != nil {
+		t.Fatalf("ParseHotkey: %v", err)
+	}
+	if hk.Modifiers != modControl|modAlt || hk.Key != 0x20 {
+		t.Errorf("ParseHotkey(Ctrl+Alt+Space) = %+v", hk)
+	}
+
+	for _, bad := range []string{"E", "Hyper+E", "Ctrl+Enter", "Ctrl+F25", "Space"} {
+		if _, err := ParseHotkey(bad); err == nil {
+			t.Errorf("ParseHotkey(%q) should fail", bad)
+		}
+	}
+}
+
+func TestSelectionRoundTrip_PreservesText(t *testing.T) {
+	tests := []struct {
+		name      string
+		selection string
+		want      string
+	}{
+		{"crlf multi-line", "first line\r\nsecond line\r\n", "first line\nsecond line\n"},
+		{"lf multi-line", "first line\nsecond line", "first line\nsecond line"},
+		{"emoji", "ship it 🚀👍🏽 naïve café", "ship it 🚀👍🏽 naïve café"},
+		{"emoji crlf", "déjà vu 😀\r\n日本語", "déjà vu 😀\n日本語"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			platform := &fakePlatform{clipboard: "original", selection: tt.selection}
+			q := newTestQuickEnhance(nil, platform)
+			defer q.cancel()
+
+			text := q.getSelectedText()
+			if text != tt.want {
+				t.Errorf("captured %q, want %q", text, tt.want)
+			}
+
+			// Pasting the text back unchanged reproduces the selection
+			q.PasteEnhanced(text)
+			if len(platform.pasted) != 1 || platform.pasted[0] != tt.selection {
+				t.Errorf("pasted %q, want %q", platform.pasted, tt.selection)
+			}
+		})
+	}
+}
+
+func TestSelectionRoundTrip_PreservesNonTextClipboard(t *testing.T) {
+	bitmap := ClipboardFormat{Format: cfDIB, Data: []byte{1, 2, 3}}
+	platform := &fakePlatform{formats: ClipboardSnapshot{bitmap}, selection: "selected"}
+	q := newTestQuickEnhance(nil, platform)
+	defer q.cancel()
+
+	if text := q.getSelectedText(); text != "selected" {
+		t.Fatalf("captured %q, want %q", text, "selected")
+	}
+	q.PasteEnhanced("enhanced")
+	q.flushClipboardRestore()
+
+	if len(platform.formats) != 1 || platform.formats[0].Format != cfDIB || string(platform.formats[0].Data) != string(bitmap.Data) {
+		t.Errorf("clipboard formats = %v after restore, want the original image", platform.formats)
+	}
+	if platform.clipboard != "" {
+		t.Errorf("clipboard text = %q after restore, want none", platform.clipboard)
+	}
+}
+
+func TestGetSelectedText_WaitsForCopy(t *testing.T) {
+	platform := &fakePlatform{clipboard: "original", selection: "slow copy", copyDelay: 50 * time.Millisecond}
+	q := newTestQuickEnhance(nil, platform)
+	q.copyTimeout = time.Second
+	defer q.cancel()
+
+	if text := q.getSelectedText(); text != "slow copy" {
+		t.Errorf("captured %q, want %q", text, "slow copy")
+	}
+}
+
+func TestGetSelectedText_CopyTimeout(t *testing.T) {
+	platform := &fakePlatform{clipboard: "original", selection: "too late", copyDelay: time.Second}
+	q := newTestQuickEnhance(nil, platform)
+	q.copyTimeout = 20 * time.Millisecond
+	defer q.cancel()
+
+	start := time.Now()
+	if text := q.getSelectedText(); text != "" {
+		t.Errorf("captured %q, want nothing when the copy times out", text)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("getSelectedText took %v, want it bounded by the copy timeout", elapsed)
+	}
+}
@@ -0,0 +1,149 @@
+package quickenhance
+
+import (
+	"context"
+	"log"
+	"runtime"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// Windows API constants
+const wmHotkey = 0x0312
+
+var (
+	user32DLL            = windows.NewLazySystemDLL("user32.dll")
+	kernel32DLL          = windows.NewLazySystemDLL("kernel32.dll")
+	procRegisterHotKey   = user32DLL.NewProc("RegisterHotKey")
+	procUnregisterHotKey = user32DLL.NewProc("UnregisterHotKey")
+	procPeekMessage      = user32DLL.NewProc("PeekMessageW")
+	procTranslateMessage = user32DLL.NewProc("TranslateMessage")
+	procDispatchMessage  = user32DLL.NewProc("DispatchMessageW")
+	procOpenClipboard    = user32DLL.NewProc("OpenClipboard")
+	procCloseClipboard   = user32DLL.NewProc("CloseClipboard")
+	procEmptyClipboard   = user32DLL.NewProc("EmptyClipboard")
+	procGetClipboardData = user32DLL.NewProc("GetClipboardData")
+	procSetClipboardData = user32DLL.NewProc("SetClipboardData")
+	procGlobalLock       = kernel32DLL.NewProc("GlobalLock")
+	procGlobalUnlock     = kernel32DLL.NewProc("GlobalUnlock")
+	procGlobalAlloc      = kernel32DLL.NewProc("GlobalAlloc")
+	procGlobalFree       = kernel32DLL.NewProc("GlobalFree")
+	procRtlMoveMemory    = kernel32DLL.NewProc("RtlMoveMemory")
+	procGetCursorPos     = user32DLL.NewProc("GetCursorPos")
+)
+
+// newPlatform returns the Win32 clipboard and hotkey platform
+func newPlatform() Platform {
+	return winPlatform{}
+}
+
+// cursorPos returns the mouse cursor's screen position
+func cursorPos() (x, y int) {
+	var pt struct {
+		X int32
+		Y int32
+	}
+	procGetCursorPos.Call(uintptr(unsafe.Pointer(&pt)))
+	return int(pt.X), int(pt.Y)
+}
+
+// hotkeyListener listens for the global hotkey, reporting on ready
+// whether registration succeeded
+func (q *QuickEnhance) hotkeyListener(ctx context.Context, ready chan<- error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	// Hotkeys must be registered on the thread that pumps messages
+	q.mu.RLock()
+	hotkeys := q.hotkeys
+	q.mu.RUnlock()
+
+	hk, err := registerFirst(q.platform, q.hotkeyID, hotkeys.Hotkeys, hotkeys.Attempts, hotkeys.Backoff)
+	if err == nil {
+		q.setRegistered(hk.Name)
+	}
+	ready <- err
+	if err != nil {
+		return
+	}
+	log.Printf("[QuickEnhance] Registered hotkey %s", hk.Name)
+	defer q.unregisterHotkey()
+
+	// Message loop
+	var msg struct {
+		Hwnd    windows.HWND
+		Message uint32
+		WParam  uintptr
+		LParam  uintptr
+		Time    uint32
+		PtX     int32
+		PtY     int32
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		// PeekMessage with PM_REMOVE = 1
+		ret, _, _ := procPeekMessage.Call(
+			uintptr(unsafe.Pointer(&msg)),
+			0, 0, 0, 1,
+		)
+
+		if ret != 0 {
+			if msg.Message == wmHotkey && int(msg.WParam) == q.hotkeyID {
+				go q.handleHotkey()
+			}
+			procTranslateMessage.Call(uintptr(unsafe.Pointer(&msg)))
+			procDispatchMessage.Call(uintptr(unsafe.Pointer(&msg)))
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// RegisterHotkey registers a global hotkey for the calling thread
+func (winPlatform) RegisterHotkey(id int, hk Hotkey) bool {
+	ret, _, _ := procRegisterHotKey.Call(0, uintptr(id), uintptr(hk.Modifiers), uintptr(hk.Key))
+	return ret != 0
+}
+
+// UnregisterHotkey unregisters a global hotkey
+func (winPlatform) UnregisterHotkey(id int) {
+	procUnregisterHotKey.Call(0, uintptr(id))
+}
+
+// SendCopy simulates Ctrl+C
+func (winPlatform) SendCopy() {
+	// Use keybd_event to send Ctrl+C
+	// VK_CONTROL = 0x11, VK_C = 0x43
+	keybdEvent := user32DLL.NewProc("keybd_event")
+
+	// Press Ctrl
+	keybdEvent.Call(0x11, 0, 0, 0)
+	// Press C
+	keybdEvent.Call(0x43, 0, 0, 0)
+	// Release C
+	keybdEvent.Call(0x43, 0, 2, 0)
+	// Release Ctrl
+	keybdEvent.Call(0x11, 0, 2, 0)
+}
+
+// SendPaste simulates Ctrl+V
+func (winPlatform) SendPaste() {
+	keybdEvent := user32DLL.NewProc("keybd_event")
+
+	// Press Ctrl
+	keybdEvent.Call(0x11, 0, 0, 0)
+	// Press V
+	keybdEvent.Call(0x56, 0, 0, 0)
+	// Release V
+	keybdEvent.Call(0x56, 0, 2, 0)
+	// Release Ctrl
+	keybdEvent.Call(0x11, 0, 2, 0)
+}
@@ -0,0 +1,163 @@
+package quickenhance
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Hotkey modifier flags and the F1 virtual-key code
+const (
+	modAlt     = 0x0001
+	modControl = 0x0002
+	modShift   = 0x0004
+	modWin     = 0x0008
+	vkF1       = 0x70
+)
+
+// DefaultHotkeys are tried in order when none are configured
+var DefaultHotkeys = []string{"Ctrl+Alt+E", "Win+Shift+E"}
+
+// ErrHotkeyUnavailable is returned when none of the hotkeys could be registered
+var ErrHotkeyUnavailable = errors.New("hotkey in use by another app")
+
+// ErrUnsupported is returned by Start on platforms without global hotkeys
+var ErrUnsupported = errors.New("quick enhance hotkeys are only supported on Windows")
+
+// Hotkey is a global key combination such as Ctrl+Alt+E
+type Hotkey struct {
+	Name      string
+	Modifiers uint32
+	Key       uint32
+}
+
+// HotkeyConfig sets the hotkeys quick enhance registers: each combination
+// of modifiers and virtual-key code is tried in order, Attempts times with
+// Backoff in between, until one registers
+type HotkeyConfig struct {
+	Hotkeys  []Hotkey
+	Attempts int
+	Backoff  time.Duration
+}
+
+// ParseHotkeyConfig parses combinations such as "Ctrl+Alt+Space" from
+// config; an empty list means DefaultHotkeys
+func ParseHotkeyConfig(combos []string, attempts int, backoff time.Duration) (HotkeyConfig, error) {
+	if len(combos) == 0 {
+		combos = DefaultHotkeys
+	}
+	hotkeys, err := parseHotkeys(combos)
+	if err != nil {
+		return HotkeyConfig{}, err
+	}
+	return HotkeyConfig{Hotkeys: hotkeys, Attempts: attempts, Backoff: backoff}, nil
+}
+
+// DefaultHotkeyConfig tries each of DefaultHotkeys once
+func DefaultHotkeyConfig() HotkeyConfig {
+	hotkeys, _ := parseHotkeys(DefaultHotkeys)
+	return HotkeyConfig{Hotkeys: hotkeys, Attempts: 1}
+}
+
+// modifierFlags maps modifier names to their flags
+var modifierFlags = map[string]uint32{
+	"ctrl":    modControl,
+	"control": modControl,
+	"alt":     modAlt,
+	"shift":   modShift,
+	"win":     modWin,
+}
+
+// namedKeys maps key names to virtual-key codes. Enter, Tab and Escape
+// are left out: grabbing them globally would break typing everywhere.
+var namedKeys = map[string]uint32{
+	"SPACE":    0x20,
+	"PAGEUP":   0x21,
+	"PAGEDOWN": 0x22,
+	"END":      0x23,
+	"HOME":     0x24,
+	"LEFT":     0x25,
+	"UP":       0x26,
+	"RIGHT":    0x27,
+	"DOWN":     0x28,
+	"INSERT":   0x2D,
+	"DELETE":   0x2E,
+}
+
+// ParseHotkey parses a combination such as "Ctrl+Alt+E". The key must be
+// a letter, a digit, F1-F24 or a named key such as Space or Home, and at
+// least one modifier is required.
+func ParseHotkey(s string) (Hotkey, error) {
+	parts := strings.Split(s, "+")
+	hk := Hotkey{Name: strings.TrimSpace(s)}
+
+	for _, part := range parts[:len(parts)-1] {
+		flag, ok := modifierFlags[strings.ToLower(strings.TrimSpace(part))]
+		if !ok {
+			return Hotkey{}, fmt.Errorf("hotkey %q: unknown modifier %q", s, part)
+		}
+		hk.Modifiers |= flag
+	}
+	if hk.Modifiers == 0 {
+		return Hotkey{}, fmt.Errorf("hotkey %q: at least one modifier is required", s)
+	}
+
+	key := strings.ToUpper(strings.TrimSpace(parts[len(parts)-1]))
+	if vk, ok := namedKeys[key]; ok {
+		hk.Key = vk
+		return hk, nil
+	}
+	switch {
+	case len(key) == 1 && (key[0] >= 'A' && key[0] <= 'Z' || key[0] >= '0' && key[0] <= '9'):
+		// Letters and digits use their ASCII code
+		hk.Key = uint32(key[0])
+	case len(key) > 1 && key[0] == 'F':
+		n, err := strconv.Atoi(key[1:])
+		if err != nil || n < 1 || n > 24 {
+			return Hotkey{}, fmt.Errorf("hotkey %q: unknown key %q", s, key)
+		}
+		hk.Key = vkF1 + uint32(n-1)
+	default:
+		return Hotkey{}, fmt.Errorf("hotkey %q: unknown key %q", s, key)
+	}
+
+	return hk, nil
+}
+
+// parseHotkeys parses an ordered list of combinations
+func parseHotkeys(combos []string) ([]Hotkey, error) {
+	hotkeys := make([]Hotkey, 0, len(combos))
+	for _, combo := range combos {
+		hk, err := ParseHotkey(combo)
+		if err != nil {
+			return nil, err
+		}
+		hotkeys = append(hotkeys, hk)
+	}
+	return hotkeys, nil
+}
+
+// registerFirst tries each hotkey in order, attempting each up to attempts
+// times with backoff in between, and returns the first one registered
+func registerFirst(p Platform, id int, hotkeys []Hotkey, attempts int, backoff time.Duration) (Hotkey, error) {
+	if attempts <= 0 {
+		attempts = 1
+	}
+
+	var tried []string
+	for _, hk := range hotkeys {
+		for attempt := 1; attempt <= attempts; attempt++ {
+			if p.RegisterHotkey(id, hk) {
+				return hk, nil
+			}
+			if attempt < attempts {
+				time.Sleep(backoff)
+			}
+		}
+		tried = append(tried, hk.Name)
+	}
+
+	return Hotkey{}, fmt.Errorf("%w: tried %s", ErrHotkeyUnavailable, strings.Join(tried, ", "))
+}
@@ -0,0 +1,163 @@
+package quickenhance
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+
+	"screen-memory-assistant/internal/enhancer"
+	"screen-memory-assistant/internal/llm"
+)
+
+// Standard clipboard format IDs
+const (
+	cfUnicodeText = 13
+	cfBitmap      = 2
+	cfDIB         = 8
+)
+
+// Platform abstracts the OS clipboard operations used by quick enhance
+type Platform interface {
+	GetClipboardText() string
+	SetClipboardText(text string) bool
+	// GetClipboardImage returns the clipboard image (CF_DIB/CF_BITMAP), if any
+	GetClipboardImage() (image.Image, bool)
+	// SaveClipboard snapshots every clipboard format it can copy, so
+	// images and files survive a copy or paste; ok is false when the
+	// clipboard couldn't be read
+	SaveClipboard() (snapshot ClipboardSnapshot, ok bool)
+	// RestoreClipboard replaces the clipboard with a snapshot
+	RestoreClipboard(snapshot ClipboardSnapshot) bool
+	// ClipboardSequence changes whenever the clipboard's contents change
+	ClipboardSequence() uint32
+	// RegisterHotkey registers a global hotkey on the calling thread
+	RegisterHotkey(id int, hk Hotkey) bool
+	UnregisterHotkey(id int)
+	// SendCopy and SendPaste simulate Ctrl+C and Ctrl+V in the focused window
+	SendCopy()
+	SendPaste()
+}
+
+// ClipboardFormat is one format's data from a clipboard snapshot
+type ClipboardFormat struct {
+	Format uint32
+	Data   []byte
+}
+
+// ClipboardSnapshot holds the clipboard's formats in their original order
+type ClipboardSnapshot []ClipboardFormat
+
+// Enhancer enhances prompts with stored memories
+type Enhancer interface {
+	Enhance(ctx context.Context, prompt, pageContext string, maxMemories int) (*enhancer.EnhancementResult, error)
+}
+
+// ImageAnalyzer analyzes an image the same way a screen capture is analyzed
+type ImageAnalyzer interface {
+	AnalyzeScreen(ctx context.Context, imageData []byte, previousContext string) (*llm.AnalysisResult, error)
+}
+
+// contentKind describes what the quick-enhance hotkey should act on
+type contentKind int
+
+const (
+	contentNone contentKind = iota
+	contentText
+	contentImage
+)
+
+// classifyClipboard decides whether to enhance text or analyze an image.
+// Selected text always wins; an image is only used when no text was copied.
+func classifyClipboard(text string, img image.Image) contentKind {
+	if strings.TrimSpace(text) != "" {
+		return contentText
+	}
+	if img != nil {
+		return contentImage
+	}
+	return contentNone
+}
+
+// normalizeNewlines converts CRLF and lone CR line endings to LF so the
+// text reads the same to the enhancer on every platform. It reports
+// whether the text used CRLF, so a paste can put them back.
+func normalizeNewlines(text string) (string, bool) {
+	crlf := strings.Contains(text, "\r\n")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.ReplaceAll(text, "\r", "\n"), crlf
+}
+
+// restoreNewlines converts line endings to CRLF when crlf is set. Text
+// coming back from the LLM may mix styles, so it is normalized first.
+func restoreNewlines(text string, crlf bool) string {
+	text, _ = normalizeNewlines(text)
+	if !crlf {
+		return text
+	}
+	return strings.ReplaceAll(text, "\n", "\r\n")
+}
+
+// BITMAPINFOHEADER compression values
+const (
+	biRGB       = 0
+	biBitfields = 3
+)
+
+// decodeDIB converts a packed device-independent bitmap (CF_DIB clipboard
+// data) into an image. Only uncompressed 24 and 32 bpp bitmaps are supported.
+func decodeDIB(data []byte) (image.Image, error) {
+	if len(data) < 40 {
+		return nil, fmt.Errorf("DIB too short: %d bytes", len(data))
+	}
+
+	headerSize := int(binary.LittleEndian.Uint32(data[0:4]))
+	width := int(int32(binary.LittleEndian.Uint32(data[4:8])))
+	height := int(int32(binary.LittleEndian.Uint32(data[8:12])))
+	bitCount := int(binary.LittleEndian.Uint16(data[14:16]))
+	compression := binary.LittleEndian.Uint32(data[16:20])
+
+	if width <= 0 || height == 0 {
+		return nil, fmt.Errorf("invalid DIB dimensions %dx%d", width, height)
+	}
+	if bitCount != 24 && bitCount != 32 {
+		return nil, fmt.Errorf("unsupported DIB bit depth: %d", bitCount)
+	}
+	if compression != biRGB && compression != biBitfields {
+		return nil, fmt.Errorf("unsupported DIB compression: %d", compression)
+	}
+
+	offset := headerSize
+	if compression == biBitfields && headerSize == 40 {
+		offset += 12 // color masks follow a BITMAPINFOHEADER
+	}
+
+	// Positive height means rows are stored bottom-up
+	bottomUp := height > 0
+	if height < 0 {
+		height = -height
+	}
+
+	stride := ((width*bitCount + 31) / 32) * 4
+	if offset+stride*height > len(data) {
+		return nil, fmt.Errorf("DIB pixel data truncated")
+	}
+
+	bytesPerPixel := bitCount / 8
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		row := y
+		if bottomUp {
+			row = height - 1 - y
+		}
+		start := offset + row*stride
+		for x := 0; x < width; x++ {
+			p := data[start+x*bytesPerPixel:]
+			img.Set(x, y, color.RGBA{R: p[2], G: p[1], B: p[0], A: 255})
+		}
+	}
+
+	return img, nil
+}
@@ -0,0 +1,40 @@
+//go:build !windows
+
+package quickenhance
+
+import (
+	"context"
+	"image"
+)
+
+// otherPlatform stands in for the clipboard and hotkeys where they aren't
+// implemented: prompts can still be enhanced, but nothing is captured
+type otherPlatform struct{}
+
+func newPlatform() Platform {
+	return otherPlatform{}
+}
+
+func (otherPlatform) GetClipboardText() string                         { return "" }
+func (otherPlatform) SetClipboardText(text string) bool                { return false }
+func (otherPlatform) GetClipboardImage() (image.Image, bool)           { return nil, false }
+func (otherPlatform) SaveClipboard() (ClipboardSnapshot, bool)         { return nil, false }
+func (otherPlatform) RestoreClipboard(snapshot ClipboardSnapshot) bool { return false }
+func (otherPlatform) ClipboardSequence() uint32                        { return 0 }
+func (otherPlatform) RegisterHotkey(id int, hk Hotkey) bool            { return false }
+func (otherPlatform) UnregisterHotkey(id int)                          {}
+func (otherPlatform) SendCopy()                                        {}
+func (otherPlatform) SendPaste()                                       {}
+
+// cursorPos is unknown off Windows
+func cursorPos() (x, y int) {
+	return 0, 0
+}
+
+// hotkeyListener reports that global hotkeys aren't supported here
+func (q *QuickEnhance) hotkeyListener(ctx context.Context, ready chan<- error) {
+	ready <- ErrUnsupported
+}
+
+// watchEscape is a no-op: the Escape key can't be watched globally here
+func (q *QuickEnhance) watchEscape(ctx context.Context) {}
@@ -0,0 +1,85 @@
+package quickenhance
+
+import (
+	"encoding/binary"
+	"image"
+	"testing"
+)
+
+func TestClassifyClipboard(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+
+	tests := []struct {
+		name string
+		text string
+		img  image.Image
+		want contentKind
+	}{
+		{"text only", "hello", nil, contentText},
+		{"text wins over image", "hello", img, contentText},
+		{"image only", "", img, contentImage},
+		{"whitespace text with image", "  \n", img, contentImage},
+		{"empty clipboard", "", nil, contentNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyClipboard(tt.text, tt.img); got != tt.want {
+				t.Errorf("classifyClipboard() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeDIB(t *testing.T) {
+	// 2x2 bottom-up 24bpp bitmap: bottom row blue, top row red
+	width, height, stride := 2, 2, 8
+	data := make([]byte, 40+stride*height)
+	binary.LittleEndian.PutUint32(data[0:4], 40)
+	binary.LittleEndian.PutUint32(data[4:8], uint32(width))
+	binary.LittleEndian.PutUint32(data[8:12], uint32(height))
+	binary.LittleEndian.PutUint16(data[12:14], 1)
+	binary.LittleEndian.PutUint16(data[14:16], 24)
+
+	pixels := data[40:]
+	for x := 0; x < width; x++ {
+		pixels[x*3] = 255          // bottom row: B
+		pixels[stride+x*3+2] = 255 // top row: R
+	}
+
+	img, err := decodeDIB(data)
+	if err != nil {
+		t.Fatalf("decodeDIB failed: %v", err)
+	}
+
+	r, _, b, _ := img.At(0, 0).RGBA()
+	if r == 0 || b != 0 {
+		t.Errorf("top-left pixel should be red, got r=%d b=%d", r, b)
+	}
+	r, _, b, _ = img.At(0, 1).RGBA()
+	if b == 0 || r != 0 {
+		t.Errorf("bottom-left pixel should be blue, got r=%d b=%d", r, b)
+	}
+
+	if _, err := decodeDIB(data[:20]); err == nil {
+		t.Error("expected error for truncated header")
+	}
+}
+
+func TestRestoreNewlines(t *testing.T) {
+	tests := []struct {
+		text string
+		crlf bool
+		want string
+	}{
+		{"a\nb", true, "a\r\nb"},
+		{"a\r\nb\nc", true, "a\r\nb\r\nc"},
+		{"a\r\nb\rc", false, "a\nb\nc"},
+		{"single line 😀", true, "single line 😀"},
+	}
+	for _, tt := range tests {
+		if got := restoreNewlines(tt.text, tt.crlf); got != tt.want {
+			t.Errorf("restoreNewlines(%q, %v) = %q, want %q", tt.text, tt.crlf, got, tt.want)
+		}
+	}
+}
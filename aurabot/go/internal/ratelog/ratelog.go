@@ -0,0 +1,107 @@
+// Package ratelog collapses repeated identical log lines, so a failure
+// that recurs on every tick doesn't flood the log.
+package ratelog
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// maxTracked bounds how many distinct messages are remembered; expired
+// ones are dropped once it is reached
+const maxTracked = 1000
+
+// Logger prints each distinct message at most once per window. Repeats
+// inside the window are counted and reported with the next occurrence
+// after it, or by Flush.
+type Logger struct {
+	window time.Duration
+
+	mu   sync.Mutex
+	seen map[string]*entry
+
+	// Replaceable in tests
+	now    func() time.Time
+	output func(msg string)
+}
+
+type entry struct {
+	logged  time.Time
+	repeats int
+}
+
+// New returns a Logger collapsing repeats within window; window <= 0
+// prints every message
+func New(window time.Duration) *Logger {
+	return &Logger{
+		window: window,
+		seen:   make(map[string]*entry),
+		now:    time.Now,
+		output: func(msg string) { log.Print(msg) },
+	}
+}
+
+// SetOutput replaces where messages go, log.Print by default
+func (l *Logger) SetOutput(output func(msg string)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.output = output
+}
+
+// Printf logs the formatted message unless it was already logged within
+// the window
+func (l *Logger) Printf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if l.window <= 0 {
+		l.output(msg)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	e, ok := l.seen[msg]
+	if ok && now.Sub(e.logged) < l.window {
+		e.repeats++
+		return
+	}
+
+	line := msg
+	if ok && e.repeats > 0 {
+		line = fmt.Sprintf("%s (repeated %d times)", msg, e.repeats)
+	}
+	if !ok && len(l.seen) >= maxTracked {
+		l.prune(now)
+	}
+	l.seen[msg] = &entry{logged: now}
+	l.output(line)
+}
+
+// prune forgets messages whose window has passed, reporting their repeats
+func (l *Logger) prune(now time.Time) {
+	for msg, e := range l.seen {
+		if now.Sub(e.logged) < l.window {
+			continue
+		}
+		if e.repeats > 0 {
+			l.output(fmt.Sprintf("%s (repeated %d times)", msg, e.repeats))
+		}
+		delete(l.seen, msg)
+	}
+}
+
+// Flush reports the repeats not logged yet, e.g. before shutdown
+func (l *Logger) Flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for msg, e := range l.seen {
+		if e.repeats > 0 {
+			l.output(fmt.Sprintf("%s (repeated %d times)", msg, e.repeats))
+			e.repeats = 0
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package ratelog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLogger_CollapsesRepeatsWithinWindow(t *testing.T) {
+	now := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	var lines []string
+	l := New(time.Minute)
+	l.now = func() time.Time { return now }
+	l.output = func(msg string) { lines = append(lines, msg) }
+
+	for i := 0; i < 5; i++ {
+		l.Printf("Capture failed: %v", "no displays")
+		now = now.Add(5 * time.Second)
+	}
+	l.Printf("Failed to store memory: %v", "timeout")
+
+	if len(lines) != 2 {
+		t.Fatalf("lines = %q, want the first of each message", lines)
+	}
+
+	// After the window the next occurrence reports the collapsed ones
+	now = now.Add(time.Minute)
+	l.Printf("Capture failed: %v", "no displays")
+	if want := "Capture failed: no displays (repeated 4 times)"; lines[2] != want {
+		t.Errorf("line = %q, want %q", lines[2], want)
+	}
+
+	l.Printf("Capture failed: %v", "no displays")
+	l.Flush()
+	if want := "Capture failed: no displays (repeated 1 times)"; len(lines) != 4 || lines[3] != want {
+		t.Errorf("after flush lines = %q, want %q last", lines, want)
+	}
+}
+
+func TestLogger_NoWindowPrintsAll(t *testing.T) {
+	var lines []string
+	l := New(0)
+	l.output = func(msg string) { lines = append(lines, msg) }
+
+	l.Printf("same")
+	l.Printf("same")
+	if len(lines) != 2 {
+		t.Errorf("lines = %q, want both", lines)
+	}
+}
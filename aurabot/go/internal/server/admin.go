@@ -0,0 +1,68 @@
+package server
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"screen-memory-assistant/internal/memory"
+)
+
+// SetAdminToken enables the admin endpoints, which require this token as
+// an "Authorization: Bearer" header; empty leaves them unregistered
+func (s *Server) SetAdminToken(token string) {
+	s.adminToken = token
+}
+
+// requireAdmin rejects requests without the admin token
+func (s *Server) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || s.adminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, r, http.StatusUnauthorized, "Admin token required")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// handleAdminSearch searches the memories of every user
+func (s *Server) handleAdminSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		writeError(w, r, http.StatusBadRequest, "Query parameter 'q' is required")
+		return
+	}
+
+	limit := 5
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = l
+	}
+
+	memories, err := s.enhancer.SearchAllUsers(r.Context(), query, limit)
+	if err != nil {
+		log.Printf("Admin memory search failed: %v", err)
+		status := http.StatusInternalServerError
+		if errors.Is(err, memory.ErrAdminRequired) {
+			status = http.StatusForbidden
+		}
+		writeError(w, r, status, fmt.Sprintf("Search failed: %v", err))
+		return
+	}
+
+	writeData(w, r, http.StatusOK, map[string]interface{}{
+		"query":    query,
+		"memories": memories,
+		"count":    len(memories),
+	})
+}
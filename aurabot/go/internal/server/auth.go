@@ -0,0 +1,59 @@
+package server
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// SetAPIKey requires this key as an "Authorization: Bearer" header on every
+// /api/* route except health, and on /metrics; empty leaves the API open
+func (s *Server) SetAPIKey(key string) {
+	s.apiKey = key
+}
+
+// requireAPIKey rejects /api/* and /metrics requests without the API key,
+// with the envelope on versioned routes. Health stays
+// open so clients can tell the app is running, and the admin endpoints
+// check their own token instead.
+func (s *Server) requireAPIKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.apiKey == "" || !needsAPIKey(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.apiKey)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, versionedRequest(r), http.StatusUnauthorized, "API key required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// needsAPIKey reports whether a path is guarded by the API key
+func needsAPIKey(path string) bool {
+	// Metrics reveal usage, so they need the key too
+	if path == "/metrics" {
+		return true
+	}
+	if !strings.HasPrefix(path, "/api/") {
+		return false
+	}
+	switch {
+	case path == apiPrefix+"/health":
+		return false
+	case strings.HasPrefix(path, apiPrefix+"/admin/"):
+		return false
+	}
+	return true
+}
+
+// warnIfOpen logs that the API is readable by any local process
+func (s *Server) warnIfOpen() {
+	if s.apiKey == "" {
+		log.Printf("Warning: extension.api_key is not set; any local process can read memories through the extension API")
+	}
+}
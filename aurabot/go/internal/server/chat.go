@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"screen-memory-assistant/internal/llm"
+)
+
+// ChatSource answers chat messages; see service.Service
+type ChatSource interface {
+	// Chat returns the whole answer along with the backend that gave it
+	Chat(ctx context.Context, message string) (*llm.ChatResponse, error)
+	// ChatStream sends the answer as it is generated
+	ChatStream(ctx context.Context, message string) (<-chan llm.Chunk, error)
+}
+
+// SetChatSource serves chat at /api/v1/chat and /api/v1/chat/stream; nil
+// leaves them unregistered
+func (s *Server) SetChatSource(chat ChatSource) {
+	s.chat = chat
+}
+
+// handleChat answers {"message": "..."} with the reply, the model and
+// backend that produced it, and whether it came from the local fallback
+func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req struct {
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Message == "" {
+		writeError(w, r, http.StatusBadRequest, "Field 'message' is required")
+		return
+	}
+
+	resp, err := s.chat.Chat(r.Context(), req.Message)
+	if err != nil {
+		log.Printf("Chat failed: %v", err)
+		writeError(w, r, http.StatusInternalServerError, fmt.Sprintf("Chat failed: %v", err))
+		return
+	}
+	writeData(w, r, http.StatusOK, resp)
+}
+
+// handleChatStream answers {"message": "..."} with Server-Sent Events: a
+// "chunk" event per piece of the answer, the first naming the backend,
+// then "done", or "error" if the stream fails part way
+func (s *Server) handleChatStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req struct {
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Message == "" {
+		writeError(w, r, http.StatusBadRequest, "Field 'message' is required")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, "Streaming unsupported")
+		return
+	}
+
+	chunks, err := s.chat.ChatStream(r.Context(), req.Message)
+	if err != nil {
+		log.Printf("Chat failed: %v", err)
+		writeError(w, r, http.StatusInternalServerError, fmt.Sprintf("Chat failed: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	for chunk := range chunks {
+		if chunk.Err != nil {
+			log.Printf("Chat stream failed: %v", chunk.Err)
+			writeEvent(w, "error", map[string]string{"message": chunk.Err.Error()})
+			flusher.Flush()
+			return
+		}
+		event := map[string]interface{}{"text": chunk.Text}
+		if chunk.Backend != "" {
+			event["backend"] = chunk.Backend
+			event["degraded"] = chunk.Degraded
+		}
+		writeEvent(w, "chunk", event)
+		flusher.Flush()
+	}
+
+	// The client went away; there is no one to tell
+	if r.Context().Err() != nil {
+		return
+	}
+	writeEvent(w, "done", struct{}{})
+	flusher.Flush()
+}
+
+// writeEvent writes one Server-Sent Event with a JSON payload
+func writeEvent(w http.ResponseWriter, event string, data interface{}) {
+	payload, _ := json.Marshal(data)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxBuckets bounds how many clients are tracked; full buckets are
+// dropped once it is reached
+const maxBuckets = 1000
+
+// rateLimiter is a token bucket per client. Each bucket refills at rate
+// tokens a second and holds up to one second's worth, at least one.
+type rateLimiter struct {
+	rate  float64
+	burst float64
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+
+	// Replaceable in tests
+	now func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate float64) *rateLimiter {
+	return &rateLimiter{
+		rate:    rate,
+		burst:   math.Max(rate, 1),
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// allow takes a token from key's bucket, or reports how long until one
+// is available
+func (l *rateLimiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= maxBuckets {
+			l.prune(now)
+		}
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// prune drops the buckets that have refilled, which behave like new ones
+func (l *rateLimiter) prune(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// SetRateLimit limits each client to perSec /api/* requests a second,
+// not counting health checks; <= 0 disables the limit
+func (s *Server) SetRateLimit(perSec float64) {
+	s.limiter = nil
+	if perSec > 0 {
+		s.limiter = newRateLimiter(perSec)
+	}
+}
+
+// rateLimit answers 429 with Retry-After to clients over the limit
+func (s *Server) rateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.limiter == nil || !isLimited(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if ok, wait := s.limiter.allow(clientKey(r)); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeError(w, versionedRequest(r), http.StatusTooManyRequests, "Too many requests")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isLimited reports whether a path counts against the rate limit
+func isLimited(path string) bool {
+	return strings.HasPrefix(path, "/api/") && path != apiPrefix+"/health"
+}
+
+// clientKey identifies the client by its remote address without the port
+func clientKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
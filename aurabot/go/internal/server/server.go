@@ -3,14 +3,18 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
 	"screen-memory-assistant/internal/enhancer"
+	"screen-memory-assistant/internal/memory"
 )
 
 // Server handles HTTP requests from browser extension
@@ -18,8 +22,27 @@ type Server struct {
 	enhancer   *enhancer.Enhancer
 	httpServer *http.Server
 	port       int
+	metrics    func(w io.Writer)
+	webUI      bool
+	// adminToken guards the admin endpoints; empty disables them
+	adminToken string
+	// apiKey guards the /api/* routes; empty leaves them open
+	apiKey string
+	// bindAddress is the interface to listen on
+	bindAddress string
+	// limiter caps requests per client; nil disables it
+	limiter *rateLimiter
+	// health reports the app's dependencies for /health; nil reports
+	// only that the server is up
+	health HealthSource
+	// chat answers chat messages; nil leaves the chat routes unregistered
+	chat ChatSource
 }
 
+// HealthSource returns the app's overall status ("ok", "degraded" or
+// "starting") and per-dependency details
+type HealthSource func() (status string, dependencies interface{})
+
 // New creates a new HTTP server
 func New(enhancer *enhancer.Enhancer, port int) *Server {
 	if port <= 0 {
@@ -27,30 +50,44 @@ func New(enhancer *enhancer.Enhancer, port int) *Server {
 	}
 
 	return &Server{
-		enhancer: enhancer,
-		port:     port,
+		enhancer:    enhancer,
+		port:        port,
+		bindAddress: "127.0.0.1",
 	}
 }
 
-// Start begins listening for requests
-func (s *Server) Start() error {
-	mux := http.NewServeMux()
+// SetBindAddress sets the interface to listen on, e.g. "0.0.0.0" for LAN
+// access; empty keeps the localhost-only default
+func (s *Server) SetBindAddress(address string) {
+	if address != "" {
+		s.bindAddress = address
+	}
+}
 
-	// CORS middleware
-	handler := corsMiddleware(mux)
+// addr is the host:port the server listens on
+func (s *Server) addr() string {
+	return net.JoinHostPort(s.bindAddress, strconv.Itoa(s.port))
+}
 
-	// Routes
-	mux.HandleFunc("/health", s.handleHealth)
-	mux.HandleFunc("/api/enhance", s.handleEnhance)
-	mux.HandleFunc("/api/memories/search", s.handleMemorySearch)
-	mux.HandleFunc("/api/status", s.handleStatus)
+// SetHealthSource adds the app's dependency status to /health
+func (s *Server) SetHealthSource(health HealthSource) {
+	s.health = health
+}
 
+// SetMetricsSource sets the writer used to render the /metrics endpoint
+func (s *Server) SetMetricsSource(metrics func(w io.Writer)) {
+	s.metrics = metrics
+}
+
+// Start begins listening for requests
+func (s *Server) Start() error {
 	s.httpServer = &http.Server{
-		Addr:    fmt.Sprintf(":%d", s.port),
-		Handler: handler,
+		Addr:    s.addr(),
+		Handler: s.routes(),
 	}
 
-	log.Printf("Extension server starting on port %d", s.port)
+	log.Printf("Extension server starting on %s", s.addr())
+	s.warnIfOpen()
 
 	go func() {
 		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -61,6 +98,59 @@ func (s *Server) Start() error {
 	return nil
 }
 
+// routes builds the request handler with versioned routes, their deprecated
+// unversioned aliases, rate limiting, API key checks and CORS
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+
+	// CORS middleware runs first so preflight requests need no key; the
+	// rate limit comes before the key so guessing it is throttled too
+	handler := corsMiddleware(s.rateLimit(s.requireAPIKey(mux)))
+
+	// Versioned routes answer with the {data, error, meta} envelope; the
+	// legacy paths keep their old shapes until clients have migrated
+	api := []struct {
+		legacy  string
+		path    string
+		handler http.HandlerFunc
+	}{
+		{"/health", "/health", s.handleHealth},
+		{"/api/enhance", "/enhance", s.handleEnhance},
+		{"/api/enhance/test", "/enhance/test", s.handleEnhanceTest},
+		{"/api/memories", "/memories", s.handleMemories},
+		{"/api/memories/search", "/memories/search", s.handleMemorySearch},
+		{"/api/memories/recent", "/memories/recent", s.handleMemoryRecentOnly},
+		{"/api/memories/{id}", "/memories/{id}", s.handleMemoryDelete},
+		{"/api/memories/{id}/similar", "/memories/{id}/similar", s.handleMemorySimilar},
+		{"/api/status", "/status", s.handleStatus},
+		{"/api/capabilities", "/capabilities", s.handleCapabilities},
+	}
+	for _, route := range api {
+		versioned := apiPrefix + route.path
+		mux.HandleFunc(versioned, withEnvelope(route.handler))
+		mux.HandleFunc(route.legacy, deprecated(route.handler, versioned))
+	}
+
+	// Admin endpoints have no legacy alias and only exist with a token
+	if s.adminToken != "" {
+		mux.HandleFunc(apiPrefix+"/admin/memories/search", withEnvelope(s.requireAdmin(s.handleAdminSearch)))
+	}
+
+	// Chat streams Server-Sent Events, so it has no legacy alias either
+	if s.chat != nil {
+		mux.HandleFunc(apiPrefix+"/chat", withEnvelope(s.handleChat))
+		mux.HandleFunc(apiPrefix+"/chat/stream", withEnvelope(s.handleChatStream))
+	}
+
+	mux.HandleFunc("/metrics", s.handleMetrics)
+
+	if s.webUI {
+		mux.Handle("/", webHandler())
+	}
+
+	return handler
+}
+
 // Stop gracefully shuts down the server
 func (s *Server) Stop(ctx context.Context) error {
 	if s.httpServer == nil {
@@ -71,11 +161,11 @@ func (s *Server) Stop(ctx context.Context) error {
 
 // allowedOrigins defines the list of permitted CORS origins
 var allowedOrigins = []string{
-	"http://localhost:3000",    // React dev server
-	"http://localhost:8080",    // Swift app
-	"http://localhost:7345",    // Extension API
-	"chrome-extension://",      // Chrome extension (prefix match)
-	"https://chat.openai.com",  // ChatGPT
+	"http://localhost:3000",   // React dev server
+	"http://localhost:8080",   // Swift app
+	"http://localhost:7345",   // Extension API
+	"chrome-extension://",     // Chrome extension (prefix match)
+	"https://chat.openai.com", // ChatGPT
 	"https://chatgpt.com",
 	"https://claude.ai",
 	"https://gemini.google.com",
@@ -103,14 +193,14 @@ func isAllowedOrigin(origin string) bool {
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		origin := r.Header.Get("Origin")
-		
+
 		// Only set CORS headers for allowed origins
 		if isAllowedOrigin(origin) {
 			if origin == "" {
 				origin = "http://localhost:3000"
 			}
 			w.Header().Set("Access-Control-Allow-Origin", origin)
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
 			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 			w.Header().Set("Access-Control-Allow-Credentials", "true")
 		}
@@ -127,7 +217,7 @@ func corsMiddleware(next http.Handler) http.Handler {
 // handleHealth returns server status
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
@@ -136,41 +226,56 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 		"service":   "aurabot-extension-api",
 		"timestamp": time.Now().Unix(),
 	}
+	// The server answers even while a dependency is down, so the UI can
+	// say which one rather than showing the app as offline
+	if s.health != nil {
+		status, dependencies := s.health()
+		response["status"] = status
+		response["dependencies"] = dependencies
+	}
 
-	writeJSON(w, response)
+	writeData(w, r, http.StatusOK, response)
 }
 
 // handleEnhanceRequest represents a prompt enhancement request
 type handleEnhanceRequest struct {
 	Prompt      string `json:"prompt"`
-	Context     string `json:"context,omitempty"`     // Optional page context (e.g., "chatgpt", "claude")
+	Context     string `json:"context,omitempty"`      // Optional page context (e.g., "chatgpt", "claude")
 	MaxMemories int    `json:"max_memories,omitempty"` // Max memories to include
+	Explain     bool   `json:"explain,omitempty"`      // Include why the enhancement type was chosen
+	// Optional per-request recency ranking: true/false or a weight in [0, 1]
+	RecencyBoost json.RawMessage `json:"recency_boost,omitempty"`
+	// Optional URL of the page, fetched for extra context if its host is allowed
+	URL string `json:"url,omitempty"`
 }
 
 // handleEnhanceResponse represents the enhancement response
 type handleEnhanceResponse struct {
-	OriginalPrompt   string   `json:"original_prompt"`
-	EnhancedPrompt   string   `json:"enhanced_prompt"`
-	MemoriesUsed     []string `json:"memories_used"`
-	MemoryCount      int      `json:"memory_count"`
-	EnhancementType  string   `json:"enhancement_type"`
+	OriginalPrompt  string                `json:"original_prompt"`
+	EnhancedPrompt  string                `json:"enhanced_prompt"`
+	MemoriesUsed    []string              `json:"memories_used"`
+	MemoryCount     int                   `json:"memory_count"`
+	EnhancementType string                `json:"enhancement_type"`
+	Explanation     *enhancer.Explanation `json:"explanation,omitempty"`
+	// DroppedForBudget counts memories left out to fit max_prompt_tokens
+	DroppedForBudget int `json:"dropped_for_budget,omitempty"`
 }
 
 // handleEnhance enhances a prompt with relevant memories
 func (s *Server) handleEnhance(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	var req handleEnhanceRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
 		return
 	}
 
 	if req.Prompt == "" {
-		http.Error(w, "Prompt is required", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Prompt is required")
 		return
 	}
 
@@ -178,11 +283,21 @@ func (s *Server) handleEnhance(w http.ResponseWriter, r *http.Request) {
 		req.MaxMemories = 5
 	}
 
+	ctx, err := s.recencyContext(r.Context(), strings.Trim(string(req.RecencyBoost), `"`))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if req.URL != "" {
+		ctx = enhancer.WithPageURL(ctx, req.URL)
+	}
+
 	// Enhance the prompt
-	result, err := s.enhancer.Enhance(r.Context(), req.Prompt, req.Context, req.MaxMemories)
+	result, err := s.enhancer.Enhance(ctx, req.Prompt, req.Context, req.MaxMemories)
 	if err != nil {
 		log.Printf("Enhancement failed: %v", err)
-		http.Error(w, fmt.Sprintf("Enhancement failed: %v", err), http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, fmt.Sprintf("Enhancement failed: %v", err))
 		return
 	}
 
@@ -192,21 +307,169 @@ func (s *Server) handleEnhance(w http.ResponseWriter, r *http.Request) {
 		MemoriesUsed:    result.MemoriesUsed,
 		MemoryCount:     len(result.MemoriesUsed),
 		EnhancementType: result.EnhancementType,
+
+		DroppedForBudget: result.DroppedForBudget,
+	}
+	if req.Explain {
+		response.Explanation = result.Explanation
+	}
+
+	writeData(w, r, http.StatusOK, response)
+}
+
+// handleEnhanceTestRequest is an enhance request with explicit memories
+type handleEnhanceTestRequest struct {
+	Prompt   string `json:"prompt"`
+	Context  string `json:"context,omitempty"`
+	Memories []struct {
+		Content string  `json:"content"`
+		Context string  `json:"context,omitempty"`
+		Score   float64 `json:"score"`
+	} `json:"memories"`
+}
+
+// handleEnhanceTest enhances a prompt with the memories given in the
+// request instead of the store, so templating can be tried out
+// deterministically. The explanation is always included.
+func (s *Server) handleEnhanceTest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req handleEnhanceTestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+		return
+	}
+
+	if req.Prompt == "" {
+		writeError(w, r, http.StatusBadRequest, "Prompt is required")
+		return
+	}
+
+	results := make([]memory.SearchResult, 0, len(req.Memories))
+	for i, m := range req.Memories {
+		results = append(results, memory.SearchResult{
+			Memory: memory.Memory{
+				ID:       fmt.Sprintf("sample-%d", i),
+				Content:  m.Content,
+				Metadata: memory.Metadata{Context: m.Context},
+			},
+			Score: m.Score,
+		})
+	}
+
+	result := s.enhancer.EnhanceWithMemories(req.Prompt, req.Context, results)
+
+	writeData(w, r, http.StatusOK, handleEnhanceResponse{
+		OriginalPrompt:  req.Prompt,
+		EnhancedPrompt:  result.EnhancedPrompt,
+		MemoriesUsed:    result.MemoriesUsed,
+		MemoryCount:     len(result.MemoriesUsed),
+		EnhancementType: result.EnhancementType,
+		Explanation:     result.Explanation,
+	})
+}
+
+// handleMemories lists recent memories or stores a new one
+func (s *Server) handleMemories(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.handleMemoryRecent(w, r)
+	case http.MethodPost:
+		s.handleMemoryAdd(w, r)
+	default:
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// maxRecentLimit caps how many recent memories one request can list
+const maxRecentLimit = 50
+
+// maxSearchLimit caps how many search results one request can return
+const maxSearchLimit = 50
+
+// handleMemoryRecentOnly serves the recent-activity listing on its own path
+func (s *Server) handleMemoryRecentOnly(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	s.handleMemoryRecent(w, r)
+}
+
+// handleMemoryRecent returns the most recent memories
+func (s *Server) handleMemoryRecent(w http.ResponseWriter, r *http.Request) {
+	limit := 10
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l <= 0 {
+			writeError(w, r, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = min(l, maxRecentLimit)
+	}
+	source := r.URL.Query().Get("source")
+
+	memories, err := s.enhancer.GetRecentMemories(source, limit)
+	if err != nil {
+		log.Printf("Listing memories failed: %v", err)
+		writeError(w, r, http.StatusInternalServerError, fmt.Sprintf("Listing failed: %v", err))
+		return
+	}
+
+	writeData(w, r, http.StatusOK, map[string]interface{}{
+		"source":   source,
+		"memories": memories,
+		"count":    len(memories),
+	})
+}
+
+// handleAddMemoryRequest represents a memory submitted by the extension
+type handleAddMemoryRequest struct {
+	Content string `json:"content"`
+	Context string `json:"context,omitempty"` // Optional page context
+}
+
+// handleMemoryAdd stores a memory sent by the browser extension
+func (s *Server) handleMemoryAdd(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req handleAddMemoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+		return
 	}
 
-	writeJSON(w, response)
+	if strings.TrimSpace(req.Content) == "" {
+		writeError(w, r, http.StatusBadRequest, "Content is required")
+		return
+	}
+
+	info, err := s.enhancer.AddMemory(req.Content, req.Context, memory.SourceExtension)
+	if err != nil {
+		log.Printf("Adding memory failed: %v", err)
+		writeError(w, r, http.StatusInternalServerError, fmt.Sprintf("Add failed: %v", err))
+		return
+	}
+
+	writeData(w, r, http.StatusCreated, info)
 }
 
 // handleMemorySearch searches memories without enhancing
 func (s *Server) handleMemorySearch(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	query := r.URL.Query().Get("q")
 	if query == "" {
-		http.Error(w, "Query parameter 'q' is required", http.StatusBadRequest)
+		writeError(w, r, http.StatusBadRequest, "Query parameter 'q' is required")
 		return
 	}
 
@@ -214,39 +477,204 @@ func (s *Server) handleMemorySearch(w http.ResponseWriter, r *http.Request) {
 	limit := 5
 	if limitStr != "" {
 		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
-			limit = l
+			limit = min(l, maxSearchLimit)
+		}
+	}
+
+	// Optional paging through older results; 0 starts at the top
+	offset := 0
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		o, err := strconv.Atoi(offsetStr)
+		if err != nil || o < 0 {
+			writeError(w, r, http.StatusBadRequest, "offset must be a non-negative integer")
+			return
+		}
+		offset = o
+	}
+
+	// Optional natural time window, e.g. "today" or "last 7 days"
+	window := r.URL.Query().Get("window")
+	if window != "" {
+		if _, _, err := memory.RelativeWindow(window, time.Now()); err != nil {
+			writeError(w, r, http.StatusBadRequest, err.Error())
+			return
 		}
 	}
+	// Optional origin filter, e.g. "capture" or "extension"
+	source := r.URL.Query().Get("source")
 
-	memories, err := s.enhancer.SearchMemories(r.Context(), query, limit)
+	ctx, err := s.recencyContext(r.Context(), r.URL.Query().Get("recency_boost"))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	// Optional per-result scoring details for tuning relevance
+	if r.URL.Query().Get("debug") == "true" {
+		ctx = enhancer.WithScoreDebug(ctx)
+	}
+
+	var memories []enhancer.MemoryInfo
+	if window != "" {
+		// Time windows are filtered client-side, so the page comes last
+		memories, err = s.enhancer.SearchMemoriesRelative(ctx, query, source, window, offset+limit)
+		if offset < len(memories) {
+			memories = memories[offset:]
+		} else {
+			memories = nil
+		}
+	} else {
+		memories, err = s.enhancer.SearchMemoriesPaged(ctx, query, source, limit, offset)
+	}
 	if err != nil {
 		log.Printf("Memory search failed: %v", err)
-		http.Error(w, fmt.Sprintf("Search failed: %v", err), http.StatusInternalServerError)
+		writeError(w, r, http.StatusInternalServerError, fmt.Sprintf("Search failed: %v", err))
 		return
 	}
 
-	writeJSON(w, map[string]interface{}{
+	writeData(w, r, http.StatusOK, map[string]interface{}{
 		"query":    query,
+		"window":   window,
+		"source":   source,
+		"offset":   offset,
 		"memories": memories,
 		"count":    len(memories),
 	})
 }
 
+// handleMemorySimilar returns the memories most similar to a stored one
+func (s *Server) handleMemorySimilar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id := r.PathValue("id")
+
+	n := 5
+	if nStr := r.URL.Query().Get("n"); nStr != "" {
+		if v, err := strconv.Atoi(nStr); err == nil && v > 0 {
+			n = v
+		}
+	}
+
+	memories, err := s.enhancer.SimilarMemories(r.Context(), id, n)
+	if errors.Is(err, memory.ErrNotFound) {
+		writeError(w, r, http.StatusNotFound, fmt.Sprintf("Memory %q not found", id))
+		return
+	}
+	if err != nil {
+		log.Printf("Similar memory search failed: %v", err)
+		writeError(w, r, http.StatusInternalServerError, fmt.Sprintf("Search failed: %v", err))
+		return
+	}
+
+	writeData(w, r, http.StatusOK, map[string]interface{}{
+		"id":       id,
+		"memories": memories,
+		"count":    len(memories),
+	})
+}
+
+// handleMemoryDelete removes a memory, e.g. a bad capture spotted in the UI
+func (s *Server) handleMemoryDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id := r.PathValue("id")
+
+	// Results from extra collections carry their collection; IDs are only
+	// unique within one
+	err := s.enhancer.DeleteMemory(r.URL.Query().Get("collection"), id)
+	if errors.Is(err, memory.ErrNotFound) {
+		writeError(w, r, http.StatusNotFound, fmt.Sprintf("Memory %q not found", id))
+		return
+	}
+	if err != nil {
+		log.Printf("Memory delete failed: %v", err)
+		writeError(w, r, http.StatusInternalServerError, fmt.Sprintf("Delete failed: %v", err))
+		return
+	}
+
+	writeData(w, r, http.StatusOK, map[string]interface{}{
+		"id":      id,
+		"deleted": true,
+	})
+}
+
+// recencyContext applies a per-request recency_boost ("true", "false" or a
+// weight in [0, 1]) to ctx. An empty value keeps the configured ranking.
+func (s *Server) recencyContext(ctx context.Context, boost string) (context.Context, error) {
+	switch boost {
+	case "", "null":
+		return ctx, nil
+	case "true":
+		return enhancer.WithRecencyBoost(ctx, s.enhancer.RecencyBoostWeight()), nil
+	case "false":
+		return enhancer.WithRecencyBoost(ctx, 0), nil
+	}
+
+	weight, err := strconv.ParseFloat(boost, 64)
+	if err != nil || weight < 0 || weight > 1 {
+		return nil, fmt.Errorf("recency_boost must be true, false or a weight between 0 and 1")
+	}
+	return enhancer.WithRecencyBoost(ctx, weight), nil
+}
+
 // handleStatus returns the current service status
 func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	stats := s.enhancer.GetStats()
-	writeJSON(w, map[string]interface{}{
-		"status":  "running",
-		"port":    s.port,
-		"stats":   stats,
+	writeData(w, r, http.StatusOK, map[string]interface{}{
+		"status": "running",
+		"port":   s.port,
+		"stats":  stats,
 	})
 }
 
+// handleCapabilities reports the enhancement options and features the
+// server supports, so clients can adapt instead of hard-coding them
+func (s *Server) handleCapabilities(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	writeData(w, r, http.StatusOK, map[string]interface{}{
+		"enhancer": s.enhancer.Capabilities(),
+		"limits": map[string]int{
+			"default_max_memories":  5,
+			"default_search_limit":  5,
+			"default_memories_list": 10,
+		},
+		"features": map[string]bool{
+			"auth":      s.apiKey != "",
+			"streaming": s.chat != nil,
+			"metrics":   s.metrics != nil,
+			"web_ui":    s.webUI,
+			"admin":     s.adminToken != "",
+		},
+	})
+}
+
+// handleMetrics returns counters in Prometheus text format
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	if s.metrics != nil {
+		s.metrics(w)
+	}
+}
+
 // writeJSON writes a JSON response
 func writeJSON(w http.ResponseWriter, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
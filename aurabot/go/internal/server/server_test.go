@@ -0,0 +1,977 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"screen-memory-assistant/internal/config"
+	"screen-memory-assistant/internal/enhancer"
+	"screen-memory-assistant/internal/llm"
+	"screen-memory-assistant/internal/memory"
+)
+
+// fakeMem0 records the metadata of every memory added
+func fakeMem0(t *testing.T, added *[]memory.Metadata) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Metadata memory.Metadata `json:"metadata"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		*added = append(*added, payload.Metadata)
+		w.WriteHeader(http.StatusCreated)
+	}))
+}
+
+func TestHandleMemoryAdd_StampsExtensionSource(t *testing.T) {
+	var added []memory.Metadata
+	mem0 := fakeMem0(t, &added)
+	defer mem0.Close()
+
+	store := memory.NewStore(&config.MemoryConfig{BaseURL: mem0.URL})
+	s := New(enhancer.New(store), 0)
+
+	body := strings.NewReader(`{"content": "Reading about Go generics", "context": "chatgpt"}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/memories", body)
+	rec := httptest.NewRecorder()
+
+	s.handleMemoryAdd(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	if len(added) != 1 {
+		t.Fatalf("memories added = %d, want 1", len(added))
+	}
+	if added[0].Source != memory.SourceExtension {
+		t.Errorf("source = %q, want %q", added[0].Source, memory.SourceExtension)
+	}
+	if added[0].Context != "chatgpt" {
+		t.Errorf("context = %q, want 'chatgpt'", added[0].Context)
+	}
+}
+
+func TestHandleMemoryAdd_RequiresContent(t *testing.T) {
+	s := New(enhancer.New(memory.NewStore(&config.MemoryConfig{})), 0)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/memories", strings.NewReader(`{"content": "  "}`))
+	rec := httptest.NewRecorder()
+
+	s.handleMemoryAdd(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestHandleMemorySearch_Window(t *testing.T) {
+	now := time.Now().Format(time.RFC3339)
+	mem0 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": []map[string]interface{}{
+			{"id": "m1", "memory": "captured screen", "score": 0.9, "metadata": map[string]string{"timestamp": now}},
+			{"id": "m2", "memory": "saved from extension", "score": 0.8, "metadata": map[string]string{"timestamp": now, "source": "extension"}},
+		}})
+	}))
+	defer mem0.Close()
+
+	s := New(enhancer.New(memory.NewStore(&config.MemoryConfig{BaseURL: mem0.URL})), 0)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/memories/search?q=x&window=fortnight", nil)
+	rec := httptest.NewRecorder()
+	s.handleMemorySearch(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid window: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/memories/search?q=x&window=today&source=extension", nil)
+	rec = httptest.NewRecorder()
+	s.handleMemorySearch(rec, req)
+	var body struct {
+		Memories []enhancer.MemoryInfo `json:"memories"`
+	}
+	json.NewDecoder(rec.Body).Decode(&body)
+	if rec.Code != http.StatusOK || len(body.Memories) != 1 || body.Memories[0].ID != "m2" {
+		t.Errorf("status = %d, memories = %+v; want only the extension memory", rec.Code, body.Memories)
+	}
+}
+
+func TestVersionedRoutes_Envelope(t *testing.T) {
+	var added []memory.Metadata
+	mem0 := fakeMem0(t, &added)
+	defer mem0.Close()
+
+	s := New(enhancer.New(memory.NewStore(&config.MemoryConfig{BaseURL: mem0.URL})), 0)
+	handler := s.routes()
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantError  bool
+	}{
+		{"health", http.MethodGet, "/api/v1/health", "", http.StatusOK, false},
+		{"status", http.MethodGet, "/api/v1/status", "", http.StatusOK, false},
+		{"add memory", http.MethodPost, "/api/v1/memories", `{"content": "note"}`, http.StatusCreated, false},
+		{"search without query", http.MethodGet, "/api/v1/memories/search", "", http.StatusBadRequest, true},
+		{"enhance without prompt", http.MethodPost, "/api/v1/enhance", `{}`, http.StatusBadRequest, true},
+		{"wrong method", http.MethodDelete, "/api/v1/status", "", http.StatusMethodNotAllowed, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+
+			var env map[string]json.RawMessage
+			if err := json.Unmarshal(rec.Body.Bytes(), &env); err != nil {
+				t.Fatalf("response is not JSON: %v: %s", err, rec.Body.String())
+			}
+			for _, key := range []string{"data", "error", "meta"} {
+				if _, ok := env[key]; !ok {
+					t.Errorf("envelope missing %q: %s", key, rec.Body.String())
+				}
+			}
+
+			var meta envelopeMeta
+			json.Unmarshal(env["meta"], &meta)
+			if meta.Version != APIVersion {
+				t.Errorf("meta.version = %q, want %q", meta.Version, APIVersion)
+			}
+
+			hasError := string(env["error"]) != "null"
+			if hasError != tt.wantError {
+				t.Errorf("error = %s, want error present: %v", env["error"], tt.wantError)
+			}
+		})
+	}
+}
+
+func TestLegacyRoutes_DeprecatedAliases(t *testing.T) {
+	s := New(enhancer.New(memory.NewStore(&config.MemoryConfig{})), 0)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/status", nil)
+	rec := httptest.NewRecorder()
+	s.routes().ServeHTTP(rec, req)
+
+	if rec.Header().Get("Deprecation") != "true" {
+		t.Error("legacy route should set Deprecation header")
+	}
+	if !strings.Contains(rec.Header().Get("Link"), "/api/v1/status") {
+		t.Errorf("Link = %q, want successor /api/v1/status", rec.Header().Get("Link"))
+	}
+
+	var body map[string]interface{}
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	if body["status"] != "running" {
+		t.Errorf("legacy body = %v, want old {status, port, stats} shape", body)
+	}
+}
+
+func TestHandleMemorySimilar_ExcludesSourceAndOrdersByScore(t *testing.T) {
+	var searchQuery string
+	mem0 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			json.NewEncoder(w).Encode([]map[string]interface{}{
+				{"id": "m1", "content": "Debugging the Go capture loop"},
+				{"id": "m2", "content": "Lunch plans"},
+			})
+		case http.MethodPost:
+			var payload struct {
+				Query string `json:"query"`
+			}
+			json.NewDecoder(r.Body).Decode(&payload)
+			searchQuery = payload.Query
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"results": []map[string]interface{}{
+					{"id": "m3", "memory": "Profiling capture", "score": 0.6},
+					{"id": "m1", "memory": "Debugging the Go capture loop", "score": 1.0},
+					{"id": "m4", "memory": "Capture loop fix", "score": 0.9},
+				},
+			})
+		}
+	}))
+	defer mem0.Close()
+
+	s := New(enhancer.New(memory.NewStore(&config.MemoryConfig{BaseURL: mem0.URL})), 0)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/memories/m1/similar?n=2", nil)
+	rec := httptest.NewRecorder()
+	s.routes().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if searchQuery != "Debugging the Go capture loop" {
+		t.Errorf("search query = %q, want the source memory's content", searchQuery)
+	}
+
+	var resp struct {
+		Data struct {
+			Memories []enhancer.MemoryInfo `json:"memories"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+
+	var ids []string
+	for i, m := range resp.Data.Memories {
+		ids = append(ids, m.ID)
+		if i > 0 && m.Score > resp.Data.Memories[i-1].Score {
+			t.Errorf("results not ordered by score: %v", resp.Data.Memories)
+		}
+	}
+	if strings.Join(ids, ",") != "m4,m3" {
+		t.Errorf("similar ids = %v, want [m4 m3]", ids)
+	}
+}
+
+func TestHandleMemorySimilar_UnknownID(t *testing.T) {
+	mem0 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	}))
+	defer mem0.Close()
+
+	s := New(enhancer.New(memory.NewStore(&config.MemoryConfig{BaseURL: mem0.URL})), 0)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/memories/missing/similar", nil)
+	rec := httptest.NewRecorder()
+	s.routes().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestHandleMemoryDelete(t *testing.T) {
+	var deleted []string
+	mem0 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("mem0 got %s, want DELETE", r.Method)
+		}
+		if r.URL.Path == "/v1/memories/missing/" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		deleted = append(deleted, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer mem0.Close()
+
+	s := New(enhancer.New(memory.NewStore(&config.MemoryConfig{BaseURL: mem0.URL})), 0)
+	handler := s.routes()
+
+	tests := []struct {
+		path       string
+		wantStatus int
+	}{
+		{"/api/v1/memories/m1", http.StatusOK},
+		{"/api/memories/m2", http.StatusOK},
+		{"/api/v1/memories/missing", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodDelete, tt.path, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.wantStatus {
+			t.Errorf("DELETE %s: status = %d, want %d: %s", tt.path, rec.Code, tt.wantStatus, rec.Body.String())
+		}
+	}
+	if strings.Join(deleted, ",") != "/v1/memories/m1/,/v1/memories/m2/" {
+		t.Errorf("deleted = %v, want m1 and m2", deleted)
+	}
+
+	// Browsers preflight DELETE from the extension
+	req := httptest.NewRequest(http.MethodOptions, "/api/v1/memories/m1", nil)
+	req.Header.Set("Origin", "chrome-extension://abc")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if !strings.Contains(rec.Header().Get("Access-Control-Allow-Methods"), "DELETE") {
+		t.Errorf("Access-Control-Allow-Methods = %q, want DELETE", rec.Header().Get("Access-Control-Allow-Methods"))
+	}
+}
+
+func TestHandleMemoryRecent(t *testing.T) {
+	var limits []string
+	mem0 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limits = append(limits, r.URL.Query().Get("limit"))
+		w.Write([]byte(`[{"id":"m1","memory":"Reviewed a PR","metadata":{"context":"work"}}]`))
+	}))
+	defer mem0.Close()
+
+	s := New(enhancer.New(memory.NewStore(&config.MemoryConfig{BaseURL: mem0.URL})), 0)
+	handler := s.routes()
+
+	tests := []struct {
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{http.MethodGet, "/api/v1/memories/recent", http.StatusOK},
+		{http.MethodGet, "/api/memories/recent?limit=3", http.StatusOK},
+		{http.MethodGet, "/api/v1/memories/recent?limit=500", http.StatusOK},
+		{http.MethodGet, "/api/v1/memories/recent?limit=abc", http.StatusBadRequest},
+		{http.MethodGet, "/api/v1/memories/recent?limit=0", http.StatusBadRequest},
+		{http.MethodPost, "/api/v1/memories/recent", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.wantStatus {
+			t.Errorf("%s %s: status = %d, want %d: %s", tt.method, tt.path, rec.Code, tt.wantStatus, rec.Body.String())
+		}
+		if tt.path == "/api/memories/recent?limit=3" && !strings.Contains(rec.Body.String(), `"content":"Reviewed a PR"`) {
+			t.Errorf("recent memories = %s, want the stored memory", rec.Body.String())
+		}
+	}
+
+	// The default, the given limit and the clamped one reach the store
+	if strings.Join(limits, ",") != "10,3,50" {
+		t.Errorf("limits sent to the store = %v, want 10, 3 and 50", limits)
+	}
+}
+
+func TestWebUI_ServesAssets(t *testing.T) {
+	s := New(enhancer.New(memory.NewStore(&config.MemoryConfig{})), 0)
+	s.SetWebUI(true)
+	handler := s.routes()
+
+	tests := []struct {
+		path        string
+		contentType string
+		contains    string
+	}{
+		{"/", "text/html", "<title>AuraBot</title>"},
+		{"/app.js", "javascript", "/api/v1"},
+		{"/style.css", "text/css", ".container"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %s status = %d, want %d", tt.path, rec.Code, http.StatusOK)
+			continue
+		}
+		if ct := rec.Header().Get("Content-Type"); !strings.Contains(ct, tt.contentType) {
+			t.Errorf("GET %s Content-Type = %q, want %s", tt.path, ct, tt.contentType)
+		}
+		if !strings.Contains(rec.Body.String(), tt.contains) {
+			t.Errorf("GET %s body missing %q", tt.path, tt.contains)
+		}
+	}
+}
+
+func TestWebUI_DisabledByDefault(t *testing.T) {
+	s := New(enhancer.New(memory.NewStore(&config.MemoryConfig{})), 0)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	s.routes().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d with the web UI off", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestHandleEnhance_ExplanationOnRequest(t *testing.T) {
+	mem0 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"results": []map[string]interface{}{{"id": "m1", "memory": "Go service", "score": 0.9}},
+		})
+	}))
+	defer mem0.Close()
+
+	s := New(enhancer.New(memory.NewStore(&config.MemoryConfig{BaseURL: mem0.URL})), 0)
+
+	for _, explain := range []bool{false, true} {
+		body := strings.NewReader(fmt.Sprintf(`{"prompt": "help", "explain": %v}`, explain))
+		req := httptest.NewRequest(http.MethodPost, "/api/enhance", body)
+		rec := httptest.NewRecorder()
+		s.handleEnhance(rec, req)
+
+		var resp map[string]json.RawMessage
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		if _, ok := resp["explanation"]; ok != explain {
+			t.Errorf("explain=%v: explanation present = %v", explain, ok)
+		}
+	}
+}
+
+func TestHandleMemorySearch_RecencyBoost(t *testing.T) {
+	now := time.Now()
+	mem0 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"results": []map[string]interface{}{
+				{"id": "old", "memory": "a", "score": 0.9, "created_at": now.Add(-30 * 24 * time.Hour).Format(time.RFC3339)},
+				{"id": "new", "memory": "b", "score": 0.7, "created_at": now.Add(-time.Hour).Format(time.RFC3339)},
+			},
+		})
+	}))
+	defer mem0.Close()
+
+	s := New(enhancer.New(memory.NewStore(&config.MemoryConfig{BaseURL: mem0.URL})), 0)
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", "old,new"},
+		{"&recency_boost=false", "old,new"},
+		{"&recency_boost=true", "new,old"},
+		{"&recency_boost=0.8", "new,old"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/memories/search?q=x"+tt.query, nil)
+		rec := httptest.NewRecorder()
+		s.handleMemorySearch(rec, req)
+
+		var resp struct {
+			Memories []enhancer.MemoryInfo `json:"memories"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: decoding response: %v: %s", tt.query, err, rec.Body.String())
+		}
+		var ids []string
+		for _, m := range resp.Memories {
+			ids = append(ids, m.ID)
+		}
+		if got := strings.Join(ids, ","); got != tt.want {
+			t.Errorf("%q: order = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/memories/search?q=x&recency_boost=2", nil)
+	rec := httptest.NewRecorder()
+	s.handleMemorySearch(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("out-of-range weight status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestHandleEnhanceTest_UsesGivenMemories(t *testing.T) {
+	// The store is unreachable: the sample memories must be used instead
+	s := New(enhancer.New(memory.NewStore(&config.MemoryConfig{BaseURL: "http://127.0.0.1:1"})), 0)
+
+	body := `{
+		"prompt": "Draft the release notes",
+		"memories": [
+			{"content": "Release 2.3 added SSO", "context": "work", "score": 0.95},
+			{"content": "Release 2.3 fixed the upload bug", "score": 0.9},
+			{"content": "Lunch with Sam", "score": 0.2}
+		]
+	}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/enhance/test", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	s.routes().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+
+	var resp struct {
+		Data struct {
+			EnhancedPrompt  string               `json:"enhanced_prompt"`
+			EnhancementType string               `json:"enhancement_type"`
+			Explanation     enhancer.Explanation `json:"explanation"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+
+	want := "Draft the release notes\n\n" +
+		"[Context from previous sessions]\n" +
+		"Based on my previous activities and context:\n" +
+		"- Release 2.3 added SSO\n" +
+		"- Release 2.3 fixed the upload bug\n" +
+		"\nAdditional context:\n" +
+		"- Lunch with Sam\n"
+	if resp.Data.EnhancedPrompt != want {
+		t.Errorf("enhanced prompt = %q, want %q", resp.Data.EnhancedPrompt, want)
+	}
+	if resp.Data.EnhancementType != "contextual" || resp.Data.Explanation.HighRelevance != 2 {
+		t.Errorf("type = %q, explanation = %+v", resp.Data.EnhancementType, resp.Data.Explanation)
+	}
+
+	// Trying out the enhancer doesn't count as an enhancement
+	if got := s.enhancer.GetStats().EnhancementsMade; got != 0 {
+		t.Errorf("enhancements made = %d, want 0", got)
+	}
+}
+
+func TestHandleCapabilities_ReflectsConfig(t *testing.T) {
+	e := enhancer.New(memory.NewStore(&config.MemoryConfig{BaseURL: "http://127.0.0.1:1"}))
+	if err := e.UseReranker(memory.RerankUsage); err != nil {
+		t.Fatalf("UseReranker failed: %v", err)
+	}
+	e.SetMaxMemoryChars(300)
+	e.SetContextFetcher(enhancer.NewContextFetcher([]string{"docs.example.com"}, time.Second))
+
+	s := New(e, 0)
+	s.SetWebUI(true)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/capabilities", nil)
+	rec := httptest.NewRecorder()
+	s.routes().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+
+	var resp struct {
+		Data struct {
+			Enhancer enhancer.Capabilities `json:"enhancer"`
+			Features map[string]bool       `json:"features"`
+			Limits   map[string]int        `json:"limits"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+
+	caps := resp.Data.Enhancer
+	if len(caps.EnhancementTypes) != 3 {
+		t.Errorf("enhancement types = %v", caps.EnhancementTypes)
+	}
+	if caps.Reranker != memory.RerankUsage {
+		t.Errorf("reranker = %q, want %q", caps.Reranker, memory.RerankUsage)
+	}
+	if caps.MaxMemoryChars != 300 || !caps.PageFetch || caps.Profile {
+		t.Errorf("capabilities = %+v, want max chars 300, page fetch on, profile off", caps)
+	}
+	if !resp.Data.Features["web_ui"] || resp.Data.Features["metrics"] || resp.Data.Features["auth"] || resp.Data.Features["streaming"] {
+		t.Errorf("features = %v", resp.Data.Features)
+	}
+	if resp.Data.Limits["default_max_memories"] != 5 {
+		t.Errorf("limits = %v", resp.Data.Limits)
+	}
+
+	// Streaming is reported once the chat stream is served
+	s.SetChatSource(fakeChat{})
+	rec = httptest.NewRecorder()
+	s.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/capabilities", nil))
+	resp.Data.Features = nil
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if !resp.Data.Features["streaming"] {
+		t.Errorf("features = %v, want streaming with a chat source", resp.Data.Features)
+	}
+}
+
+func TestHandleMemorySearch_DebugScores(t *testing.T) {
+	now := time.Now()
+	mem0 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"results": []map[string]interface{}{
+				{"id": "a", "memory": "a", "score": 0.8, "distance": 0.2, "created_at": now.Format(time.RFC3339)},
+				{"id": "b", "memory": "b", "score": 0.4, "distance": 0.6, "created_at": now.Format(time.RFC3339)},
+			},
+		})
+	}))
+	defer mem0.Close()
+
+	s := New(enhancer.New(memory.NewStore(&config.MemoryConfig{BaseURL: mem0.URL})), 0)
+
+	search := func(query string) []enhancer.MemoryInfo {
+		req := httptest.NewRequest(http.MethodGet, "/api/memories/search?q=x"+query, nil)
+		rec := httptest.NewRecorder()
+		s.handleMemorySearch(rec, req)
+
+		var resp struct {
+			Memories []enhancer.MemoryInfo `json:"memories"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: decoding response: %v: %s", query, err, rec.Body.String())
+		}
+		if got, want := strings.Contains(rec.Body.String(), `"debug"`), strings.Contains(query, "debug=true"); got != want {
+			t.Errorf("%q: debug fields present = %v, want %v", query, got, want)
+		}
+		return resp.Memories
+	}
+
+	search("")
+
+	memories := search("&debug=true&recency_boost=0.5")
+	if len(memories) != 2 || memories[1].Debug == nil {
+		t.Fatalf("memories = %+v, want debug details", memories)
+	}
+	d := memories[1].Debug
+	if d.RawScore != 0.4 || d.Distance != 0.6 || d.NormalizedScore != 0.5 {
+		t.Errorf("debug = %+v, want raw 0.4, distance 0.6, normalized 0.5", d)
+	}
+	if d.RecencyFactor < 0.99 || d.RecencyFactor > 1 {
+		t.Errorf("recency factor = %v, want ~1 for a fresh memory", d.RecencyFactor)
+	}
+}
+
+func TestHandleMemorySearch_Offset(t *testing.T) {
+	var offsets []interface{}
+	mem0 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		offsets = append(offsets, payload["offset"])
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": []map[string]interface{}{
+			{"id": "m6", "memory": "older result", "score": 0.4},
+		}})
+	}))
+	defer mem0.Close()
+
+	s := New(enhancer.New(memory.NewStore(&config.MemoryConfig{BaseURL: mem0.URL})), 0)
+
+	for _, tt := range []struct {
+		offset     string
+		wantStatus int
+	}{
+		{"5", http.StatusOK},
+		{"-1", http.StatusBadRequest},
+		{"many", http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/memories/search?q=x&offset="+tt.offset, nil)
+		rec := httptest.NewRecorder()
+		s.routes().ServeHTTP(rec, req)
+		if rec.Code != tt.wantStatus {
+			t.Errorf("offset=%s: status = %d, want %d", tt.offset, rec.Code, tt.wantStatus)
+		}
+	}
+
+	if len(offsets) != 1 || offsets[0] != float64(5) {
+		t.Errorf("backend offsets = %v, want only 5", offsets)
+	}
+}
+
+func TestHandleMemorySearch_ClampsLimit(t *testing.T) {
+	var limits []interface{}
+	mem0 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		limits = append(limits, payload["limit"])
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": []map[string]interface{}{}})
+	}))
+	defer mem0.Close()
+
+	s := New(enhancer.New(memory.NewStore(&config.MemoryConfig{BaseURL: mem0.URL})), 0)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/memories/search?q=x&limit=100000&offset=10", nil)
+	rec := httptest.NewRecorder()
+	s.routes().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if len(limits) != 1 || limits[0] != float64(maxSearchLimit) {
+		t.Errorf("backend limits = %v, want only %d", limits, maxSearchLimit)
+	}
+}
+
+func TestAdminSearch_RequiresToken(t *testing.T) {
+	mem0 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": []map[string]interface{}{
+			{"id": "m1", "memory": "Roadmap review", "user_id": "bob", "score": 0.9},
+		}})
+	}))
+	defer mem0.Close()
+
+	store := memory.NewStore(&config.MemoryConfig{BaseURL: mem0.URL, UserID: "alice"})
+	store.SetAdmin(true)
+	s := New(enhancer.New(store), 0)
+
+	get := func(auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/memories/search?q=roadmap", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		s.routes().ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Without a configured token the endpoint doesn't exist
+	if rec := get("Bearer "); rec.Code != http.StatusNotFound {
+		t.Errorf("no admin token: status = %d, want 404", rec.Code)
+	}
+
+	s.SetAdminToken("s3cret")
+	for _, auth := range []string{"", "Bearer wrong", "s3cret", "Basic s3cret"} {
+		if rec := get(auth); rec.Code != http.StatusUnauthorized {
+			t.Errorf("Authorization %q: status = %d, want 401", auth, rec.Code)
+		}
+	}
+
+	rec := get("Bearer s3cret")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Data struct {
+			Memories []enhancer.MemoryInfo `json:"memories"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if len(resp.Data.Memories) != 1 || resp.Data.Memories[0].UserID != "bob" {
+		t.Errorf("memories = %+v, want bob's memory with its owner", resp.Data.Memories)
+	}
+}
+
+func TestAPIKey_GuardsAPIRoutes(t *testing.T) {
+	mem0 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.Write([]byte(`{"results":[]}`))
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer mem0.Close()
+
+	s := New(enhancer.New(memory.NewStore(&config.MemoryConfig{BaseURL: mem0.URL})), 0)
+	s.SetAPIKey("k3y")
+	handler := s.routes()
+
+	get := func(path, auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, path := range []string{"/api/v1/memories/search?q=x", "/api/memories/search?q=x", "/api/v1/memories", "/metrics"} {
+		for _, auth := range []string{"", "Bearer wrong", "k3y"} {
+			if rec := get(path, auth); rec.Code != http.StatusUnauthorized {
+				t.Errorf("%s with Authorization %q: status = %d, want 401", path, auth, rec.Code)
+			}
+		}
+		if rec := get(path, "Bearer k3y"); rec.Code != http.StatusOK {
+			t.Errorf("%s with the key: status = %d, want 200: %s", path, rec.Code, rec.Body.String())
+		}
+	}
+
+	// Versioned clients get the 401 in the envelope
+	var body envelope
+	rec := get("/api/v1/memories", "")
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error == nil || body.Error.Code != http.StatusUnauthorized {
+		t.Errorf("401 body = %q, want an envelope error", rec.Body.String())
+	}
+
+	// Health stays open so clients can tell the app is running
+	for _, path := range []string{"/health", "/api/v1/health"} {
+		if rec := get(path, ""); rec.Code != http.StatusOK {
+			t.Errorf("%s without a key: status = %d, want 200", path, rec.Code)
+		}
+	}
+
+	// Preflight requests carry no credentials
+	req := httptest.NewRequest(http.MethodOptions, "/api/v1/enhance", nil)
+	req.Header.Set("Origin", "chrome-extension://abc")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("preflight: status = %d, want 200", rec.Code)
+	}
+}
+
+func TestHandleHealth_ReportsDependencies(t *testing.T) {
+	s := New(nil, 0)
+	s.SetHealthSource(func() (string, interface{}) {
+		return "degraded", map[string]interface{}{"llm": map[string]interface{}{"healthy": false}}
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/health", nil)
+	rec := httptest.NewRecorder()
+	s.routes().ServeHTTP(rec, req)
+
+	// A dependency being down doesn't make the server itself unhealthy
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	var body struct {
+		Data struct {
+			Status       string                            `json:"status"`
+			Dependencies map[string]map[string]interface{} `json:"dependencies"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decoding %s: %v", rec.Body.String(), err)
+	}
+	if body.Data.Status != "degraded" || body.Data.Dependencies["llm"]["healthy"] != false {
+		t.Errorf("health = %+v, want degraded with the LLM down", body.Data)
+	}
+}
+
+func TestAddr_DefaultsToLocalhost(t *testing.T) {
+	s := New(nil, 0)
+	if got := s.addr(); got != "127.0.0.1:7345" {
+		t.Errorf("addr() = %q, want 127.0.0.1:7345", got)
+	}
+
+	s.SetBindAddress("")
+	if got := s.addr(); got != "127.0.0.1:7345" {
+		t.Errorf("addr() after an empty bind address = %q, want the default", got)
+	}
+
+	s.SetBindAddress("0.0.0.0")
+	if got := s.addr(); got != "0.0.0.0:7345" {
+		t.Errorf("addr() = %q, want 0.0.0.0:7345", got)
+	}
+
+	s.SetBindAddress("::1")
+	if got := s.addr(); got != "[::1]:7345" {
+		t.Errorf("addr() = %q, want [::1]:7345", got)
+	}
+}
+
+func TestRateLimit_RejectsBursts(t *testing.T) {
+	s := New(enhancer.New(memory.NewStore(&config.MemoryConfig{})), 0)
+	s.SetRateLimit(5)
+	now := time.Now()
+	s.limiter.now = func() time.Time { return now }
+	handler := s.routes()
+
+	get := func(path, remote string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remote
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// A burst of 12 allows one second's worth and rejects the rest
+	rejected := 0
+	var last *httptest.ResponseRecorder
+	for i := 0; i < 12; i++ {
+		rec := get("/api/v1/capabilities", fmt.Sprintf("10.0.0.1:%d", 5000+i))
+		if rec.Code == http.StatusTooManyRequests {
+			rejected++
+			last = rec
+		}
+	}
+	if rejected != 7 {
+		t.Errorf("rejected %d of 12 requests, want 7", rejected)
+	}
+	if last == nil || last.Header().Get("Retry-After") != "1" {
+		t.Fatalf("Retry-After missing from 429 response")
+	}
+	var body envelope
+	if err := json.Unmarshal(last.Body.Bytes(), &body); err != nil || body.Error == nil || body.Error.Code != http.StatusTooManyRequests {
+		t.Errorf("429 body = %q, want an envelope error", last.Body.String())
+	}
+
+	// Other clients and health checks are unaffected
+	if rec := get("/api/v1/capabilities", "10.0.0.2:5000"); rec.Code != http.StatusOK {
+		t.Errorf("other client: status = %d, want 200", rec.Code)
+	}
+	if rec := get("/api/v1/health", "10.0.0.1:5000"); rec.Code != http.StatusOK {
+		t.Errorf("health check: status = %d, want 200", rec.Code)
+	}
+
+	// The bucket refills over time
+	now = now.Add(time.Second)
+	if rec := get("/api/v1/capabilities", "10.0.0.1:5000"); rec.Code != http.StatusOK {
+		t.Errorf("after refill: status = %d, want 200", rec.Code)
+	}
+}
+
+// fakeChat answers "Hello, <message>"; "fail" makes the stream break part
+// way and Chat return an error
+type fakeChat struct{}
+
+func (fakeChat) Chat(ctx context.Context, message string) (*llm.ChatResponse, error) {
+	if message == "fail" {
+		return nil, errors.New("both backends down")
+	}
+	return &llm.ChatResponse{Content: "Hello, " + message, Model: "local-model", Backend: llm.BackendLocal, Degraded: true}, nil
+}
+
+func (fakeChat) ChatStream(ctx context.Context, message string) (<-chan llm.Chunk, error) {
+	chunks := make(chan llm.Chunk, 3)
+	chunks <- llm.Chunk{Text: "Hello", Backend: llm.BackendCerebras}
+	chunks <- llm.Chunk{Text: ", " + message}
+	if message == "fail" {
+		chunks <- llm.Chunk{Err: errors.New("connection reset")}
+	}
+	close(chunks)
+	return chunks, nil
+}
+
+func TestHandleChat_ReturnsBackend(t *testing.T) {
+	s := New(nil, 0)
+	s.SetChatSource(fakeChat{})
+
+	post := func(message string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/chat", strings.NewReader(`{"message":"`+message+`"}`))
+		rec := httptest.NewRecorder()
+		s.routes().ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := post("world")
+	var resp struct {
+		Data llm.ChatResponse `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	want := llm.ChatResponse{Content: "Hello, world", Model: "local-model", Backend: llm.BackendLocal, Degraded: true}
+	if rec.Code != http.StatusOK || resp.Data != want {
+		t.Errorf("status = %d, data = %+v; want %+v", rec.Code, resp.Data, want)
+	}
+
+	if rec := post("fail"); rec.Code != http.StatusInternalServerError {
+		t.Errorf("failed chat: status = %d, want 500", rec.Code)
+	}
+	if rec := post(""); rec.Code != http.StatusBadRequest {
+		t.Errorf("empty message: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestHandleChatStream_SendsEvents(t *testing.T) {
+	s := New(nil, 0)
+	s.SetChatSource(fakeChat{})
+
+	post := func(message string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/chat/stream", strings.NewReader(`{"message":"`+message+`"}`))
+		rec := httptest.NewRecorder()
+		s.routes().ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := post("world")
+	want := "event: chunk\ndata: {\"backend\":\"cerebras\",\"degraded\":false,\"text\":\"Hello\"}\n\n" +
+		"event: chunk\ndata: {\"text\":\", world\"}\n\n" +
+		"event: done\ndata: {}\n\n"
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/event-stream" || rec.Body.String() != want {
+		t.Errorf("status = %d, type = %q, body = %q; want %q", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String(), want)
+	}
+
+	rec = post("fail")
+	if body := rec.Body.String(); !strings.HasSuffix(body, "event: error\ndata: {\"message\":\"connection reset\"}\n\n") {
+		t.Errorf("failed stream body = %q, want a trailing error event", body)
+	}
+
+	if rec := post(""); rec.Code != http.StatusBadRequest {
+		t.Errorf("empty message: status = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,122 @@
+// AuraBot web UI - talks to the versioned JSON endpoints on this server
+(function() {
+  'use strict';
+
+  const API = '/api/v1';
+
+  // Call an endpoint and unwrap the {data, error, meta} envelope. When the
+  // server requires an API key, ask for it once and keep it for later visits
+  async function request(path, options, retried) {
+    const key = localStorage.getItem('aurabotApiKey');
+    const init = Object.assign({}, options);
+    if (key) {
+      init.headers = Object.assign({}, init.headers, { 'Authorization': 'Bearer ' + key });
+    }
+
+    const response = await fetch(API + path, init);
+    if (response.status === 401 && !retried) {
+      const entered = window.prompt('AuraBot API key');
+      if (entered) {
+        localStorage.setItem('aurabotApiKey', entered);
+        return request(path, options, true);
+      }
+    }
+    const body = await response.json();
+    if (body.error) {
+      throw new Error(body.error.message);
+    }
+    return body.data;
+  }
+
+  function renderMemories(list, memories) {
+    list.innerHTML = '';
+    if (!memories || memories.length === 0) {
+      const empty = document.createElement('li');
+      empty.textContent = 'No memories found';
+      list.appendChild(empty);
+      return;
+    }
+
+    for (const m of memories) {
+      const item = document.createElement('li');
+      item.textContent = m.content;
+
+      const meta = document.createElement('span');
+      meta.className = 'meta';
+      const parts = [new Date(m.date).toLocaleString(), m.source];
+      if (m.collection) parts.push(m.collection);
+      if (m.score) parts.push('score ' + m.score.toFixed(2));
+      meta.textContent = parts.filter(Boolean).join(' · ');
+
+      item.appendChild(meta);
+      list.appendChild(item);
+    }
+  }
+
+  function renderError(list, error) {
+    list.innerHTML = '';
+    const item = document.createElement('li');
+    item.className = 'error';
+    item.textContent = error.message;
+    list.appendChild(item);
+  }
+
+  async function checkStatus() {
+    const status = document.getElementById('status');
+    try {
+      await request('/health');
+      status.textContent = 'Running';
+      status.classList.add('online');
+    } catch (e) {
+      status.textContent = 'Offline';
+      status.classList.remove('online');
+    }
+  }
+
+  async function loadRecent() {
+    const list = document.getElementById('recentMemories');
+    try {
+      const data = await request('/memories?limit=10');
+      renderMemories(list, data.memories);
+    } catch (e) {
+      renderError(list, e);
+    }
+  }
+
+  document.getElementById('searchForm').addEventListener('submit', async (e) => {
+    e.preventDefault();
+    const list = document.getElementById('searchResults');
+    const params = new URLSearchParams({ q: document.getElementById('searchQuery').value });
+    const range = document.getElementById('searchWindow').value;
+    if (range) params.set('window', range);
+
+    try {
+      const data = await request('/memories/search?' + params);
+      renderMemories(list, data.memories);
+    } catch (err) {
+      renderError(list, err);
+    }
+  });
+
+  document.getElementById('enhanceForm').addEventListener('submit', async (e) => {
+    e.preventDefault();
+    const output = document.getElementById('enhanceResult');
+    output.classList.remove('error');
+    output.textContent = 'Enhancing...';
+
+    try {
+      const data = await request('/enhance', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ prompt: document.getElementById('enhancePrompt').value, context: 'web' })
+      });
+      output.textContent = data.enhanced_prompt + '\n\n(' + data.memory_count + ' memories, ' + data.enhancement_type + ')';
+    } catch (err) {
+      output.classList.add('error');
+      output.textContent = err.message;
+    }
+  });
+
+  checkStatus();
+  loadRecent();
+})();
@@ -0,0 +1,28 @@
+package server
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// webAssets holds the built-in web UI, a plain page that talks to the
+// JSON endpoints with fetch
+//
+//go:embed web
+var webAssets embed.FS
+
+// SetWebUI enables serving the built-in web UI at /
+func (s *Server) SetWebUI(enabled bool) {
+	s.webUI = enabled
+}
+
+// webHandler serves the embedded web UI assets
+func webHandler() http.Handler {
+	sub, err := fs.Sub(webAssets, "web")
+	if err != nil {
+		// The directory is embedded at build time, so this can't happen
+		panic(err)
+	}
+	return http.FileServerFS(sub)
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"screen-memory-assistant/internal/capture"
+	"screen-memory-assistant/internal/crypt"
+)
+
+// archiveTimeFormat names archived frames so they sort by capture time
+const archiveTimeFormat = "20060102-150405.000"
+
+// sealedExt marks archived frames encrypted with the memory cipher
+const sealedExt = ".enc"
+
+// archiveFrame writes the compressed frame to dir as
+// {timestamp}-{display}.jpg (or .png) and returns its path. With a
+// cipher the frame is encrypted and ".enc" appended to the name.
+func archiveFrame(dir string, cap *capture.Capture, cipher *crypt.Cipher) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("creating archive dir: %w", err)
+	}
+
+	ext := ".jpg"
+	if cap.MIMEType == "image/png" {
+		ext = ".png"
+	}
+	data := cap.Compressed
+	if cipher != nil {
+		sealed, err := cipher.Seal(data)
+		if err != nil {
+			return "", fmt.Errorf("encrypting frame: %w", err)
+		}
+		data = sealed
+		ext += sealedExt
+	}
+	name := fmt.Sprintf("%s-%d%s", cap.Timestamp.Format(archiveTimeFormat), cap.DisplayNum, ext)
+	path := filepath.Join(dir, name)
+
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", fmt.Errorf("archiving frame: %w", err)
+	}
+	return path, nil
+}
+
+// pruneArchive removes archived frames older than maxAge and then the
+// oldest ones beyond maxFiles; zero disables either limit. Only files
+// named like archived frames are touched.
+func pruneArchive(dir string, maxFiles int, maxAge time.Duration, now time.Time) error {
+	if maxFiles <= 0 && maxAge <= 0 {
+		return nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("reading archive dir: %w", err)
+	}
+
+	type frame struct {
+		path  string
+		taken time.Time
+	}
+	var frames []frame
+	for _, e := range entries {
+		name := e.Name()
+		ext := filepath.Ext(strings.TrimSuffix(name, sealedExt))
+		if e.IsDir() || (ext != ".jpg" && ext != ".png") || len(name) < len(archiveTimeFormat) {
+			continue
+		}
+		taken, err := time.ParseInLocation(archiveTimeFormat, name[:len(archiveTimeFormat)], time.Local)
+		if err != nil || !strings.HasPrefix(name[len(archiveTimeFormat):], "-") {
+			continue
+		}
+		frames = append(frames, frame{filepath.Join(dir, name), taken})
+	}
+
+	// Newest first, so whatever is past maxFiles is the oldest
+	sort.Slice(frames, func(i, j int) bool { return frames[i].taken.After(frames[j].taken) })
+
+	var errs []string
+	for i, f := range frames {
+		tooOld := maxAge > 0 && now.Sub(f.taken) > maxAge
+		tooMany := maxFiles > 0 && i >= maxFiles
+		if !tooOld && !tooMany {
+			continue
+		}
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("pruning archive: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// archive stores the frame of a new memory if Capture.ArchiveDir is set,
+// pruning the archive afterwards, and returns the image path or ""
+func (s *Service) archive(cap *capture.Capture) string {
+	cfg := s.currentConfig()
+	dir := cfg.Capture.ArchiveDir
+	if dir == "" {
+		return ""
+	}
+
+	path, err := archiveFrame(dir, cap, s.memory.Cipher())
+	if err != nil {
+		s.logs.Printf("Failed to archive capture: %v", err)
+		return ""
+	}
+
+	maxAge := time.Duration(cfg.Capture.ArchiveMaxAgeDays) * 24 * time.Hour
+	if err := pruneArchive(dir, cfg.Capture.ArchiveMaxFiles, maxAge, s.now()); err != nil {
+		s.logs.Printf("%v", err)
+	}
+	return path
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"screen-memory-assistant/internal/capture"
+	"screen-memory-assistant/internal/crypt"
+	"screen-memory-assistant/internal/memory"
+)
+
+func TestAnalyzeAndStore_ArchivesFrame(t *testing.T) {
+	var memories []memory.Memory
+	mem0 := listingMem0(&memories)
+	defer mem0.Close()
+
+	dir := filepath.Join(t.TempDir(), "archive")
+	svc := newTestService(t, "http://127.0.0.1:1", mem0.URL)
+	svc.config.Capture.ArchiveDir = dir
+	svc.config.Capture.ArchiveMaxFiles = 2
+	svc.analyzer = &scriptedAnalyzer{context: "work", summaries: []string{"Editing main.go", "Running tests", "Reviewing a PR"}}
+
+	start := time.Date(2026, 3, 2, 10, 0, 0, 0, time.Local)
+	for i := 0; i < 3; i++ {
+		svc.analyzeAndStore(context.Background(), &capture.Capture{
+			Timestamp:  start.Add(time.Duration(i) * time.Minute),
+			Compressed: []byte{0xff, 0xd8, byte(i)},
+			MIMEType:   "image/jpeg",
+			DisplayNum: 1,
+		})
+	}
+
+	if len(memories) != 3 {
+		t.Fatalf("stored %d memories, want 3", len(memories))
+	}
+	last := memories[2].Metadata.ImagePath
+	if want := filepath.Join(dir, "20260302-100200.000-1.jpg"); last != want {
+		t.Errorf("image path = %q, want %q", last, want)
+	}
+	if data, err := os.ReadFile(last); err != nil || !bytes.Equal(data, []byte{0xff, 0xd8, 2}) {
+		t.Errorf("archived frame = %v, %v; want the compressed bytes", data, err)
+	}
+
+	// Only the two newest frames are kept
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Fatalf("archive has %d files, want 2", len(entries))
+	}
+	if _, err := os.Stat(memories[0].Metadata.ImagePath); !os.IsNotExist(err) {
+		t.Errorf("oldest frame still archived: %v", err)
+	}
+
+	// Age pruning leaves unrelated files alone
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("keep"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := pruneArchive(dir, 0, 90*time.Second, start.Add(3*time.Minute)); err != nil {
+		t.Fatalf("pruneArchive: %v", err)
+	}
+	entries, _ = os.ReadDir(dir)
+	if len(entries) != 2 || entries[0].Name() != "20260302-100200.000-1.jpg" || entries[1].Name() != "notes.txt" {
+		t.Errorf("archive after age pruning = %v, want the newest frame and notes.txt", entries)
+	}
+
+	// With encryption on, frames are sealed and still pruned
+	cipher, err := crypt.New("correct horse", "u")
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame := &capture.Capture{Timestamp: start, Compressed: []byte{0xff, 0xd8, 9}, MIMEType: "image/jpeg"}
+	path, err := archiveFrame(dir, frame, cipher)
+	if err != nil || !strings.HasSuffix(path, ".jpg.enc") {
+		t.Fatalf("archiveFrame = %q, %v; want a .jpg.enc file", path, err)
+	}
+	sealed, _ := os.ReadFile(path)
+	if data, err := cipher.Open(sealed); err != nil || !bytes.Equal(data, frame.Compressed) {
+		t.Errorf("decrypted frame = %v, %v; want the compressed bytes", data, err)
+	}
+	if err := pruneArchive(dir, 0, time.Minute, start.Add(3*time.Minute)); err != nil {
+		t.Fatalf("pruneArchive: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expired encrypted frame still archived: %v", err)
+	}
+}
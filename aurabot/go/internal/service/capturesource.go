@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+
+	"screen-memory-assistant/internal/capture"
+	"screen-memory-assistant/internal/config"
+	"screen-memory-assistant/internal/llm"
+)
+
+// Capture sources, as used in App.CaptureSource
+const (
+	// CaptureScreen captures the screen on the configured interval
+	CaptureScreen = "screen"
+	// CaptureManual only captures when CaptureNow is called
+	CaptureManual = "manual"
+	// CaptureNone never touches the screen; chat, notes and the
+	// enhancer keep working on existing memories
+	CaptureNone = "none"
+)
+
+// ErrCaptureDisabled is returned by CaptureNow when the capture source is "none"
+var ErrCaptureDisabled = errors.New("screen capture is disabled")
+
+// captureSource returns the configured source, defaulting to the screen
+func captureSource(cfg *config.Config) string {
+	switch cfg.App.CaptureSource {
+	case CaptureManual, CaptureNone:
+		return cfg.App.CaptureSource
+	default:
+		return CaptureScreen
+	}
+}
+
+// capturesPeriodically reports whether the capture loop should run
+func capturesPeriodically(cfg *config.Config) bool {
+	return cfg.Capture.Enabled && captureSource(cfg) == CaptureScreen
+}
+
+// CaptureNow takes a capture and analyzes and stores it right away,
+// regardless of the capture interval, returning the analysis. Like
+// scheduled captures it skips blocklisted windows and unchanged or
+// near-duplicate screens, returning a *SkipError. With several displays
+// each is stored and the first stored analysis returned. The result is
+// nil when App.ProcessOnCapture is off. It fails in "none" mode.
+func (s *Service) CaptureNow(ctx context.Context) (*llm.AnalysisResult, error) {
+	cfg := s.currentConfig()
+	if captureSource(cfg) == CaptureNone {
+		return nil, ErrCaptureDisabled
+	}
+
+	captures, err := s.takeCaptures(cfg.Capture.Displays)
+	if err != nil {
+		return nil, s.skip(SkipCaptureFailed, err)
+	}
+
+	var first *llm.AnalysisResult
+	var firstErr error
+	for _, cap := range captures {
+		if !cfg.App.ProcessOnCapture {
+			s.skips.inc(SkipProcessingDisabled)
+			continue
+		}
+
+		var result *llm.AnalysisResult
+		var err error
+		if s.nearDuplicate(cap, cfg.Capture.DedupThreshold) {
+			err = s.skip(SkipNearDuplicate, nil)
+		} else {
+			result, err = s.storeCaptureSafely(ctx, cap)
+		}
+		if first == nil && result != nil {
+			first = result
+		}
+		if firstErr == nil && err != nil {
+			firstErr = err
+		}
+	}
+
+	if first != nil {
+		return first, nil
+	}
+	return nil, firstErr
+}
+
+// storeCaptureSafely runs storeCapture, turning a panic into an error
+func (s *Service) storeCaptureSafely(ctx context.Context, cap *capture.Capture) (result *llm.AnalysisResult, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("recovered from panic", "where", "capture now", "panic", r, "stack", string(debug.Stack()))
+			result, err = nil, s.skip(SkipPanicked, fmt.Errorf("panic: %v", r))
+		}
+	}()
+	return s.storeCapture(ctx, cap)
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"screen-memory-assistant/internal/capture"
+	"screen-memory-assistant/internal/llm"
+	"screen-memory-assistant/internal/memory"
+)
+
+func TestCaptureSourceNone_NoCaptureLoopButChatWorks(t *testing.T) {
+	llmServer := fakeLLM("You were reading docs.")
+	defer llmServer.Close()
+	memServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": []interface{}{}})
+	}))
+	defer memServer.Close()
+
+	svc := newTestService(t, llmServer.URL, memServer.URL)
+	svc.config.Capture.Enabled = true
+	svc.config.Capture.IntervalSeconds = 1
+	svc.config.App.CaptureSource = CaptureNone
+	var captures atomic.Int32
+	svc.captureFrame = func() (*capture.Capture, error) {
+		captures.Add(1)
+		return testCapture(), nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- svc.Run(ctx) }()
+
+	reply, err := svc.Chat(context.Background(), "what was I doing?")
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if reply.Content != "You were reading docs." || reply.Backend != llm.BackendLocal || reply.Degraded {
+		t.Errorf("reply = %+v, want the local model's answer, not degraded", reply)
+	}
+	if _, err := svc.CaptureNow(context.Background()); !errors.Is(err, ErrCaptureDisabled) {
+		t.Errorf("CaptureNow error = %v, want ErrCaptureDisabled", err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if n := captures.Load(); n != 0 {
+		t.Errorf("captured %d frames in none mode, want 0", n)
+	}
+}
+
+func TestCaptureSourceManual_CapturesOnlyOnRequest(t *testing.T) {
+	svc := newTestService(t, "http://127.0.0.1:1", "http://127.0.0.1:1")
+	svc.config.Capture.Enabled = true
+	svc.config.App.CaptureSource = CaptureManual
+	svc.config.App.ProcessOnCapture = false
+	svc.sessionLocked = func() (bool, error) { return false, nil }
+	var captures int
+	svc.captureFrame = func() (*capture.Capture, error) {
+		captures++
+		return testCapture(), nil
+	}
+
+	if capturesPeriodically(svc.config) {
+		t.Error("manual mode should not run the capture loop")
+	}
+	if _, err := svc.CaptureNow(context.Background()); err != nil {
+		t.Fatalf("CaptureNow failed: %v", err)
+	}
+	if captures != 1 {
+		t.Errorf("captures = %d, want 1", captures)
+	}
+}
+
+func TestCaptureNow_StoresAndReturnsAnalysis(t *testing.T) {
+	var memories []memory.Memory
+	mem0 := listingMem0(&memories)
+	defer mem0.Close()
+
+	svc := newTestService(t, "http://127.0.0.1:1", mem0.URL)
+	svc.config.App.CaptureSource = CaptureManual
+	svc.config.Capture.Blocklist = []string{"KeePass"}
+	svc.analyzer = &scriptedAnalyzer{context: "work", summaries: []string{"Reading the design doc"}}
+	frame := byte(0)
+	svc.captureFrame = func() (*capture.Capture, error) {
+		frame++
+		return &capture.Capture{Timestamp: time.Now(), Compressed: []byte{0xff, 0xd8, frame}}, nil
+	}
+	title := "Design doc - Browser"
+	svc.activeWindowTitle = func() (string, error) { return title, nil }
+
+	result, err := svc.CaptureNow(context.Background())
+	if err != nil {
+		t.Fatalf("CaptureNow: %v", err)
+	}
+	if result == nil || result.Summary != "Reading the design doc" {
+		t.Errorf("result = %+v, want the analysis", result)
+	}
+	if len(memories) != 1 {
+		t.Errorf("stored %d memories, want 1 before CaptureNow returns", len(memories))
+	}
+
+	// The blocklist applies to manual captures too
+	title = "Passwords.kdbx - KeePass"
+	var skipped *SkipError
+	if _, err := svc.CaptureNow(context.Background()); !errors.As(err, &skipped) || skipped.Reason != SkipBlocked {
+		t.Errorf("CaptureNow on a blocklisted window: err = %v, want a %s skip", err, SkipBlocked)
+	}
+	if len(memories) != 1 {
+		t.Errorf("stored %d memories, want the blocklisted capture skipped", len(memories))
+	}
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"screen-memory-assistant/internal/memory"
+)
+
+// sessionSummaryContext marks memories stored by session compaction
+const sessionSummaryContext = "session_summary"
+
+const compactSystemPrompt = "You condense a user's screen activity from one work session into a short timeline. Keep what was worked on, in order, and anything left unfinished. Drop repetition. Be concise."
+
+// compactOnShutdown runs the compaction pass when enabled, bounded by
+// App.CompactTimeoutSeconds
+func (s *Service) compactOnShutdown() {
+	cfg := s.currentConfig()
+	if !cfg.App.CompactOnShutdown || s.startedAt.IsZero() {
+		return
+	}
+
+	timeout := time.Duration(cfg.App.CompactTimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := s.compactSession(ctx, s.startedAt, cfg.App.CompactPrune); err != nil {
+		s.logger.Warn("session compaction failed", "error", err)
+	}
+}
+
+// compactSession summarizes the captures stored since into one memory and,
+// with prune set, deletes them. Sessions with fewer than two captures are
+// left alone.
+func (s *Service) compactSession(ctx context.Context, since time.Time, prune bool) error {
+	memories, err := s.memory.GetRecent(summaryScanLimit)
+	if err != nil {
+		return fmt.Errorf("loading memories: %w", err)
+	}
+
+	var session []memory.Memory
+	for _, m := range memories {
+		if m.Metadata.Source == memory.SourceCapture && !m.Time().Before(since) {
+			session = append(session, m)
+		}
+	}
+	if len(session) < 2 {
+		return nil
+	}
+	sort.Slice(session, func(i, j int) bool { return session[i].Time().Before(session[j].Time()) })
+
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "Session from %s:\n", since.Format("Monday, January 2 15:04"))
+	for _, m := range session {
+		fmt.Fprintf(&prompt, "- [%s] %s\n", m.Time().In(since.Location()).Format("15:04"), m.Content)
+	}
+	prompt.WriteString("\nCondense this session.")
+
+	summary, err := s.streamer.StreamChat(ctx, compactSystemPrompt, prompt.String(), nil)
+	if err != nil {
+		return fmt.Errorf("summarizing session: %w", err)
+	}
+	if strings.TrimSpace(summary) == "" {
+		return fmt.Errorf("summarizing session: empty summary")
+	}
+
+	metadata := memory.Metadata{
+		Timestamp:    session[0].Time().Format(time.RFC3339),
+		EndTimestamp: session[len(session)-1].Time().Format(time.RFC3339),
+		Context:      sessionSummaryContext,
+		Source:       memory.SourceManual,
+	}
+	if _, err := s.memory.Add(summary, metadata); err != nil {
+		return fmt.Errorf("storing session summary: %w", err)
+	}
+
+	// Only prune once the summary is safely stored
+	if !prune {
+		return nil
+	}
+	var errs []error
+	for _, m := range session {
+		if ctx.Err() != nil {
+			errs = append(errs, ctx.Err())
+			break
+		}
+		if err := s.memory.Delete(m.Collection, m.ID); err != nil {
+			errs = append(errs, fmt.Errorf("deleting %s: %w", m.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"screen-memory-assistant/internal/memory"
+)
+
+func TestCompactOnShutdown_SummarizesAndPrunes(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+	at := func(offset time.Duration) string { return start.Add(offset).Format(time.RFC3339) }
+	memories := []memory.Memory{
+		{ID: "old", Content: "Yesterday's capture", Metadata: memory.Metadata{Timestamp: at(-24 * time.Hour), Source: memory.SourceCapture}},
+		{ID: "c1", Content: "Editing main.go", Metadata: memory.Metadata{Timestamp: at(time.Minute), Source: memory.SourceCapture}},
+		{ID: "c2", Content: "Running tests", Metadata: memory.Metadata{Timestamp: at(2 * time.Minute), Source: memory.SourceCapture}},
+		{ID: "note", Content: "Dentist on Friday", Metadata: memory.Metadata{Timestamp: at(3 * time.Minute), Source: memory.SourceManual}},
+	}
+	mem0 := listingMem0(&memories)
+	defer mem0.Close()
+
+	svc := newTestService(t, "http://127.0.0.1:1", mem0.URL)
+	svc.config.App.CompactOnShutdown = true
+	svc.config.App.CompactPrune = true
+	streamer := &fakeStreamer{chunks: []string{"Edited main.go ", "and ran the tests."}}
+	svc.streamer = streamer
+	svc.startedAt = start
+
+	svc.compactOnShutdown()
+
+	if !strings.Contains(streamer.prompt, "Editing main.go") || strings.Contains(streamer.prompt, "Dentist") ||
+		strings.Contains(streamer.prompt, "Yesterday") {
+		t.Errorf("prompt should list only this session's captures:\n%s", streamer.prompt)
+	}
+
+	var ids []string
+	var summary *memory.Memory
+	for i, m := range memories {
+		ids = append(ids, m.ID)
+		if m.Metadata.Context == sessionSummaryContext {
+			summary = &memories[i]
+		}
+	}
+	if summary == nil {
+		t.Fatalf("no session summary stored; memories %v", ids)
+	}
+	if summary.Content != "Edited main.go and ran the tests." {
+		t.Errorf("summary = %q", summary.Content)
+	}
+	if summary.Metadata.Timestamp != at(time.Minute) || summary.Metadata.EndTimestamp != at(2*time.Minute) {
+		t.Errorf("summary spans %s - %s, want the session's captures", summary.Metadata.Timestamp, summary.Metadata.EndTimestamp)
+	}
+	if len(memories) != 3 || memories[0].ID != "old" || memories[1].ID != "note" {
+		t.Errorf("memories after pruning = %v, want old, note and the summary", ids)
+	}
+}
@@ -0,0 +1,29 @@
+package service
+
+// Actions for an analysis below the confidence threshold
+const (
+	LowConfidenceSkip = "skip"
+	LowConfidenceFlag = "flag"
+)
+
+// confidenceDecision is what to do with an analysis after the confidence gate
+type confidenceDecision int
+
+const (
+	confidenceStore confidenceDecision = iota
+	confidenceFlag
+	confidenceSkip
+)
+
+// confidenceGate decides whether an analysis is stored. A missing
+// confidence or a zero threshold always stores; below the threshold the
+// analysis is flagged when action is "flag" and skipped otherwise.
+func confidenceGate(confidence *float64, threshold float64, action string) confidenceDecision {
+	if confidence == nil || threshold <= 0 || *confidence >= threshold {
+		return confidenceStore
+	}
+	if action == LowConfidenceFlag {
+		return confidenceFlag
+	}
+	return confidenceSkip
+}
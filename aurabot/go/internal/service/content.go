@@ -0,0 +1,96 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+)
+
+// OCR merge strategies
+const (
+	OCRMergeDedup    = "dedup"
+	OCRMergeAppend   = "append"
+	OCRMergeMetadata = "metadata"
+)
+
+// ocrCoverageThreshold is the share of an OCR line's words that must already
+// appear in the summary for the line to be considered covered
+const ocrCoverageThreshold = 0.8
+
+// mergeOCRText combines the vision summary with OCR text according to the
+// strategy. It returns the memory content and the text to keep in metadata.
+func mergeOCRText(summary, ocrText, strategy string) (string, string) {
+	ocrText = strings.TrimSpace(ocrText)
+	if ocrText == "" {
+		return summary, ""
+	}
+
+	switch strategy {
+	case OCRMergeAppend:
+		return summary + "\nScreen text: " + ocrText, ""
+	case OCRMergeMetadata:
+		return summary, ocrText
+	}
+
+	// Default: dedup - keep only lines not already covered by the summary
+	covered := wordSet(summary)
+	seen := make(map[string]bool)
+	var extra []string
+
+	for _, line := range strings.Split(ocrText, "\n") {
+		line = strings.TrimSpace(line)
+		words := tokenize(line)
+		if len(words) == 0 {
+			continue
+		}
+
+		key := strings.Join(words, " ")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		hits := 0
+		for _, w := range words {
+			if covered[w] {
+				hits++
+			}
+		}
+		if float64(hits)/float64(len(words)) >= ocrCoverageThreshold {
+			continue
+		}
+
+		extra = append(extra, line)
+		for _, w := range words {
+			covered[w] = true
+		}
+	}
+
+	if len(extra) == 0 {
+		return summary, ""
+	}
+	return summary + "\nScreen text: " + strings.Join(extra, " / "), ""
+}
+
+// tokenize lowercases text and splits it into words
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// wordSet returns the set of words in text
+func wordSet(text string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range tokenize(text) {
+		set[w] = true
+	}
+	return set
+}
+
+// truncate shortens text to at most n runes, for log previews
+func truncate(text string, n int) string {
+	if runes := []rune(text); len(runes) > n {
+		return string(runes[:n])
+	}
+	return text
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+
+	"github.com/robfig/cron/v3"
+)
+
+// cronLoop captures at the times given by a standard five-field cron
+// spec (e.g. "0 9 * * 1-5") instead of on a fixed interval
+func (s *Service) cronLoop(ctx context.Context, spec string) {
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		s.logger.Error("invalid capture schedule, not capturing", "spec", spec, "error", err)
+		return
+	}
+
+	for {
+		now := s.now()
+		next := schedule.Next(now)
+		if next.IsZero() {
+			s.logs.Printf("Capture schedule has no upcoming times, stopping captures")
+			return
+		}
+
+		select {
+		case <-s.after(next.Sub(now)):
+			s.scheduledCapture(ctx)
+		case <-s.stopChan:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCaptureLoop_CronSchedule(t *testing.T) {
+	svc := newTestService(t, "http://127.0.0.1:1", "http://127.0.0.1:1")
+	svc.config.Capture.IntervalSeconds = 30
+	svc.config.App.CaptureCron = "0 9 * * 1-5"
+
+	captures := 0
+	svc.sessionLocked = func() (bool, error) {
+		captures++
+		return true, nil
+	}
+
+	// A fake clock that jumps ahead by every wait; Friday morning
+	clock := time.Date(2024, 5, 17, 8, 30, 0, 0, time.UTC)
+	svc.now = func() time.Time { return clock }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var fires []time.Time
+	svc.after = func(d time.Duration) <-chan time.Time {
+		if len(fires) == 3 {
+			cancel()
+			return nil
+		}
+		clock = clock.Add(d)
+		fires = append(fires, clock)
+		ch := make(chan time.Time, 1)
+		ch <- clock
+		return ch
+	}
+
+	svc.wg.Add(1)
+	svc.captureLoop(ctx)
+
+	want := []time.Time{
+		time.Date(2024, 5, 17, 9, 0, 0, 0, time.UTC),
+		time.Date(2024, 5, 20, 9, 0, 0, 0, time.UTC),
+		time.Date(2024, 5, 21, 9, 0, 0, 0, time.UTC),
+	}
+	if len(fires) != len(want) {
+		t.Fatalf("fired at %v, want %v", fires, want)
+	}
+	for i := range want {
+		if !fires[i].Equal(want[i]) {
+			t.Errorf("fire %d at %v, want %v", i, fires[i], want[i])
+		}
+	}
+	if captures != 3 {
+		t.Errorf("%d captures, want one per fire and none at startup", captures)
+	}
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"screen-memory-assistant/internal/capture"
+	"screen-memory-assistant/internal/llm"
+)
+
+// SetDryRunOutput writes the full analysis of every capture to w while
+// App.DryRun is set, so prompt changes can be compared
+func (s *Service) SetDryRunOutput(w io.Writer) {
+	s.dryRunOut = w
+}
+
+// reportDryRun logs an analysis that won't be stored and writes it to the
+// dry-run output, if set
+func (s *Service) reportDryRun(cap *capture.Capture, result *llm.AnalysisResult) {
+	s.logger.Info("dry run: analysis not stored", "display", cap.DisplayNum, "summary", result.Summary,
+		"context", result.Context, "activities", result.Activities, "key_elements", result.KeyElements,
+		"user_intent", result.UserIntent)
+
+	if s.dryRunOut == nil {
+		return
+	}
+	out, err := json.MarshalIndent(struct {
+		Timestamp string              `json:"timestamp"`
+		Display   int                 `json:"display"`
+		Analysis  *llm.AnalysisResult `json:"analysis"`
+	}{cap.Timestamp.Format(time.RFC3339), cap.DisplayNum, result}, "", "  ")
+	if err != nil {
+		s.logger.Warn("dry run: encoding analysis failed", "error", err)
+		return
+	}
+	fmt.Fprintln(s.dryRunOut, string(out))
+}
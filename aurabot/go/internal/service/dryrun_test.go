@@ -0,0 +1,43 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"screen-memory-assistant/internal/llm"
+	"screen-memory-assistant/internal/memory"
+)
+
+func TestAnalyzeAndStore_DryRun(t *testing.T) {
+	var added []memory.Metadata
+	mem0 := recordingMem0(&added)
+	defer mem0.Close()
+
+	svc := newTestService(t, "http://127.0.0.1:1", mem0.URL)
+	svc.config.App.DryRun = true
+	svc.config.Capture.ArchiveDir = t.TempDir()
+	svc.analyzer = &scriptedAnalyzer{context: "work", summaries: []string{"Editing main.go"}}
+	var out bytes.Buffer
+	svc.SetDryRunOutput(&out)
+
+	svc.analyzeAndStore(context.Background(), testCapture())
+
+	if len(added) != 0 {
+		t.Errorf("dry run stored %d memories", len(added))
+	}
+	var printed struct {
+		Analysis llm.AnalysisResult `json:"analysis"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &printed); err != nil {
+		t.Fatalf("dry-run output %q: %v", out.String(), err)
+	}
+	if printed.Analysis.Summary != "Editing main.go" || printed.Analysis.Context != "work" {
+		t.Errorf("printed analysis = %+v, want the model's result", printed.Analysis)
+	}
+	if entries, _ := os.ReadDir(svc.config.Capture.ArchiveDir); len(entries) != 0 {
+		t.Errorf("dry run archived %d frames", len(entries))
+	}
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"screen-memory-assistant/internal/config"
+	"screen-memory-assistant/internal/events"
+	"screen-memory-assistant/internal/memory"
+)
+
+// storedEvent is the payload of a memory.stored event
+type storedEvent struct {
+	Content string `json:"content"`
+	Context string `json:"context"`
+	Source  string `json:"source"`
+}
+
+// newEvents creates the event bus, forwarding to webhooks when configured
+func newEvents(cfg *config.EventsConfig) (*events.Bus, *events.Webhooks) {
+	bus := events.NewBus(0)
+	if len(cfg.Webhooks) == 0 {
+		return bus, nil
+	}
+
+	webhooks := events.NewWebhooks(cfg.Webhooks, time.Duration(cfg.WebhookTimeoutSeconds)*time.Second)
+	webhooks.SetRetry(cfg.WebhookMaxAttempts,
+		time.Duration(cfg.WebhookBackoffMs)*time.Millisecond,
+		time.Duration(cfg.WebhookMaxBackoffMs)*time.Millisecond)
+	bus.Subscribe(webhooks.Handle)
+	return bus, webhooks
+}
+
+// publishStored announces a newly stored memory
+func (s *Service) publishStored(content string, metadata memory.Metadata) {
+	data := storedEvent{
+		Content: content,
+		Context: metadata.Context,
+		Source:  metadata.Source,
+	}
+	if !s.events.Publish(events.MemoryStored, data) && s.currentConfig().App.Verbose {
+		s.logs.Printf("Event queue full, dropped %s event", events.MemoryStored)
+	}
+}
+
+// flushEvents drains the event bus and waits for webhook deliveries, up
+// to the configured timeout, so pending events aren't lost on quit
+func (s *Service) flushEvents() {
+	timeout := time.Duration(s.currentConfig().Events.FlushTimeoutSeconds) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if n := s.events.Close(ctx); n > 0 {
+		s.logger.Warn("events were not dispatched before shutdown", "count", n)
+	}
+	if s.webhooks == nil {
+		return
+	}
+	if n := s.webhooks.Flush(ctx); n > 0 {
+		s.logger.Warn("webhook deliveries were not completed before shutdown", "count", n)
+	}
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"screen-memory-assistant/internal/config"
+	"screen-memory-assistant/internal/events"
+	"screen-memory-assistant/internal/memory"
+)
+
+func TestStop_FlushesQueuedWebhook(t *testing.T) {
+	// The first attempt fails, so delivery is still retrying at shutdown
+	var attempts int32
+	delivered := make(chan string, 1)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		var e struct {
+			Type string `json:"type"`
+		}
+		json.NewDecoder(r.Body).Decode(&e)
+		delivered <- e.Type
+	}))
+	defer hook.Close()
+
+	var added []memory.Metadata
+	memServer := recordingMem0(&added)
+	defer memServer.Close()
+
+	svc := newTestService(t, "http://127.0.0.1:1", memServer.URL)
+	svc.config.Events = config.EventsConfig{
+		Webhooks:              []string{hook.URL},
+		WebhookTimeoutSeconds: 1,
+		FlushTimeoutSeconds:   5,
+	}
+	svc.events, svc.webhooks = newEvents(&svc.config.Events)
+
+	if _, err := svc.AddNote("Ship the release notes"); err != nil {
+		t.Fatalf("AddNote failed: %v", err)
+	}
+	svc.stop()
+
+	select {
+	case got := <-delivered:
+		if got != events.MemoryStored {
+			t.Errorf("event type = %q, want %q", got, events.MemoryStored)
+		}
+	default:
+		t.Fatal("webhook was not delivered before stop returned")
+	}
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Dependencies named in a HealthReport
+const (
+	DependencyLLM    = "llm"
+	DependencyMemory = "memory"
+)
+
+// Overall statuses of a HealthReport
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+	HealthStarting = "starting"
+)
+
+// Dependencies are checked often while one is down, so the app recovers
+// soon after e.g. LM Studio starts, and rarely once all are up
+const (
+	healthRetryInterval = 10 * time.Second
+	healthCheckInterval = time.Minute
+)
+
+// DependencyStatus is the result of the last check of one dependency
+type DependencyStatus struct {
+	Healthy   bool      `json:"healthy"`
+	URL       string    `json:"url"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// HealthReport is the status of every dependency. Status is "ok" when all
+// are healthy, "degraded" when any is down and "starting" until the first
+// check has finished.
+type HealthReport struct {
+	Status       string                      `json:"status"`
+	Dependencies map[string]DependencyStatus `json:"dependencies"`
+}
+
+// healthState holds the latest dependency checks
+type healthState struct {
+	mu     sync.RWMutex
+	status map[string]DependencyStatus
+}
+
+// HealthReport returns the result of the latest dependency checks
+func (s *Service) HealthReport() HealthReport {
+	s.health.mu.RLock()
+	defer s.health.mu.RUnlock()
+
+	report := HealthReport{Status: HealthOK, Dependencies: make(map[string]DependencyStatus, len(s.health.status))}
+	if len(s.health.status) == 0 {
+		report.Status = HealthStarting
+	}
+	for name, status := range s.health.status {
+		report.Dependencies[name] = status
+		if !status.Healthy {
+			report.Status = HealthDegraded
+		}
+	}
+	return report
+}
+
+// checkDependencies checks the LLM and memory backend and records the
+// results, logging dependencies that went down or came back. It reports
+// whether all of them are healthy.
+func (s *Service) checkDependencies(ctx context.Context) bool {
+	cfg := s.currentConfig()
+	now := s.now()
+	checks := []struct {
+		name, label, url string
+		err              error
+	}{
+		{DependencyLLM, "LLM", cfg.LLM.BaseURL, s.checkLLM(ctx)},
+		{DependencyMemory, "Mem0", cfg.Memory.BaseURL, s.checkMemory()},
+	}
+
+	healthy := true
+	for _, c := range checks {
+		status := DependencyStatus{Healthy: c.err == nil, URL: c.url, CheckedAt: now}
+		if c.err != nil {
+			status.Error = c.err.Error()
+			healthy = false
+		}
+
+		s.health.mu.Lock()
+		previous, seen := s.health.status[c.name]
+		s.health.status[c.name] = status
+		s.health.mu.Unlock()
+
+		switch {
+		case status.Healthy && (!seen || !previous.Healthy):
+			s.logger.Info(c.label+" connected", "base_url", c.url)
+		case !status.Healthy && (!seen || previous.Healthy):
+			s.logger.Warn(c.label+" not available, running degraded until it is", "base_url", c.url, "error", c.err)
+		}
+	}
+	return healthy
+}
+
+// healthLoop keeps checking the dependencies, more often while one of
+// them is down
+func (s *Service) healthLoop(ctx context.Context, healthy bool) {
+	defer s.wg.Done()
+
+	for {
+		interval := healthCheckInterval
+		if !healthy {
+			interval = healthRetryInterval
+		}
+
+		select {
+		case <-s.after(interval):
+			healthy = s.checkDependencies(ctx)
+		case <-s.stopChan:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRun_StartsDegradedAndRecovers(t *testing.T) {
+	svc := newTestService(t, "http://127.0.0.1:1", "http://127.0.0.1:1")
+	svc.config.App.CaptureSource = CaptureNone
+	var llmUp atomic.Bool
+	svc.checkLLM = func(ctx context.Context) error {
+		if !llmUp.Load() {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+	svc.checkMemory = func() error { return nil }
+	svc.after = func(time.Duration) <-chan time.Time { return time.After(5 * time.Millisecond) }
+
+	if got := svc.HealthReport().Status; got != HealthStarting {
+		t.Errorf("status before Run = %q, want %q", got, HealthStarting)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- svc.Run(ctx) }()
+
+	waitFor := func(status string) HealthReport {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			report := svc.HealthReport()
+			if report.Status == status || time.Now().After(deadline) {
+				return report
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	report := waitFor(HealthDegraded)
+	llm := report.Dependencies[DependencyLLM]
+	if report.Status != HealthDegraded || llm.Healthy || llm.Error != "connection refused" {
+		t.Fatalf("report with the LLM down = %+v, want degraded with its error", report)
+	}
+	if !report.Dependencies[DependencyMemory].Healthy {
+		t.Errorf("memory reported down: %+v", report.Dependencies[DependencyMemory])
+	}
+
+	// The app keeps running and notices the LLM coming up
+	llmUp.Store(true)
+	if report := waitFor(HealthOK); report.Status != HealthOK {
+		t.Errorf("report after the LLM came up = %+v, want ok", report)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+}
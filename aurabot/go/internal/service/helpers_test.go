@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"screen-memory-assistant/internal/capture"
+	"screen-memory-assistant/internal/config"
+	"screen-memory-assistant/internal/llm"
+	"screen-memory-assistant/internal/memory"
+)
+
+// newTestService creates a service pointed at the given LLM and Mem0 URLs
+func newTestService(t *testing.T, llmURL, memURL string) *Service {
+	t.Helper()
+	cfg := &config.Config{
+		LLM: config.LLMConfig{
+			BaseURL:        llmURL,
+			Model:          "test-model",
+			TimeoutSeconds: 5,
+		},
+		Memory: config.MemoryConfig{
+			BaseURL: memURL,
+			UserID:  "test_user",
+		},
+		App: config.AppConfig{
+			ProcessOnCapture: true,
+			MemoryWindow:     5,
+		},
+	}
+	svc, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return svc
+}
+
+// fakeLLM returns a server answering chat completions with the given content
+func fakeLLM(content string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":%q}}]}`, content)
+	}))
+}
+
+func testCapture() *capture.Capture {
+	return &capture.Capture{Timestamp: time.Now(), Compressed: []byte{0xff, 0xd8}}
+}
+
+// recordingMem0 returns a Mem0 stand-in recording the metadata of added memories
+func recordingMem0(added *[]memory.Metadata) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Write([]byte("[]"))
+			return
+		}
+		var payload struct {
+			Metadata memory.Metadata `json:"metadata"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		*added = append(*added, payload.Metadata)
+		w.WriteHeader(http.StatusCreated)
+	}))
+}
+
+// fakeStreamer streams fixed chunks, optionally cancelling after some
+type fakeStreamer struct {
+	chunks      []string
+	cancelAfter int
+	cancel      context.CancelFunc
+	prompt      string
+}
+
+func (f *fakeStreamer) StreamChat(ctx context.Context, systemPrompt, userPrompt string, onChunk func(string)) (string, error) {
+	f.prompt = userPrompt
+	var full strings.Builder
+	for i, chunk := range f.chunks {
+		if f.cancel != nil && i == f.cancelAfter {
+			f.cancel()
+		}
+		if ctx.Err() != nil {
+			return full.String(), ctx.Err()
+		}
+		full.WriteString(chunk)
+		if onChunk != nil {
+			onChunk(chunk)
+		}
+	}
+	return full.String(), nil
+}
+
+// scriptedAnalyzer returns the next summary on each call, all in one context
+type scriptedAnalyzer struct {
+	context   string
+	summaries []string
+}
+
+func (a *scriptedAnalyzer) AnalyzeCapture(ctx context.Context, imageData []byte, previousContext, windowTitle string) (*llm.AnalysisResult, error) {
+	summary := a.summaries[0]
+	a.summaries = a.summaries[1:]
+	return &llm.AnalysisResult{Summary: summary, Context: a.context, Activities: []string{summary}}, nil
+}
+
+// listingMem0 is a Mem0 stand-in that keeps added memories, applies
+// updates and deletions and lists them
+func listingMem0(memories *[]memory.Memory) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+			Text     string          `json:"text"`
+			Metadata memory.Metadata `json:"metadata"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+
+		switch r.Method {
+		case http.MethodGet:
+			json.NewEncoder(w).Encode(*memories)
+		case http.MethodPost:
+			m := memory.Memory{ID: fmt.Sprintf("m%d", len(*memories)+1), Metadata: payload.Metadata}
+			if len(payload.Messages) > 0 {
+				m.Content = payload.Messages[0].Content
+			}
+			*memories = append(*memories, m)
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(m)
+		case http.MethodPut:
+			for i := range *memories {
+				if strings.Contains(r.URL.Path, "/"+(*memories)[i].ID+"/") {
+					(*memories)[i].Content = payload.Text
+					(*memories)[i].Metadata = payload.Metadata
+				}
+			}
+		case http.MethodDelete:
+			for i := range *memories {
+				if strings.Contains(r.URL.Path, "/"+(*memories)[i].ID+"/") {
+					*memories = append((*memories)[:i], (*memories)[i+1:]...)
+					break
+				}
+			}
+		}
+	}))
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"time"
+)
+
+// keepWarmLoop pings the vision model every interval so it stays loaded
+func (s *Service) keepWarmLoop(ctx context.Context, interval time.Duration) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.keepWarm(ctx)
+		case <-s.stopChan:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// keepWarm sends one warmup ping unless the app is idle or quiet
+func (s *Service) keepWarm(ctx context.Context) {
+	cfg := s.currentConfig()
+
+	// No captures are coming, so there's no reload to avoid
+	if !capturesPeriodically(cfg) || s.Paused() || !cfg.App.ProcessOnCapture {
+		return
+	}
+	if locked, err := s.sessionLocked(); err == nil && locked {
+		return
+	}
+	if !withinHours(time.Now().Hour(), cfg.LLM.KeepWarmStartHour, cfg.LLM.KeepWarmEndHour) {
+		return
+	}
+
+	// An analysis in flight already keeps the model loaded
+	select {
+	case s.visionSem <- struct{}{}:
+		defer func() { <-s.visionSem }()
+	default:
+		return
+	}
+
+	if err := s.warmup(ctx); err != nil && cfg.App.Verbose {
+		s.logs.Printf("Keep-warm ping failed: %v", err)
+	}
+}
+
+// withinHours reports whether hour falls in [start, end), wrapping past
+// midnight when end is before start. Equal bounds mean all day.
+func withinHours(hour, start, end int) bool {
+	if start == end {
+		return true
+	}
+	if start < end {
+		return hour >= start && hour < end
+	}
+	return hour >= start || hour < end
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestKeepWarmLoop_Cadence(t *testing.T) {
+	var pings int32
+	llmServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&pings, 1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":"ok"}}]}`)
+	}))
+	defer llmServer.Close()
+
+	svc := newTestService(t, llmServer.URL, "http://127.0.0.1:1")
+	svc.config.Capture.Enabled = true
+	svc.sessionLocked = func() (bool, error) { return false, nil }
+
+	svc.wg.Add(1)
+	go svc.keepWarmLoop(context.Background(), 25*time.Millisecond)
+	time.Sleep(140 * time.Millisecond)
+	close(svc.stopChan)
+	svc.wg.Wait()
+
+	got := atomic.LoadInt32(&pings)
+	if got < 3 || got > 6 {
+		t.Errorf("pings = %d in 140ms at 25ms intervals, want about 5", got)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if after := atomic.LoadInt32(&pings); after != got {
+		t.Errorf("pings continued after stop: %d -> %d", got, after)
+	}
+}
+
+func TestKeepWarm_SkipsWhenIdle(t *testing.T) {
+	var pings int32
+	llmServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&pings, 1)
+	}))
+	defer llmServer.Close()
+
+	svc := newTestService(t, llmServer.URL, "http://127.0.0.1:1")
+
+	// Capture disabled
+	svc.keepWarm(context.Background())
+
+	// Session locked
+	svc.config.Capture.Enabled = true
+	svc.sessionLocked = func() (bool, error) { return true, nil }
+	svc.keepWarm(context.Background())
+
+	if got := atomic.LoadInt32(&pings); got != 0 {
+		t.Errorf("pings = %d, want none while idle", got)
+	}
+}
+
+func TestWithinHours(t *testing.T) {
+	tests := []struct {
+		hour, start, end int
+		want             bool
+	}{
+		{3, 0, 0, true},
+		{9, 9, 18, true},
+		{18, 9, 18, false},
+		{23, 22, 6, true},
+		{5, 22, 6, true},
+		{12, 22, 6, false},
+	}
+	for _, tt := range tests {
+		if got := withinHours(tt.hour, tt.start, tt.end); got != tt.want {
+			t.Errorf("withinHours(%d, %d, %d) = %v, want %v", tt.hour, tt.start, tt.end, got, tt.want)
+		}
+	}
+}
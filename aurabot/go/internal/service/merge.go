@@ -0,0 +1,84 @@
+package service
+
+import (
+	"strings"
+	"time"
+
+	"screen-memory-assistant/internal/memory"
+)
+
+// mergeTarget returns the latest memory when a capture at ts in the given
+// context continues it: same context, from a capture, and no more than
+// window after the memory's last capture. It returns nil otherwise.
+func mergeTarget(recent []memory.Memory, context string, ts time.Time, window time.Duration) *memory.Memory {
+	if window <= 0 || len(recent) == 0 || strings.TrimSpace(context) == "" {
+		return nil
+	}
+
+	latest := &recent[0]
+	for i := range recent[1:] {
+		if recent[i+1].Time().After(latest.Time()) {
+			latest = &recent[i+1]
+		}
+	}
+
+	if latest.Metadata.Source != memory.SourceCapture ||
+		!strings.EqualFold(strings.TrimSpace(latest.Metadata.Context), strings.TrimSpace(context)) {
+		return nil
+	}
+
+	end := latest.Time()
+	if t, err := time.Parse(time.RFC3339, latest.Metadata.EndTimestamp); err == nil {
+		end = t
+	}
+	if gap := ts.Sub(end); gap < 0 || gap > window {
+		return nil
+	}
+	return latest
+}
+
+// mergeMemory folds a new capture into prev, appending its summary and
+// extending the end time
+func mergeMemory(prev *memory.Memory, summary string, metadata memory.Metadata) (string, memory.Metadata) {
+	content := prev.Content
+	if !strings.Contains(content, summary) {
+		content += " | Then: " + summary
+	}
+
+	merged := prev.Metadata
+	merged.EndTimestamp = metadata.Timestamp
+	merged.Activities = appendMissing(merged.Activities, metadata.Activities)
+	merged.KeyElements = appendMissing(merged.KeyElements, metadata.KeyElements)
+	if metadata.UserIntent != "" {
+		merged.UserIntent = metadata.UserIntent
+	}
+	merged.LowConfidence = merged.LowConfidence || metadata.LowConfidence
+	if len(metadata.Extra) > 0 {
+		extra := make(map[string]interface{}, len(merged.Extra)+len(metadata.Extra))
+		for k, v := range merged.Extra {
+			extra[k] = v
+		}
+		for k, v := range metadata.Extra {
+			extra[k] = v
+		}
+		merged.Extra = extra
+	}
+	return content, merged
+}
+
+// appendMissing appends the items of extra not already in list
+func appendMissing(list, extra []string) []string {
+	for _, item := range extra {
+		found := false
+		for _, have := range list {
+			if strings.EqualFold(have, item) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, item)
+		}
+	}
+	return list
+}
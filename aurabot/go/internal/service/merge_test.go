@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"screen-memory-assistant/internal/capture"
+	"screen-memory-assistant/internal/memory"
+)
+
+func TestAnalyzeAndStore_MergesSameContextWithinWindow(t *testing.T) {
+	var memories []memory.Memory
+	mem0 := listingMem0(&memories)
+	defer mem0.Close()
+
+	svc := newTestService(t, "http://127.0.0.1:1", mem0.URL)
+	svc.config.App.MergeWindowSeconds = 120
+	svc.analyzer = &scriptedAnalyzer{context: "work", summaries: []string{"Editing main.go", "Running tests", "Reviewing a PR"}}
+
+	start := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	captureAt := func(offset time.Duration, frame byte) {
+		svc.analyzeAndStore(context.Background(), &capture.Capture{
+			Timestamp:  start.Add(offset),
+			Compressed: []byte{0xff, 0xd8, frame},
+		})
+	}
+	captureAt(0, 1)
+	captureAt(time.Minute, 2)
+	captureAt(10*time.Minute, 3) // outside the window of the merged memory's end
+
+	if len(memories) != 2 {
+		t.Fatalf("stored %d memories, want 2: %+v", len(memories), memories)
+	}
+	merged := memories[0]
+	if !strings.Contains(merged.Content, "Editing main.go") || !strings.Contains(merged.Content, "Running tests") {
+		t.Errorf("merged content = %q, want both summaries", merged.Content)
+	}
+	if want := start.Add(time.Minute).Format(time.RFC3339); merged.Metadata.EndTimestamp != want {
+		t.Errorf("end timestamp = %q, want %q", merged.Metadata.EndTimestamp, want)
+	}
+	if merged.Metadata.Timestamp != start.Format(time.RFC3339) {
+		t.Errorf("start timestamp = %q, want the first capture's", merged.Metadata.Timestamp)
+	}
+	if len(merged.Metadata.Activities) != 2 {
+		t.Errorf("activities = %v, want both captures'", merged.Metadata.Activities)
+	}
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// Skip reasons recorded when a capture is dropped or an analysis doesn't run
+const (
+	SkipCaptureFailed      = "capture_failed"
+	SkipProcessingDisabled = "processing_disabled"
+	SkipCancelled          = "cancelled"
+	SkipAnalysisFailed     = "analysis_failed"
+	SkipStoreFailed        = "store_failed"
+	SkipSessionLocked      = "session_locked"
+	SkipLowConfidence      = "low_confidence"
+	SkipPanicked           = "panicked"
+	SkipUnchanged          = "unchanged"
+	SkipNearDuplicate      = "near_duplicate"
+	SkipPaused             = "paused"
+	SkipIdle               = "idle"
+	SkipBlocked            = "blocked"
+)
+
+// SkipError reports why a capture wasn't stored, with the underlying
+// error if there was one
+type SkipError struct {
+	Reason string
+	Err    error
+}
+
+func (e *SkipError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("capture not stored (%s): %v", e.Reason, e.Err)
+	}
+	return fmt.Sprintf("capture not stored (%s)", e.Reason)
+}
+
+func (e *SkipError) Unwrap() error { return e.Err }
+
+// skip counts a skipped capture and returns the error describing it
+func (s *Service) skip(reason string, err error) error {
+	s.skips.inc(reason)
+	return &SkipError{Reason: reason, Err: err}
+}
+
+// skipCounters holds labeled counters of skipped captures and analyses
+type skipCounters struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func newSkipCounters() *skipCounters {
+	return &skipCounters{counts: make(map[string]int64)}
+}
+
+// inc increments the counter for reason
+func (c *skipCounters) inc(reason string) {
+	c.mu.Lock()
+	c.counts[reason]++
+	c.mu.Unlock()
+}
+
+// snapshot returns a copy of all counters
+func (c *skipCounters) snapshot() map[string]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[string]int64, len(c.counts))
+	for k, v := range c.counts {
+		out[k] = v
+	}
+	return out
+}
+
+// SkipCounts returns how often each skip reason has occurred
+func (s *Service) SkipCounts() map[string]int64 {
+	return s.skips.snapshot()
+}
+
+// WriteMetrics writes the skip counters in Prometheus text format
+func (s *Service) WriteMetrics(w io.Writer) {
+	counts := s.skips.snapshot()
+	reasons := make([]string, 0, len(counts))
+	for r := range counts {
+		reasons = append(reasons, r)
+	}
+	sort.Strings(reasons)
+
+	fmt.Fprintln(w, "# HELP aurabot_skipped_total Captures dropped or analyses skipped, by reason.")
+	fmt.Fprintln(w, "# TYPE aurabot_skipped_total counter")
+	for _, r := range reasons {
+		fmt.Fprintf(w, "aurabot_skipped_total{reason=%q} %d\n", r, counts[r])
+	}
+
+	if s.webhooks != nil {
+		fmt.Fprintln(w, "# HELP aurabot_webhook_dropped_total Webhook deliveries dropped after exhausting their retries.")
+		fmt.Fprintln(w, "# TYPE aurabot_webhook_dropped_total counter")
+		fmt.Fprintf(w, "aurabot_webhook_dropped_total %d\n", s.webhooks.Dropped())
+	}
+}
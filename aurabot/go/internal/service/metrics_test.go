@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSkipCounters_Cancelled(t *testing.T) {
+	svc := newTestService(t, "http://127.0.0.1:1", "http://127.0.0.1:1")
+	svc.visionSem <- struct{}{} // occupy the only slot
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	svc.analyzeAndStore(ctx, testCapture())
+
+	if got := svc.SkipCounts()[SkipCancelled]; got != 1 {
+		t.Errorf("%s = %d, want 1", SkipCancelled, got)
+	}
+}
+
+func TestSkipCounters_AnalysisFailed(t *testing.T) {
+	llmServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer llmServer.Close()
+
+	svc := newTestService(t, llmServer.URL, "http://127.0.0.1:1")
+	svc.analyzeAndStore(context.Background(), testCapture())
+
+	counts := svc.SkipCounts()
+	if counts[SkipAnalysisFailed] != 1 {
+		t.Errorf("%s = %d, want 1", SkipAnalysisFailed, counts[SkipAnalysisFailed])
+	}
+	if counts[SkipStoreFailed] != 0 {
+		t.Errorf("%s = %d, want 0", SkipStoreFailed, counts[SkipStoreFailed])
+	}
+}
+
+func TestSkipCounters_StoreFailed(t *testing.T) {
+	llmServer := fakeLLM(`{"summary": "Reading docs", "context": "work"}`)
+	defer llmServer.Close()
+	memServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer memServer.Close()
+
+	svc := newTestService(t, llmServer.URL, memServer.URL)
+	svc.analyzeAndStore(context.Background(), testCapture())
+
+	if got := svc.SkipCounts()[SkipStoreFailed]; got != 1 {
+		t.Errorf("%s = %d, want 1", SkipStoreFailed, got)
+	}
+}
+
+func TestSkipCounters_CaptureFailedOrDisabled(t *testing.T) {
+	svc := newTestService(t, "http://127.0.0.1:1", "http://127.0.0.1:1")
+	svc.config.App.ProcessOnCapture = false
+
+	svc.processCapture(context.Background())
+
+	// Headless environments fail the capture; otherwise processing is disabled
+	counts := svc.SkipCounts()
+	if counts[SkipCaptureFailed]+counts[SkipProcessingDisabled] != 1 {
+		t.Errorf("expected exactly one capture skip, got %v", counts)
+	}
+}
+
+func TestWriteMetrics(t *testing.T) {
+	svc := newTestService(t, "http://127.0.0.1:1", "http://127.0.0.1:1")
+	svc.skips.inc(SkipAnalysisFailed)
+	svc.skips.inc(SkipAnalysisFailed)
+
+	var buf strings.Builder
+	svc.WriteMetrics(&buf)
+
+	if !strings.Contains(buf.String(), `aurabot_skipped_total{reason="analysis_failed"} 2`) {
+		t.Errorf("metrics output missing counter:\n%s", buf.String())
+	}
+
+	skipped, ok := svc.GetStatus()["skipped"].(map[string]int64)
+	if !ok || skipped[SkipAnalysisFailed] != 2 {
+		t.Errorf("status skipped = %v, want analysis_failed=2", skipped)
+	}
+}
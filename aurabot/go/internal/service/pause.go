@@ -0,0 +1,29 @@
+package service
+
+import "context"
+
+// Pause stops scheduled captures until Resume is called. The capture loop
+// keeps running, so no restart is needed; CaptureNow still works.
+func (s *Service) Pause() {
+	s.paused.Store(true)
+}
+
+// Resume restarts scheduled captures after Pause
+func (s *Service) Resume() {
+	s.paused.Store(false)
+}
+
+// Paused reports whether scheduled captures are paused
+func (s *Service) Paused() bool {
+	return s.paused.Load()
+}
+
+// scheduledCapture runs one capture from the interval or cron schedule,
+// unless capture is paused
+func (s *Service) scheduledCapture(ctx context.Context) {
+	if s.Paused() {
+		s.skips.inc(SkipPaused)
+		return
+	}
+	s.processCapture(ctx)
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCaptureLoop_PauseResume(t *testing.T) {
+	svc := newTestService(t, "http://127.0.0.1:1", "http://127.0.0.1:1")
+	svc.config.App.CaptureCron = "* * * * *"
+
+	captures := 0
+	svc.sessionLocked = func() (bool, error) {
+		captures++
+		return true, nil
+	}
+
+	clock := time.Date(2024, 5, 17, 8, 30, 0, 0, time.UTC)
+	svc.now = func() time.Time { return clock }
+
+	// Pause before the second fire and resume before the fourth,
+	// from outside the loop as the desktop app would
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fires := 0
+	svc.after = func(d time.Duration) <-chan time.Time {
+		switch fires {
+		case 1:
+			svc.Pause()
+		case 3:
+			svc.Resume()
+		case 4:
+			cancel()
+			return nil
+		}
+		fires++
+		clock = clock.Add(d)
+		ch := make(chan time.Time, 1)
+		ch <- clock
+		return ch
+	}
+
+	svc.wg.Add(1)
+	svc.captureLoop(ctx)
+
+	if captures != 2 {
+		t.Errorf("%d captures over 4 fires with 2 paused, want 2", captures)
+	}
+	if got := svc.SkipCounts()[SkipPaused]; got != 2 {
+		t.Errorf("%s = %d, want 2", SkipPaused, got)
+	}
+	if svc.Paused() {
+		t.Error("still paused after Resume")
+	}
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"screen-memory-assistant/internal/memory"
+)
+
+// Profile tuning
+const (
+	// profileWindow is how many recent memories the profile is built from
+	profileWindow = 200
+	// profileTopN is how many entries each profile list keeps
+	profileTopN = 5
+)
+
+// Profile summarizes what the user does most, from recent memories
+type Profile struct {
+	Activities []string  `json:"activities"`
+	Tools      []string  `json:"tools"`
+	Contexts   []string  `json:"contexts"`
+	Memories   int       `json:"memories"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Summary renders the profile as a short line for prompts; empty when
+// there is nothing to say
+func (p Profile) Summary() string {
+	var parts []string
+	if len(p.Activities) > 0 {
+		parts = append(parts, "often "+strings.Join(p.Activities, ", "))
+	}
+	if len(p.Tools) > 0 {
+		parts = append(parts, "uses "+strings.Join(p.Tools, ", "))
+	}
+	if len(p.Contexts) > 0 {
+		parts = append(parts, "mostly in "+strings.Join(p.Contexts, ", ")+" contexts")
+	}
+	return strings.Join(parts, "; ")
+}
+
+// buildProfile counts recurring activities, key elements and contexts
+func buildProfile(memories []memory.Memory, now time.Time) Profile {
+	activities := make(map[string]int)
+	tools := make(map[string]int)
+	contexts := make(map[string]int)
+	for _, m := range memories {
+		for _, a := range m.Metadata.Activities {
+			activities[strings.ToLower(strings.TrimSpace(a))]++
+		}
+		for _, k := range m.Metadata.KeyElements {
+			tools[strings.TrimSpace(k)]++
+		}
+		if c := strings.TrimSpace(m.Metadata.Context); c != "" && c != summaryContext {
+			contexts[strings.ToLower(c)]++
+		}
+	}
+
+	return Profile{
+		Activities: topCounts(activities, profileTopN),
+		Tools:      topCounts(tools, profileTopN),
+		Contexts:   topCounts(contexts, profileTopN),
+		Memories:   len(memories),
+		UpdatedAt:  now,
+	}
+}
+
+// topCounts returns the n most frequent keys, ties broken alphabetically
+func topCounts(counts map[string]int, n int) []string {
+	var keys []string
+	for k := range counts {
+		if k != "" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+	return keys
+}
+
+// GetProfile returns the current user profile
+func (s *Service) GetProfile() Profile {
+	s.profileMu.Lock()
+	defer s.profileMu.Unlock()
+	return s.profile
+}
+
+// ProfileSummary returns the profile as a prompt line; it can be handed
+// to the enhancer
+func (s *Service) ProfileSummary() string {
+	return s.GetProfile().Summary()
+}
+
+// RefreshProfile rebuilds the profile from recent memories
+func (s *Service) RefreshProfile() error {
+	memories, err := s.memory.GetRecent(profileWindow)
+	if err != nil {
+		return fmt.Errorf("loading memories: %w", err)
+	}
+
+	profile := buildProfile(memories, time.Now())
+	s.profileMu.Lock()
+	s.profile = profile
+	s.newSinceProfile = 0
+	s.profileMu.Unlock()
+	return nil
+}
+
+// noteNewMemory counts a stored memory and refreshes the profile in the
+// background every App.ProfileEveryMemories memories
+func (s *Service) noteNewMemory() {
+	every := s.currentConfig().App.ProfileEveryMemories
+	if every <= 0 {
+		return
+	}
+
+	s.profileMu.Lock()
+	s.newSinceProfile++
+	due := s.newSinceProfile >= every
+	if due {
+		s.newSinceProfile = 0
+	}
+	s.profileMu.Unlock()
+
+	if due {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.refreshProfileLogged()
+		}()
+	}
+}
+
+// profileLoop refreshes the profile at startup and then every interval
+func (s *Service) profileLoop(ctx context.Context, interval time.Duration) {
+	defer s.wg.Done()
+
+	s.refreshProfileLogged()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.refreshProfileLogged()
+		case <-s.stopChan:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Service) refreshProfileLogged() {
+	if err := s.RefreshProfile(); err != nil && s.currentConfig().App.Verbose {
+		s.logs.Printf("Profile refresh failed: %v", err)
+	}
+}
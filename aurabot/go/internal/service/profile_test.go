@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"screen-memory-assistant/internal/config"
+	"screen-memory-assistant/internal/enhancer"
+	"screen-memory-assistant/internal/memory"
+)
+
+func TestProfile_UpdatesAndIsIncludedInEnhancements(t *testing.T) {
+	var mu sync.Mutex
+	var stored []memory.Memory
+	memServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/search/"):
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"results": []map[string]interface{}{{"id": "m1", "memory": "Fixed the build", "score": 0.9}},
+			})
+		case r.Method == http.MethodPost:
+			var payload struct {
+				Metadata memory.Metadata `json:"metadata"`
+			}
+			json.NewDecoder(r.Body).Decode(&payload)
+			stored = append(stored, memory.Memory{Metadata: payload.Metadata})
+			w.WriteHeader(http.StatusCreated)
+		default:
+			json.NewEncoder(w).Encode(stored)
+		}
+	}))
+	defer memServer.Close()
+
+	svc := newTestService(t, "http://127.0.0.1:1", memServer.URL)
+	svc.config.App.ProfileEveryMemories = 2
+
+	if err := svc.RefreshProfile(); err != nil {
+		t.Fatalf("RefreshProfile failed: %v", err)
+	}
+	if got := svc.ProfileSummary(); got != "" {
+		t.Errorf("empty store gave profile %q", got)
+	}
+
+	// Two new memories trigger a background refresh
+	svc.AddNote("Standup notes")
+	svc.AddNote("Sprint planning")
+	svc.wg.Wait()
+
+	profile := svc.GetProfile()
+	if profile.Memories != 2 || len(profile.Contexts) != 1 || profile.Contexts[0] != "note" {
+		t.Fatalf("profile = %+v, want 2 memories in the note context", profile)
+	}
+
+	e := enhancer.New(memory.NewStore(&config.MemoryConfig{BaseURL: memServer.URL}))
+	e.SetProfile(svc.ProfileSummary)
+	result, err := e.Enhance(context.Background(), "what's next?", "", 5)
+	if err != nil {
+		t.Fatalf("Enhance failed: %v", err)
+	}
+	if !strings.Contains(result.EnhancedPrompt, "[About me: mostly in note contexts]") {
+		t.Errorf("enhanced prompt lacks the profile:\n%s", result.EnhancedPrompt)
+	}
+}
+
+func TestBuildProfile_TopEntries(t *testing.T) {
+	memories := []memory.Memory{
+		{Metadata: memory.Metadata{Context: "work", Activities: []string{"Coding", "reviewing"}, KeyElements: []string{"VS Code"}}},
+		{Metadata: memory.Metadata{Context: "work", Activities: []string{"coding"}, KeyElements: []string{"VS Code", "Slack"}}},
+		{Metadata: memory.Metadata{Context: "personal", Activities: []string{"reading"}}},
+	}
+
+	p := buildProfile(memories, time.Now())
+	want := "often coding, reading, reviewing; uses VS Code, Slack; mostly in work, personal contexts"
+	if got := p.Summary(); got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+}
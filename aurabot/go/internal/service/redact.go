@@ -0,0 +1,106 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"screen-memory-assistant/internal/llm"
+)
+
+// redactedText replaces text matching a redaction pattern
+const redactedText = "[redacted]"
+
+// blockedBy returns the blocklist entry matching the window title, if
+// any. Entries match case-insensitively anywhere in the title, with "*"
+// matching any run of characters.
+func blockedBy(title string, blocklist []string) (string, bool) {
+	if title == "" {
+		return "", false
+	}
+	title = strings.ToLower(title)
+	for _, entry := range blocklist {
+		if entry != "" && matchWildcard(title, strings.ToLower(entry)) {
+			return entry, true
+		}
+	}
+	return "", false
+}
+
+// matchWildcard reports whether the parts of pattern between "*"s appear
+// in s in order
+func matchWildcard(s, pattern string) bool {
+	for _, part := range strings.Split(pattern, "*") {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return true
+}
+
+// redactor masks sensitive text, such as card numbers and email
+// addresses, before memories are stored
+type redactor struct {
+	patterns []*regexp.Regexp
+}
+
+// newRedactor compiles the redaction patterns
+func newRedactor(patterns []string) (*redactor, error) {
+	r := &redactor{}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("redact pattern %q: %w", p, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// redact masks every match in s
+func (r *redactor) redact(s string) string {
+	for _, re := range r.patterns {
+		s = re.ReplaceAllString(s, redactedText)
+	}
+	return s
+}
+
+// redactAnalysis masks the free-text fields of an analysis, including
+// fields outside the schema, which end up in the stored memory
+func (r *redactor) redactAnalysis(result *llm.AnalysisResult) {
+	if len(r.patterns) == 0 {
+		return
+	}
+	result.Summary = r.redact(result.Summary)
+	result.Context = r.redact(result.Context)
+	result.UserIntent = r.redact(result.UserIntent)
+	result.OCRText = r.redact(result.OCRText)
+	for k, v := range result.Metadata {
+		result.Metadata[k] = r.redactValue(v)
+	}
+	for i := range result.Activities {
+		result.Activities[i] = r.redact(result.Activities[i])
+	}
+	for i := range result.KeyElements {
+		result.KeyElements[i] = r.redact(result.KeyElements[i])
+	}
+}
+
+// redactValue masks the strings in a decoded JSON value
+func (r *redactor) redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return r.redact(v)
+	case []interface{}:
+		for i := range v {
+			v[i] = r.redactValue(v[i])
+		}
+	case map[string]interface{}:
+		for k := range v {
+			v[k] = r.redactValue(v[k])
+		}
+	}
+	return v
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"screen-memory-assistant/internal/config"
+	"screen-memory-assistant/internal/memory"
+)
+
+func TestAnalyzeAndStore_BlocklistAndRedaction(t *testing.T) {
+	var memories []memory.Memory
+	mem0 := listingMem0(&memories)
+	defer mem0.Close()
+
+	svc := newTestService(t, "http://127.0.0.1:1", mem0.URL)
+	svc.config.Capture.Blocklist = []string{"Bitwarden", "*.mybank.com"}
+	redactor, err := newRedactor(config.DefaultRedactPatterns)
+	if err != nil {
+		t.Fatalf("default patterns: %v", err)
+	}
+	svc.redactor = redactor
+	analyzer := &scriptedAnalyzer{context: "work", summaries: []string{"Paying 4111 1111 1111 1111 for jane.doe@example.com"}}
+	svc.analyzer = analyzer
+
+	for i, title := range []string{"Vault - bitwarden", "Accounts | secure.MyBank.com - Chrome"} {
+		cap := testCapture()
+		cap.Compressed = []byte{0xff, 0xd8, byte(i)}
+		cap.WindowTitle = title
+		svc.analyzeAndStore(context.Background(), cap)
+	}
+	if got := svc.SkipCounts()[SkipBlocked]; got != 2 || len(analyzer.summaries) != 1 {
+		t.Fatalf("%s = %d with %d analyses left; want both blocklisted windows skipped before analysis",
+			SkipBlocked, got, len(analyzer.summaries))
+	}
+
+	cap := testCapture()
+	cap.WindowTitle = "Checkout - jane.doe@example.com"
+	svc.analyzeAndStore(context.Background(), cap)
+
+	if len(memories) != 1 {
+		t.Fatalf("stored %d memories, want 1", len(memories))
+	}
+	m := memories[0]
+	for _, text := range []string{m.Content, m.Metadata.WindowTitle, strings.Join(m.Metadata.Activities, " ")} {
+		if strings.Contains(text, "4111") || strings.Contains(text, "jane.doe") || !strings.Contains(text, "[redacted]") {
+			t.Errorf("stored text %q, want card number and email redacted", text)
+		}
+	}
+}
+
+func TestAnalyzeAndStore_RedactsContextAndExtraFields(t *testing.T) {
+	var added []memory.Metadata
+	mem0 := recordingMem0(&added)
+	defer mem0.Close()
+	llmServer := fakeLLM(`{"summary": "Checking out", "context": "paying with 4111 1111 1111 1111",
+		"merchant": {"contact": "jane.doe@example.com"}, "cards": ["4111 1111 1111 1111"]}`)
+	defer llmServer.Close()
+
+	svc := newTestService(t, llmServer.URL, mem0.URL)
+	redactor, err := newRedactor(config.DefaultRedactPatterns)
+	if err != nil {
+		t.Fatalf("default patterns: %v", err)
+	}
+	svc.redactor = redactor
+	svc.analyzeAndStore(context.Background(), testCapture())
+
+	if len(added) != 1 {
+		t.Fatalf("stored %d memories, want 1", len(added))
+	}
+	stored, _ := json.Marshal(added[0])
+	if strings.Contains(string(stored), "4111") || strings.Contains(string(stored), "jane.doe") {
+		t.Errorf("stored metadata %s, want card numbers and emails redacted", stored)
+	}
+	if added[0].Extra["cards"] == nil || !strings.Contains(added[0].Context, "[redacted]") {
+		t.Errorf("context = %q, extra = %v; want the card number redacted and the extra fields kept", added[0].Context, added[0].Extra)
+	}
+}
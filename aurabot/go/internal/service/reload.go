@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"screen-memory-assistant/internal/config"
+)
+
+// configPath is the file config.Load reads and the watcher follows
+const configPath = "config.yaml"
+
+// reloadDebounce waits out the burst of events a single save makes
+const reloadDebounce = 200 * time.Millisecond
+
+// SetOnConfigReload sets a function called with the config applied by
+// each reload, so the app can keep its own copy current
+func (s *Service) SetOnConfigReload(fn func(cfg *config.Config)) {
+	s.onConfigReload = fn
+}
+
+// ReloadConfig loads and validates config.yaml and applies the settings
+// that can change while running: capture interval, quality and enabled,
+// and the memory window. Other changes are logged as needing a restart.
+func (s *Service) ReloadConfig() error {
+	loaded, err := s.loadConfig()
+	if err != nil {
+		s.logger.Warn("config reload failed, keeping current config", "error", err)
+		return fmt.Errorf("reloading config: %w", err)
+	}
+
+	current := s.currentConfig()
+	for _, field := range restartOnlyChanges(current, loaded) {
+		s.logger.Warn("config change requires restart", "field", field)
+	}
+
+	if loaded.Capture.IntervalSeconds == current.Capture.IntervalSeconds &&
+		loaded.Capture.Quality == current.Capture.Quality &&
+		loaded.Capture.Enabled == current.Capture.Enabled &&
+		loaded.App.MemoryWindow == current.App.MemoryWindow {
+		return nil
+	}
+
+	next := *current
+	next.Capture.IntervalSeconds = loaded.Capture.IntervalSeconds
+	next.Capture.Quality = loaded.Capture.Quality
+	next.Capture.Enabled = loaded.Capture.Enabled
+	next.App.MemoryWindow = loaded.App.MemoryWindow
+
+	s.UpdateConfig(&next)
+	if next.Capture.Enabled {
+		s.Resume()
+	} else {
+		s.Pause()
+	}
+	s.logger.Info("config reloaded", "capture_interval_seconds", next.Capture.IntervalSeconds,
+		"quality", next.Capture.Quality, "capture_enabled", next.Capture.Enabled, "memory_window", next.App.MemoryWindow)
+
+	if s.onConfigReload != nil {
+		s.onConfigReload(&next)
+	}
+	return nil
+}
+
+// restartOnlyChanges names the changed settings that only take effect
+// after a restart
+func restartOnlyChanges(current, loaded *config.Config) []string {
+	fields := []struct {
+		name            string
+		current, loaded interface{}
+	}{
+		{"llm.base_url", current.LLM.BaseURL, loaded.LLM.BaseURL},
+		{"llm.model", current.LLM.Model, loaded.LLM.Model},
+		{"memory.base_url", current.Memory.BaseURL, loaded.Memory.BaseURL},
+		{"memory.user_id", current.Memory.UserID, loaded.Memory.UserID},
+		{"extension.enabled", current.Extension.Enabled, loaded.Extension.Enabled},
+		{"extension.port", current.Extension.Port, loaded.Extension.Port},
+		{"extension.bind_address", current.Extension.BindAddress, loaded.Extension.BindAddress},
+		{"app.capture_source", current.App.CaptureSource, loaded.App.CaptureSource},
+		{"app.capture_cron", current.App.CaptureCron, loaded.App.CaptureCron},
+	}
+
+	var changed []string
+	for _, f := range fields {
+		if f.current != f.loaded {
+			changed = append(changed, f.name)
+		}
+	}
+	return changed
+}
+
+// watchConfig reloads the config whenever the file at path changes. It
+// watches the directory, since saves that rename a temporary file into
+// place replace the file being watched.
+func (s *Service) watchConfig(ctx context.Context, path string) {
+	defer s.wg.Done()
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		s.logger.Warn("config watcher unavailable", "error", err)
+		return
+	}
+	defer watcher.Close()
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		s.logger.Warn("config watcher unavailable", "error", err)
+		return
+	}
+	if err := watcher.Add(filepath.Dir(abs)); err != nil {
+		s.logger.Warn("config watcher unavailable", "path", abs, "error", err)
+		return
+	}
+
+	// Fires once a burst of events has settled
+	debounce := time.NewTimer(reloadDebounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) == abs && event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+				debounce.Reset(reloadDebounce)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			s.logs.Printf("config watcher error: %v", err)
+		case <-debounce.C:
+			// A rename away with nothing in its place isn't a new config
+			if _, err := os.Stat(abs); err == nil {
+				s.ReloadConfig()
+			}
+		case <-s.stopChan:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
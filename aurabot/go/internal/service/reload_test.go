@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"screen-memory-assistant/internal/config"
+)
+
+func TestReloadConfig_AppliesLiveSettings(t *testing.T) {
+	svc := newTestService(t, "http://127.0.0.1:1", "http://127.0.0.1:1")
+	svc.config.Capture = config.CaptureConfig{IntervalSeconds: 30, Quality: 60, Enabled: true}
+
+	var logs strings.Builder
+	svc.SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	loaded := *svc.currentConfig()
+	loaded.Capture = config.CaptureConfig{IntervalSeconds: 5, Quality: 80, Enabled: false}
+	loaded.App.MemoryWindow = 20
+	loaded.Memory.BaseURL = "http://elsewhere:8000"
+	svc.loadConfig = func() (*config.Config, error) {
+		copied := loaded
+		return &copied, nil
+	}
+
+	var reloaded *config.Config
+	svc.SetOnConfigReload(func(cfg *config.Config) { reloaded = cfg })
+
+	if err := svc.ReloadConfig(); err != nil {
+		t.Fatalf("ReloadConfig failed: %v", err)
+	}
+
+	cfg := svc.currentConfig()
+	if cfg.Capture.IntervalSeconds != 5 || cfg.Capture.Quality != 80 || cfg.App.MemoryWindow != 20 {
+		t.Errorf("config = %+v, memory window %d; want the reloaded values", cfg.Capture, cfg.App.MemoryWindow)
+	}
+	if !svc.Paused() {
+		t.Error("capture not paused after reloading with enabled: false")
+	}
+	if reloaded != cfg {
+		t.Error("reload callback didn't get the applied config")
+	}
+	select {
+	case <-svc.intervalChanged:
+	default:
+		t.Error("capture loop not told about the new interval")
+	}
+
+	// The base URL needs a restart: logged, not applied
+	if cfg.Memory.BaseURL != "http://127.0.0.1:1" {
+		t.Errorf("memory.base_url = %q, want it unchanged until restart", cfg.Memory.BaseURL)
+	}
+	if !strings.Contains(logs.String(), "requires restart") || !strings.Contains(logs.String(), "memory.base_url") {
+		t.Errorf("logs don't mention the restart:\n%s", logs.String())
+	}
+
+	// An invalid file keeps the current config
+	svc.loadConfig = func() (*config.Config, error) { return nil, errors.New("invalid capture.quality 0") }
+	if err := svc.ReloadConfig(); err == nil {
+		t.Error("ReloadConfig succeeded with an invalid file")
+	}
+	if svc.currentConfig() != cfg {
+		t.Error("config replaced by a failed reload")
+	}
+}
+
+func TestWatchConfig_ReloadsOnSave(t *testing.T) {
+	svc := newTestService(t, "http://127.0.0.1:1", "http://127.0.0.1:1")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("capture:\n  quality: 60\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reloads := make(chan struct{}, 10)
+	svc.loadConfig = func() (*config.Config, error) {
+		reloads <- struct{}{}
+		return nil, errors.New("not under test")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	svc.wg.Add(1)
+	go svc.watchConfig(ctx, path)
+	defer func() {
+		cancel()
+		svc.wg.Wait()
+	}()
+
+	// Saving through a rename, as config.Save does, is seen as well
+	deadline := time.After(5 * time.Second)
+	for {
+		next := &config.Config{Capture: config.CaptureConfig{IntervalSeconds: 10, Quality: 70}}
+		if err := next.Save(path); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-reloads:
+			return
+		case <-time.After(500 * time.Millisecond):
+			// The watcher may not have been added yet
+		case <-deadline:
+			t.Fatal("config not reloaded after saving")
+		}
+	}
+}
@@ -3,68 +3,242 @@ package service
 import (
 	"context"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"os"
+	"runtime/debug"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"screen-memory-assistant/internal/capture"
 	"screen-memory-assistant/internal/config"
+	"screen-memory-assistant/internal/events"
 	"screen-memory-assistant/internal/llm"
 	"screen-memory-assistant/internal/memory"
+	"screen-memory-assistant/internal/ratelog"
 )
 
+// screenAnalyzer turns a screen capture into a structured analysis
+type screenAnalyzer interface {
+	AnalyzeCapture(ctx context.Context, imageData []byte, previousContext, windowTitle string) (*llm.AnalysisResult, error)
+}
+
 // Service orchestrates the screen capture and memory pipeline
 type Service struct {
 	config   *config.Config
+	configMu sync.RWMutex
 	capturer *capture.Capturer
 	llm      *llm.Client
-	memory   *memory.Store
+	memory   memory.MemoryStore
+	analyzer screenAnalyzer
+	streamer chatStreamer
 
 	running   bool
+	startedAt time.Time
 	stopChan  chan struct{}
 	wg        sync.WaitGroup
 	lastState string
-	
+
+	// Hash of the last analyzed frame of each display, persisted to
+	// App.StateFile
+	stateMu  sync.Mutex
+	lastHash map[int]string
+	// Fingerprint of the last frame of each display sent for analysis,
+	// for Capture.DedupThreshold
+	lastFingerprint map[int][]uint8
+
 	// Rate limiting for LLM vision requests
 	visionSem chan struct{}
+
+	// Counters for skipped captures/analyses, by reason
+	skips *skipCounters
+
+	// Scheduled captures are skipped while paused (Pause/Resume)
+	paused atomic.Bool
+
+	// Session lock detection; capture pauses while locked
+	sessionLocked func() (bool, error)
+	locked        bool
+
+	// Time since the last user input, for the capture cooldown and idle
+	// detection; away is set while captures are skipped as idle
+	idleTime func() (time.Duration, error)
+	away     bool
+
+	// Title of the foreground window, recorded with each capture
+	activeWindowTitle func() (string, error)
+
+	// after waits out the capture start delay and cron schedule gaps,
+	// and now is the clock schedules are computed from; replaceable in tests
+	after func(d time.Duration) <-chan time.Time
+	now   func() time.Time
+
+	// warmup pings the vision model to keep it loaded
+	warmup func(ctx context.Context) error
+
+	// logger is the leveled logger (App.LogLevel, App.LogFormat); logs
+	// collapses failures repeated on every capture
+	logger *slog.Logger
+	logs   *ratelog.Logger
+
+	// captureFrame grabs the workspace or primary display, captureAll
+	// every display and captureDisplay one by index; replaceable in tests
+	captureFrame   func() (*capture.Capture, error)
+	captureAll     func() ([]*capture.Capture, error)
+	captureDisplay func(display int) (*capture.Capture, error)
+
+	// Running user profile built from recent memories
+	profileMu       sync.Mutex
+	profile         Profile
+	newSinceProfile int
+
+	// Events about stored memories, optionally forwarded to webhooks
+	events   *events.Bus
+	webhooks *events.Webhooks
+
+	// loadConfig reads config.yaml for ReloadConfig; replaceable in tests.
+	// intervalChanged tells the capture loop to reset its ticker.
+	loadConfig      func() (*config.Config, error)
+	onConfigReload  func(cfg *config.Config)
+	intervalChanged chan struct{}
+
+	// dryRunOut receives each dry-run analysis as JSON; nil only logs it
+	dryRunOut io.Writer
+
+	// redactor masks sensitive text in memories before they are stored
+	redactor *redactor
+
+	// Dependency checks, replaceable in tests, and their latest results
+	checkLLM    func(ctx context.Context) error
+	checkMemory func() error
+	health      healthState
 }
 
 // New creates a new service instance
 func New(cfg *config.Config) (*Service, error) {
 	capturer := capture.New(&cfg.Capture)
 	llmClient := llm.NewClient(&cfg.LLM)
-	memoryStore := memory.NewStore(&cfg.Memory)
+	llmClient.SetAnalysisMode(cfg.App.AnalysisMode)
+	llmClient.SetRequestConfidence(cfg.App.ConfidenceThreshold > 0)
+	// A wrong backend would scope stored memories where searches never look
+	memoryStore, err := memory.Open(&cfg.Memory)
+	if err != nil {
+		return nil, fmt.Errorf("memory config: %w", err)
+	}
+	if _, err := llm.ParsePromptTemplate(cfg.LLM.PromptTemplate); err != nil {
+		return nil, fmt.Errorf("llm config: %w", err)
+	}
+	redactor, err := newRedactor(cfg.Capture.RedactPatterns)
+	if err != nil {
+		return nil, fmt.Errorf("capture config: %w", err)
+	}
+
+	logs := ratelog.New(time.Duration(cfg.App.LogRepeatWindowSeconds) * time.Second)
+	capturer.SetLogger(logs)
 
-	return &Service{
+	s := &Service{
 		config:    cfg,
 		capturer:  capturer,
 		llm:       llmClient,
 		memory:    memoryStore,
+		analyzer:  llmClient,
+		streamer:  llmClient,
 		stopChan:  make(chan struct{}),
 		visionSem: make(chan struct{}, 1), // Only 1 vision request at a time
-	}, nil
+		skips:     newSkipCounters(),
+
+		sessionLocked:     capture.IsSessionLocked,
+		idleTime:          capture.IdleTime,
+		activeWindowTitle: capture.ActiveWindowTitle,
+		after:             time.After,
+		now:               time.Now,
+		warmup:            llmClient.Warmup,
+		captureFrame:      capturer.Capture,
+		captureAll:        capturer.CaptureScreen,
+		captureDisplay:    capturer.CaptureDisplay,
+		lastHash:          make(map[int]string),
+		lastFingerprint:   make(map[int][]uint8),
+		logger:            slog.Default(),
+		logs:              logs,
+		loadConfig:        config.Load,
+		intervalChanged:   make(chan struct{}, 1),
+		redactor:          redactor,
+		checkLLM:          llmClient.CheckHealth,
+		checkMemory:       memoryStore.CheckHealth,
+		health:            healthState{status: make(map[string]DependencyStatus)},
+	}
+	s.events, s.webhooks = newEvents(&cfg.Events)
+	s.restoreCaptureState()
+
+	return s, nil
+}
+
+// SetLogger replaces the logger, slog.Default() by default, for the
+// service and the LLM client and memory store it owns. Collapsed repeats
+// are logged as warnings.
+func (s *Service) SetLogger(l *slog.Logger) {
+	s.logger = l
+	s.llm.SetLogger(l)
+	s.memory.SetLogger(l)
+	s.logs.SetOutput(func(msg string) { l.Warn(msg) })
 }
 
 // Run starts the service
 func (s *Service) Run(ctx context.Context) error {
-	// Health checks
-	if err := s.checkDependencies(ctx); err != nil {
-		return fmt.Errorf("dependency check failed: %w", err)
+	// A dependency that is down (e.g. LM Studio still starting) leaves the
+	// app running degraded; healthLoop notices when it comes up
+	healthy := s.checkDependencies(ctx)
+
+	cfg := s.currentConfig()
+
+	switch source := captureSource(cfg); source {
+	case CaptureScreen:
+		s.logger.Info("Screen Memory Assistant started", "capture_interval_seconds", cfg.Capture.IntervalSeconds,
+			"platform", capture.GetPlatform())
+	default:
+		s.logger.Info("Screen Memory Assistant started", "capture_source", source, "periodic_capture", false)
+	}
+	if cfg.App.DryRun {
+		s.logger.Warn("dry run: captures are analyzed but not stored")
 	}
-
-	log.Println("Screen Memory Assistant started")
-	log.Printf("Capture interval: %ds", s.config.Capture.IntervalSeconds)
-	log.Printf("Platform: %s", capture.GetPlatform())
 
 	s.running = true
+	s.startedAt = time.Now()
 
-	// Start capture loop if enabled
-	if s.config.Capture.Enabled {
+	// Start the capture loop even when disabled, paused, so enabling
+	// capture later (Resume) takes effect without a restart
+	if captureSource(cfg) == CaptureScreen {
+		if !cfg.Capture.Enabled {
+			s.Pause()
+		}
 		s.wg.Add(1)
 		go s.captureLoop(ctx)
 	}
 
+	s.wg.Add(1)
+	go s.healthLoop(ctx, healthy)
+
+	// Keep the user profile current
+	if cfg.App.ProfileRefreshMinutes > 0 {
+		s.wg.Add(1)
+		go s.profileLoop(ctx, time.Duration(cfg.App.ProfileRefreshMinutes)*time.Minute)
+	}
+
+	// Apply edits to config.yaml without a restart
+	if cfg.App.WatchConfig {
+		s.wg.Add(1)
+		go s.watchConfig(ctx, configPath)
+	}
+
+	// Keep the local vision model from being unloaded between captures
+	if cfg.LLM.KeepWarmMinutes > 0 {
+		s.wg.Add(1)
+		go s.keepWarmLoop(ctx, time.Duration(cfg.LLM.KeepWarmMinutes)*time.Minute)
+	}
+
 	// Wait for shutdown
 	<-ctx.Done()
 	s.stop()
@@ -76,16 +250,36 @@ func (s *Service) Run(ctx context.Context) error {
 func (s *Service) captureLoop(ctx context.Context) {
 	defer s.wg.Done()
 
-	ticker := time.NewTicker(time.Duration(s.config.Capture.IntervalSeconds) * time.Second)
+	// A cron schedule takes over from the interval
+	if spec := s.currentConfig().App.CaptureCron; spec != "" {
+		s.cronLoop(ctx, spec)
+		return
+	}
+
+	// Let the app finish launching so the first frame shows real work
+	// rather than a splash screen or our own window
+	if delay := time.Duration(s.currentConfig().Capture.StartDelaySeconds) * time.Second; delay > 0 {
+		select {
+		case <-s.after(delay):
+		case <-s.stopChan:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	ticker := time.NewTicker(time.Duration(s.currentConfig().Capture.IntervalSeconds) * time.Second)
 	defer ticker.Stop()
 
-	// Do first capture immediately
-	s.processCapture(ctx)
+	// First capture right away (or as soon as the start delay is over)
+	s.scheduledCapture(ctx)
 
 	for {
 		select {
 		case <-ticker.C:
-			s.processCapture(ctx)
+			s.scheduledCapture(ctx)
+		case <-s.intervalChanged:
+			ticker.Reset(time.Duration(s.currentConfig().Capture.IntervalSeconds) * time.Second)
 		case <-s.stopChan:
 			return
 		case <-ctx.Done():
@@ -96,44 +290,234 @@ func (s *Service) captureLoop(ctx context.Context) {
 
 // processCapture captures screen and optionally processes with LLM
 func (s *Service) processCapture(ctx context.Context) {
-	cap, err := s.capturer.CapturePrimary()
+	// A bad frame must not stop the capture loop
+	defer s.recoverPanic("capture")
+
+	cfg := s.currentConfig()
+
+	// Lock-screen frames are useless and a privacy concern
+	if s.pausedForLock() {
+		s.skips.inc(SkipSessionLocked)
+		return
+	}
+
+	// Nothing changes on screen while the user is away
+	if s.userAway(time.Duration(cfg.Capture.IdleThresholdSeconds) * time.Second) {
+		s.skips.inc(SkipIdle)
+		return
+	}
+
+	// Let the screen settle if the user was just clicking or typing
+	if !s.waitForInputCooldown(ctx, time.Duration(cfg.Capture.InputCooldownMs)*time.Millisecond) {
+		s.skips.inc(SkipCancelled)
+		return
+	}
+
+	captures, err := s.takeCaptures(cfg.Capture.Displays)
 	if err != nil {
-		if s.config.App.Verbose {
-			log.Printf("Capture failed: %v", err)
+		s.skips.inc(SkipCaptureFailed)
+		if cfg.App.Verbose {
+			s.logs.Printf("Capture failed: %v", err)
 		}
 		return
 	}
 
-	if s.config.App.Verbose {
-		log.Printf("Captured display %d (%d bytes)", cap.DisplayNum, len(cap.Compressed))
+	for _, cap := range captures {
+		if !cfg.App.ProcessOnCapture {
+			s.skips.inc(SkipProcessingDisabled)
+			continue
+		}
+
+		// A static screen would store the same memory every interval
+		if s.nearDuplicate(cap, cfg.Capture.DedupThreshold) {
+			s.skips.inc(SkipNearDuplicate)
+			continue
+		}
+
+		// Process with LLM in background; visionSem keeps the analyses
+		// of several displays to one request at a time
+		s.analyzeInBackground(ctx, cap)
+	}
+}
+
+// takeCaptures takes the frames for one capture and tags them with the
+// foreground window's title
+func (s *Service) takeCaptures(displays string) ([]*capture.Capture, error) {
+	captures, err := s.captureFrames(displays)
+	if err != nil {
+		return nil, err
 	}
 
-	if !s.config.App.ProcessOnCapture {
-		return
+	title, titleErr := s.activeWindowTitle()
+	for _, cap := range captures {
+		if titleErr == nil {
+			cap.WindowTitle = title
+		}
+		s.logger.Debug("captured display", "display", cap.DisplayNum, "bytes", len(cap.Compressed), "window", cap.WindowTitle)
+	}
+	return captures, nil
+}
+
+// captureFrames takes the frames for one capture: the primary frame,
+// every display or a single display by index (see Capture.Displays)
+func (s *Service) captureFrames(displays string) ([]*capture.Capture, error) {
+	switch displays {
+	case "", config.DisplaysPrimary:
+		cap, err := s.captureFrame()
+		if err != nil {
+			return nil, err
+		}
+		return []*capture.Capture{cap}, nil
+	case config.DisplaysAll:
+		return s.captureAll()
+	}
+
+	display, err := strconv.Atoi(displays)
+	if err != nil {
+		return nil, fmt.Errorf("invalid capture.displays %q", displays)
 	}
+	cap, err := s.captureDisplay(display)
+	if err != nil {
+		return nil, err
+	}
+	return []*capture.Capture{cap}, nil
+}
 
-	// Process with LLM in background
+// analyzeInBackground runs analyzeAndStore in its own goroutine, recovering
+// from panics so a malformed response can't crash the app
+func (s *Service) analyzeInBackground(ctx context.Context, cap *capture.Capture) {
 	s.wg.Add(1)
 	go func() {
 		defer s.wg.Done()
+		defer s.recoverPanic("analysis")
 		s.analyzeAndStore(ctx, cap)
 	}()
 }
 
+// recoverPanic logs a recovered panic with its stack trace and counts it.
+// It must be called directly by defer.
+func (s *Service) recoverPanic(where string) {
+	if r := recover(); r != nil {
+		s.skips.inc(SkipPanicked)
+		s.logger.Error("recovered from panic", "where", where, "panic", r, "stack", string(debug.Stack()))
+	}
+}
+
+// inputCooldownDelay returns how much longer to wait so that at least
+// cooldown has passed since the last input
+func inputCooldownDelay(idle, cooldown time.Duration) time.Duration {
+	if idle >= cooldown {
+		return 0
+	}
+	return cooldown - idle
+}
+
+// waitForInputCooldown waits out the input cooldown, returning false if
+// the service stopped meanwhile. Without idle information it doesn't wait.
+func (s *Service) waitForInputCooldown(ctx context.Context, cooldown time.Duration) bool {
+	if cooldown <= 0 {
+		return true
+	}
+	idle, err := s.idleTime()
+	if err != nil {
+		return true
+	}
+
+	delay := inputCooldownDelay(idle, cooldown)
+	if delay == 0 {
+		return true
+	}
+
+	select {
+	case <-s.after(delay):
+		return true
+	case <-s.stopChan:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// pausedForLock reports whether the session is locked, logging transitions
+func (s *Service) pausedForLock() bool {
+	locked, err := s.sessionLocked()
+	if err != nil {
+		if s.currentConfig().App.Verbose {
+			s.logs.Printf("Lock state check failed: %v", err)
+		}
+		return false
+	}
+
+	if locked != s.locked {
+		if locked {
+			s.logger.Info("session locked, pausing capture")
+		} else {
+			s.logger.Info("session unlocked, resuming capture")
+		}
+		s.locked = locked
+	}
+
+	return locked
+}
+
+// userAway reports whether there has been no input for threshold, logging
+// transitions in verbose mode. It's never away without idle information.
+func (s *Service) userAway(threshold time.Duration) bool {
+	if threshold <= 0 {
+		return false
+	}
+	idle, err := s.idleTime()
+	if err != nil {
+		return false
+	}
+
+	away := idle >= threshold
+	if away != s.away && s.currentConfig().App.Verbose {
+		if away {
+			s.logs.Printf("No input for %v, skipping captures until activity", idle.Round(time.Second))
+		} else {
+			s.logs.Printf("Input detected, resuming captures")
+		}
+	}
+	s.away = away
+	return away
+}
+
 // analyzeAndStore sends to LLM and stores in memory
 func (s *Service) analyzeAndStore(ctx context.Context, cap *capture.Capture) {
+	s.storeCapture(ctx, cap)
+}
+
+// storeCapture analyzes a capture and stores the memory, returning the
+// analysis. Captures that are deliberately not stored return a
+// *SkipError; in a dry run the analysis is returned without storing it.
+func (s *Service) storeCapture(ctx context.Context, cap *capture.Capture) (*llm.AnalysisResult, error) {
+	// Blocklisted windows (password managers, banking) never reach the model
+	if entry, blocked := blockedBy(cap.WindowTitle, s.currentConfig().Capture.Blocklist); blocked {
+		s.logger.Debug("skipping blocklisted window", "display", cap.DisplayNum, "entry", entry)
+		return nil, s.skip(SkipBlocked, nil)
+	}
+
+	// An unchanged screen has nothing new to remember
+	hash := frameHash(cap.Compressed)
+	if s.unchangedFrame(cap.DisplayNum, hash) {
+		return nil, s.skip(SkipUnchanged, nil)
+	}
+
 	// Rate limit: only 1 vision request at a time to prevent LM Studio overload
 	select {
 	case s.visionSem <- struct{}{}:
 		defer func() { <-s.visionSem }()
 	case <-ctx.Done():
-		return
+		return nil, s.skip(SkipCancelled, ctx.Err())
 	}
 
+	cfg := s.currentConfig()
+
 	// Get recent memories for context
-	memories, err := s.memory.GetRecent(s.config.App.MemoryWindow)
-	if err != nil && s.config.App.Verbose {
-		log.Printf("Failed to get memories: %v", err)
+	memories, err := s.memory.GetRecent(cfg.App.MemoryWindow)
+	if err != nil && cfg.App.Verbose {
+		s.logs.Printf("Failed to get memories: %v", err)
 	}
 
 	// Build context from previous memories
@@ -144,17 +528,34 @@ func (s *Service) analyzeAndStore(ctx context.Context, cap *capture.Capture) {
 	}
 
 	// Analyze with LLM
-	result, err := s.llm.AnalyzeScreen(ctx, cap.Compressed, contextBuilder.String())
+	result, err := s.analyzer.AnalyzeCapture(ctx, cap.Compressed, contextBuilder.String(), cap.WindowTitle)
 	if err != nil {
-		if s.config.App.Verbose {
-			log.Printf("LLM analysis failed: %v", err)
+		if cfg.App.Verbose {
+			s.logs.Printf("LLM analysis failed: %v", err)
 		}
-		return
+		return nil, s.skip(SkipAnalysisFailed, err)
+	}
+
+	// Dry runs show what the model produced without storing anything
+	if cfg.App.DryRun {
+		s.reportDryRun(cap, result)
+		s.recordFrame(cap.DisplayNum, hash, result.Summary)
+		return result, nil
 	}
 
+	// Drop or flag analyses the model itself isn't sure about
+	gate := confidenceGate(result.Confidence, cfg.App.ConfidenceThreshold, cfg.App.LowConfidenceAction)
+	if gate == confidenceSkip {
+		s.logger.Debug("skipping low-confidence analysis", "confidence", *result.Confidence, "summary", result.Summary)
+		return nil, s.skip(SkipLowConfidence, nil)
+	}
+
+	s.redactor.redactAnalysis(result)
+
 	// Create memory content
 	memoryContent := fmt.Sprintf("%s | Context: %s | Intent: %s",
 		result.Summary, result.Context, result.UserIntent)
+	memoryContent, ocrMetadata := mergeOCRText(memoryContent, result.OCRText, cfg.App.OCRMergeStrategy)
 
 	// Store in Mem0
 	metadata := memory.Metadata{
@@ -164,75 +565,144 @@ func (s *Service) analyzeAndStore(ctx context.Context, cap *capture.Capture) {
 		KeyElements: result.KeyElements,
 		UserIntent:  result.UserIntent,
 		DisplayNum:  cap.DisplayNum,
+		OCRText:     ocrMetadata,
+		Source:      memory.SourceCapture,
+		Extra:       result.Metadata,
+		WindowTitle: s.redactor.redact(cap.WindowTitle),
+
+		LowConfidence: gate == confidenceFlag,
+	}
+
+	// A capture continuing the last memory's activity extends it instead
+	// of fragmenting the timeline
+	window := time.Duration(cfg.App.MergeWindowSeconds) * time.Second
+	if prev := mergeTarget(memories, result.Context, cap.Timestamp, window); prev != nil {
+		content, merged := mergeMemory(prev, result.Summary, metadata)
+		if err := s.memory.Update(prev.Collection, prev.ID, content, merged); err == nil {
+			s.recordFrame(cap.DisplayNum, hash, result.Summary)
+			s.logger.Debug("memory merged", "id", prev.ID, "summary", result.Summary)
+			return result, nil
+		} else if cfg.App.Verbose {
+			s.logs.Printf("Failed to merge memory, storing separately: %v", err)
+		}
 	}
 
+	metadata.ImagePath = s.archive(cap)
 	_, err = s.memory.Add(memoryContent, metadata)
 	if err != nil {
-		if s.config.App.Verbose {
-			log.Printf("Failed to store memory: %v", err)
+		if metadata.ImagePath != "" {
+			os.Remove(metadata.ImagePath)
 		}
-		return
+		if cfg.App.Verbose {
+			s.logs.Printf("Failed to store memory: %v", err)
+		}
+		return nil, s.skip(SkipStoreFailed, err)
 	}
 
-	s.lastState = result.Summary
-	if s.config.App.Verbose {
-		log.Printf("Memory stored: %s", result.Summary)
+	s.recordFrame(cap.DisplayNum, hash, result.Summary)
+	s.publishStored(memoryContent, metadata)
+	s.noteNewMemory()
+	s.logger.Debug("memory stored", "summary", result.Summary)
+	return result, nil
+}
+
+// Chat allows conversational interaction with context. The response says
+// which backend answered and whether it was a degraded local fallback.
+func (s *Service) Chat(ctx context.Context, message string) (*llm.ChatResponse, error) {
+	// Generate response
+	resp, err := s.llm.Chat(ctx, message, s.chatMemories(message))
+	if err != nil {
+		return nil, err
 	}
+	s.logger.Debug("chat answered", "backend", resp.Backend, "model", resp.Model, "degraded", resp.Degraded)
+	return resp, nil
+}
+
+// ChatStream answers like Chat, streaming the answer as it is generated;
+// see llm.Client.GenerateResponseStream
+func (s *Service) ChatStream(ctx context.Context, message string) (<-chan llm.Chunk, error) {
+	return s.llm.GenerateResponseStream(ctx, message, s.chatMemories(message))
 }
 
-// Chat allows conversational interaction with context
-func (s *Service) Chat(ctx context.Context, message string) (string, error) {
+// chatMemories returns the contents of the memories relevant to message
+func (s *Service) chatMemories(message string) []string {
 	// Get relevant memories
-	results, err := s.memory.Search(message, s.config.App.MemoryWindow)
+	results, err := s.memory.Search(message, s.currentConfig().App.MemoryWindow)
 	if err != nil {
-		log.Printf("[DEBUG] Memory search failed: %v", err)
-	} else {
-		log.Printf("[DEBUG] Memory search returned %d results", len(results))
-		for i, r := range results {
-			log.Printf("[DEBUG] Result %d: ID=%s, ContentLength=%d, ContentPreview=%.50s", i+1, r.Memory.ID, len(r.Memory.Content), r.Memory.Content)
-		}
+		s.logger.Warn("chat memory search failed", "error", err)
 	}
 
 	// Extract memory contents
 	var memories []string
 	for i, r := range results {
-		log.Printf("[DEBUG] Memory %d: ID=%s, ContentLength=%d, ContentPreview=%.50s", i+1, r.Memory.ID, len(r.Memory.Content), r.Memory.Content)
+		s.logger.Debug("chat memory", "rank", i+1, "id", r.Memory.ID, "length", len(r.Memory.Content),
+			"preview", truncate(r.Memory.Content, 50))
 		memories = append(memories, r.Memory.Content)
 	}
-	log.Printf("[DEBUG] Extracted %d memories for prompt", len(memories))
+	s.logger.Debug("extracted memories for chat prompt", "count", len(memories))
+	return memories
+}
 
-	// Generate response
-	return s.llm.GenerateResponse(ctx, message, memories)
+// AddNote stores a memory entered manually by the user
+func (s *Service) AddNote(content string) (*memory.Memory, error) {
+	metadata := memory.Metadata{
+		Timestamp: time.Now().Format(time.RFC3339),
+		Context:   "note",
+		Source:    memory.SourceManual,
+	}
+	m, err := s.memory.Add(content, metadata)
+	if err != nil {
+		return nil, err
+	}
+	s.publishStored(content, metadata)
+	s.noteNewMemory()
+	return m, nil
 }
 
 // GetStatus returns current service status
 func (s *Service) GetStatus() map[string]interface{} {
+	cfg := s.currentConfig()
+	s.stateMu.Lock()
+	lastState := s.lastState
+	s.stateMu.Unlock()
 	return map[string]interface{}{
 		"running":    s.running,
 		"platform":   capture.GetPlatform(),
-		"last_state": s.lastState,
+		"last_state": lastState,
+		"skipped":    s.skips.snapshot(),
 		"config": map[string]interface{}{
-			"capture_interval": s.config.Capture.IntervalSeconds,
-			"capture_enabled":  s.config.Capture.Enabled,
+			"capture_interval": cfg.Capture.IntervalSeconds,
+			"capture_enabled":  cfg.Capture.Enabled,
+			"capture_paused":   s.Paused(),
+			"capture_source":   captureSource(cfg),
 		},
 	}
 }
 
-// checkDependencies verifies all services are available
-func (s *Service) checkDependencies(ctx context.Context) error {
-	// Check LLM
-	if err := s.llm.CheckHealth(ctx); err != nil {
-		return fmt.Errorf("LLM not available at %s: %w", s.config.LLM.BaseURL, err)
-	}
-	log.Println("✓ LLM connected")
+// currentConfig returns the active configuration snapshot
+func (s *Service) currentConfig() *config.Config {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	return s.config
+}
 
-	// Check Mem0
-	if err := s.memory.CheckHealth(); err != nil {
-		return fmt.Errorf("Mem0 not available at %s: %w", s.config.Memory.BaseURL, err)
-	}
-	log.Println("✓ Mem0 connected")
+// UpdateConfig atomically swaps in a new configuration. Callers must pass
+// a fresh copy rather than mutating the active config in place.
+func (s *Service) UpdateConfig(cfg *config.Config) {
+	s.configMu.Lock()
+	previous := s.config
+	s.config = cfg
+	s.configMu.Unlock()
 
-	return nil
+	s.capturer.SetConfig(&cfg.Capture)
+
+	// The capture loop picks the new interval up on its next wakeup
+	if previous == nil || previous.Capture.IntervalSeconds != cfg.Capture.IntervalSeconds {
+		select {
+		case s.intervalChanged <- struct{}{}:
+		default:
+		}
+	}
 }
 
 // stop gracefully shuts down the service
@@ -240,5 +710,8 @@ func (s *Service) stop() {
 	s.running = false
 	close(s.stopChan)
 	s.wg.Wait()
-	log.Println("Service stopped")
+	s.compactOnShutdown()
+	s.flushEvents()
+	s.logs.Flush()
+	s.logger.Info("service stopped")
 }
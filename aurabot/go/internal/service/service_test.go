@@ -1,9 +1,24 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"screen-memory-assistant/internal/capture"
 	"screen-memory-assistant/internal/config"
+	"screen-memory-assistant/internal/llm"
+	"screen-memory-assistant/internal/memory"
 )
 
 func TestNew(t *testing.T) {
@@ -107,3 +122,478 @@ func TestService_lastStateTracking(t *testing.T) {
 		t.Error("lastState not cleared")
 	}
 }
+
+func TestMergeOCRText_Dedup(t *testing.T) {
+	summary := "User is editing main.go in VS Code | Context: work | Intent: fix build"
+	ocr := "main.go - VS Code\nEditing main.go in VS Code\nfunc handleRequest(w http.ResponseWriter)\nfunc handleRequest(w http.ResponseWriter)"
+
+	content, meta := mergeOCRText(summary, ocr, OCRMergeDedup)
+
+	if meta != "" {
+		t.Errorf("metadata = %q, want empty for dedup", meta)
+	}
+	if strings.Count(content, "VS Code") != 1 {
+		t.Errorf("content repeats text already in summary: %q", content)
+	}
+	if strings.Count(content, "handleRequest") != 1 {
+		t.Errorf("content should contain uncovered OCR line exactly once: %q", content)
+	}
+}
+
+func TestMergeOCRText_Strategies(t *testing.T) {
+	summary := "Reading docs"
+	ocr := "Getting started guide"
+
+	content, meta := mergeOCRText(summary, ocr, OCRMergeMetadata)
+	if content != summary || meta != ocr {
+		t.Errorf("metadata strategy: content=%q meta=%q", content, meta)
+	}
+
+	content, meta = mergeOCRText(summary, ocr, OCRMergeAppend)
+	if !strings.Contains(content, ocr) || meta != "" {
+		t.Errorf("append strategy: content=%q meta=%q", content, meta)
+	}
+
+	content, _ = mergeOCRText(summary, "  ", OCRMergeDedup)
+	if content != summary {
+		t.Errorf("empty OCR should leave summary unchanged, got %q", content)
+	}
+}
+
+func TestService_UpdateConfigConcurrent(t *testing.T) {
+	cfg := &config.Config{
+		Capture: config.CaptureConfig{IntervalSeconds: 30, Quality: 60},
+		App:     config.AppConfig{MemoryWindow: 5},
+	}
+	svc, _ := New(cfg)
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+
+	// Simulated capture loop reading the config
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			c := svc.currentConfig()
+			_ = c.Capture.Quality + c.App.MemoryWindow
+			_ = svc.GetStatus()
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		next := *svc.currentConfig()
+		next.Capture.Quality = 50 + i%50
+		svc.UpdateConfig(&next)
+	}
+	close(done)
+	wg.Wait()
+
+	if got := svc.currentConfig().Capture.Quality; got != 50+99%50 {
+		t.Errorf("Quality = %d, want %d", got, 50+99%50)
+	}
+}
+
+func TestConfidenceGate(t *testing.T) {
+	conf := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name       string
+		confidence *float64
+		threshold  float64
+		action     string
+		want       confidenceDecision
+	}{
+		{"absent confidence stores", nil, 0.6, LowConfidenceSkip, confidenceStore},
+		{"gate disabled", conf(0.1), 0, LowConfidenceSkip, confidenceStore},
+		{"above threshold", conf(0.9), 0.6, LowConfidenceSkip, confidenceStore},
+		{"at threshold", conf(0.6), 0.6, LowConfidenceSkip, confidenceStore},
+		{"below threshold skips", conf(0.3), 0.6, LowConfidenceSkip, confidenceSkip},
+		{"below threshold flags", conf(0.3), 0.6, LowConfidenceFlag, confidenceFlag},
+		{"unknown action skips", conf(0.3), 0.6, "", confidenceSkip},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := confidenceGate(tt.confidence, tt.threshold, tt.action); got != tt.want {
+				t.Errorf("confidenceGate() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessCapture_RecordsWindowTitle(t *testing.T) {
+	var prompt string
+	llmServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		prompt = string(body)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":%q}}]}`, `{"summary": "Editing code", "context": "work"}`)
+	}))
+	defer llmServer.Close()
+
+	var added []memory.Metadata
+	memServer := recordingMem0(&added)
+	defer memServer.Close()
+
+	svc := newTestService(t, llmServer.URL, memServer.URL)
+	svc.sessionLocked = func() (bool, error) { return false, nil }
+	svc.captureFrame = func() (*capture.Capture, error) { return testCapture(), nil }
+	svc.activeWindowTitle = func() (string, error) { return "main.go - Visual Studio Code", nil }
+
+	svc.processCapture(context.Background())
+	svc.wg.Wait()
+
+	if !strings.Contains(prompt, "Active window: main.go - Visual Studio Code") {
+		t.Errorf("analysis prompt doesn't mention the window title: %s", prompt)
+	}
+	if len(added) != 1 || added[0].WindowTitle != "main.go - Visual Studio Code" {
+		t.Errorf("stored metadata = %+v, want the window title", added)
+	}
+}
+
+func TestProcessCapture_AllDisplays(t *testing.T) {
+	// Count concurrent analyses to check they are bounded
+	var inFlight, maxInFlight atomic.Int32
+	llmServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		if n > maxInFlight.Load() {
+			maxInFlight.Store(n)
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":%q}}]}`, `{"summary": "Reading docs", "context": "work"}`)
+	}))
+	defer llmServer.Close()
+
+	var added []memory.Metadata
+	memServer := recordingMem0(&added)
+	defer memServer.Close()
+
+	svc := newTestService(t, llmServer.URL, memServer.URL)
+	svc.config.Capture.Displays = config.DisplaysAll
+	svc.sessionLocked = func() (bool, error) { return false, nil }
+	svc.captureAll = func() ([]*capture.Capture, error) {
+		var captures []*capture.Capture
+		for display := 0; display < 3; display++ {
+			cap := testCapture()
+			cap.DisplayNum = display
+			cap.Compressed = []byte{0xff, 0xd8, byte(display)}
+			captures = append(captures, cap)
+		}
+		return captures, nil
+	}
+
+	svc.processCapture(context.Background())
+	svc.wg.Wait()
+
+	displays := map[int]bool{}
+	for _, m := range added {
+		displays[m.DisplayNum] = true
+	}
+	if len(added) != 3 || !displays[0] || !displays[1] || !displays[2] {
+		t.Errorf("stored metadata = %+v, want one memory per display", added)
+	}
+	if got := maxInFlight.Load(); got != 1 {
+		t.Errorf("%d analyses ran at once, want 1", got)
+	}
+
+	// A specific display captures only that one
+	svc.config.Capture.Displays = "2"
+	var requested []int
+	svc.captureDisplay = func(display int) (*capture.Capture, error) {
+		requested = append(requested, display)
+		return nil, errors.New("display unplugged")
+	}
+	svc.processCapture(context.Background())
+	if len(requested) != 1 || requested[0] != 2 {
+		t.Errorf("captured displays %v, want [2]", requested)
+	}
+	if got := svc.SkipCounts()[SkipCaptureFailed]; got != 1 {
+		t.Errorf("%s = %d, want 1", SkipCaptureFailed, got)
+	}
+}
+
+func TestSources_CaptureAndManual(t *testing.T) {
+	llmServer := fakeLLM(`{"summary": "Reading docs", "context": "work"}`)
+	defer llmServer.Close()
+
+	var added []memory.Metadata
+	memServer := recordingMem0(&added)
+	defer memServer.Close()
+
+	svc := newTestService(t, llmServer.URL, memServer.URL)
+	svc.analyzeAndStore(context.Background(), testCapture())
+	if _, err := svc.AddNote("Dentist on Friday"); err != nil {
+		t.Fatalf("AddNote failed: %v", err)
+	}
+
+	if len(added) != 2 {
+		t.Fatalf("memories added = %d, want 2", len(added))
+	}
+	if added[0].Source != memory.SourceCapture {
+		t.Errorf("capture source = %q, want %q", added[0].Source, memory.SourceCapture)
+	}
+	if added[1].Source != memory.SourceManual {
+		t.Errorf("note source = %q, want %q", added[1].Source, memory.SourceManual)
+	}
+}
+
+func TestProcessCapture_PausedWhileLocked(t *testing.T) {
+	svc := newTestService(t, "http://127.0.0.1:1", "http://127.0.0.1:1")
+
+	locked := true
+	svc.sessionLocked = func() (bool, error) { return locked, nil }
+
+	svc.processCapture(context.Background())
+
+	counts := svc.SkipCounts()
+	if counts[SkipSessionLocked] != 1 {
+		t.Errorf("%s = %d, want 1", SkipSessionLocked, counts[SkipSessionLocked])
+	}
+	if counts[SkipCaptureFailed] != 0 {
+		t.Error("capture should not be attempted while locked")
+	}
+	if !svc.locked {
+		t.Error("expected locked state to be recorded")
+	}
+
+	// Unlocking resumes capture
+	locked = false
+	if svc.pausedForLock() {
+		t.Error("pausedForLock() = true after unlock")
+	}
+	if svc.locked {
+		t.Error("expected unlocked state to be recorded")
+	}
+}
+
+func TestPausedForLock_ErrorDoesNotPause(t *testing.T) {
+	svc := newTestService(t, "http://127.0.0.1:1", "http://127.0.0.1:1")
+	svc.sessionLocked = func() (bool, error) { return true, fmt.Errorf("unavailable") }
+
+	if svc.pausedForLock() {
+		t.Error("pausedForLock() = true when lock state is unknown")
+	}
+}
+
+func TestCaptureLoop_StartDelay(t *testing.T) {
+	svc := newTestService(t, "http://127.0.0.1:1", "http://127.0.0.1:1")
+	svc.config.Capture.IntervalSeconds = 3600
+	svc.config.Capture.StartDelaySeconds = 15
+
+	// Every capture attempt checks the lock first; report locked so no
+	// real screen is grabbed
+	captures := make(chan struct{}, 10)
+	svc.sessionLocked = func() (bool, error) {
+		captures <- struct{}{}
+		return true, nil
+	}
+
+	var waited time.Duration
+	release := make(chan time.Time)
+	svc.after = func(d time.Duration) <-chan time.Time {
+		waited = d
+		return release
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	svc.wg.Add(1)
+	go svc.captureLoop(ctx)
+
+	select {
+	case <-captures:
+		t.Fatal("first capture ran before the start delay elapsed")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release <- time.Now()
+	select {
+	case <-captures:
+	case <-time.After(time.Second):
+		t.Fatal("first capture didn't run after the start delay")
+	}
+
+	cancel()
+	svc.wg.Wait()
+
+	if waited != 15*time.Second {
+		t.Errorf("start delay = %v, want 15s", waited)
+	}
+}
+
+// panickingAnalyzer simulates a bug tripped by a malformed response
+type panickingAnalyzer struct{}
+
+func (panickingAnalyzer) AnalyzeCapture(ctx context.Context, imageData []byte, previousContext, windowTitle string) (*llm.AnalysisResult, error) {
+	var result *llm.AnalysisResult
+	_ = result.Summary // nil dereference
+	return result, nil
+}
+
+func TestAnalyzeInBackground_RecoversPanic(t *testing.T) {
+	var added []memory.Metadata
+	mem0 := recordingMem0(&added)
+	defer mem0.Close()
+	llmServer := fakeLLM(`{"summary": "Reading docs", "context": "work"}`)
+	defer llmServer.Close()
+
+	svc := newTestService(t, llmServer.URL, mem0.URL)
+	realAnalyzer := svc.analyzer
+	svc.analyzer = panickingAnalyzer{}
+
+	svc.analyzeInBackground(context.Background(), testCapture())
+	svc.analyzeInBackground(context.Background(), testCapture())
+	svc.wg.Wait()
+
+	if got := svc.SkipCounts()[SkipPanicked]; got != 2 {
+		t.Errorf("%s = %d, want 2", SkipPanicked, got)
+	}
+
+	// The vision slot was released, so later frames are still analyzed
+	svc.analyzer = realAnalyzer
+	svc.analyzeInBackground(context.Background(), testCapture())
+	svc.wg.Wait()
+
+	if len(added) != 1 {
+		t.Errorf("memories stored after recovery = %d, want 1", len(added))
+	}
+}
+
+func TestProcessCapture_RecoversPanic(t *testing.T) {
+	svc := newTestService(t, "http://127.0.0.1:1", "http://127.0.0.1:1")
+	svc.sessionLocked = func() (bool, error) { panic("lock query crashed") }
+
+	svc.processCapture(context.Background())
+	svc.processCapture(context.Background())
+
+	if got := svc.SkipCounts()[SkipPanicked]; got != 2 {
+		t.Errorf("%s = %d, want 2", SkipPanicked, got)
+	}
+}
+
+func TestInputCooldownDelay(t *testing.T) {
+	cooldown := 800 * time.Millisecond
+	tests := []struct {
+		idle time.Duration
+		want time.Duration
+	}{
+		{0, 800 * time.Millisecond},
+		{300 * time.Millisecond, 500 * time.Millisecond},
+		{800 * time.Millisecond, 0},
+		{time.Minute, 0},
+	}
+	for _, tt := range tests {
+		if got := inputCooldownDelay(tt.idle, cooldown); got != tt.want {
+			t.Errorf("inputCooldownDelay(%v, %v) = %v, want %v", tt.idle, cooldown, got, tt.want)
+		}
+	}
+}
+
+func TestProcessCapture_SkipsWhileIdle(t *testing.T) {
+	svc := newTestService(t, "http://127.0.0.1:1", "http://127.0.0.1:1")
+	svc.config.Capture.IdleThresholdSeconds = 300
+	svc.config.App.ProcessOnCapture = false
+	svc.sessionLocked = func() (bool, error) { return false, nil }
+
+	captures := 0
+	svc.captureFrame = func() (*capture.Capture, error) {
+		captures++
+		return testCapture(), nil
+	}
+
+	// Away for ten minutes: skipped without grabbing the screen
+	svc.idleTime = func() (time.Duration, error) { return 10 * time.Minute, nil }
+	svc.processCapture(context.Background())
+	if captures != 0 || svc.SkipCounts()[SkipIdle] != 1 {
+		t.Errorf("captures = %d, %s = %d; want 0 and 1", captures, SkipIdle, svc.SkipCounts()[SkipIdle])
+	}
+
+	// Back at the keyboard, or idle time unknown: captures resume
+	svc.idleTime = func() (time.Duration, error) { return time.Second, nil }
+	svc.processCapture(context.Background())
+	svc.idleTime = func() (time.Duration, error) { return 0, fmt.Errorf("unsupported") }
+	svc.processCapture(context.Background())
+	if captures != 2 {
+		t.Errorf("captures = %d after activity, want 2", captures)
+	}
+}
+
+func TestWaitForInputCooldown(t *testing.T) {
+	svc := newTestService(t, "http://127.0.0.1:1", "http://127.0.0.1:1")
+
+	var waited []time.Duration
+	svc.after = func(d time.Duration) <-chan time.Time {
+		waited = append(waited, d)
+		ch := make(chan time.Time, 1)
+		ch <- time.Now()
+		return ch
+	}
+
+	// Input 200ms ago: wait the remaining 300ms
+	svc.idleTime = func() (time.Duration, error) { return 200 * time.Millisecond, nil }
+	if !svc.waitForInputCooldown(context.Background(), 500*time.Millisecond) {
+		t.Fatal("waitForInputCooldown() = false, want true")
+	}
+
+	// Idle long enough, or idle time unknown: capture right away
+	svc.idleTime = func() (time.Duration, error) { return time.Second, nil }
+	svc.waitForInputCooldown(context.Background(), 500*time.Millisecond)
+	svc.idleTime = func() (time.Duration, error) { return 0, fmt.Errorf("unsupported") }
+	svc.waitForInputCooldown(context.Background(), 500*time.Millisecond)
+
+	if len(waited) != 1 || waited[0] != 300*time.Millisecond {
+		t.Errorf("waited %v, want a single 300ms wait", waited)
+	}
+
+	// Stopping during the wait abandons the capture
+	svc.idleTime = func() (time.Duration, error) { return 0, nil }
+	svc.after = func(time.Duration) <-chan time.Time { return nil }
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if svc.waitForInputCooldown(ctx, 500*time.Millisecond) {
+		t.Error("waitForInputCooldown() = true after cancel, want false")
+	}
+}
+
+func TestProcessCapture_SkipsNearDuplicates(t *testing.T) {
+	svc := newTestService(t, "http://127.0.0.1:1", "http://127.0.0.1:1")
+	svc.config.Capture.DedupThreshold = 0.98
+	svc.sessionLocked = func() (bool, error) { return false, nil }
+
+	frame := func(shade uint8, cursor bool) *capture.Capture {
+		img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+		for i := range img.Pix {
+			img.Pix[i] = shade
+		}
+		if cursor {
+			img.Set(20, 20, color.White)
+		}
+		cap := testCapture()
+		cap.Image = img
+		return cap
+	}
+	frames := []*capture.Capture{frame(100, false), frame(100, true), frame(240, false), frame(101, false)}
+	svc.captureFrame = func() (*capture.Capture, error) {
+		cap := frames[0]
+		frames = frames[1:]
+		return cap, nil
+	}
+
+	for range frames {
+		svc.processCapture(context.Background())
+	}
+	svc.wg.Wait()
+
+	// The blinking cursor is skipped; the bright frame is new, and the last
+	// one is compared with the bright frame rather than the first
+	if got := svc.SkipCounts()[SkipNearDuplicate]; got != 1 {
+		t.Errorf("%s = %d, want 1", SkipNearDuplicate, got)
+	}
+}
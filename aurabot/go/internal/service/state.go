@@ -0,0 +1,145 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"screen-memory-assistant/internal/capture"
+)
+
+// captureState is persisted between runs so change detection survives a
+// restart
+type captureState struct {
+	// Hashes holds the last processed frame of each display
+	Hashes    map[int]string `json:"hashes"`
+	Summary   string         `json:"summary"`
+	UpdatedAt time.Time      `json:"updated_at"`
+
+	// Hash and Display are the single-frame form older versions wrote
+	Hash    string `json:"hash,omitempty"`
+	Display int    `json:"display,omitempty"`
+}
+
+// frameHash identifies a captured frame by its encoded bytes
+func frameHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// loadCaptureState reads the state file. A missing or corrupt file yields
+// an empty state, so the next frame is simply analyzed.
+func loadCaptureState(path string) captureState {
+	var state captureState
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Warn("reading capture state", "path", path, "error", err)
+		}
+		return state
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		slog.Warn("ignoring corrupt capture state", "path", path, "error", err)
+		return captureState{}
+	}
+	if state.Hash != "" {
+		if state.Hashes == nil {
+			state.Hashes = make(map[int]string)
+		}
+		if _, ok := state.Hashes[state.Display]; !ok {
+			state.Hashes[state.Display] = state.Hash
+		}
+		state.Hash, state.Display = "", 0
+	}
+	return state
+}
+
+// saveCaptureState writes the state file atomically
+func saveCaptureState(path string, state captureState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("marshaling capture state: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("writing capture state: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("replacing capture state: %w", err)
+	}
+	return nil
+}
+
+// restoreCaptureState loads the last processed frame from the state file
+func (s *Service) restoreCaptureState() {
+	path := s.currentConfig().App.StateFile
+	if path == "" {
+		return
+	}
+
+	state := loadCaptureState(path)
+	s.stateMu.Lock()
+	for display, hash := range state.Hashes {
+		s.lastHash[display] = hash
+	}
+	if s.lastState == "" {
+		s.lastState = state.Summary
+	}
+	s.stateMu.Unlock()
+}
+
+// unchangedFrame reports whether hash matches the last processed frame of
+// the display
+func (s *Service) unchangedFrame(display int, hash string) bool {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	return hash != "" && hash == s.lastHash[display]
+}
+
+// nearDuplicate reports whether cap looks like the last frame sent for
+// analysis, with a similarity of at least threshold (0 disables the
+// check). Otherwise cap becomes the frame later ones are compared with.
+func (s *Service) nearDuplicate(cap *capture.Capture, threshold float64) bool {
+	if threshold <= 0 || cap.Image == nil {
+		return false
+	}
+	fp := capture.Fingerprint(cap.Image)
+
+	s.stateMu.Lock()
+	similarity := capture.Similarity(fp, s.lastFingerprint[cap.DisplayNum])
+	duplicate := similarity >= threshold
+	if !duplicate {
+		s.lastFingerprint[cap.DisplayNum] = fp
+	}
+	s.stateMu.Unlock()
+
+	if duplicate {
+		s.logger.Debug("skipping capture similar to the last analyzed frame", "similarity", similarity)
+	}
+	return duplicate
+}
+
+// recordFrame remembers the processed frame of a display and persists
+// the last frame of every display
+func (s *Service) recordFrame(display int, hash, summary string) {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	s.lastHash[display] = hash
+	s.lastState = summary
+
+	path := s.currentConfig().App.StateFile
+	if path == "" {
+		return
+	}
+	// Saved under the lock so displays finishing together can't write
+	// an older snapshot over a newer one
+	state := captureState{Hashes: s.lastHash, Summary: summary, UpdatedAt: time.Now()}
+	if err := saveCaptureState(path, state); err != nil {
+		s.logger.Warn("saving capture state", "error", err)
+	}
+}
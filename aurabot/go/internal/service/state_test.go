@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"screen-memory-assistant/internal/capture"
+	"screen-memory-assistant/internal/memory"
+)
+
+func TestCaptureState_SurvivesRestart(t *testing.T) {
+	var added []memory.Metadata
+	mem0 := recordingMem0(&added)
+	defer mem0.Close()
+	llmServer := fakeLLM(`{"summary": "Reading docs", "context": "work"}`)
+	defer llmServer.Close()
+
+	statePath := filepath.Join(t.TempDir(), "capture_state.json")
+
+	// First run analyzes the frame and persists its hash
+	first := newTestService(t, llmServer.URL, mem0.URL)
+	first.config.App.StateFile = statePath
+	first.analyzeAndStore(context.Background(), testCapture())
+	if len(added) != 1 {
+		t.Fatalf("first run stored %d memories, want 1", len(added))
+	}
+
+	// After a restart the identical frame is recognized
+	second := newTestService(t, llmServer.URL, mem0.URL)
+	second.config.App.StateFile = statePath
+	second.restoreCaptureState()
+	second.analyzeAndStore(context.Background(), testCapture())
+
+	if len(added) != 1 {
+		t.Errorf("identical frame after restart stored again: %d memories", len(added))
+	}
+	if got := second.SkipCounts()[SkipUnchanged]; got != 1 {
+		t.Errorf("%s = %d, want 1", SkipUnchanged, got)
+	}
+	if got := second.GetStatus()["last_state"]; got != "Reading docs" {
+		t.Errorf("restored last_state = %v, want 'Reading docs'", got)
+	}
+}
+
+func TestCaptureState_CorruptFile(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "capture_state.json")
+	if err := os.WriteFile(statePath, []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if state := loadCaptureState(statePath); len(state.Hashes) != 0 {
+		t.Errorf("corrupt state loaded hashes %v, want none", state.Hashes)
+	}
+	if state := loadCaptureState(filepath.Join(t.TempDir(), "missing.json")); len(state.Hashes) != 0 {
+		t.Errorf("missing state loaded hashes %v, want none", state.Hashes)
+	}
+}
+
+func TestCaptureState_KeyedByDisplay(t *testing.T) {
+	var added []memory.Metadata
+	mem0 := recordingMem0(&added)
+	defer mem0.Close()
+	llmServer := fakeLLM(`{"summary": "Reading docs", "context": "work"}`)
+	defer llmServer.Close()
+
+	statePath := filepath.Join(t.TempDir(), "capture_state.json")
+	frame := func(display int, data byte) *capture.Capture {
+		cap := testCapture()
+		cap.DisplayNum = display
+		cap.Compressed = []byte{0xff, 0xd8, data}
+		return cap
+	}
+
+	// Each display's save must keep the other's frame
+	first := newTestService(t, llmServer.URL, mem0.URL)
+	first.config.App.StateFile = statePath
+	first.analyzeAndStore(context.Background(), frame(0, 1))
+	first.analyzeAndStore(context.Background(), frame(1, 2))
+
+	second := newTestService(t, llmServer.URL, mem0.URL)
+	second.config.App.StateFile = statePath
+	second.restoreCaptureState()
+	second.analyzeAndStore(context.Background(), frame(0, 1))
+	second.analyzeAndStore(context.Background(), frame(1, 2))
+
+	if len(added) != 2 {
+		t.Errorf("stored %d memories, want 2 with both frames unchanged after restart", len(added))
+	}
+	if got := second.SkipCounts()[SkipUnchanged]; got != 2 {
+		t.Errorf("%s = %d, want 2", SkipUnchanged, got)
+	}
+}
+
+func TestCaptureState_ReadsSingleDisplayFile(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "capture_state.json")
+	if err := os.WriteFile(statePath, []byte(`{"hash":"abc","display":1,"summary":"Reading docs"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	state := loadCaptureState(statePath)
+	if len(state.Hashes) != 1 || state.Hashes[1] != "abc" {
+		t.Errorf("hashes = %v, want display 1 = abc", state.Hashes)
+	}
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"screen-memory-assistant/internal/memory"
+)
+
+// summaryScanLimit bounds how many recent memories a daily summary reads
+const summaryScanLimit = 500
+
+// summaryContext marks stored daily summaries
+const summaryContext = "daily_summary"
+
+const summarySystemPrompt = "You summarize a user's day from their screen activity history. Group related activities, mention what was accomplished and what was left open. Be concise."
+
+// chatStreamer streams a chat completion chunk by chunk
+type chatStreamer interface {
+	StreamChat(ctx context.Context, systemPrompt, userPrompt string, onChunk func(string)) (string, error)
+}
+
+// DailySummary summarizes the memories from day, streaming the text to
+// onChunk as it is generated. With store set, the finished summary is
+// saved as a memory; a cancelled summary is never stored.
+func (s *Service) DailySummary(ctx context.Context, day time.Time, store bool, onChunk func(string)) (string, error) {
+	memories, err := s.memory.GetRecent(summaryScanLimit)
+	if err != nil {
+		return "", fmt.Errorf("loading memories: %w", err)
+	}
+
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	end := start.AddDate(0, 0, 1)
+
+	var today []memory.Memory
+	for _, m := range memories {
+		t := m.Time()
+		if t.Before(start) || !t.Before(end) || m.Metadata.Context == summaryContext {
+			continue
+		}
+		today = append(today, m)
+	}
+	if len(today) == 0 {
+		return "", fmt.Errorf("no memories for %s", start.Format("2006-01-02"))
+	}
+	sort.Slice(today, func(i, j int) bool { return today[i].Time().Before(today[j].Time()) })
+
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "Activity on %s:\n", start.Format("Monday, January 2"))
+	for _, m := range today {
+		fmt.Fprintf(&prompt, "- [%s] %s\n", m.Time().In(day.Location()).Format("15:04"), m.Content)
+	}
+	prompt.WriteString("\nSummarize this day.")
+
+	summary, err := s.streamer.StreamChat(ctx, summarySystemPrompt, prompt.String(), onChunk)
+	if err != nil {
+		return summary, err
+	}
+
+	if store {
+		metadata := memory.Metadata{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Context:   summaryContext,
+			Source:    memory.SourceManual,
+		}
+		if _, err := s.memory.Add(summary, metadata); err != nil {
+			return summary, fmt.Errorf("storing summary: %w", err)
+		}
+	}
+
+	return summary, nil
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"screen-memory-assistant/internal/memory"
+)
+
+// dayMem0 serves memories from today and yesterday and records additions
+func dayMem0(now time.Time, added *[]memory.Metadata) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			var payload struct {
+				Metadata memory.Metadata `json:"metadata"`
+			}
+			json.NewDecoder(r.Body).Decode(&payload)
+			*added = append(*added, payload.Metadata)
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		json.NewEncoder(w).Encode([]memory.Memory{
+			{ID: "old", Content: "Yesterday's standup", Metadata: memory.Metadata{Timestamp: now.AddDate(0, 0, -1).Format(time.RFC3339)}},
+			{ID: "today", Content: "Reviewed the ingest PR", Metadata: memory.Metadata{Timestamp: now.Format(time.RFC3339)}},
+		})
+	}))
+}
+
+func TestDailySummary_StreamsChunks(t *testing.T) {
+	now := time.Now()
+	var added []memory.Metadata
+	memServer := dayMem0(now, &added)
+	defer memServer.Close()
+
+	svc := newTestService(t, "http://127.0.0.1:1", memServer.URL)
+	streamer := &fakeStreamer{chunks: []string{"You reviewed ", "the ingest ", "PR."}}
+	svc.streamer = streamer
+
+	var got []string
+	summary, err := svc.DailySummary(context.Background(), now, true, func(chunk string) {
+		got = append(got, chunk)
+	})
+	if err != nil {
+		t.Fatalf("DailySummary failed: %v", err)
+	}
+
+	if len(got) != 3 {
+		t.Errorf("chunks = %q, want 3", got)
+	}
+	if summary != "You reviewed the ingest PR." {
+		t.Errorf("summary = %q", summary)
+	}
+	if !strings.Contains(streamer.prompt, "ingest PR") || strings.Contains(streamer.prompt, "standup") {
+		t.Errorf("prompt should only cover today: %q", streamer.prompt)
+	}
+	if len(added) != 1 || added[0].Context != summaryContext {
+		t.Errorf("stored = %+v, want one daily summary", added)
+	}
+}
+
+func TestDailySummary_CancelledMidStream(t *testing.T) {
+	now := time.Now()
+	var added []memory.Metadata
+	memServer := dayMem0(now, &added)
+	defer memServer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	svc := newTestService(t, "http://127.0.0.1:1", memServer.URL)
+	svc.streamer = &fakeStreamer{chunks: []string{"one ", "two ", "three"}, cancelAfter: 2, cancel: cancel}
+
+	summary, err := svc.DailySummary(ctx, now, true, func(string) {})
+	if err != context.Canceled {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if summary != "one two " {
+		t.Errorf("partial summary = %q, want %q", summary, "one two ")
+	}
+	if len(added) != 0 {
+		t.Errorf("cancelled summary was stored: %+v", added)
+	}
+}
@@ -2,21 +2,35 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"screen-memory-assistant/internal/config"
+	"screen-memory-assistant/internal/logging"
 	"screen-memory-assistant/internal/service"
 )
 
 func main() {
+	dryRun := flag.Bool("dry-run", false, "analyze captures and print the results without storing them")
+	verbose := flag.Bool("verbose", false, "enable debug logging")
+	flag.Parse()
+
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	if *dryRun {
+		cfg.App.DryRun = true
+	}
+	if *verbose {
+		cfg.App.Verbose = true
+	}
+	slog.SetDefault(logging.New(os.Stderr, &cfg.App))
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -29,6 +43,9 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to create service: %v", err)
 	}
+	if cfg.App.DryRun {
+		svc.SetDryRunOutput(os.Stdout)
+	}
 
 	go func() {
 		<-sigChan